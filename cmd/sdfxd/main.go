@@ -0,0 +1,353 @@
+//-----------------------------------------------------------------------------
+/*
+
+sdfxd: A headless render service.
+
+POST a serialized SDF3 (see MarshalSDF3) to /render and get back the result:
+
+  curl --data-binary @part.json "localhost:8080/render?format=stl&cells=200" > part.stl
+
+Or render a model from a plugin (GET or POST, no body):
+
+  curl "localhost:8080/render?plugin=bracket&format=3mf" > bracket.3mf
+
+Query parameters:
+
+format: "stl" (ASCII STL), "3mf" or "png" (a shaded top view preview)
+cells: number of cells (or pixels) on the longest axis
+plugin: the name of a model plugin (instead of a serialized SDF3)
+
+STL output is streamed: the triangles are written to the response as the
+octree renderer generates them, so the client gets the first triangles while
+the rest of the model is still rendering and the server doesn't hold the
+mesh. Binary STL has the triangle count at the start, so the stream is ASCII
+STL. 3MF output has a vertex list ahead of the triangles, so the mesh is
+rendered into memory and then encoded. PNG previews are a top view, the shade
+is the height of the surface.
+
+A render that takes longer than the timeout (or whose client goes away) is
+abandoned. If nothing has been sent the request fails with 503 Service
+Unavailable, a streamed STL response is cut off (it has no "endsolid").
+
+Plugins: With -plugins dir the server loads each dir/*.so at startup. A plugin
+is a Go plugin (go build -buildmode=plugin) that exports the model function:
+
+  func Model() sdf.SDF3
+
+The plugin name is the file name without ".so". A plugin must be built with
+the same Go toolchain and sdfx version as the server.
+
+*/
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"path/filepath"
+	"plugin"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// limits on the requests
+var max_cells = flag.Int("max-cells", 400, "maximum number of cells on the longest axis")
+var max_body = flag.Int64("max-body", 1<<20, "maximum size of a request body (bytes)")
+var render_timeout = flag.Duration("timeout", time.Minute, "maximum render time")
+var read_timeout = flag.Duration("read-timeout", 30*time.Second, "maximum time to read a request")
+var write_timeout = flag.Duration("write-timeout", 30*time.Second, "maximum time to write a response (after the render)")
+
+//-----------------------------------------------------------------------------
+
+// An SDF3 that gives up when a context is done. The distance is then infinite,
+// so the renderers skip the rest of the model and return quickly.
+type bounded_sdf3 struct {
+	SDF3
+	done int32 // set when the context is done
+}
+
+// Return an SDF3 that gives up when the context is done.
+func bounded(ctx context.Context, s SDF3) *bounded_sdf3 {
+	b := &bounded_sdf3{SDF3: s}
+	go func() {
+		<-ctx.Done()
+		atomic.StoreInt32(&b.done, 1)
+	}()
+	return b
+}
+
+func (s *bounded_sdf3) Evaluate(p V3) float64 {
+	if atomic.LoadInt32(&s.done) != 0 {
+		return math.Inf(1)
+	}
+	return s.SDF3.Evaluate(p)
+}
+
+//-----------------------------------------------------------------------------
+
+// Return a top view of an SDF3, shaded by the height of the surface.
+func preview(s SDF3, pixels int) (image.Image, error) {
+	bb := RefineBB(s, REFINE_ITERATIONS)
+	size := bb.Size()
+	k := float64(pixels) / math.Max(size.X, size.Y)
+	grid := V2i{int(math.Ceil(size.X * k)), int(math.Ceil(size.Y * k))}
+	m, err := NewMap2(Box2{V2{bb.Min.X, bb.Min.Y}, V2{bb.Max.X, bb.Max.Y}}, grid, true)
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewGray(image.Rect(0, 0, grid[0], grid[1]))
+	eps := 0.5 / k
+	for j := 0; j < grid[1]; j++ {
+		for i := 0; i < grid[0]; i++ {
+			// trace a ray down from the top of the box
+			p := m.ToV2(V2i{i, j})
+			z := bb.Max.Z
+			for z >= bb.Min.Z {
+				d := s.Evaluate(V3{p.X, p.Y, z})
+				if d < eps {
+					// light is high
+					c := 64 + 191*(z-bb.Min.Z)/math.Max(size.Z, EPSILON)
+					img.SetGray(i, j, color.Gray{uint8(math.Min(c, 255))})
+					break
+				}
+				z -= d
+			}
+		}
+	}
+	return img, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// A writer that records if anything has been written.
+type sent_writer struct {
+	io.Writer
+	sent bool
+}
+
+func (w *sent_writer) Write(p []byte) (int, error) {
+	w.sent = true
+	return w.Writer.Write(p)
+}
+
+// Stream the triangles of an SDF3 as an ASCII STL file.
+// The triangles are written as the renderer generates them. The final
+// "endsolid" is only written if the render wasn't abandoned.
+func stream_stl(ctx context.Context, cancel context.CancelFunc, w io.Writer, s SDF3, cells int) error {
+	resolution := s.BoundingBox().Size().MaxComponent() / float64(cells)
+	buf := bufio.NewWriter(w)
+	output := make(chan *Triangle3)
+	done := make(chan error)
+	go func() {
+		_, err := io.WriteString(buf, "solid sdfx\n")
+		for t := range output {
+			if err != nil {
+				// keep reading until the render stops
+				continue
+			}
+			n := t.Normal()
+			_, err = fmt.Fprintf(buf, "facet normal %g %g %g\nouter loop\nvertex %g %g %g\nvertex %g %g %g\nvertex %g %g %g\nendloop\nendfacet\n",
+				float32(n.X), float32(n.Y), float32(n.Z),
+				float32(t.V[0].X), float32(t.V[0].Y), float32(t.V[0].Z),
+				float32(t.V[1].X), float32(t.V[1].Y), float32(t.V[1].Z),
+				float32(t.V[2].X), float32(t.V[2].Y), float32(t.V[2].Z))
+			if err != nil {
+				// the client has gone, stop the render
+				cancel()
+			}
+		}
+		done <- err
+	}()
+	MarchingCubes_Octree(s, resolution, output)
+	close(output)
+	if err := <-done; err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if _, err := io.WriteString(buf, "endsolid sdfx\n"); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+//-----------------------------------------------------------------------------
+
+// models loaded from plugins, by name
+var plugins = make(map[string]func() SDF3)
+
+// Load the model plugins in a directory.
+func load_plugins(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		p, err := plugin.Open(path)
+		if err != nil {
+			return err
+		}
+		sym, err := p.Lookup("Model")
+		if err != nil {
+			return err
+		}
+		var model func() SDF3
+		switch f := sym.(type) {
+		case func() SDF3:
+			model = f
+		case *func() SDF3:
+			model = *f
+		default:
+			return fmt.Errorf("%s: Model is a %T, not a func() SDF3", path, sym)
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".so")
+		plugins[name] = model
+		log.Printf("loaded plugin %s", name)
+	}
+	return nil
+}
+
+// Return the model of a plugin.
+func plugin_model(name string) (s SDF3, err error) {
+	model, ok := plugins[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown plugin \"%s\"", name)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			s, err = nil, fmt.Errorf("plugin %s: %v", name, r)
+		}
+	}()
+	if s = model(); s == nil {
+		return nil, fmt.Errorf("plugin %s: no model", name)
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
+
+func render(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	name := q.Get("plugin")
+	if name == "" && r.Method != http.MethodPost {
+		http.Error(w, "POST a serialized SDF3", http.StatusMethodNotAllowed)
+		return
+	}
+	format := q.Get("format")
+	cells := 200
+	if x := q.Get("cells"); x != "" {
+		n, err := strconv.Atoi(x)
+		if err != nil || n <= 0 || n > *max_cells {
+			http.Error(w, fmt.Sprintf("cells must be 1..%d", *max_cells), http.StatusBadRequest)
+			return
+		}
+		cells = n
+	}
+	var sdf SDF3
+	if name != "" {
+		var err error
+		sdf, err = plugin_model(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, *max_body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		sdf, err = UnmarshalSDF3(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	// the render stops when the time is up or the client goes away
+	ctx, cancel := context.WithTimeout(r.Context(), *render_timeout)
+	defer cancel()
+	s := bounded(ctx, sdf)
+	switch format {
+	case "stl", "":
+		w.Header().Set("Content-Type", "model/stl")
+		sw := &sent_writer{Writer: w}
+		if err := stream_stl(ctx, cancel, sw, s, cells); err != nil {
+			if !sw.sent && ctx.Err() != nil {
+				http.Error(w, "render: "+ctx.Err().Error(), http.StatusServiceUnavailable)
+				return
+			}
+			// the status has been sent, cut off the response
+			log.Printf("render: %s", err)
+			panic(http.ErrAbortHandler)
+		}
+	case "3mf":
+		mesh := RenderMesh(s, cells)
+		if ctx.Err() != nil {
+			http.Error(w, "render: "+ctx.Err().Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "model/3mf")
+		if err := Encode3MF(w, []*Object3MF{{Name: name, Mesh: mesh.Triangles()}}, nil); err != nil {
+			log.Printf("render: %s", err)
+		}
+	case "png":
+		img, err := preview(s, cells)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if ctx.Err() != nil {
+			http.Error(w, "render: "+ctx.Err().Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		if err := png.Encode(w, img); err != nil {
+			log.Printf("render: %s", err)
+		}
+	default:
+		http.Error(w, fmt.Sprintf("unsupported format \"%s\"", format), http.StatusBadRequest)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	dir := flag.String("plugins", "", "directory of model plugins")
+	flag.Parse()
+	if *dir != "" {
+		if err := load_plugins(*dir); err != nil {
+			log.Fatal(err)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/render", render)
+	srv := &http.Server{
+		Addr:        *addr,
+		Handler:     mux,
+		ReadTimeout: *read_timeout,
+		// the response is written during or after the render
+		WriteTimeout: *render_timeout + *write_timeout,
+	}
+	log.Printf("listening on %s", *addr)
+	log.Fatal(srv.ListenAndServe())
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,100 @@
+//-----------------------------------------------------------------------------
+/*
+
+Animations
+
+Render a sequence of raymarched frames (see RenderImage) and save them as an
+animated GIF. The camera orbits the model (a turntable), the model is rebuilt
+for each frame from an animation parameter, or both.
+
+The build function is called with t = i/frames for frame i, so t runs over
+[0, 1) and the animation loops without repeating a frame. The camera is fixed
+to fit the models of all the frames.
+
+GIF frames are limited to 256 colors, the frames are dithered to the Plan 9
+palette.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"os"
+)
+
+//-----------------------------------------------------------------------------
+
+type AnimationParms struct {
+	Frames    int       // number of frames
+	Delay     int       // delay between frames (1/100 s)
+	Azimuth   float64   // camera azimuth for the first frame (radians)
+	Turns     float64   // camera turns over the animation (0 for a fixed camera)
+	Elevation float64   // camera elevation (radians)
+	Fov       float64   // camera field of view (radians), 0 for orthographic
+	Pixels    V2i       // image size
+	Lighting  *Lighting // lighting (nil for DefaultLighting)
+}
+
+// Animate returns the frames of an animation. build returns the model for
+// the animation parameter t.
+func Animate(build func(t float64) SDF3, k *AnimationParms) []*image.RGBA {
+	if k.Frames < 1 {
+		panic("invalid number of frames")
+	}
+	// build the models, the camera fits all of them
+	models := make([]SDF3, k.Frames)
+	var bb Box3
+	for i := range models {
+		models[i] = build(float64(i) / float64(k.Frames))
+		if i == 0 {
+			bb = models[i].BoundingBox()
+		} else {
+			bb = bb.Extend(models[i].BoundingBox())
+		}
+	}
+	frames := make([]*image.RGBA, k.Frames)
+	for i, s := range models {
+		t := float64(i) / float64(k.Frames)
+		az := k.Azimuth + k.Turns*TAU*t
+		c := ViewCamera(bb, az, k.Elevation, k.Fov, k.Pixels)
+		frames[i] = RenderImage(s, c, k.Lighting)
+	}
+	return frames
+}
+
+// Turntable returns the frames of a camera orbiting a model.
+func Turntable(s SDF3, k *AnimationParms) []*image.RGBA {
+	return Animate(func(t float64) SDF3 { return s }, k)
+}
+
+// SaveGIF saves a sequence of frames as a looping animated GIF.
+func SaveGIF(path string, frames []*image.RGBA, delay int) error {
+	anim := gif.GIF{}
+	for _, f := range frames {
+		img := image.NewPaletted(f.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(img, f.Bounds(), f, image.Point{})
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, delay)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(file, &anim); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// RenderGIF renders an animation to a GIF file.
+func RenderGIF(build func(t float64) SDF3, k *AnimationParms, path string) error {
+	return SaveGIF(path, Animate(build, k), k.Delay)
+}
+
+//-----------------------------------------------------------------------------
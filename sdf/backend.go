@@ -0,0 +1,316 @@
+//-----------------------------------------------------------------------------
+/*
+
+Evaluation Backends
+
+The marching cubes renderer samples the SDF3 on a grid a layer at a time. By
+default the samples are evaluated on the CPU with a goroutine per core. A
+backend can take over the sampling, E.g. by compiling the CSG tree into an
+OpenCL kernel or compute shader and evaluating each layer on a GPU.
+
+A backend compiles an SDF3 into a batch function. If there is no backend, or
+the backend can't compile the SDF3 (E.g. it has a node type the backend
+doesn't support), the renderer falls back to the CPU.
+
+The grid renderer (MarchingCubes) evaluates each layer as a batch. The octree
+renderer (MarchingCubes_Octree, RenderSTL) works a level at a time with a
+backend: the cube centers of each level are evaluated as a batch, and then the
+corners of the smallest cubes. It evaluates the same points as without a
+backend, so the mesh is the same (the order of the triangles is different).
+
+CPUBackend compiles the primitive and CSG nodes (box, sphere, cylinder,
+transforms, scaling, union, difference, intersection, offset and shell) into
+batch functions. Each node is evaluated over the whole batch in a loop, rather
+than walking the tree for each point, and a large batch is split across the
+CPU cores. Each goroutine has its own scratch buffers, they are reused from
+batch to batch. The distances are the same as Evaluate. The octree renderer is
+otherwise single threaded, so a complex tree on a multi-core machine gains the
+most, for a simple tree the batching overhead can outweigh the gain.
+
+	SetBackend(CPUBackend{})
+
+The OpenCL backend (the sdf/opencl package, built with -tags opencl) compiles
+the same nodes into an OpenCL kernel (see OpenCLSource) and evaluates the
+batches on a GPU. The kernel works in single precision, so the mesh can differ
+slightly from a CPU render. It needs cgo and the OpenCL library, so it isn't part of the
+default build:
+
+	if b, err := opencl.NewBackend(); err == nil {
+		SetBackend(b)
+	}
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+//-----------------------------------------------------------------------------
+
+// BatchFunc evaluates an SDF3 at a batch of points, out[i] = sdf(p[i]).
+type BatchFunc func(p []V3, out []float64)
+
+// Backend compiles SDF3s for batch evaluation.
+type Backend interface {
+	Name() string
+	Compile(s SDF3) (BatchFunc, error)
+}
+
+var backend_lock sync.Mutex
+var backend Backend
+
+// SetBackend sets the evaluation backend (nil for the CPU).
+func SetBackend(b Backend) {
+	backend_lock.Lock()
+	backend = b
+	backend_lock.Unlock()
+}
+
+// GetBackend returns the evaluation backend (nil for the CPU).
+func GetBackend() Backend {
+	backend_lock.Lock()
+	defer backend_lock.Unlock()
+	return backend
+}
+
+// Return the backend batch function for an SDF3, or nil to use the CPU.
+func compile_batch(s SDF3) BatchFunc {
+	b := GetBackend()
+	if b == nil {
+		return nil
+	}
+	f, err := b.Compile(s)
+	if err != nil {
+		return nil
+	}
+	return f
+}
+
+//-----------------------------------------------------------------------------
+// CPU Backend
+
+// minimum number of points per core for a parallel batch
+const CPU_BATCH_MIN = 256
+
+// CPUBackend compiles the primitive and CSG nodes of an SDF3 for batch
+// evaluation on the CPU.
+type CPUBackend struct{}
+
+// Name returns the name of the backend.
+func (CPUBackend) Name() string {
+	return "cpu"
+}
+
+// Compile returns the batch function for an SDF3.
+// A large batch is split across the CPU cores.
+func (CPUBackend) Compile(s SDF3) (BatchFunc, error) {
+	c := cpu_compiler{}
+	f, err := c.compile(s)
+	if err != nil {
+		return nil, err
+	}
+	// scratch buffers, one set per goroutine
+	pool := sync.Pool{New: func() interface{} {
+		return &cpu_buffers{make([][]V3, c.nv), make([][]float64, c.nf)}
+	}}
+	run := func(p []V3, out []float64) {
+		b := pool.Get().(*cpu_buffers)
+		f(p, out, b)
+		pool.Put(b)
+	}
+	return func(p []V3, out []float64) {
+		ncpu := runtime.NumCPU()
+		if len(p) < CPU_BATCH_MIN*ncpu {
+			run(p, out)
+			return
+		}
+		n := (len(p) + ncpu - 1) / ncpu
+		var wg sync.WaitGroup
+		for i := 0; i < len(p); i += n {
+			j := i + n
+			if j > len(p) {
+				j = len(p)
+			}
+			wg.Add(1)
+			go func(i, j int) {
+				defer wg.Done()
+				run(p[i:j], out[i:j])
+			}(i, j)
+		}
+		wg.Wait()
+	}, nil
+}
+
+// cpu_buffers are the scratch buffers of a compiled SDF3.
+// Each node that needs a buffer is given its own slot when it is compiled.
+type cpu_buffers struct {
+	v [][]V3
+	f [][]float64
+}
+
+// Return point buffer i with length n.
+func (b *cpu_buffers) points(i, n int) []V3 {
+	if cap(b.v[i]) < n {
+		b.v[i] = make([]V3, n)
+	}
+	return b.v[i][:n]
+}
+
+// Return distance buffer i with length n.
+func (b *cpu_buffers) distances(i, n int) []float64 {
+	if cap(b.f[i]) < n {
+		b.f[i] = make([]float64, n)
+	}
+	return b.f[i][:n]
+}
+
+// cpu_func evaluates a compiled node with a set of scratch buffers.
+type cpu_func func(p []V3, out []float64, b *cpu_buffers)
+
+// cpu_compiler allocates the buffer slots as the nodes are compiled.
+type cpu_compiler struct {
+	nv, nf int // number of point and distance buffers
+}
+
+// Return the batch function for an SDF3 node (and the nodes below it).
+func (c *cpu_compiler) compile(s SDF3) (cpu_func, error) {
+	switch s := s.(type) {
+	case *BoxSDF3:
+		return func(p []V3, out []float64, _ *cpu_buffers) {
+			for i := range p {
+				out[i] = sdf_box3d(p[i], s.size) - s.round
+			}
+		}, nil
+	case *SphereSDF3:
+		return func(p []V3, out []float64, _ *cpu_buffers) {
+			for i := range p {
+				out[i] = p[i].Length() - s.radius
+			}
+		}, nil
+	case *CylinderSDF3:
+		return func(p []V3, out []float64, _ *cpu_buffers) {
+			for i, v := range p {
+				out[i] = sdf_box2d(V2{V2{v.X, v.Y}.Length(), v.Z}, V2{s.radius, s.height}) - s.round
+			}
+		}, nil
+	case *TransformSDF3:
+		return c.compile_points(s.sdf, func(p V3) V3 {
+			return s.inverse.MulPosition(p)
+		}, 1)
+	case *ScaleUniformSDF3:
+		return c.compile_points(s.sdf, func(p V3) V3 {
+			return p.MulScalar(s.inv_k)
+		}, s.k)
+	case *ScaleNonUniformSDF3:
+		return c.compile_points(s.sdf, func(p V3) V3 {
+			return p.Mul(s.inv_k)
+		}, s.k_min)
+	case *UnionSDF3:
+		fs := make([]cpu_func, len(s.sdf))
+		for i, x := range s.sdf {
+			f, err := c.compile(x)
+			if err != nil {
+				return nil, err
+			}
+			fs[i] = f
+		}
+		k := c.nf
+		c.nf++
+		return func(p []V3, out []float64, b *cpu_buffers) {
+			fs[0](p, out, b)
+			d := b.distances(k, len(p))
+			for _, f := range fs[1:] {
+				f(p, d, b)
+				for i := range out {
+					out[i] = s.min(out[i], d[i])
+				}
+			}
+		}, nil
+	case *DifferenceSDF3:
+		return c.compile_pair(s.s0, s.s1, func(d0, d1 float64) float64 {
+			return s.max(d0, -d1)
+		})
+	case *IntersectionSDF3:
+		return c.compile_pair(s.s0, s.s1, func(d0, d1 float64) float64 {
+			return s.max(d0, d1)
+		})
+	case *OffsetSDF3:
+		f, err := c.compile(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		return func(p []V3, out []float64, b *cpu_buffers) {
+			f(p, out, b)
+			for i := range out {
+				out[i] -= s.offset
+			}
+		}, nil
+	case *ShellSDF3:
+		f, err := c.compile(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		return func(p []V3, out []float64, b *cpu_buffers) {
+			f(p, out, b)
+			for i := range out {
+				out[i] = Abs(out[i]) - s.delta
+			}
+		}, nil
+	case *refined_sdf3:
+		return c.compile(s.SDF3)
+	case *refined_interval_sdf3:
+		return c.compile(s.SDF3)
+	}
+	return nil, fmt.Errorf("cpu backend: unsupported node %T", s)
+}
+
+// Return the batch function for a node that maps the points and scales the distance.
+func (c *cpu_compiler) compile_points(s SDF3, f func(p V3) V3, k float64) (cpu_func, error) {
+	fs, err := c.compile(s)
+	if err != nil {
+		return nil, err
+	}
+	j := c.nv
+	c.nv++
+	return func(p []V3, out []float64, b *cpu_buffers) {
+		q := b.points(j, len(p))
+		for i := range p {
+			q[i] = f(p[i])
+		}
+		fs(q, out, b)
+		if k != 1 {
+			for i := range out {
+				out[i] *= k
+			}
+		}
+	}, nil
+}
+
+// Return the batch function for a node that combines the distances of two nodes.
+func (c *cpu_compiler) compile_pair(s0, s1 SDF3, f func(d0, d1 float64) float64) (cpu_func, error) {
+	f0, err := c.compile(s0)
+	if err != nil {
+		return nil, err
+	}
+	f1, err := c.compile(s1)
+	if err != nil {
+		return nil, err
+	}
+	k := c.nf
+	c.nf++
+	return func(p []V3, out []float64, b *cpu_buffers) {
+		f0(p, out, b)
+		d := b.distances(k, len(p))
+		f1(p, d, b)
+		for i := range out {
+			out[i] = f(out[i], d[i])
+		}
+	}, nil
+}
+
+//-----------------------------------------------------------------------------
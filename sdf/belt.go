@@ -0,0 +1,246 @@
+//-----------------------------------------------------------------------------
+/*
+
+Belt and Chain Paths
+
+A closed belt runs around a set of pulleys in order. Each pulley is a circle
+at the pitch radius of the belt. The belt wraps counter-clockwise around a
+pulley by default, pulleys on the back side of the belt (E.g. idlers) wrap
+clockwise.
+
+The belt path is a set of tangent lines between the pulleys and arcs around
+each pulley. A signed radius (+ve counter-clockwise, -ve clockwise) gives the
+tangent lines for all cases.
+
+Timing belt pulleys are generated for GT2/GT3 and HTD belt profiles. The
+grooves are a slot with a round bottom at the standard groove depth. The pulley
+OD is inside the belt pitch line by the pitch line differential.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf/roots"
+)
+
+//-----------------------------------------------------------------------------
+
+type BeltPulley struct {
+	Center V2      // pulley center
+	Radius float64 // pitch radius
+	CW     bool    // the belt wraps clockwise around the pulley
+}
+
+// Return the signed radius of the pulley.
+func (p *BeltPulley) signed_radius() float64 {
+	if p.CW {
+		return -p.Radius
+	}
+	return p.Radius
+}
+
+// Return the start/end points of the tangent line between two pulleys.
+func belt_tangent(p0, p1 *BeltPulley) (V2, V2, error) {
+	r0 := p0.signed_radius()
+	r1 := p1.signed_radius()
+	d := p1.Center.Sub(p0.Center)
+	l := d.Length()
+	k := l*l - (r0-r1)*(r0-r1)
+	if k <= 0 {
+		return V2{}, V2{}, fmt.Errorf("no belt tangent between pulleys at %v and %v", p0.Center, p1.Center)
+	}
+	// belt direction
+	dn := d.DivScalar(l)
+	dp := V2{-dn.Y, dn.X}
+	u := dn.MulScalar(math.Sqrt(k)).Add(dp.MulScalar(r0 - r1)).DivScalar(l)
+	// normal to the belt direction (right hand side)
+	n := V2{u.Y, -u.X}
+	return p0.Center.Add(n.MulScalar(r0)), p1.Center.Add(n.MulScalar(r1)), nil
+}
+
+// Return the length of belt wrapped around a pulley between two points.
+func (p *BeltPulley) wrap(t0, t1 V2) float64 {
+	a0 := math.Atan2(t0.Y-p.Center.Y, t0.X-p.Center.X)
+	a1 := math.Atan2(t1.Y-p.Center.Y, t1.X-p.Center.X)
+	da := a1 - a0
+	if p.CW {
+		da = -da
+	}
+	da = math.Mod(da, TAU)
+	if da < 0 {
+		da += TAU
+	}
+	return p.Radius * da
+}
+
+// BeltLength returns the length of a closed belt around a set of pulleys.
+func BeltLength(pulleys []BeltPulley) (float64, error) {
+	n := len(pulleys)
+	if n < 2 {
+		return 0, fmt.Errorf("a belt needs at least 2 pulleys")
+	}
+	// tangent points leaving and arriving at each pulley
+	leave := make([]V2, n)
+	arrive := make([]V2, n)
+	length := 0.0
+	for i := range pulleys {
+		j := (i + 1) % n
+		t0, t1, err := belt_tangent(&pulleys[i], &pulleys[j])
+		if err != nil {
+			return 0, err
+		}
+		leave[i] = t0
+		arrive[j] = t1
+		length += t1.Sub(t0).Length()
+	}
+	for i := range pulleys {
+		length += pulleys[i].wrap(arrive[i], leave[i])
+	}
+	return length, nil
+}
+
+// BeltTeeth returns the number of teeth (or chain links) for a belt length.
+// The length is rounded up to a whole number of teeth.
+func BeltTeeth(length, pitch float64) int {
+	return int(math.Ceil(length/pitch - EPSILON))
+}
+
+// IdlerPosition returns the center of the i-th pulley that gives a belt length.
+// The pulley moves along a line from p0 to p1 (E.g. an adjustment slot).
+func IdlerPosition(pulleys []BeltPulley, i int, p0, p1 V2, length float64) (V2, error) {
+	k := make([]BeltPulley, len(pulleys))
+	copy(k, pulleys)
+	var err error
+	f := func(t float64) float64 {
+		k[i].Center = p0.Add(p1.Sub(p0).MulScalar(t))
+		l, e := BeltLength(k)
+		if e != nil {
+			err = e
+		}
+		return l - length
+	}
+	f0 := f(0)
+	f1 := f(1)
+	if err != nil {
+		return V2{}, err
+	}
+	if Sign(f0) == Sign(f1) {
+		return V2{}, fmt.Errorf("belt length %f is not reachable between %v and %v", length, p0, p1)
+	}
+	t, e := roots.Brent(f, 0, 1, EPSILON)
+	if e != nil {
+		return V2{}, e
+	}
+	return p0.Add(p1.Sub(p0).MulScalar(t)), err
+}
+
+//-----------------------------------------------------------------------------
+// Timing Belt Pulleys
+
+type belt_profile struct {
+	pitch float64 // tooth to tooth distance
+	depth float64 // groove depth
+	round float64 // radius at the bottom of the groove
+	pld   float64 // pitch line differential (belt pitch line to pulley OD)
+}
+
+var belt_profiles = map[string]belt_profile{
+	"GT2":   {2, 0.75, 0.555, 0.254},
+	"GT3":   {3, 1.14, 0.85, 0.381},
+	"HTD3M": {3, 1.17, 0.85, 0.381},
+	"HTD5M": {5, 2.06, 1.49, 0.5715},
+	"HTD8M": {8, 3.38, 2.4, 0.686},
+}
+
+type PulleyParms struct {
+	Profile   string  // belt profile: "GT2", "GT3", "HTD3M", "HTD5M", "HTD8M"
+	Teeth     int     // number of pulley teeth
+	Width     float64 // width of the toothed part
+	Flange    V2      // flange radius (past the teeth) and thickness (0 for none)
+	Boss      V2      // set screw boss radius and height (0 for none)
+	Bore      float64 // shaft diameter
+	SetScrew  string  // set screw thread (E.g. "M3")
+	Clearance float64 // clearance added to the grooves and bore
+}
+
+// Return the belt profile for the pulley.
+func (k *PulleyParms) profile() belt_profile {
+	p, ok := belt_profiles[k.Profile]
+	if !ok {
+		panic("belt profile not found")
+	}
+	return p
+}
+
+// PitchRadius returns the pitch radius of the pulley (the belt pitch line).
+// Use this radius for the belt path.
+func (k *PulleyParms) PitchRadius() float64 {
+	return float64(k.Teeth) * k.profile().pitch / TAU
+}
+
+// Pulley2D returns the 2D toothed profile of a timing belt pulley.
+// The first groove is on the x-axis.
+func Pulley2D(k *PulleyParms) SDF2 {
+	p := k.profile()
+	if k.Teeth < 6 {
+		panic("invalid number of teeth")
+	}
+	if k.Clearance < 0 {
+		panic("invalid clearance")
+	}
+	r := k.PitchRadius() - p.pld
+	// the groove is a slot with a round bottom
+	rg := p.round + k.Clearance
+	rc := r - p.depth - k.Clearance + rg
+	l := 2.0 * (r - rc)
+	groove := Transform2D(Line2D(l, rg), Translate2d(V2{rc + 0.5*l, 0}))
+	return Difference2D(Circle2D(r), RotateCopy2D(groove, k.Teeth))
+}
+
+// Pulley3D returns a timing belt pulley. The toothed part runs from z = 0 to
+// z = width with the flanges on each side. The set screw boss is below the
+// bottom flange, the set screw is on the x-axis.
+func Pulley3D(k *PulleyParms) SDF3 {
+	if k.Width <= 0 || k.Flange.X < 0 || k.Flange.Y < 0 || k.Boss.X < 0 || k.Boss.Y < 0 {
+		panic("invalid pulley parameters")
+	}
+	r := k.PitchRadius() - k.profile().pld
+	if k.Bore <= 0 || 0.5*k.Bore+k.Clearance >= r-k.profile().depth {
+		panic("invalid pulley bore")
+	}
+	parts := []SDF3{Transform3D(Extrude3D(Pulley2D(k), k.Width), Translate3d(V3{0, 0, 0.5 * k.Width}))}
+	f := k.Flange.Y
+	if k.Flange.X > 0 && f > 0 {
+		flange := Cylinder3D(f, r+k.Flange.X, 0)
+		parts = append(parts, Transform3D(flange, Translate3d(V3{0, 0, -0.5 * f})))
+		parts = append(parts, Transform3D(flange, Translate3d(V3{0, 0, k.Width + 0.5*f})))
+	} else {
+		f = 0
+	}
+	var cutters []SDF3
+	if k.Boss.X > 0 && k.Boss.Y > 0 {
+		boss := Cylinder3D(k.Boss.Y, k.Boss.X, 0)
+		z := -f - 0.5*k.Boss.Y
+		parts = append(parts, Transform3D(boss, Translate3d(V3{0, 0, z})))
+		if k.SetScrew != "" {
+			spec, err := ParseThread(k.SetScrew)
+			if err != nil {
+				panic(err)
+			}
+			spec.Internal = true
+			screw := Thread3D(spec, &ThreadParms{Length: k.Boss.X, LeadIn: 0.5 * spec.Thread.Pitch})
+			screw = Transform3D(screw, Translate3d(V3{0, 0, z}).Mul(RotateY(0.5*PI)))
+			cutters = append(cutters, screw)
+		}
+	}
+	h := 2.0 * (k.Width + 2.0*f + k.Boss.Y)
+	cutters = append(cutters, Cylinder3D(2.0*h, 0.5*k.Bore+k.Clearance, 0))
+	return Difference3D(Union3D(parts...), Union3D(cutters...))
+}
+
+//-----------------------------------------------------------------------------
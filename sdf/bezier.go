@@ -155,6 +155,39 @@ func (s *BezierSpline) f0(t float64) V2 {
 	return V2{s.px.f0(t), s.py.f0(t)}
 }
 
+// Return the 1st derivative for a given t value.
+func (s *BezierSpline) f1(t float64) V2 {
+	return V2{s.px.f1(t), s.py.f1(t)}
+}
+
+// Return the 2nd derivative for a given t value.
+func (s *BezierSpline) f2(t float64) V2 {
+	return V2{s.px.f2(t), s.py.f2(t)}
+}
+
+// Return the parameter range of the spline.
+func (s *BezierSpline) domain() (float64, float64) {
+	return 0, 1
+}
+
+// Return the polynomial coefficients.
+func (p *BezierPolynomial) coefficients() []float64 {
+	return []float64{p.a, p.b, p.c, p.d, p.e}[:p.n+1]
+}
+
+// Return the bounding box of the spline on [t0, t1].
+func (s *BezierSpline) bound(t0, t1 float64) Box2 {
+	x0, x1 := poly_bound(s.px.coefficients(), t0, t1)
+	y0, y1 := poly_bound(s.py.coefficients(), t0, t1)
+	return Box2{V2{x0, y0}, V2{x1, y1}}
+}
+
+// MinDistance2 returns the minimum distance squared between a point
+// and the spline, and the t value at which it occurs.
+func (s *BezierSpline) MinDistance2(p V2) (float64, float64) {
+	return CurveMinDistance2(s, p)
+}
+
 // Generate polygon samples for a bezier spline.
 func (s *BezierSpline) Sample(p *Polygon, t0, t1 float64, p0, p1 V2, n int) {
 
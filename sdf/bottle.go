@@ -0,0 +1,132 @@
+//-----------------------------------------------------------------------------
+/*
+
+Bottle Caps
+
+Screw caps for standard bottle neck finishes.
+
+GPI/SPI "400" finishes are named <T diameter>-400 (E.g. "28-400"), they have a
+shallow single start thread. PCO 1881 is the common soda bottle finish with a
+3 start thread. The dimensions are nominal values:
+
+T: thread outside diameter
+E: thread root diameter
+H: height of the finish (cap skirt)
+
+Vented Caps
+
+A vented cap for filament dry boxes. The top of the cap is a mesh of square
+holes so air reaches the bottle contents. An optional chamber above the mesh
+holds desiccant beads, the mesh keeps them out of the bottle.
+
+The cap opening is on the z = 0 plane, the cap is above it.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+// Bottle Neck Finishes
+
+type bottle_finish struct {
+	t      float64 // thread outside diameter
+	e      float64 // thread root diameter
+	h      float64 // finish height
+	pitch  float64 // thread pitch
+	starts int     // number of thread starts
+}
+
+var bottle_db = map[string]bottle_finish{
+	"24-400":  {23.88, 21.84, 9.09, 25.4 / 8.0, 1},
+	"28-400":  {27.74, 25.40, 9.45, 25.4 / 6.0, 1},
+	"33-400":  {32.64, 30.35, 9.86, 25.4 / 6.0, 1},
+	"38-400":  {37.59, 35.05, 9.86, 25.4 / 6.0, 1},
+	"PCO1881": {27.43, 25.07, 11.70, 2.7, 3},
+}
+
+// Return the bottle finish dimensions.
+func bottle_lookup(name string) bottle_finish {
+	b, ok := bottle_db[name]
+	if !ok {
+		panic("bottle finish not found")
+	}
+	return b
+}
+
+//-----------------------------------------------------------------------------
+// Mesh
+
+// Return a circular mesh of square holes.
+// The holes have side length hole and are separated by bars of width bar.
+func mesh_2d(radius, hole, bar float64) SDF2 {
+	if hole <= 0 || bar <= 0 {
+		panic("invalid mesh")
+	}
+	pitch := hole + bar
+	n := int(math.Ceil(radius/pitch)) + 1
+	l := 2.0 * (radius + pitch)
+	var bars []SDF2
+	for i := -n; i <= n; i++ {
+		// the bars are centered between the holes
+		x := (float64(i) + 0.5) * pitch
+		bars = append(bars, Transform2D(Box2D(V2{bar, l}, 0), Translate2d(V2{x, 0})))
+		bars = append(bars, Transform2D(Box2D(V2{l, bar}, 0), Translate2d(V2{0, x})))
+	}
+	return Difference2D(Circle2D(radius), Union2D(bars...))
+}
+
+//-----------------------------------------------------------------------------
+// Vented Caps
+
+type VentCapParms struct {
+	Finish    string  // bottle neck finish (E.g. "28-400", "PCO1881")
+	Wall      float64 // wall thickness
+	Tolerance float64 // radial clearance on the thread
+	Mesh      V2      // mesh hole size and bar width
+	Chamber   float64 // height of the desiccant chamber above the mesh (0 for none)
+}
+
+// VentCap3D returns a vented bottle cap with an internal mesh wall.
+func VentCap3D(k *VentCapParms) SDF3 {
+	b := bottle_lookup(k.Finish)
+	if k.Wall <= 0 || k.Tolerance < 0 || k.Chamber < 0 {
+		panic("invalid cap parameters")
+	}
+	r := 0.5*b.t + k.Tolerance
+	r_cap := r + k.Wall
+	h := b.h + k.Wall + k.Chamber
+	cap := Cylinder3D(h, r_cap, 0)
+	cap = Transform3D(cap, Translate3d(V3{0, 0, 0.5 * h}))
+
+	// internal thread: the cutter is the shape of the bottle neck
+	// (the same trapezoidal profile as the luer lock thread)
+	depth := 0.5 * (b.t - b.e)
+	pitch := b.pitch
+	neck := Screw3D(luer_thread(r, depth, pitch), b.h, pitch, b.starts)
+	neck = Transform3D(neck, Translate3d(V3{0, 0, 0.5 * b.h}))
+	// the opening is clear of the thread
+	mouth := Cylinder3D(2.0*pitch, r, 0)
+
+	// mesh wall across the top of the neck
+	r_mesh := 0.5*b.e - k.Wall
+	if r_mesh <= k.Mesh.X {
+		panic("mesh holes are too large for the bottle")
+	}
+	mesh := Extrude3D(mesh_2d(r_mesh, k.Mesh.X, k.Mesh.Y), 4.0*k.Wall)
+	mesh = Transform3D(mesh, Translate3d(V3{0, 0, b.h + 0.5*k.Wall}))
+	cutters := []SDF3{neck, mouth, mesh}
+
+	// desiccant chamber, open at the top
+	if k.Chamber > 0 {
+		chamber := Cylinder3D(2.0*k.Chamber, r, 0)
+		chamber = Transform3D(chamber, Translate3d(V3{0, 0, h}))
+		cutters = append(cutters, chamber)
+	}
+
+	return Difference3D(cap, Union3D(cutters...))
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,122 @@
+//-----------------------------------------------------------------------------
+/*
+
+Distance Field Cache
+
+Sample an expensive SDF3 (E.g. a spline shape, a lofted or swept solid) once
+on a regular grid, and evaluate it by trilinear interpolation of the samples.
+A union of many copies of the subtree then costs a grid lookup per copy.
+
+The grid covers the bounding box with a margin of one grid step. Inside the
+grid the result is the interpolated distance, the error is small when the
+resolution is small compared with the features of the solid. Outside the grid
+the result is a lower bound on the distance, and it's continuous with the
+interpolated distance at the edge of the grid.
+
+Interpolation rounds off sharp edges and corners to about the resolution. The
+field between samples is not exactly a distance field, its gradient can be up
+to sqrt(3) at creases.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"runtime"
+	"sync"
+)
+
+//-----------------------------------------------------------------------------
+
+type CacheSDF3 struct {
+	sdf    SDF3
+	res    float64 // grid step
+	margin float64 // grid margin around the bounding box
+	n      V3i     // number of samples on each axis
+	grid   Box3    // grid extent
+	d      []float64
+	bb     Box3
+}
+
+// Cache3D returns an SDF3 that interpolates samples of an SDF3 on a grid
+// with a given resolution.
+func Cache3D(sdf SDF3, resolution float64) SDF3 {
+	if resolution <= 0 {
+		panic("invalid resolution")
+	}
+	s := CacheSDF3{sdf: sdf, res: resolution, margin: resolution}
+	s.bb = sdf.BoundingBox()
+	cells := s.bb.Size().AddScalar(2 * s.margin).DivScalar(resolution).Ceil().ToV3i()
+	s.n = cells.AddScalar(1)
+	size := cells.ToV3().MulScalar(resolution)
+	s.grid = NewBox3(s.bb.Center(), size)
+	s.d = make([]float64, s.n[0]*s.n[1]*s.n[2])
+	// sample the SDF3 on the grid, a z-plane at a time
+	planes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range planes {
+				for j := 0; j < s.n[1]; j++ {
+					for i := 0; i < s.n[0]; i++ {
+						p := s.grid.Min.Add(V3{float64(i), float64(j), float64(k)}.MulScalar(resolution))
+						s.d[s.index(i, j, k)] = sdf.Evaluate(p)
+					}
+				}
+			}
+		}()
+	}
+	for k := 0; k < s.n[2]; k++ {
+		planes <- k
+	}
+	close(planes)
+	wg.Wait()
+	return &s
+}
+
+// Return the sample index for grid coordinates.
+func (s *CacheSDF3) index(i, j, k int) int {
+	return i + s.n[0]*(j+s.n[1]*k)
+}
+
+// Return the interpolated distance at a point within the grid.
+func (s *CacheSDF3) interpolate(p V3) float64 {
+	// grid coordinates
+	g := p.Sub(s.grid.Min).DivScalar(s.res)
+	i := int(Clamp(g.X, 0, float64(s.n[0]-2)))
+	j := int(Clamp(g.Y, 0, float64(s.n[1]-2)))
+	k := int(Clamp(g.Z, 0, float64(s.n[2]-2)))
+	// fractional position in the cell
+	u := g.Sub(V3{float64(i), float64(j), float64(k)})
+	lerp := func(a, b, t float64) float64 { return a + t*(b-a) }
+	x00 := lerp(s.d[s.index(i, j, k)], s.d[s.index(i+1, j, k)], u.X)
+	x10 := lerp(s.d[s.index(i, j+1, k)], s.d[s.index(i+1, j+1, k)], u.X)
+	x01 := lerp(s.d[s.index(i, j, k+1)], s.d[s.index(i+1, j, k+1)], u.X)
+	x11 := lerp(s.d[s.index(i, j+1, k+1)], s.d[s.index(i+1, j+1, k+1)], u.X)
+	return lerp(lerp(x00, x10, u.Y), lerp(x01, x11, u.Y), u.Z)
+}
+
+// Evaluate returns the interpolated distance to the cached SDF3.
+func (s *CacheSDF3) Evaluate(p V3) float64 {
+	q := p.Max(s.grid.Min).Min(s.grid.Max)
+	d := s.interpolate(q)
+	if q == p {
+		return d
+	}
+	// Outside the grid: The solid is at least margin inside the grid, so the
+	// distance is at least the distance to the grid plus the margin. The
+	// distance is also at least d - |p - q|, which makes it continuous at
+	// the edge of the grid.
+	x := p.Sub(q).Length()
+	return Max(x+s.margin, d-x)
+}
+
+// BoundingBox returns the bounding box of the cached SDF3.
+func (s *CacheSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
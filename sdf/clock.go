@@ -0,0 +1,249 @@
+//-----------------------------------------------------------------------------
+/*
+
+Clock Escapements and Gear Trains
+
+The escapement is a Graham (deadbeat) escapement. The escape wheel has
+pointed teeth with the tooth faces leaning forward, it turns counter-clockwise.
+The anchor pivots above the wheel and its pallets span a number of teeth.
+
+The pallet locking faces are arcs about the anchor pivot, so the wheel does
+not recoil while a tooth rests on a pallet. The entry pallet locks on its
+outer face, the exit pallet locks on its inner face. The impulse face at the
+end of each pallet gives the anchor a lift angle as the tooth slides across it.
+
+The gear train helper finds wheel and pinion counts for the train between
+the hands and the escape wheel, or for the motion work between the minute
+and hour hands.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"math"
+)
+
+//-----------------------------------------------------------------------------
+// Escape Wheels
+
+type EscapeWheelParms struct {
+	Teeth  int     // number of teeth
+	Radius float64 // radius at the tooth tips
+	Depth  float64 // tooth depth
+	Lean   float64 // lean of the tooth face forward of radial (radians)
+}
+
+// Return the angle (from the tooth tip) to the root of the tooth face.
+func (k *EscapeWheelParms) face_angle() float64 {
+	r := k.Radius - k.Depth
+	// the face is a line from the tip at the lean angle to the root circle
+	s := k.Radius * math.Sin(k.Lean)
+	t := k.Radius*math.Cos(k.Lean) - math.Sqrt(r*r-s*s)
+	p := V2{k.Radius, 0}.Add(V2{-math.Cos(k.Lean), math.Sin(k.Lean)}.MulScalar(t))
+	return math.Atan2(p.Y, p.X)
+}
+
+// EscapeWheel2D returns the 2D profile of a deadbeat escape wheel.
+// The wheel turns counter-clockwise, the first tooth tip is on the x-axis.
+func EscapeWheel2D(k *EscapeWheelParms) SDF2 {
+	if k.Teeth < 3 {
+		panic("invalid number of teeth")
+	}
+	if k.Radius <= 0 || k.Depth <= 0 || k.Depth >= k.Radius {
+		panic("invalid escape wheel size")
+	}
+	if k.Lean < 0 || k.Lean >= DtoR(45) || k.Radius*math.Sin(k.Lean) >= k.Radius-k.Depth {
+		panic("invalid tooth lean")
+	}
+	r := k.Radius - k.Depth
+	a := k.face_angle()
+	pitch := TAU / float64(k.Teeth)
+	// the back of the tooth runs to the face root of the previous tooth
+	tooth := Polygon2D([]V2{
+		{0, 0},
+		PolarToXY(r, a-pitch),
+		{k.Radius, 0},
+		PolarToXY(r, a),
+	})
+	return Union2D(RotateCopy2D(tooth, k.Teeth), Circle2D(r))
+}
+
+//-----------------------------------------------------------------------------
+// Deadbeat Anchors
+
+type AnchorParms struct {
+	Wheel *EscapeWheelParms // escape wheel
+	Span  float64           // number of teeth spanned by the pallets (E.g. 7.5)
+	Width float64           // pallet width (along the wheel circumference)
+	Lift  float64           // lift angle of the anchor (radians)
+	Arm   float64           // width of the anchor arms
+	Hub   float64           // radius of the hub at the pivot
+}
+
+// AnchorPivot returns the position of the anchor pivot relative to the
+// escape wheel center. The pallet lines of action are tangent to the wheel.
+func AnchorPivot(k *AnchorParms) V2 {
+	theta := PI * k.Span / float64(k.Wheel.Teeth)
+	return V2{0, k.Wheel.Radius / math.Cos(theta)}
+}
+
+// Return the profile of a pallet, and the end of the pallet away from the wheel.
+func anchor_pallet(
+	pivot V2, // anchor pivot
+	contact V2, // contact point on the wheel circle
+	lock_outside bool, // lock on the outer face?
+	k *AnchorParms,
+) (SDF2, V2) {
+	v := contact.Sub(pivot)
+	l := v.Length()
+	phi := math.Atan2(v.Y, v.X)
+	// work out the direction of rotation (about the pivot) into the wheel
+	s := 1.0
+	if contact.Dot(V2{-v.Y, v.X}) > 0 {
+		s = -1.0
+	}
+	r_lock := l - 0.5*k.Width
+	r_other := l + 0.5*k.Width
+	if lock_outside {
+		r_lock, r_other = r_other, r_lock
+	}
+	// the pallet runs back (away from the wheel) to the arm
+	a0 := phi - s*k.Width/l
+	// impulse face corners
+	a_lock := phi + s*0.5*k.Lift
+	a_other := phi - s*0.5*k.Lift
+	const n = 8
+	var pts []V2
+	for i := 0; i <= n; i++ {
+		a := Mix(a0, a_lock, float64(i)/n)
+		pts = append(pts, pivot.Add(PolarToXY(r_lock, a)))
+	}
+	for i := 0; i <= n; i++ {
+		a := Mix(a_other, a0, float64(i)/n)
+		pts = append(pts, pivot.Add(PolarToXY(r_other, a)))
+	}
+	return Polygon2D(pts), pivot.Add(PolarToXY(l, a0))
+}
+
+// Anchor2D returns the 2D profile of a deadbeat anchor.
+// The profile is positioned relative to the escape wheel center (see AnchorPivot).
+// The anchor is shown at the center of its swing.
+func Anchor2D(k *AnchorParms) SDF2 {
+	w := k.Wheel
+	if k.Span < 1 || k.Span >= 0.5*float64(w.Teeth) {
+		panic("invalid pallet span")
+	}
+	if k.Width <= 0 || k.Width >= TAU*w.Radius/float64(w.Teeth) {
+		panic("invalid pallet width")
+	}
+	if k.Lift <= 0 || k.Arm <= 0 || k.Hub <= 0 {
+		panic("invalid anchor size")
+	}
+	theta := PI * k.Span / float64(w.Teeth)
+	pivot := AnchorPivot(k)
+	c := V2{w.Radius * math.Sin(theta), w.Radius * math.Cos(theta)}
+	// the counter-clockwise teeth meet the entry pallet first
+	entry, p0 := anchor_pallet(pivot, c, true, k)
+	exit, p1 := anchor_pallet(pivot, V2{-c.X, c.Y}, false, k)
+	parts := []SDF2{entry, exit, Transform2D(Circle2D(k.Hub), Translate2d(pivot))}
+	// arms from the hub to the pallets
+	for _, p := range []V2{p0, p1} {
+		v := p.Sub(pivot)
+		m := Rotate2d(math.Atan2(v.Y, v.X))
+		m = Translate2d(pivot.Add(v.MulScalar(0.5))).Mul(m)
+		parts = append(parts, Transform2D(Line2D(v.Length(), 0.5*k.Arm), m))
+	}
+	return Union2D(parts...)
+}
+
+//-----------------------------------------------------------------------------
+// Gear Trains
+
+type GearPair struct {
+	Wheel  int // number of teeth on the driving wheel
+	Pinion int // number of teeth on the driven pinion
+}
+
+// Ratio returns the turns of the pinion for each turn of the wheel.
+func (g GearPair) Ratio() float64 {
+	return float64(g.Wheel) / float64(g.Pinion)
+}
+
+// CenterDistance returns the distance between the wheel and pinion centers.
+func (g GearPair) CenterDistance(gear_module float64) float64 {
+	return 0.5 * gear_module * float64(g.Wheel+g.Pinion)
+}
+
+// TrainRatio returns the overall ratio of a gear train.
+func TrainRatio(train []GearPair) float64 {
+	r := 1.0
+	for _, g := range train {
+		r *= g.Ratio()
+	}
+	return r
+}
+
+// EscapeWheelRate returns the turns per hour of an escape wheel.
+// The beat is the time (in seconds) for one swing of the pendulum.
+// One tooth escapes for every two beats.
+func EscapeWheelRate(teeth int, beat float64) float64 {
+	return 3600.0 / (2.0 * float64(teeth) * beat)
+}
+
+// ClockTrain returns the wheel and pinion counts for a gear train with an
+// exact ratio (E.g. 12 for the motion work, or the escape wheel rate for the
+// train from the minute hand). Pinion and wheel counts are within the
+// [min, max] ranges. The train with the least number of teeth is returned.
+func ClockTrain(ratio float64, stages int, pinion, wheel [2]int) ([]GearPair, error) {
+	if stages < 1 {
+		return nil, fmt.Errorf("need at least 1 stage")
+	}
+	if pinion[0] < 1 || pinion[0] > pinion[1] || wheel[0] < 1 || wheel[0] > wheel[1] {
+		return nil, fmt.Errorf("bad tooth count range")
+	}
+	r_min := float64(wheel[0]) / float64(pinion[1])
+	r_max := float64(wheel[1]) / float64(pinion[0])
+
+	var best []GearPair
+	best_teeth := math.MaxInt32
+	train := make([]GearPair, stages)
+
+	var search func(i int, r float64, teeth int)
+	search = func(i int, r float64, teeth int) {
+		n := float64(stages - i)
+		if teeth >= best_teeth || r < math.Pow(r_min, n)*(1-EPSILON) || r > math.Pow(r_max, n)*(1+EPSILON) {
+			return
+		}
+		for p := pinion[0]; p <= pinion[1]; p++ {
+			if i == stages-1 {
+				// the last stage must give the remaining ratio
+				w := r * float64(p)
+				wi := int(math.Round(w))
+				if Abs(w-float64(wi)) > EPSILON*w || wi < wheel[0] || wi > wheel[1] {
+					continue
+				}
+				if t := teeth + wi + p; t < best_teeth {
+					train[i] = GearPair{wi, p}
+					best = append([]GearPair(nil), train...)
+					best_teeth = t
+				}
+				continue
+			}
+			for w := wheel[0]; w <= wheel[1]; w++ {
+				train[i] = GearPair{w, p}
+				search(i+1, r/train[i].Ratio(), teeth+w+p)
+			}
+		}
+	}
+	search(0, ratio, 0)
+
+	if best == nil {
+		return nil, fmt.Errorf("no gear train for ratio %f", ratio)
+	}
+	return best, nil
+}
+
+//-----------------------------------------------------------------------------
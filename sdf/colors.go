@@ -0,0 +1,149 @@
+//-----------------------------------------------------------------------------
+/*
+
+Color Separation
+
+Multi-color (dual extrusion) prints need one body per color, and the bodies
+must not leave gaps where the colors meet. A color model is built from SDF3s
+tagged with a color name. Where bodies of different colors overlap, the
+color that was added last wins, E.g. the letters of a sign win over the base
+plate they are embedded in.
+
+Each separated body grows by an overlap into the colors added before it
+(but not into later colors or outside the model). The overlap registers the bodies against each
+other and gives the slicer a little shared volume so the colors bond.
+
+	m := NewColorModel()
+	plate, text := Sign3D(&k)
+	m.Add("base", plate)
+	m.Add("text", text)
+	m.RenderSTL(300, "sign", 0.1) // sign_base.stl, sign_text.stl
+	m.Render3MF(300, "sign.3mf", 0.1, nil)
+
+The STL files share the model coordinates, so they load in a slicer aligned
+to each other. The 3MF file has an object for each color (named for the
+color) in a single file.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"sync"
+)
+
+//-----------------------------------------------------------------------------
+
+// ColorModel is a model built from bodies tagged with a color.
+type ColorModel struct {
+	colors []string // color names, in the order they were added
+	bodies []SDF3   // the union of the bodies for each color
+}
+
+// NewColorModel returns an empty color model.
+func NewColorModel() *ColorModel {
+	return &ColorModel{}
+}
+
+// Add adds a body to the model with a color.
+func (m *ColorModel) Add(color string, s SDF3) {
+	if s == nil {
+		return
+	}
+	for i, c := range m.colors {
+		if c == color {
+			m.bodies[i] = Union3D(m.bodies[i], s)
+			return
+		}
+	}
+	m.colors = append(m.colors, color)
+	m.bodies = append(m.bodies, s)
+}
+
+// Colors returns the color names of the model.
+func (m *ColorModel) Colors() []string {
+	return m.colors
+}
+
+// SDF3 returns the whole model.
+func (m *ColorModel) SDF3() SDF3 {
+	return Union3D(m.bodies...)
+}
+
+// Separate returns the body for each color (in the order of Colors).
+// The bodies are disjoint apart from the overlap.
+func (m *ColorModel) Separate(overlap float64) []SDF3 {
+	if overlap < 0 {
+		panic("invalid overlap")
+	}
+	n := len(m.bodies)
+	// later colors win
+	own := make([]SDF3, n)
+	for i := 0; i < n; i++ {
+		own[i] = m.bodies[i]
+		if i < n-1 {
+			own[i] = Difference3D(own[i], Union3D(m.bodies[i+1:]...))
+		}
+	}
+	// grow into the earlier colors (not the parts of them covered by later colors)
+	bodies := make([]SDF3, n)
+	for i, s := range own {
+		if i > 0 && overlap > 0 {
+			s = Intersect3D(Offset3D(s, overlap), Union3D(own[:i+1]...))
+		}
+		bodies[i] = s
+	}
+	return bodies
+}
+
+// RenderSTL renders each color to an STL file (prefix_color.stl).
+// The bodies are sampled with the same resolution over refined bounding boxes.
+func (m *ColorModel) RenderSTL(mesh_cells int, prefix string, overlap float64) {
+	if len(m.bodies) == 0 {
+		return
+	}
+	bb := RefineBB(m.SDF3(), REFINE_ITERATIONS)
+	resolution := bb.Size().MaxComponent() / float64(mesh_cells)
+	for i, s := range m.Separate(overlap) {
+		path := fmt.Sprintf("%s_%s.stl", prefix, m.colors[i])
+		render_stl_resolution(refine_sdf3(s, REFINE_ITERATIONS), resolution, path)
+	}
+}
+
+// Render3MF renders the colors to a 3MF file with an object for each color.
+// The bodies are sampled as for RenderSTL.
+func (m *ColorModel) Render3MF(mesh_cells int, path string, overlap float64, meta *Metadata) {
+	if len(m.bodies) == 0 {
+		return
+	}
+	bb := RefineBB(m.SDF3(), REFINE_ITERATIONS)
+	resolution := bb.Size().MaxComponent() / float64(mesh_cells)
+	fmt.Printf("rendering %s (resolution %.2f)\n", path, resolution)
+	var objects []*Object3MF
+	for i, s := range m.Separate(overlap) {
+		mesh := collect_triangles(refine_sdf3(s, REFINE_ITERATIONS), resolution)
+		objects = append(objects, &Object3MF{Name: m.colors[i], Mesh: mesh})
+	}
+	if err := Save3MFObjects(path, objects, meta); err != nil {
+		fmt.Printf("%s", err)
+	}
+}
+
+// Render an SDF3 as an STL file (octree sampling) with a given resolution.
+func render_stl_resolution(s SDF3, resolution float64, path string) {
+	cells := s.BoundingBox().Size().DivScalar(resolution).ToV3i()
+	fmt.Printf("rendering %s (%dx%dx%d, resolution %.2f)\n", path, cells[0], cells[1], cells[2], resolution)
+	var wg sync.WaitGroup
+	output, err := WriteSTL(&wg, path)
+	if err != nil {
+		fmt.Printf("%s", err)
+		return
+	}
+	MarchingCubes_Octree(s, resolution, output)
+	close(output)
+	wg.Wait()
+}
+
+//-----------------------------------------------------------------------------
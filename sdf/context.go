@@ -0,0 +1,165 @@
+//-----------------------------------------------------------------------------
+/*
+
+Render Context
+
+Keep the data built while rendering an SDF so later renders of the same SDF
+can reuse it. E.g. a quick preview followed by the final render, or the
+same model written to several files.
+
+The data is keyed by the identity of the SDF node (the node pointer), so
+the SDF must not be changed between renders (or call Forget for the node).
+
+Distance Samples: the octree/quadtree renderers store every distance
+evaluation by position. The sample positions are on a grid with its origin
+at the bounding box minimum, so renders at the same resolution share all of
+their samples and a render at half the resolution reuses the samples from
+the coarser render.
+
+The renderers only share the distance samples. Other data built from a node
+(E.g. a polygon index or a voxel grid) isn't kept by the context unless the
+caller stores it against the node with Lookup.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "sync"
+
+//-----------------------------------------------------------------------------
+// Distance Samples
+
+type samples3 struct {
+	cache map[V3]float64 // distances by position
+	lock  sync.RWMutex   // lock the cache during reads/writes
+}
+
+func (s *samples3) read(p V3) (float64, bool) {
+	s.lock.RLock()
+	dist, found := s.cache[p]
+	s.lock.RUnlock()
+	return dist, found
+}
+
+func (s *samples3) write(p V3, dist float64) {
+	s.lock.Lock()
+	s.cache[p] = dist
+	s.lock.Unlock()
+}
+
+type samples2 struct {
+	cache map[V2]float64 // distances by position
+	lock  sync.RWMutex   // lock the cache during reads/writes
+}
+
+func (s *samples2) read(p V2) (float64, bool) {
+	s.lock.RLock()
+	dist, found := s.cache[p]
+	s.lock.RUnlock()
+	return dist, found
+}
+
+func (s *samples2) write(p V2, dist float64) {
+	s.lock.Lock()
+	s.cache[p] = dist
+	s.lock.Unlock()
+}
+
+//-----------------------------------------------------------------------------
+
+// node data key
+type render_key struct {
+	node interface{} // SDF node
+	name string      // data name
+}
+
+type RenderContext struct {
+	data map[render_key]interface{} // node data
+	lock sync.Mutex                 // lock the data during lookups
+}
+
+// NewRenderContext returns an empty render context.
+func NewRenderContext() *RenderContext {
+	return &RenderContext{data: make(map[render_key]interface{})}
+}
+
+// Lookup returns the named data for an SDF node.
+// The build function makes the data if it isn't in the context. It is called
+// without the context locked, so it may use Lookup itself. If two lookups
+// build the same data at once the first one stored is returned to both.
+func (rc *RenderContext) Lookup(node interface{}, name string, build func() interface{}) interface{} {
+	k := render_key{node, name}
+	rc.lock.Lock()
+	d, ok := rc.data[k]
+	rc.lock.Unlock()
+	if ok {
+		return d
+	}
+	d = build()
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	if x, ok := rc.data[k]; ok {
+		// built by another lookup
+		return x
+	}
+	rc.data[k] = d
+	return d
+}
+
+// Forget removes all the data for an SDF node.
+func (rc *RenderContext) Forget(node interface{}) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	for k := range rc.data {
+		if k.node == node {
+			delete(rc.data, k)
+		}
+	}
+}
+
+// Return the distance samples for an SDF3.
+func (rc *RenderContext) samples3(s SDF3) *samples3 {
+	return rc.Lookup(s, "samples", func() interface{} {
+		return &samples3{cache: make(map[V3]float64)}
+	}).(*samples3)
+}
+
+// Return the distance samples for an SDF2.
+func (rc *RenderContext) samples2(s SDF2) *samples2 {
+	return rc.Lookup(s, "samples", func() interface{} {
+		return &samples2{cache: make(map[V2]float64)}
+	}).(*samples2)
+}
+
+//-----------------------------------------------------------------------------
+
+// MarchingCubes_Octree generates a triangle mesh for an SDF3 using octree
+// subdivision, reusing the distance samples of previous renders.
+func (rc *RenderContext) MarchingCubes_Octree(s SDF3, resolution float64, output chan<- *Triangle3) {
+	marching_cubes_octree(s, resolution, output, rc.samples3(s))
+}
+
+// MarchingSquares_Quadtree generates line segments for an SDF2 using quadtree
+// subdivision, reusing the distance samples of previous renders.
+func (rc *RenderContext) MarchingSquares_Quadtree(s SDF2, resolution float64, output chan<- *Line2_PP) {
+	marching_squares_quadtree(s, resolution, output, rc.samples2(s))
+}
+
+// RenderSTL renders an SDF3 as an STL file (octree sampling).
+func (rc *RenderContext) RenderSTL(s SDF3, mesh_cells int, path string) {
+	render_stl(s, mesh_cells, path, nil, rc.samples3(s))
+}
+
+// RenderSTLWithMetadata renders an SDF3 as an STL file (octree sampling)
+// with metadata in the header.
+func (rc *RenderContext) RenderSTLWithMetadata(s SDF3, mesh_cells int, path string, meta *Metadata) {
+	render_stl(s, mesh_cells, path, meta, rc.samples3(s))
+}
+
+// RenderDXF renders an SDF2 as a DXF file (quadtree sampling).
+func (rc *RenderContext) RenderDXF(s SDF2, mesh_cells int, path string) {
+	render_dxf(s, mesh_cells, path, rc.samples2(s))
+}
+
+//-----------------------------------------------------------------------------
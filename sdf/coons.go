@@ -0,0 +1,176 @@
+//-----------------------------------------------------------------------------
+/*
+
+Coons Patches
+
+A free-form surface that fills the space between four boundary curves.
+The curves are parametric on t = [0,1]:
+
+c0(u): the v = 0 boundary
+c1(u): the v = 1 boundary
+d0(v): the u = 0 boundary
+d1(v): the u = 1 boundary
+
+The ends of the curves must meet at the corners of the patch. The bilinearly
+blended Coons patch is:
+
+S(u,v) = (1-v)c0(u) + v c1(u) + (1-u)d0(v) + u d1(v) - B(u,v)
+
+where B(u,v) is the bilinear interpolation of the four corners.
+
+The patch is thickened to a solid shell (centered on the surface) so it can be
+printed. The distance to the surface is found by sampling the patch on a grid
+and then refining the closest point with Gauss-Newton iterations.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+// 3D Curves
+
+// Curve3 returns a point on a 3D curve for t = [0,1].
+type Curve3 func(t float64) V3
+
+// BezierCurve3 returns a 3D bezier curve with the given control points.
+func BezierCurve3(ctrl ...V3) Curve3 {
+	if len(ctrl) < 2 {
+		panic("bezier curve needs at least 2 control points")
+	}
+	return func(t float64) V3 {
+		// de Casteljau's algorithm
+		p := append([]V3(nil), ctrl...)
+		for n := len(p) - 1; n > 0; n-- {
+			for i := 0; i < n; i++ {
+				p[i] = p[i].MulScalar(1 - t).Add(p[i+1].MulScalar(t))
+			}
+		}
+		return p[0]
+	}
+}
+
+//-----------------------------------------------------------------------------
+// Coons Patch
+
+type CoonsPatch struct {
+	c0, c1, d0, d1     Curve3
+	p00, p10, p01, p11 V3 // corners
+}
+
+// NewCoonsPatch returns a Coons patch for four boundary curves.
+func NewCoonsPatch(c0, c1, d0, d1 Curve3) *CoonsPatch {
+	c := CoonsPatch{c0, c1, d0, d1, c0(0), c0(1), c1(0), c1(1)}
+	// the curves must meet at the corners
+	tolerance := 1e-6 * (1 + c.p00.Sub(c.p11).Length() + c.p10.Sub(c.p01).Length())
+	if !d0(0).Equals(c.p00, tolerance) || !d1(0).Equals(c.p10, tolerance) ||
+		!d0(1).Equals(c.p01, tolerance) || !d1(1).Equals(c.p11, tolerance) {
+		panic("boundary curves don't meet at the corners")
+	}
+	return &c
+}
+
+// Evaluate returns the point on the patch at (u, v).
+func (c *CoonsPatch) Evaluate(u, v float64) V3 {
+	lc := c.c0(u).MulScalar(1 - v).Add(c.c1(u).MulScalar(v))
+	ld := c.d0(v).MulScalar(1 - u).Add(c.d1(v).MulScalar(u))
+	b := c.p00.MulScalar((1 - u) * (1 - v))
+	b = b.Add(c.p10.MulScalar(u * (1 - v)))
+	b = b.Add(c.p01.MulScalar((1 - u) * v))
+	b = b.Add(c.p11.MulScalar(u * v))
+	return lc.Add(ld).Sub(b)
+}
+
+//-----------------------------------------------------------------------------
+// Thickened Coons Patch
+
+// number of grid samples (per parameter) for the closest point search
+const coons_samples = 16
+
+type CoonsPatchSDF3 struct {
+	patch *CoonsPatch
+	delta float64 // half thickness
+	grid  []V3    // sampled patch points
+	bb    Box3
+}
+
+// CoonsPatch3D returns a Coons patch thickened to a shell.
+func CoonsPatch3D(patch *CoonsPatch, thickness float64) SDF3 {
+	if thickness <= 0 {
+		panic("invalid patch thickness")
+	}
+	s := CoonsPatchSDF3{}
+	s.patch = patch
+	s.delta = 0.5 * thickness
+	n := coons_samples
+	s.grid = make([]V3, (n+1)*(n+1))
+	bb := Box3{patch.p00, patch.p00}
+	for j := 0; j <= n; j++ {
+		for i := 0; i <= n; i++ {
+			p := patch.Evaluate(float64(i)/float64(n), float64(j)/float64(n))
+			s.grid[j*(n+1)+i] = p
+			bb = bb.Extend(Box3{p, p})
+		}
+	}
+	// allow for the surface between the samples
+	k := s.delta + 0.05*bb.Size().MaxComponent()
+	s.bb = Box3{bb.Min.SubScalar(k), bb.Max.AddScalar(k)}
+	return &s
+}
+
+// Return the closest point on the patch, refined from (u, v).
+func (s *CoonsPatchSDF3) closest(p V3, u, v float64) V3 {
+	const h = 1e-5
+	q := s.patch.Evaluate(u, v)
+	for i := 0; i < 8; i++ {
+		// partial derivatives (one sided at the edges)
+		u0, u1 := Max(u-h, 0), Min(u+h, 1)
+		v0, v1 := Max(v-h, 0), Min(v+h, 1)
+		su := s.patch.Evaluate(u1, v).Sub(s.patch.Evaluate(u0, v)).DivScalar(u1 - u0)
+		sv := s.patch.Evaluate(u, v1).Sub(s.patch.Evaluate(u, v0)).DivScalar(v1 - v0)
+		// solve the 2x2 normal equations
+		r := q.Sub(p)
+		a, b, c := su.Dot(su), su.Dot(sv), sv.Dot(sv)
+		det := a*c - b*b
+		if det == 0 {
+			break
+		}
+		du := (-r.Dot(su)*c + r.Dot(sv)*b) / det
+		dv := (-r.Dot(sv)*a + r.Dot(su)*b) / det
+		u = Clamp(u+du, 0, 1)
+		v = Clamp(v+dv, 0, 1)
+		qn := s.patch.Evaluate(u, v)
+		if qn.Sub(p).Length2() >= r.Length2() {
+			break
+		}
+		q = qn
+	}
+	return q
+}
+
+// Evaluate returns the minimum distance to a thickened Coons patch.
+func (s *CoonsPatchSDF3) Evaluate(p V3) float64 {
+	// closest grid sample
+	n := coons_samples
+	k := 0
+	d2 := math.Inf(1)
+	for i, q := range s.grid {
+		d := q.Sub(p).Length2()
+		if d < d2 {
+			d2 = d
+			k = i
+		}
+	}
+	u := float64(k%(n+1)) / float64(n)
+	v := float64(k/(n+1)) / float64(n)
+	return s.closest(p, u, v).Sub(p).Length() - s.delta
+}
+
+// BoundingBox returns the bounding box of a thickened Coons patch.
+func (s *CoonsPatchSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
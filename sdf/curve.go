@@ -0,0 +1,205 @@
+//-----------------------------------------------------------------------------
+/*
+
+Closest Point on a Parametric Curve
+
+A curve segment provides its value and derivatives (f0, f1 and optionally
+f2) over a parameter domain, and a bounding box for any sub-interval of the
+domain. The closest point to p minimizes the distance squared:
+
+d0(t) = |f0(t) - p|^2
+d1(t) = 2 (f0(t) - p).f1(t)
+d2(t) = 2 (f1(t).f1(t) + (f0(t) - p).f2(t))
+
+The domain is recursively subdivided. An interval is pruned when the minimum
+distance to its bounding box is more than the best distance found so far.
+The surviving leaf intervals are searched with roots.Minimize (bracketing and
+Brent's method, with a Newton polish if f2 is available).
+
+Polynomial curves get a tight sub-interval bound from the convex hull of
+their Bernstein (Bezier) coefficients on the sub-interval.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf/roots"
+)
+
+//-----------------------------------------------------------------------------
+
+// Curve2 is a parametric curve segment.
+type Curve2 interface {
+	f0(t float64) V2            // curve value
+	f1(t float64) V2            // 1st derivative
+	domain() (float64, float64) // parameter range
+	bound(t0, t1 float64) Box2  // bounding box of the curve on [t0, t1]
+}
+
+// curve2_f2 is a curve with a 2nd derivative.
+type curve2_f2 interface {
+	f2(t float64) V2 // 2nd derivative
+}
+
+// subdivision depth for pruning
+const CURVE_DEPTH = 2
+
+// number of sub-intervals used to bracket the distance minima (per leaf)
+const CURVE_SAMPLES = 4
+
+// CurveMinDistance2 returns the minimum distance squared between a point
+// and a curve, and the t value at which it occurs.
+func CurveMinDistance2(c Curve2, p V2) (float64, float64) {
+	d0 := func(t float64) float64 {
+		return c.f0(t).Sub(p).Length2()
+	}
+	d1 := func(t float64) float64 {
+		return 2 * c.f0(t).Sub(p).Dot(c.f1(t))
+	}
+	var d2 func(t float64) float64
+	if cf2, ok := c.(curve2_f2); ok {
+		d2 = func(t float64) float64 {
+			f1 := c.f1(t)
+			return 2 * (f1.Dot(f1) + c.f0(t).Sub(p).Dot(cf2.f2(t)))
+		}
+	}
+	t0, t1 := c.domain()
+	// the end points give the initial upper bound
+	dmin, tmin := d0(t0), t0
+	if d := d0(t1); d < dmin {
+		dmin, tmin = d, t1
+	}
+	type interval struct {
+		t0, t1 float64
+		depth  int
+	}
+	stack := []interval{{t0, t1, 0}}
+	for len(stack) != 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if c.bound(i.t0, i.t1).MinMaxDist2(p).X > dmin {
+			// the closest point isn't in this interval
+			continue
+		}
+		if i.depth == CURVE_DEPTH {
+			if t, d := roots.Minimize(d0, d1, d2, i.t0, i.t1, CURVE_SAMPLES, EPSILON); d < dmin {
+				dmin, tmin = d, t
+			}
+			continue
+		}
+		tmid := 0.5 * (i.t0 + i.t1)
+		if d := d0(tmid); d < dmin {
+			dmin, tmin = d, tmid
+		}
+		// the closer half is searched first (it's on the top of the stack)
+		a := interval{i.t0, tmid, i.depth + 1}
+		b := interval{tmid, i.t1, i.depth + 1}
+		if d0(0.5*(a.t0+a.t1)) < d0(0.5*(b.t0+b.t1)) {
+			a, b = b, a
+		}
+		stack = append(stack, a, b)
+	}
+	return dmin, tmin
+}
+
+//-----------------------------------------------------------------------------
+// Polynomial Bounds
+
+// Return the binomial coefficient n choose k.
+func binomial(n, k int) float64 {
+	b := 1.0
+	for i := 1; i <= k; i++ {
+		b = b * float64(n-k+i) / float64(i)
+	}
+	return b
+}
+
+// poly_bound returns the minimum and maximum of the Bernstein coefficients of a
+// polynomial (c[i] is the coefficient of t^i) on [t0, t1]. The polynomial lies
+// within these bounds on the interval.
+func poly_bound(c []float64, t0, t1 float64) (float64, float64) {
+	n := len(c) - 1
+	// Taylor shift: q(s) = p(t0 + s)
+	q := make([]float64, n+1)
+	copy(q, c)
+	for i := 0; i < n; i++ {
+		for j := n - 1; j >= i; j-- {
+			q[j] += t0 * q[j+1]
+		}
+	}
+	// scale: q(u) = p(t0 + (t1 - t0)u), u in [0, 1]
+	k := 1.0
+	for i := range q {
+		q[i] *= k
+		k *= t1 - t0
+	}
+	// convert to the Bernstein basis
+	min, max := q[0], q[0]
+	for i := 1; i <= n; i++ {
+		b := 0.0
+		for j := 0; j <= i; j++ {
+			b += binomial(i, j) / binomial(n, j) * q[j]
+		}
+		min = Min(min, b)
+		max = Max(max, b)
+	}
+	return min, max
+}
+
+//-----------------------------------------------------------------------------
+// Circular Arc
+
+// Arc2 is a circular arc between two angles.
+type Arc2 struct {
+	center V2
+	radius float64
+	a0, a1 float64 // a0 <= a1
+}
+
+// NewArc2 returns a circular arc between angles a0 and a1.
+func NewArc2(center V2, radius, a0, a1 float64) *Arc2 {
+	if radius <= 0 {
+		panic("invalid arc radius")
+	}
+	if a0 > a1 {
+		a0, a1 = a1, a0
+	}
+	return &Arc2{center, radius, a0, a1}
+}
+
+func (c *Arc2) f0(t float64) V2 {
+	return c.center.Add(V2{math.Cos(t), math.Sin(t)}.MulScalar(c.radius))
+}
+
+func (c *Arc2) f1(t float64) V2 {
+	return V2{-math.Sin(t), math.Cos(t)}.MulScalar(c.radius)
+}
+
+func (c *Arc2) f2(t float64) V2 {
+	return V2{-math.Cos(t), -math.Sin(t)}.MulScalar(c.radius)
+}
+
+func (c *Arc2) domain() (float64, float64) {
+	return c.a0, c.a1
+}
+
+func (c *Arc2) bound(t0, t1 float64) Box2 {
+	p := V2Set{c.f0(t0), c.f0(t1)}
+	// add the extreme points of the circle within [t0, t1]
+	for k := math.Ceil(t0 / (0.5 * PI)); k*0.5*PI <= t1; k++ {
+		p = append(p, c.f0(k*0.5*PI))
+	}
+	return Box2{p.Min(), p.Max()}
+}
+
+// MinDistance2 returns the minimum distance squared between a point
+// and the arc, and the angle at which it occurs.
+func (c *Arc2) MinDistance2(p V2) (float64, float64) {
+	return CurveMinDistance2(c, p)
+}
+
+//-----------------------------------------------------------------------------
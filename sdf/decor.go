@@ -0,0 +1,239 @@
+//-----------------------------------------------------------------------------
+/*
+
+Decorative Solids
+
+Dome: A spherical cap with a flat base on the xy plane. E.g. a lamp shade
+(use Shell3D) or a button top. The distance is exact.
+
+Torus Knot: A tube swept along a (p,q) torus knot. The curve winds p times
+around the z-axis and q times through the hole of the torus. The curve is
+periodic, so the closest point is found by sampling one period of the curve
+and refining the closest samples. Points well outside the torus that holds
+the knot get the (lower bound) distance to that torus.
+
+Mobius Band: A rectangular section swept around a circle with a frame that
+makes a half turn per revolution. The frame is periodic because the section
+is symmetric under a half turn. The twist stretches the field, so the
+distance is scaled down to a lower bound.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf/roots"
+)
+
+//-----------------------------------------------------------------------------
+// Dome (exact distance field)
+
+type DomeSDF3 struct {
+	r  float64 // sphere radius
+	h  float64 // height of the base plane above the sphere center
+	w  float64 // base radius
+	bb Box3
+}
+
+// Dome3D returns a spherical cap with a base radius and height.
+// The base is on the xy plane and the top is at z = height.
+func Dome3D(radius, height float64) SDF3 {
+	if radius <= 0 || height <= 0 {
+		panic("invalid dome")
+	}
+	s := DomeSDF3{}
+	s.r = (radius*radius + height*height) / (2 * height)
+	s.h = s.r - height
+	s.w = radius
+	// more than a hemisphere is wider than the base
+	xy := radius
+	if s.h < 0 {
+		xy = s.r
+	}
+	s.bb = Box3{V3{-xy, -xy, 0}, V3{xy, xy, height}}
+	return &s
+}
+
+// Evaluate returns the minimum distance to a dome.
+func (s *DomeSDF3) Evaluate(p V3) float64 {
+	// (radius, height) relative to the sphere center
+	q := V2{V2{p.X, p.Y}.Length(), p.Z + s.h}
+	k := Max((s.h-s.r)*q.X*q.X+s.w*s.w*(s.h+s.r-2*q.Y), s.h*q.X-s.w*q.Y)
+	if k < 0 {
+		// closest to the sphere
+		return q.Length() - s.r
+	}
+	if q.X < s.w {
+		// closest to the base
+		return s.h - q.Y
+	}
+	// closest to the base edge
+	return q.Sub(V2{s.w, s.h}).Length()
+}
+
+// BoundingBox returns the bounding box for a dome.
+func (s *DomeSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+// Torus Knot
+
+type TorusKnotSDF3 struct {
+	p, q   float64 // windings
+	r0, r1 float64 // major and minor radius of the torus
+	tube   float64 // tube radius
+	t      []float64
+	pts    []V3 // curve samples over one period
+	bb     Box3
+}
+
+// TorusKnot3D returns a tube of a radius swept along a (p,q) torus knot.
+// The knot is on a torus with major radius r0 and minor radius r1.
+func TorusKnot3D(p, q int, r0, r1, tube float64) SDF3 {
+	if p < 1 || q < 1 || r1 <= 0 || r0 <= r1 || tube <= 0 {
+		panic("invalid torus knot")
+	}
+	s := TorusKnotSDF3{p: float64(p), q: float64(q), r0: r0, r1: r1, tube: tube}
+	// sample the curve at about the tube radius
+	length := TAU * math.Sqrt(s.p*s.p*(r0+r1)*(r0+r1)+s.q*s.q*r1*r1)
+	n := int(Max(math.Ceil(length/tube), 64))
+	s.t = make([]float64, n)
+	s.pts = make([]V3, n)
+	for i := range s.t {
+		s.t[i] = TAU * float64(i) / float64(n)
+		s.pts[i] = s.curve(s.t[i])
+	}
+	xy := r0 + r1 + tube
+	z := r1 + tube
+	s.bb = Box3{V3{-xy, -xy, -z}, V3{xy, xy, z}}
+	return &s
+}
+
+// Return the knot curve position.
+func (s *TorusKnotSDF3) curve(t float64) V3 {
+	rho := s.r0 + s.r1*math.Cos(s.q*t)
+	return V3{rho * math.Cos(s.p*t), rho * math.Sin(s.p*t), s.r1 * math.Sin(s.q*t)}
+}
+
+// Return the 1st and 2nd derivatives of the knot curve.
+func (s *TorusKnotSDF3) derivatives(t float64) (V3, V3) {
+	sp, cp := math.Sincos(s.p * t)
+	sq, cq := math.Sincos(s.q * t)
+	rho := s.r0 + s.r1*cq
+	drho := -s.r1 * s.q * sq
+	ddrho := -s.r1 * s.q * s.q * cq
+	d1 := V3{drho*cp - rho*s.p*sp, drho*sp + rho*s.p*cp, s.r1 * s.q * cq}
+	d2 := V3{
+		ddrho*cp - 2*drho*s.p*sp - rho*s.p*s.p*cp,
+		ddrho*sp + 2*drho*s.p*cp - rho*s.p*s.p*sp,
+		-s.r1 * s.q * s.q * sq,
+	}
+	return d1, d2
+}
+
+// Evaluate returns the minimum distance to a torus knot.
+func (s *TorusKnotSDF3) Evaluate(p V3) float64 {
+	// the torus holding the knot
+	dt := V2{V2{p.X, p.Y}.Length() - s.r0, p.Z}.Length() - s.r1 - s.tube
+	if dt > s.tube {
+		return dt
+	}
+	n := len(s.pts)
+	d2 := make([]float64, n)
+	dmin := math.MaxFloat64
+	for i, x := range s.pts {
+		d2[i] = x.Sub(p).Length2()
+		dmin = Min(dmin, d2[i])
+	}
+	// squared distance and derivatives
+	f0 := func(t float64) float64 { return s.curve(t).Sub(p).Length2() }
+	f1 := func(t float64) float64 {
+		d1, _ := s.derivatives(t)
+		return 2 * s.curve(t).Sub(p).Dot(d1)
+	}
+	f2 := func(t float64) float64 {
+		d1, d2 := s.derivatives(t)
+		return 2 * (d1.Dot(d1) + s.curve(t).Sub(p).Dot(d2))
+	}
+	// Refine the local minima that are close to the best sample. The sample
+	// spacing is less than the tube radius.
+	dt = TAU / float64(n)
+	limit := math.Sqrt(dmin) + s.tube
+	limit *= limit
+	best := dmin
+	for i := range d2 {
+		if d2[i] > limit || d2[i] > d2[(i+n-1)%n] || d2[i] > d2[(i+1)%n] {
+			continue
+		}
+		if _, d := roots.Minimize(f0, f1, f2, s.t[i]-dt, s.t[i]+dt, 2, EPSILON); d < best {
+			best = d
+		}
+	}
+	return math.Sqrt(best) - s.tube
+}
+
+// BoundingBox returns the bounding box for a torus knot.
+func (s *TorusKnotSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+// Mobius Band (bounded distance field)
+
+type MobiusSDF3 struct {
+	radius float64 // radius of the center line
+	size   V2      // half size of the section
+	k      float64 // 1 / lipschitz constant
+	bb     Box3
+}
+
+// MobiusBand3D returns a Mobius band with a radius, width and thickness.
+// The band is in the xy plane, the width is across the band.
+func MobiusBand3D(radius, width, thickness float64) SDF3 {
+	s := MobiusSDF3{}
+	s.radius = radius
+	s.size = V2{width, thickness}.MulScalar(0.5)
+	h := s.size.Length()
+	if width <= 0 || thickness <= 0 || radius <= 2*h {
+		panic("invalid mobius band")
+	}
+	// Turning the frame by theta/2 moves a point at distance d from the
+	// center line by d/2 per radian, or d/(2 rho) per unit length along a
+	// circle of radius rho. This is the worst case within 2h of the center
+	// line.
+	g := (2 * h) / (2 * (radius - 2*h))
+	s.k = 1 / math.Sqrt(1+g*g)
+	xy := radius + h
+	s.bb = Box3{V3{-xy, -xy, -h}, V3{xy, xy, h}}
+	return &s
+}
+
+// Evaluate returns the minimum distance to a Mobius band.
+func (s *MobiusSDF3) Evaluate(p V3) float64 {
+	// section coordinates
+	u := V2{V2{p.X, p.Y}.Length() - s.radius, p.Z}
+	h := s.size.Length()
+	// the band is within a torus of minor radius h
+	l := u.Length()
+	if l > 2*h {
+		return l - h
+	}
+	// half a turn per revolution
+	a := 0.5 * math.Atan2(p.Y, p.X)
+	sa, ca := math.Sincos(a)
+	u = V2{u.X*ca + u.Y*sa, -u.X*sa + u.Y*ca}
+	// Both are lower bounds, limiting the section distance to h makes the
+	// result continuous at l = 2h.
+	return Max(l-h, Min(sdf_box2d(u, s.size)*s.k, h))
+}
+
+// BoundingBox returns the bounding box for a Mobius band.
+func (s *MobiusSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,577 @@
+//-----------------------------------------------------------------------------
+/*
+
+Enclosure Features
+
+Features that are added to (or subtracted from) the walls of boxes and lids.
+
+The box rim is described by a 2D outline (E.g. the Box2D used to extrude the
+box walls). The outline is the outside of the wall, the wall is the region
+inside the outline with a given thickness.
+
+Edge features (E.g. finger notches) are placed along a path on the top edge
+of a wall.
+
+Feet and bumpers are finishing features on the outside of a box. Stick-on
+rubber feet sit in shallow pockets, printed TPU bumpers push into a hole in
+the wall, and screw-on feet hold a hex nut for a screw through the wall.
+
+Cable features hold wiring inside a box. Zip-tie slots go through the wall (or
+under a bridge on the wall) and strain relief clamps grip a cable with two
+screws. Wall features are built with the face on the z = 0 plane, WallAnchor
+returns the transform that places them on any face of a box.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+// Tongue and Groove Seals
+
+type SealParms struct {
+	Wall      float64 // wall thickness of the box/lid rim
+	Width     float64 // width of a tongue
+	Height    float64 // height of a tongue (the groove is deeper by the clearance)
+	Clearance float64 // clearance between tongue and groove (each side)
+	Ridges    int     // number of concentric tongues (> 1 gives a labyrinth seal)
+}
+
+// seal_rings returns the 2D profile for concentric rings centered on the wall.
+func seal_rings(outline SDF2, k *SealParms, n int, width float64) SDF2 {
+	pitch := 2.0 * (k.Width + 2.0*k.Clearance)
+	var rings []SDF2
+	for i := 0; i < n; i++ {
+		// offset of the ring center from the wall center
+		ofs := (float64(i) - 0.5*float64(n-1)) * pitch
+		d := -0.5*k.Wall + ofs
+		outer := Offset2D(outline, d+0.5*width)
+		inner := Offset2D(outline, d-0.5*width)
+		rings = append(rings, Difference2D(outer, inner))
+	}
+	return Union2D(rings...)
+}
+
+// TongueAndGroove3D returns a tongue and a groove for sealing a lid to a box.
+// The mating surface of the box rim is the z = 0 plane, the box is below it.
+// The tongue extends downwards from z = 0 and should be added to the lid.
+// The groove extends downwards from z = 0 and should be subtracted from the box.
+func TongueAndGroove3D(
+	outline SDF2, // outside outline of the box walls
+	k *SealParms, // seal parameters
+) (SDF3, SDF3) {
+	if k.Wall <= 0 {
+		panic("invalid wall size")
+	}
+	if k.Width <= 0 || k.Height <= 0 {
+		panic("invalid tongue size")
+	}
+	if k.Clearance < 0 {
+		panic("invalid clearance")
+	}
+	ridges := k.Ridges
+	if ridges == 0 {
+		ridges = 1
+	}
+	if ridges < 0 {
+		panic("invalid number of ridges")
+	}
+	// the grooves must fit within the wall leaving some material on each side
+	groove_width := k.Width + 2.0*k.Clearance
+	pitch := 2.0 * groove_width
+	if float64(ridges-1)*pitch+2.0*groove_width > k.Wall {
+		panic("the tongue(s) are too wide for the wall")
+	}
+
+	// tongue
+	h := k.Height
+	tongue := Extrude3D(seal_rings(outline, k, ridges, k.Width), h)
+	tongue = Transform3D(tongue, Translate3d(V3{0, 0, -0.5 * h}))
+
+	// groove: extend above z = 0 to give a clean cut
+	h = k.Height + k.Clearance
+	groove := Extrude3D(seal_rings(outline, k, ridges, groove_width), 2.0*h)
+
+	return tongue, groove
+}
+
+//-----------------------------------------------------------------------------
+// Cable Glands
+
+// Cable gland locknut sizes (flat to flat) by thread name.
+var gland_nut_db = map[string]float64{
+	"M12x1.5": 15,
+	"M16x1.5": 19,
+	"M20x1.5": 24,
+	"M25x1.5": 30,
+	"M32x1.5": 36,
+}
+
+type CableGlandParms struct {
+	Thread       string  // thread name, E.g. "M16x1.5", "PG9"
+	Wall         float64 // wall thickness
+	Boss         float64 // height of the reinforcing boss on the inside of the wall
+	Clearance    float64 // radial clearance added to the internal thread
+	NutClearance float64 // radial clearance around the locknut on the boss
+}
+
+// CableGland3D returns a reinforcing boss and a threaded hole for a cable gland.
+// The outside of the wall is the z = 0 plane, the wall is below it.
+// The boss should be added to the wall and the hole subtracted from the result.
+// The boss is sized to give a flat seat for the locknut of the gland.
+func CableGland3D(k *CableGlandParms) (SDF3, SDF3) {
+	t := ThreadLookup(k.Thread)
+	if t.Units != "mm" {
+		panic("cable gland threads must be metric")
+	}
+	if k.Wall <= 0 {
+		panic("invalid wall size")
+	}
+	if k.Boss < 0 {
+		panic("invalid boss size")
+	}
+	if k.Clearance < 0 || k.NutClearance < 0 {
+		panic("invalid clearance")
+	}
+
+	// work out the locknut radius
+	f2f, ok := gland_nut_db[k.Thread]
+	if !ok {
+		f2f = t.Hex_Flat2Flat
+	}
+	if f2f <= 0 {
+		panic("no locknut size defined for this thread")
+	}
+	nut_r := f2f / (2.0 * math.Cos(DtoR(30)))
+
+	// boss on the inside of the wall
+	var boss SDF3
+	if k.Boss > 0 {
+		boss = Cylinder3D(k.Boss, nut_r+k.NutClearance, 0)
+		boss = Transform3D(boss, Translate3d(V3{0, 0, -k.Wall - 0.5*k.Boss}))
+	}
+
+	// threaded hole through the wall and boss: extend past both faces to give a clean cut
+	l := k.Wall + k.Boss
+	hole := Screw3D(ISOThread(t.Radius+k.Clearance, t.Pitch, "internal"), l+2.0*t.Pitch, t.Pitch, 1)
+	hole = Transform3D(hole, Translate3d(V3{0, 0, -0.5 * l}))
+
+	return boss, hole
+}
+
+//-----------------------------------------------------------------------------
+// Ventilation Louvers
+
+type LouverParms struct {
+	Length float64 // length of each slot
+	Depth  float64 // depth of the cut (the wall thickness)
+	Gap    float64 // vertical opening of each slot
+	Pitch  float64 // distance between slots
+	Angle  float64 // slot angle downwards from the wall normal (radians)
+	Slots  int     // number of slots
+}
+
+// Louver3D returns a cutter for angled ventilation slots in a wall.
+// The outside of the wall is the z = 0 plane, the wall is below it.
+// The slots run along the x-axis and are stacked along the y-axis (up).
+// They slope downwards towards the outside of the wall to shed water.
+// If Depth * tan(Angle) >= Gap there is no line of sight through the slots.
+func Louver3D(k *LouverParms) SDF3 {
+	if k.Length <= 0 || k.Depth <= 0 {
+		panic("invalid louver size")
+	}
+	if k.Gap <= 0 || k.Gap >= k.Pitch {
+		panic("invalid louver gap")
+	}
+	if k.Angle < 0 || k.Angle >= DtoR(80) {
+		panic("invalid louver angle")
+	}
+	if k.Slots <= 0 {
+		panic("invalid number of slots")
+	}
+
+	// slot profiles in the (y, z) plane, extended past both faces of the wall
+	z := 0.5*k.Depth + k.Gap
+	dy := z * math.Tan(k.Angle)
+	slots := make([]SDF2, k.Slots)
+	for i := range slots {
+		y := (float64(i) - 0.5*float64(k.Slots-1)) * k.Pitch
+		slots[i] = Polygon2D([]V2{
+			{y + dy - 0.5*k.Gap, -z},
+			{y - dy - 0.5*k.Gap, z},
+			{y - dy + 0.5*k.Gap, z},
+			{y + dy + 0.5*k.Gap, -z},
+		})
+	}
+	s := Extrude3D(Union2D(slots...), k.Length)
+	// map the extrusion (x, y, z) to (y, z, x)
+	s = Transform3D(s, Rotate3d(V3{1, 1, 1}, TAU/3))
+	return Transform3D(s, Translate3d(V3{0, 0, -0.5 * k.Depth}))
+}
+
+//-----------------------------------------------------------------------------
+// Label Recesses
+
+type LabelParms struct {
+	Size    V2      // size of the label
+	Radius  float64 // corner radius of the label
+	Depth   float64 // depth of the recess
+	Chamfer float64 // size of the chamfer on the recess edge (0 for none)
+}
+
+// LabelRecess3D returns a cutter for a rounded rectangle label/sticker pocket.
+// The face is the z = 0 plane, the part is below it.
+// Use Transform3D to place the recess on other faces.
+func LabelRecess3D(k *LabelParms) SDF3 {
+	if k.Size.X <= 0 || k.Size.Y <= 0 {
+		panic("invalid label size")
+	}
+	if k.Radius < 0 || 2.0*k.Radius > Min(k.Size.X, k.Size.Y) {
+		panic("invalid label radius")
+	}
+	if k.Depth <= 0 {
+		panic("invalid label depth")
+	}
+	if k.Chamfer < 0 || k.Chamfer > k.Depth {
+		panic("invalid label chamfer")
+	}
+	label := Box2D(k.Size, k.Radius)
+	// extend above z = 0 to give a clean cut
+	h := 2.0 * k.Depth
+	s := Extrude3D(label, h)
+	if k.Chamfer > 0 {
+		// 45 degree chamfer: the outline grows with height
+		c := k.Chamfer
+		chamfer := Loft3D(label, Offset2D(label, 2.0*c), 2.0*c, 0)
+		s = Union3D(s, chamfer)
+	}
+	return s
+}
+
+//-----------------------------------------------------------------------------
+// Finger Notches
+
+// Return the position and unit tangent at a distance along a polyline path.
+func path_position(path []V2, d float64) (V2, V2) {
+	for i := 0; i < len(path)-1; i++ {
+		v := path[i+1].Sub(path[i])
+		l := v.Length()
+		if l == 0 {
+			continue
+		}
+		if d <= l || i == len(path)-2 {
+			u := v.DivScalar(l)
+			return path[i].Add(u.MulScalar(d)), u
+		}
+		d -= l
+	}
+	panic("invalid path")
+}
+
+// Return the length of a polyline path.
+func path_length(path []V2) float64 {
+	l := 0.0
+	for i := 0; i < len(path)-1; i++ {
+		l += path[i+1].Sub(path[i]).Length()
+	}
+	return l
+}
+
+type NotchParms struct {
+	Path  []V2    // edge path (a polyline in the z = 0 plane)
+	Width float64 // width of each notch along the path
+	Depth float64 // depth of each notch below the edge
+	Reach float64 // extent of each notch either side of the path
+	Count int     // number of notches
+}
+
+// FingerNotches3D subtracts evenly spaced notches from an SDF3 along an edge.
+// The top of the edge is the z = 0 plane and the path runs along the edge.
+// Each notch is half an ellipsoid centered on the path, it is hemispherical if
+// Width = 2 * Depth = 2 * Reach. Use a Reach greater than the wall thickness to
+// notch right through a wall (E.g. a card box opening).
+func FingerNotches3D(s SDF3, k *NotchParms) SDF3 {
+	if len(k.Path) < 2 {
+		panic("invalid notch path")
+	}
+	if k.Width <= 0 || k.Depth <= 0 || k.Reach <= 0 {
+		panic("invalid notch size")
+	}
+	if k.Count <= 0 {
+		panic("invalid number of notches")
+	}
+	l := path_length(k.Path)
+	if l == 0 {
+		panic("invalid notch path")
+	}
+	// evenly spaced along the path, with a half space at each end
+	notch := Ellipsoid3D(V3{0.5 * k.Width, k.Reach, k.Depth})
+	notches := make([]SDF3, k.Count)
+	for i := range notches {
+		p, u := path_position(k.Path, (float64(i)+0.5)*l/float64(k.Count))
+		m := RotateZ(math.Atan2(u.Y, u.X))
+		m = Translate3d(V3{p.X, p.Y, 0}).Mul(m)
+		notches[i] = Transform3D(notch, m)
+	}
+	return Difference3D(s, Union3D(notches...))
+}
+
+//-----------------------------------------------------------------------------
+// Feet and Bumpers
+
+// FootPocket3D returns a cutter for a pocket that locates a stick-on rubber foot.
+// The face is the z = 0 plane, the part is below it.
+func FootPocket3D(radius, depth float64) SDF3 {
+	if radius <= 0 || depth <= 0 {
+		panic("invalid foot pocket size")
+	}
+	// extend above z = 0 to give a clean cut
+	return Cylinder3D(2.0*depth, radius, 0)
+}
+
+// Return the 2D (radius, height) profile of a foot or bumper.
+// The foot is on the y = 0 line and extends up to y = height.
+// The profile is symmetric about the y-axis so the axis is inside it.
+func foot_profile(style string, radius, height float64) SDF2 {
+	p := NewPolygon()
+	switch style {
+	case "dome":
+		// a spherical cap
+		r := (radius*radius + height*height) / (2.0 * height)
+		c := V2{0, height - r}
+		a0 := math.Atan2(-c.Y, radius)
+		const n = 32
+		for i := 0; i <= n; i++ {
+			q := c.Add(PolarToXY(r, Mix(a0, PI-a0, float64(i)/n)))
+			p.Add(q.X, q.Y)
+		}
+	case "cone":
+		p.Add(-radius, 0)
+		p.Add(radius, 0)
+		p.Add(0.6*radius, height).Smooth(0.1*radius, 4)
+		p.Add(-0.6*radius, height).Smooth(0.1*radius, 4)
+	case "pad":
+		p.Add(-radius, 0)
+		p.Add(radius, 0)
+		p.Add(radius, height).Smooth(0.2*height, 4)
+		p.Add(-radius, height).Smooth(0.2*height, 4)
+	default:
+		panic("unknown foot style")
+	}
+	return Polygon2D(p.Vertices())
+}
+
+type BumperParms struct {
+	Style  string  // bumper profile: "dome", "cone", "pad"
+	Radius float64 // radius at the face
+	Height float64 // height above the face
+	Peg    V2      // push-in peg radius and length (0 for none)
+}
+
+// Bumper3D returns a bumper (E.g. printed in TPU) for the outside of a box.
+// The face is the z = 0 plane, the bumper is above it. The peg goes into
+// a hole in the wall below the face, a barb at the end of the peg holds it.
+func Bumper3D(k *BumperParms) SDF3 {
+	if k.Radius <= 0 || k.Height <= 0 {
+		panic("invalid bumper size")
+	}
+	s := Revolve3D(foot_profile(k.Style, k.Radius, k.Height))
+	if k.Peg.X > 0 && k.Peg.Y > 0 {
+		r := k.Peg.X
+		l := k.Peg.Y
+		b := Min(0.25*r, 0.25*l)
+		peg := NewPolygon()
+		peg.Add(-r, 0)
+		peg.Add(-r, -l+2.0*b)
+		peg.Add(-r-b, -l+b)
+		peg.Add(-r, -l)
+		peg.Add(r, -l)
+		peg.Add(r+b, -l+b)
+		peg.Add(r, -l+2.0*b)
+		peg.Add(r, 0)
+		s = Union3D(s, Revolve3D(Polygon2D(peg.Vertices())))
+	}
+	return s
+}
+
+type ScrewFootParms struct {
+	Style     string  // foot profile: "dome", "cone", "pad"
+	Radius    float64 // radius at the face
+	Height    float64 // height above the face
+	Screw     string  // screw thread (E.g. "M3")
+	Wall      float64 // wall thickness (for the screw hole)
+	Clearance float64 // clearance on the screw hole and nut pocket
+}
+
+// ScrewFoot3D returns a screw-on foot and a cutter for the screw hole in the wall.
+// The face is the z = 0 plane, the foot is above it and the wall is below it.
+// The hex nut pocket is in the bottom of the foot, it is recessed so the nut
+// stays off the ground.
+func ScrewFoot3D(k *ScrewFootParms) (SDF3, SDF3) {
+	if k.Wall <= 0 || k.Clearance < 0 {
+		panic("invalid foot parameters")
+	}
+	spec, err := ParseThread(k.Screw)
+	if err != nil {
+		panic(err)
+	}
+	t := spec.Thread
+	rs := t.Radius + k.Clearance
+	nut_r := hex_f2f(t)/(2.0*math.Cos(DtoR(30))) + k.Clearance
+	nut_h := 0.8 * 2.0 * t.Radius
+	recess := 1.0
+	if k.Height <= nut_h+recess+1.0 || k.Radius <= nut_r+1.0 {
+		panic("foot is too small for the nut")
+	}
+	foot := Revolve3D(foot_profile(k.Style, k.Radius, k.Height))
+	// the nut pocket is open at the bottom of the foot
+	pocket := Extrude3D(Polygon2D(Nagon(6, nut_r)), 2.0*(nut_h+recess))
+	pocket = Transform3D(pocket, Translate3d(V3{0, 0, k.Height}))
+	hole := Cylinder3D(2.0*(k.Height+k.Wall), rs, 0)
+	foot = Difference3D(foot, Union3D(pocket, hole))
+	// screw hole through the wall
+	hole = Cylinder3D(2.0*k.Wall, rs, 0)
+	return foot, hole
+}
+
+//-----------------------------------------------------------------------------
+// Cable Management
+
+// WallAnchor returns a transform that places a wall feature on a face.
+// The feature face is the z = 0 plane with y up, the wall is below it.
+// p is the position on the face, normal is the outward face normal and
+// up is the direction for the feature y-axis.
+func WallAnchor(p, normal, up V3) M44 {
+	z := normal.Normalize()
+	// the component of up in the face
+	y := up.Sub(z.MulScalar(up.Dot(z)))
+	if y.Length() < EPSILON {
+		panic("up is normal to the face")
+	}
+	y = y.Normalize()
+	x := y.Cross(z)
+	return M44{
+		x.X, y.X, z.X, p.X,
+		x.Y, y.Y, z.Y, p.Y,
+		x.Z, y.Z, z.Z, p.Z,
+		0, 0, 0, 1}
+}
+
+// Common zip-tie sizes (width, thickness) by nominal width.
+var zip_tie_db = map[string]V2{
+	"2.5": {2.5, 1.1},
+	"3.6": {3.6, 1.3},
+	"4.8": {4.8, 1.5},
+	"7.6": {7.6, 2.0},
+}
+
+type ZipTieParms struct {
+	Tie       string  // nominal tie width, E.g. "3.6"
+	Span      float64 // distance between the slots (the cable goes between them)
+	Wall      float64 // wall thickness (slots) or bridge thickness (mount)
+	Clearance float64 // clearance around the tie
+}
+
+// Return the slot size for a zip-tie.
+func zip_tie_slot(k *ZipTieParms) V2 {
+	tie, ok := zip_tie_db[k.Tie]
+	if !ok {
+		panic("unknown zip-tie size")
+	}
+	if k.Wall <= 0 || k.Clearance < 0 {
+		panic("invalid zip-tie parameters")
+	}
+	// thickness along x, width along y
+	return V2{tie.Y, tie.X}.AddScalar(2.0 * k.Clearance)
+}
+
+// ZipTieSlots3D returns a cutter for a pair of zip-tie slots through a wall.
+// The face is the z = 0 plane, the wall is below it. The cable runs along the
+// y-axis between the slots and the tie loops around it through the wall.
+func ZipTieSlots3D(k *ZipTieParms) SDF3 {
+	slot := zip_tie_slot(k)
+	if k.Span <= 0 {
+		panic("invalid zip-tie span")
+	}
+	s := Box3D(V3{slot.X, slot.Y, 2.0 * k.Wall}, 0)
+	x := 0.5 * (k.Span + slot.X)
+	s0 := Transform3D(s, Translate3d(V3{-x, 0, -0.5 * k.Wall}))
+	s1 := Transform3D(s, Translate3d(V3{x, 0, -0.5 * k.Wall}))
+	return Union3D(s0, s1)
+}
+
+// ZipTieMount3D returns a bridge for a zip-tie, for walls that can't have
+// holes in them. The face is the z = 0 plane, the bridge is above it and the
+// tie goes through the tunnel under it. The cable runs along the y-axis on top
+// of the bridge. The span is not used.
+func ZipTieMount3D(k *ZipTieParms) SDF3 {
+	slot := zip_tie_slot(k)
+	// the tunnel runs along x, it is as wide as the tie
+	w := slot.Y + 2.0*k.Wall
+	h := slot.X + k.Wall
+	bridge := Box3D(V3{w, w, h}, 0)
+	bridge = Transform3D(bridge, Translate3d(V3{0, 0, 0.5 * h}))
+	tunnel := Box3D(V3{2.0 * w, slot.Y, 2.0 * slot.X}, 0)
+	return Difference3D(bridge, tunnel)
+}
+
+type StrainReliefParms struct {
+	Cable     float64 // cable diameter
+	Length    float64 // length of the clamp along the cable
+	Screw     string  // clamp screw thread (E.g. "M3")
+	Grip      float64 // diametral squeeze on the cable
+	Clearance float64 // clearance on the screw holes
+}
+
+// StrainRelief3D returns the base and the clamp bar of a cable strain relief.
+// The face is the z = 0 plane, the base is above it and the cable runs along
+// the y-axis. The clamp is returned in its assembled position. The halves
+// have a gap of Grip between them, the screws close the gap and squeeze the
+// cable. The base has pilot holes for self-tapping screws.
+func StrainRelief3D(k *StrainReliefParms) (SDF3, SDF3) {
+	if k.Cable <= 0 || k.Length <= 0 || k.Clearance < 0 {
+		panic("invalid strain relief parameters")
+	}
+	if k.Grip < 0 || k.Grip >= 0.5*k.Cable {
+		panic("invalid grip")
+	}
+	spec, err := ParseThread(k.Screw)
+	if err != nil {
+		panic(err)
+	}
+	t := spec.Thread
+	r := 0.5 * k.Cable
+	g := 0.5 * k.Grip
+	// material around the cable and the screws
+	m := 2.0 * t.Radius
+	rs := t.Radius + k.Clearance
+	xs := r + m + rs
+	w := 2.0 * (xs + rs + m)
+	// cable axis
+	zc := m + r
+	cable := Cylinder3D(2.0*k.Length, r, 0)
+	cable = Transform3D(cable, Translate3d(V3{0, 0, zc}).Mul(RotateX(DtoR(90))))
+	// screw holes
+	pilot := Cylinder3D(2.0*zc, t.Radius-0.5*t.Pitch, 0)
+	hole := Cylinder3D(4.0*zc, rs, 0)
+	holes := func(s SDF3) SDF3 {
+		s0 := Transform3D(s, Translate3d(V3{-xs, 0, zc}))
+		s1 := Transform3D(s, Translate3d(V3{xs, 0, zc}))
+		return Union3D(s0, s1)
+	}
+	// base
+	hb := zc - g
+	base := Box3D(V3{w, k.Length, hb}, 0)
+	base = Transform3D(base, Translate3d(V3{0, 0, 0.5 * hb}))
+	base = Difference3D(base, Union3D(cable, holes(pilot)))
+	// clamp
+	hc := r - g + m
+	clamp := Box3D(V3{w, k.Length, hc}, 0)
+	clamp = Transform3D(clamp, Translate3d(V3{0, 0, zc + g + 0.5*hc}))
+	clamp = Difference3D(clamp, Union3D(cable, holes(hole)))
+	return base, clamp
+}
+
+//-----------------------------------------------------------------------------
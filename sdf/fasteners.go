@@ -0,0 +1,479 @@
+//-----------------------------------------------------------------------------
+/*
+
+Bolts, Nuts and Washers
+
+Hardware for test fits built on the thread primitives. Threads are given by
+designation (E.g. "M6x1-6g", "1/4-20 UNC-2A") so the class fit is applied. An
+extra tolerance can be given for 3d printed parts (+ve is looser).
+
+Bolts have their axis on the z-axis with the underside of the head on the
+z = 0 plane. The head is below z = 0 and the shank/thread runs up to the
+length of the bolt. Nuts and washers sit on the z = 0 plane.
+
+Head proportions are based on the nominal diameter (d):
+
+hex head (ISO 4017): height 0.64d, flats from the thread database (or 1.6d)
+socket head (ISO 4762): diameter 1.5d, height d, hex socket 0.8d
+countersunk (ISO 10642): diameter 2d, 90 degree countersink, hex socket 0.6d
+
+Tapping screws (wood screws and thread forming screws for plastics) have a
+tapered tip where the thread runs out to a point. Screw bosses give the pilot
+hole, boss diameter and engagement depth for a tapping screw into a plastic
+part. The boss factors (times d) are typical values from the screw makers'
+design guides for PT/Delta PT screws:
+
+material: pilot hole, boss diameter, engagement depth
+ABS: 0.80, 2.0, 2.0
+PA: 0.75, 1.85, 1.7
+PC: 0.85, 2.5, 2.2
+PP: 0.70, 2.0, 2.0
+
+The entry of the boss has a counterbore (1.05d, 0.3d deep) to stop the
+first thread from cracking the edge of the hole. 3d printed holes are often
+undersize, use the clearance to open up the pilot hole.
+
+Threaded standoffs (E.g. PCB spacers) have a hex or round body with a male
+stud or female thread at each end, so they can be stacked. The body is from
+z = 0 to the length, a male stud is on the top and a female thread is in the
+bottom of a male-female standoff. The body size is the hex nut flat to flat
+distance for the thread, the default stud length is 2d and the default
+female thread depth is 2.5d (or the body length). Plain spacers have a
+clearance hole (1.08d) for the bolt.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+
+// Return the thread specification with an extra tolerance.
+func fastener_thread(name string, tolerance float64) *ThreadSpec {
+	spec, err := ParseThread(name)
+	if err != nil {
+		panic(err)
+	}
+	if tolerance < 0 {
+		panic("invalid tolerance")
+	}
+	if spec.Internal {
+		spec.Offset += tolerance
+	} else {
+		spec.Offset -= tolerance
+	}
+	return spec
+}
+
+// Return the hex flat to flat distance for a thread.
+func hex_f2f(t *ThreadParameters) float64 {
+	if t.Hex_Flat2Flat > 0 {
+		return t.Hex_Flat2Flat
+	}
+	return 1.6 * 2.0 * t.Radius
+}
+
+// Return a hex socket cutter with the top at z = 0.
+func hex_socket(f2f, depth float64) SDF3 {
+	r := f2f / (2.0 * math.Cos(DtoR(30)))
+	s := Extrude3D(Polygon2D(Nagon(6, r)), 2.0*depth)
+	return s
+}
+
+//-----------------------------------------------------------------------------
+// Bolts
+
+type BoltParms struct {
+	Thread      string  // thread designation (E.g. "M6x1-6g")
+	Style       string  // head style: "hex", "socket", "countersunk"
+	Tolerance   float64 // extra clearance on the thread radius
+	TotalLength float64 // threaded length + shank length (not including the head)
+	ShankLength float64 // non threaded length
+}
+
+// Bolt3D returns a bolt.
+func Bolt3D(k *BoltParms) SDF3 {
+	spec := fastener_thread(k.Thread, k.Tolerance)
+	if spec.Internal {
+		panic("bolts need an external thread")
+	}
+	if k.TotalLength <= 0 || k.ShankLength < 0 || k.ShankLength >= k.TotalLength {
+		panic("invalid bolt length")
+	}
+	t := spec.Thread
+	d := 2.0 * t.Radius
+
+	// head
+	var head SDF3
+	switch k.Style {
+	case "hex":
+		r := hex_f2f(t) / (2.0 * math.Cos(DtoR(30)))
+		h := 0.64 * d
+		head = HexHead3D(r, h, "b")
+		head = Transform3D(head, Translate3d(V3{0, 0, -0.5 * h}))
+	case "socket":
+		h := d
+		head = Cylinder3D(h, 0.75*d, 0.05*d)
+		head = Transform3D(head, Translate3d(V3{0, 0, -0.5 * h}))
+		head = Difference3D(head, Transform3D(hex_socket(0.8*d, 0.6*d), Translate3d(V3{0, 0, -h})))
+	case "countersunk":
+		h := 0.5 * d
+		head = Cone3D(h, d, 0.5*d, 0)
+		head = Transform3D(head, Translate3d(V3{0, 0, -0.5 * h}))
+		head = Difference3D(head, Transform3D(hex_socket(0.6*d, 0.6*h), Translate3d(V3{0, 0, -h})))
+	default:
+		panic("unknown bolt head style")
+	}
+
+	// shank: overlap the head to join them
+	var shank SDF3
+	if k.ShankLength > 0 {
+		l := k.ShankLength + 0.5*d
+		shank = Cylinder3D(l, t.Radius+spec.Offset, 0)
+		shank = Transform3D(shank, Translate3d(V3{0, 0, k.ShankLength - 0.5*l}))
+	}
+
+	// thread: lead-in chamfer at the end, runout next to the shank/head
+	l := k.TotalLength - k.ShankLength
+	tp := ThreadParms{Length: l, LeadIn: Min(0.5*t.Pitch, 0.5*l), Runout: Min(2.0*t.Pitch, 0.5*l)}
+	thread := Thread3D(spec, &tp)
+	thread = Transform3D(thread, Translate3d(V3{0, 0, k.ShankLength}))
+
+	return Union3D(head, shank, thread)
+}
+
+//-----------------------------------------------------------------------------
+// Nuts
+
+type NutParms struct {
+	Thread    string  // thread designation (E.g. "M6x1-6H")
+	Style     string  // nut style: "hex", "knurl"
+	Tolerance float64 // extra clearance on the thread radius
+}
+
+// Nut3D returns a nut. The thread is countersunk on both faces.
+func Nut3D(k *NutParms) SDF3 {
+	spec := fastener_thread(k.Thread, k.Tolerance)
+	if !spec.Internal {
+		if spec.Class != "" {
+			panic("nuts need an internal thread")
+		}
+		// basic profile: make it internal
+		spec.Internal = true
+		spec.Offset = -spec.Offset
+	}
+	t := spec.Thread
+	d := 2.0 * t.Radius
+	r := hex_f2f(t) / (2.0 * math.Cos(DtoR(30)))
+	h := 0.8 * d
+
+	var nut SDF3
+	switch k.Style {
+	case "hex":
+		nut = HexHead3D(r, h, "tb")
+	case "knurl":
+		nut = KnurledHead3D(r, h, r*0.25)
+	default:
+		panic("unknown nut style")
+	}
+	nut = Transform3D(nut, Translate3d(V3{0, 0, 0.5 * h}))
+
+	// internal thread: countersink the top, extend past the bottom
+	c := 0.5 * t.Pitch
+	thread := Thread3D(spec, &ThreadParms{Length: h + t.Pitch, LeadIn: c})
+	thread = Transform3D(thread, Translate3d(V3{0, 0, -t.Pitch}))
+	// countersink the bottom
+	cone := Cone3D(2.0*c, t.Radius+spec.Offset+2.0*c, t.Radius+spec.Offset, 0)
+	thread = Union3D(thread, cone)
+
+	return Difference3D(nut, thread)
+}
+
+//-----------------------------------------------------------------------------
+// Washers
+
+type WasherParms struct {
+	Thread    string  // thread designation for the bolt (E.g. "M6")
+	Clearance float64 // extra clearance on the hole radius
+}
+
+// PlainWasher3D returns a plain washer for a bolt (ISO 7089 proportions).
+// The hole is a clearance hole (1.08d), the outside diameter is 2d and the
+// thickness is 0.16d.
+func PlainWasher3D(k *WasherParms) SDF3 {
+	spec, err := ParseThread(k.Thread)
+	if err != nil {
+		panic(err)
+	}
+	if k.Clearance < 0 {
+		panic("invalid clearance")
+	}
+	d := 2.0 * spec.Thread.Radius
+	h := 0.16 * d
+	s := Washer3D(h, 0.54*d+k.Clearance, d)
+	return Transform3D(s, Translate3d(V3{0, 0, 0.5 * h}))
+}
+
+//-----------------------------------------------------------------------------
+// Tapping Screws
+
+type TappingScrewParms struct {
+	Diameter float64 // nominal (major) diameter
+	Pitch    float64 // thread to thread distance
+	Length   float64 // length under the head (including the tip)
+	Tip      float64 // length of the tapered tip
+	Thread   string  // thread form: "wood", "plastic"
+	Head     string  // head style: "pan", "countersunk"
+}
+
+// TappingScrew3D returns a tapping screw with a tapered tip.
+func TappingScrew3D(k *TappingScrewParms) SDF3 {
+	d := k.Diameter
+	r := 0.5 * d
+	p := k.Pitch
+	if d <= 0 || p <= 0 || p >= r {
+		panic("invalid diameter/pitch")
+	}
+	if k.Length <= 0 || k.Tip <= 0 || k.Tip > k.Length {
+		panic("invalid screw length")
+	}
+	var profile func(radius, pitch float64) SDF2
+	switch k.Thread {
+	case "wood":
+		profile = WoodScrewThread
+	case "plastic":
+		profile = PlasticScrewThread
+	default:
+		panic("unknown thread form")
+	}
+
+	// head
+	var head SDF3
+	switch k.Head {
+	case "pan":
+		h := 0.7 * d
+		head = Cylinder3D(h, d, 0.2*d)
+		head = Transform3D(head, Translate3d(V3{0, 0, -0.5 * h}))
+		head = Difference3D(head, Transform3D(hex_socket(0.5*d, 0.5*h), Translate3d(V3{0, 0, -h})))
+	case "countersunk":
+		h := 0.5 * d
+		head = Cone3D(h, d, 0.5*d, 0)
+		head = Transform3D(head, Translate3d(V3{0, 0, -0.5 * h}))
+		head = Difference3D(head, Transform3D(hex_socket(0.5*d, 0.6*h), Translate3d(V3{0, 0, -h})))
+	default:
+		panic("unknown screw head style")
+	}
+
+	// body
+	l := k.Length - k.Tip
+	var body SDF3
+	if l > 0 {
+		body = Screw3D(profile(r, p), l, p, 1)
+		body = Transform3D(body, Translate3d(V3{0, 0, 0.5 * l}))
+	}
+
+	// tip: the thread tapers from r to 0, the profile is at r/2 in the middle.
+	// Rotate the tip so the thread lines up with the body.
+	zc := l + 0.5*k.Tip
+	tip := TaperScrew3D(profile(0.5*r, p), k.Tip, p, r/k.Tip, 1)
+	phase := TAU * (zc - 0.5*l) / p
+	tip = Transform3D(tip, Translate3d(V3{0, 0, zc}).Mul(RotateZ(phase)))
+
+	return Union3D(head, body, tip)
+}
+
+//-----------------------------------------------------------------------------
+// Screw Bosses
+
+type boss_factors struct {
+	pilot   float64 // pilot hole diameter / d
+	outside float64 // boss diameter / d
+	depth   float64 // engagement depth / d
+}
+
+var boss_db = map[string]boss_factors{
+	"ABS":  {0.80, 2.0, 2.0},
+	"ASA":  {0.78, 2.0, 2.0},
+	"PA":   {0.75, 1.85, 1.7},
+	"PBT":  {0.75, 1.85, 1.7},
+	"PC":   {0.85, 2.5, 2.2},
+	"PE":   {0.70, 1.8, 1.8},
+	"POM":  {0.75, 1.95, 2.0},
+	"PP":   {0.70, 2.0, 2.0},
+	"PLA":  {0.80, 2.2, 2.0},
+	"PETG": {0.80, 2.2, 2.0},
+}
+
+type ScrewBoss struct {
+	Pilot       float64 // pilot hole diameter
+	Outside     float64 // boss diameter
+	Depth       float64 // screw engagement depth
+	Relief      float64 // entry counterbore diameter
+	ReliefDepth float64 // entry counterbore depth
+}
+
+// ScrewBossLookup returns the boss dimensions for a tapping screw of a given
+// diameter into a material.
+func ScrewBossLookup(diameter float64, material string) *ScrewBoss {
+	if diameter <= 0 {
+		panic("invalid diameter")
+	}
+	f, ok := boss_db[material]
+	if !ok {
+		panic("boss material not found")
+	}
+	d := diameter
+	return &ScrewBoss{
+		Pilot:       f.pilot * d,
+		Outside:     f.outside * d,
+		Depth:       f.depth * d,
+		Relief:      1.05 * d,
+		ReliefDepth: 0.3 * d,
+	}
+}
+
+type ScrewBossParms struct {
+	Diameter  float64 // nominal screw diameter
+	Material  string  // boss material (E.g. "ABS", "PP")
+	Height    float64 // height of the boss
+	Clearance float64 // extra clearance on the pilot hole radius
+}
+
+// ScrewBoss3D returns a boss for a tapping screw. The boss sits on the z = 0
+// plane, the pilot hole is in the top and leaves room for the screw tip.
+func ScrewBoss3D(k *ScrewBossParms) SDF3 {
+	b := ScrewBossLookup(k.Diameter, k.Material)
+	if k.Clearance < 0 {
+		panic("invalid clearance")
+	}
+	h := k.Height
+	if h < b.ReliefDepth+b.Depth {
+		panic("boss is too short for the engagement depth")
+	}
+	boss := Cylinder3D(h, 0.5*b.Outside, 0)
+	boss = Transform3D(boss, Translate3d(V3{0, 0, 0.5 * h}))
+	// pilot hole: engagement depth + the tip, below the counterbore
+	l := Min(b.ReliefDepth+b.Depth+0.5*k.Diameter, h)
+	hole := Cylinder3D(l+1, 0.5*b.Pilot+k.Clearance, 0)
+	hole = Transform3D(hole, Translate3d(V3{0, 0, h + 0.5 - 0.5*l}))
+	relief := Cylinder3D(b.ReliefDepth+1, 0.5*b.Relief+k.Clearance, 0)
+	relief = Transform3D(relief, Translate3d(V3{0, 0, h + 0.5 - 0.5*b.ReliefDepth}))
+	return Difference3D(boss, Union3D(hole, relief))
+}
+
+//-----------------------------------------------------------------------------
+// Standoffs and Spacers
+
+// Return a hex or round body from z = 0 to h.
+func standoff_body(style string, f2f, h float64) SDF3 {
+	var body SDF3
+	switch style {
+	case "hex":
+		body = HexHead3D(f2f/(2.0*math.Cos(DtoR(30))), h, "")
+	case "round":
+		body = Cylinder3D(h, 0.5*f2f, 0)
+	default:
+		panic("unknown standoff body style")
+	}
+	return Transform3D(body, Translate3d(V3{0, 0, 0.5 * h}))
+}
+
+type ThreadedStandoffParms struct {
+	Thread    string  // basic thread designation (E.g. "M3")
+	Ends      string  // "female-female", "male-female", "male-male"
+	Body      string  // body style: "hex", "round"
+	Length    float64 // body length
+	Stud      float64 // male stud length (0 for the default)
+	Depth     float64 // female thread depth (0 for the default)
+	Tolerance float64 // extra clearance on the thread radius
+}
+
+// ThreadedStandoff3D returns a threaded standoff.
+func ThreadedStandoff3D(k *ThreadedStandoffParms) SDF3 {
+	spec, err := ParseThread(k.Thread)
+	if err != nil {
+		panic(err)
+	}
+	if spec.Class != "" {
+		panic("standoffs need a basic thread designation")
+	}
+	if k.Length <= 0 || k.Stud < 0 || k.Depth < 0 || k.Tolerance < 0 {
+		panic("invalid standoff parameters")
+	}
+	t := spec.Thread
+	d := 2.0 * t.Radius
+	male := *spec
+	male.Offset = -k.Tolerance
+	female := *spec
+	female.Internal = true
+	female.Offset = k.Tolerance
+	stud := k.Stud
+	if stud == 0 {
+		stud = 2.0 * d
+	}
+	depth := k.Depth
+	if depth == 0 {
+		depth = 2.5 * d
+	}
+	depth = Min(depth, k.Length)
+
+	// male stud on top of the body
+	male_stud := func() SDF3 {
+		tp := ThreadParms{Length: stud, LeadIn: Min(0.5*t.Pitch, 0.5*stud)}
+		return Transform3D(Thread3D(&male, &tp), Translate3d(V3{0, 0, k.Length}))
+	}
+	// female thread cutter with the entry at the top of the body
+	female_hole := func() SDF3 {
+		tp := ThreadParms{Length: depth, LeadIn: Min(0.5*t.Pitch, 0.5*depth)}
+		return Transform3D(Thread3D(&female, &tp), Translate3d(V3{0, 0, k.Length - depth}))
+	}
+	// flip the top end to the bottom
+	flip := Translate3d(V3{0, 0, k.Length}).Mul(RotateX(PI))
+
+	body := standoff_body(k.Body, hex_f2f(t), k.Length)
+	switch k.Ends {
+	case "female-female":
+		return Difference3D(body, Union3D(female_hole(), Transform3D(female_hole(), flip)))
+	case "male-female":
+		return Union3D(Difference3D(body, Transform3D(female_hole(), flip)), male_stud())
+	case "male-male":
+		return Union3D(body, male_stud(), Transform3D(male_stud(), flip))
+	}
+	panic("unknown standoff ends")
+}
+
+type SpacerParms struct {
+	Thread    string  // thread designation for the bolt (E.g. "M3")
+	Body      string  // body style: "hex", "round"
+	Length    float64 // spacer length
+	Size      float64 // round diameter or hex flat to flat (0 for the default)
+	Clearance float64 // extra clearance on the hole radius
+}
+
+// Spacer3D returns a plain spacer with a clearance hole for a bolt.
+func Spacer3D(k *SpacerParms) SDF3 {
+	spec, err := ParseThread(k.Thread)
+	if err != nil {
+		panic(err)
+	}
+	if k.Length <= 0 || k.Size < 0 || k.Clearance < 0 {
+		panic("invalid spacer parameters")
+	}
+	t := spec.Thread
+	size := k.Size
+	if size == 0 {
+		size = hex_f2f(t)
+	}
+	hole := 0.54*2.0*t.Radius + k.Clearance
+	if hole >= 0.5*size {
+		panic("spacer is too small for the hole")
+	}
+	body := standoff_body(k.Body, size, k.Length)
+	cutter := Cylinder3D(k.Length+2.0, hole, 0)
+	cutter = Transform3D(cutter, Translate3d(V3{0, 0, 0.5 * k.Length}))
+	return Difference3D(body, cutter)
+}
+
+//-----------------------------------------------------------------------------
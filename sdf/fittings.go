@@ -0,0 +1,187 @@
+//-----------------------------------------------------------------------------
+/*
+
+Fluid Fittings
+
+Push-Fit Stems
+
+Plug-in stems for push-to-connect pneumatic fittings (4, 6, 8mm tube OD).
+The stem has the outside diameter of the tube and is pushed into the collet
+of the fitting. The collet grips on the stem OD, so the clearance is small.
+
+Luer Fittings (ISO 80369-7)
+
+Luer slip fittings seal on a 6% taper (on the diameter). Luer lock fittings
+add a 2 start thread (5mm lead): the lugs on the female hub screw into the
+collar around the male tip.
+
+male tip: 3.95mm diameter at the tip, 7.5mm long
+female socket: 4.27mm diameter at the opening, 8mm deep
+
+The fittings have their axis on the z-axis and the base on the z = 0 plane.
+The free end (tip, socket opening) is at the top. The fluid path bore runs
+through the fitting.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+// Push-Fit Stems
+
+type pushfit_size struct {
+	od    float64 // tube outside diameter
+	id    float64 // tube inside diameter (stem bore)
+	depth float64 // insertion depth
+}
+
+var pushfit_db = map[string]pushfit_size{
+	"4mm": {4, 2.5, 15},
+	"6mm": {6, 4, 16.5},
+	"8mm": {8, 5.5, 18.5},
+}
+
+type PushFitParms struct {
+	Size      string  // tube size: "4mm", "6mm", "8mm"
+	Tolerance float64 // radial clearance (+ve makes the stem smaller)
+	Length    float64 // stem length (0 for the standard insertion depth)
+}
+
+// PushFitStem3D returns a plug-in stem for a push-to-connect fitting.
+func PushFitStem3D(k *PushFitParms) SDF3 {
+	t, ok := pushfit_db[k.Size]
+	if !ok {
+		panic("push-fit size not found")
+	}
+	if k.Tolerance < 0 || k.Tolerance > 0.1*t.od {
+		panic("invalid tolerance")
+	}
+	l := k.Length
+	if l == 0 {
+		l = t.depth
+	}
+	if l <= 0 {
+		panic("invalid stem length")
+	}
+	r := 0.5*t.od - k.Tolerance
+	// the tip is chamfered to lead into the collet and o-ring
+	c := Min(0.25*(r-0.5*t.id), 0.5)
+	stem := NewPolygon()
+	stem.Add(0.5*t.id, 0)
+	stem.Add(r, 0)
+	stem.Add(r, l-c)
+	stem.Add(r-c, l)
+	stem.Add(0.5*t.id, l)
+	return Revolve3D(Polygon2D(stem.Vertices()))
+}
+
+//-----------------------------------------------------------------------------
+// Luer Fittings
+
+const (
+	luer_taper     = 0.03  // change in radius per unit length
+	luer_male_r    = 1.975 // male tip radius
+	luer_male_l    = 7.5   // male tip length
+	luer_female_r  = 2.135 // female socket radius at the opening
+	luer_female_l  = 8.0   // female socket depth
+	luer_lead      = 5.0   // lock thread lead
+	luer_lug_r     = 3.9   // radius at the tips of the female lugs
+	luer_lug_depth = 0.6   // depth of the lock thread
+)
+
+type LuerParms struct {
+	Lock      bool    // luer lock (else luer slip)
+	Tolerance float64 // radial clearance on the taper and lock thread
+	Bore      float64 // fluid path radius (0 for the default)
+}
+
+// Return the fluid path radius.
+func (k *LuerParms) bore() float64 {
+	if k.Tolerance < 0 || k.Tolerance > 0.2 {
+		panic("invalid tolerance")
+	}
+	if k.Bore == 0 {
+		return 0.6
+	}
+	if k.Bore < 0 || k.Bore >= luer_male_r-0.5 {
+		panic("invalid bore")
+	}
+	return k.Bore
+}
+
+// Return the 2d profile for the lock thread.
+// This is a shallow trapezoidal thread (30 degree flanks).
+func luer_thread(radius, depth, pitch float64) SDF2 {
+	t := 0.25 * pitch
+	dx := 0.5 * depth * math.Tan(DtoR(15))
+	lt := NewPolygon()
+	lt.Add(pitch, 0)
+	lt.Add(pitch, radius-depth)
+	lt.Add(t+dx, radius-depth)
+	lt.Add(t-dx, radius)
+	lt.Add(-t+dx, radius)
+	lt.Add(-t-dx, radius-depth)
+	lt.Add(-pitch, radius-depth)
+	lt.Add(-pitch, 0)
+	return Polygon2D(lt.Vertices())
+}
+
+// Return the lock thread from z = 0 to z = length.
+func luer_lock_thread(radius, length float64) SDF3 {
+	pitch := 0.5 * luer_lead
+	s := Screw3D(luer_thread(radius, luer_lug_depth, pitch), length, pitch, 2)
+	return Transform3D(s, Translate3d(V3{0, 0, 0.5 * length}))
+}
+
+// MaleLuer3D returns a male luer slip or luer lock fitting.
+func MaleLuer3D(k *LuerParms) SDF3 {
+	b := k.bore()
+	// 6% taper, smaller at the tip
+	r := luer_male_r - k.Tolerance
+	tip := Cone3D(luer_male_l, r+luer_taper*luer_male_l, r, 0)
+	tip = Transform3D(tip, Translate3d(V3{0, 0, 0.5 * luer_male_l}))
+	s := tip
+	if k.Lock {
+		// threaded collar around the tip
+		l := luer_male_l - 1.0
+		r_lug := luer_lug_r + k.Tolerance
+		collar := Cylinder3D(l, r_lug+1.2, 0)
+		collar = Transform3D(collar, Translate3d(V3{0, 0, 0.5 * l}))
+		// the thread cutter is the shape of the lugs, it runs from the base plate
+		thread := luer_lock_thread(r_lug, l+luer_lead)
+		thread = Transform3D(thread, Translate3d(V3{0, 0, 1.0}))
+		collar = Difference3D(collar, thread)
+		s = Union3D(collar, tip)
+	}
+	bore := Cylinder3D(4.0*luer_male_l, b, 0)
+	return Difference3D(s, bore)
+}
+
+// FemaleLuer3D returns a female luer slip or luer lock fitting.
+func FemaleLuer3D(k *LuerParms) SDF3 {
+	b := k.bore()
+	r := luer_female_r + k.Tolerance
+	l := luer_female_l + 1.0
+	hub := Cylinder3D(l, r+1.0, 0)
+	hub = Transform3D(hub, Translate3d(V3{0, 0, 0.5 * l}))
+	if k.Lock {
+		// lugs at the opening
+		lh := 3.0
+		lugs := luer_lock_thread(luer_lug_r-k.Tolerance, lh)
+		lugs = Transform3D(lugs, Translate3d(V3{0, 0, l - lh}))
+		hub = Union3D(hub, lugs)
+	}
+	// 6% taper, larger at the opening
+	socket := Cone3D(luer_female_l, r-luer_taper*luer_female_l, r, 0)
+	socket = Transform3D(socket, Translate3d(V3{0, 0, l - 0.5*luer_female_l}))
+	// extend the socket past the opening
+	mouth := Cylinder3D(2.0, r, 0)
+	mouth = Transform3D(mouth, Translate3d(V3{0, 0, l + 0.5}))
+	bore := Cylinder3D(4.0*l, b, 0)
+	return Difference3D(hub, Union3D(socket, mouth, bore))
+}
+
+//-----------------------------------------------------------------------------
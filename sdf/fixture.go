@@ -0,0 +1,227 @@
+//-----------------------------------------------------------------------------
+/*
+
+Fixtures
+
+Generate soft jaws (or fixture blocks) that hold an existing part.
+The cavity in the jaws is the offset negative of the part.
+
+Generate drill guide plates from a list of holes.
+
+Generate stands (or docks) with a cradle that conforms to an object.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+
+type SoftJawParms struct {
+	Grip      V3      // direction the jaws close along (E.g. V3{1, 0, 0})
+	Width     float64 // minimum jaw width (across the grip direction)
+	Margin    float64 // material around the part
+	Clearance float64 // clearance between the part and the cavity
+}
+
+// Return a rotation that maps v onto the x-axis.
+func rotate_to_x(v V3) M44 {
+	v = v.Normalize()
+	x := V3{1, 0, 0}
+	axis := v.Cross(x)
+	if axis.Length() < EPSILON {
+		if v.X > 0 {
+			return Identity3d()
+		}
+		return RotateZ(PI)
+	}
+	return Rotate3d(axis, math.Acos(Clamp(v.Dot(x), -1, 1)))
+}
+
+// SoftJaws3D returns a pair of jaws that hold a part.
+// The jaws close along the grip direction and hold the part up to the
+// height (z) of its bounding box center, leaving the top of the part exposed.
+// The first jaw is on the -ve side of the grip direction.
+// Note: Undercuts in the part are not removed from the cavity.
+func SoftJaws3D(part SDF3, k *SoftJawParms) (SDF3, SDF3) {
+	if k.Grip.Length() == 0 {
+		panic("invalid grip direction")
+	}
+	if k.Grip.Z != 0 {
+		panic("the grip direction must be horizontal")
+	}
+	if k.Margin <= 0 {
+		panic("invalid margin")
+	}
+	if k.Clearance < 0 {
+		panic("invalid clearance")
+	}
+
+	// work in a frame with the grip along the x-axis
+	m := rotate_to_x(k.Grip)
+	part = Transform3D(part, m)
+	bb := part.BoundingBox()
+	c := bb.Center()
+	size := bb.Size().AddScalar(2 * (k.Margin + k.Clearance))
+
+	// jaw block: up to the part center
+	y := Max(size.Y, k.Width)
+	z := c.Z - (bb.Min.Z - k.Margin - k.Clearance)
+	block := Box3D(V3{size.X, y, z}, 0)
+	block = Transform3D(block, Translate3d(V3{c.X, c.Y, c.Z - 0.5*z}))
+	block = Difference3D(block, Offset3D(part, k.Clearance))
+
+	// split into two jaws
+	jaw0 := Cut3D(block, c, V3{-1, 0, 0})
+	jaw1 := Cut3D(block, c, V3{1, 0, 0})
+
+	// back to the original frame
+	m = m.Inverse()
+	return Transform3D(jaw0, m), Transform3D(jaw1, m)
+}
+
+//-----------------------------------------------------------------------------
+// Drill Guides
+
+type DrillHole struct {
+	Position V2      // hole position
+	Diameter float64 // hole diameter
+}
+
+type DrillGuideParms struct {
+	Holes        []DrillHole // holes (the workpiece corner is at the origin)
+	Thickness    float64     // plate thickness
+	Margin       float64     // plate margin around the holes
+	BushingWall  float64     // wall thickness of the drill bushings (0 for none)
+	BushingDepth float64     // depth of the bushing pockets
+	Registration float64     // depth of the registration lips on the x = 0 and y = 0 edges (0 for none)
+}
+
+// DrillGuide3D returns a drill guide plate for a set of holes.
+// The bottom of the plate is the z = 0 plane and sits on the workpiece.
+// Bushing pockets are in the top of the plate, with a shoulder to hold the bushing.
+// The registration lips hang below the plate and locate it against the
+// edges of the workpiece, with the workpiece corner at the origin.
+func DrillGuide3D(k *DrillGuideParms) SDF3 {
+	if len(k.Holes) == 0 {
+		panic("no holes")
+	}
+	if k.Thickness <= 0 || k.Margin <= 0 {
+		panic("invalid plate size")
+	}
+	if k.BushingWall < 0 || k.BushingDepth < 0 || k.BushingDepth >= k.Thickness {
+		panic("invalid bushing size")
+	}
+	if k.Registration < 0 {
+		panic("invalid registration depth")
+	}
+
+	// work out the plate outline
+	var bb Box2
+	for i, h := range k.Holes {
+		if h.Diameter <= 0 {
+			panic("invalid hole diameter")
+		}
+		r := 0.5*h.Diameter + k.BushingWall
+		hb := Box2{h.Position.SubScalar(r), h.Position.AddScalar(r)}
+		if i == 0 {
+			bb = hb
+		} else {
+			bb = bb.Extend(hb)
+		}
+	}
+	bb = Box2{bb.Min.SubScalar(k.Margin), bb.Max.AddScalar(k.Margin)}
+	if k.Registration > 0 {
+		// the plate extends past the workpiece edges
+		bb = bb.Extend(Box2{V2{-k.Margin, -k.Margin}, V2{-k.Margin, -k.Margin}})
+	}
+	plate := Extrude3D(Box2D(bb.Size(), 0), k.Thickness)
+	plate = Transform3D(plate, Translate3d(V3{bb.Center().X, bb.Center().Y, 0.5 * k.Thickness}))
+
+	// registration lips
+	if k.Registration > 0 {
+		h := k.Registration
+		size := bb.Size()
+		lx := Box3D(V3{k.Margin, size.Y, h}, 0)
+		lx = Transform3D(lx, Translate3d(V3{-0.5 * k.Margin, bb.Center().Y, -0.5 * h}))
+		ly := Box3D(V3{size.X, k.Margin, h}, 0)
+		ly = Transform3D(ly, Translate3d(V3{bb.Center().X, -0.5 * k.Margin, -0.5 * h}))
+		plate = Union3D(plate, lx, ly)
+	}
+
+	// holes and bushing pockets
+	var holes []SDF3
+	for _, h := range k.Holes {
+		r := 0.5 * h.Diameter
+		// extend past the plate faces to give a clean cut
+		l := 2.0 * (k.Thickness + k.Registration)
+		hole := Cylinder3D(l, r, 0)
+		if k.BushingWall > 0 && k.BushingDepth > 0 {
+			d := 2.0 * k.BushingDepth
+			pocket := Cylinder3D(d, r+k.BushingWall, 0)
+			pocket = Transform3D(pocket, Translate3d(V3{0, 0, k.Thickness}))
+			hole = Union3D(hole, pocket)
+		}
+		holes = append(holes, Transform3D(hole, Translate3d(V3{h.Position.X, h.Position.Y, 0})))
+	}
+	return Difference3D(plate, Union3D(holes...))
+}
+
+//-----------------------------------------------------------------------------
+// Conformal Stands
+
+type StandParms struct {
+	Wall      float64 // wall thickness around the object
+	Base      float64 // base thickness below the object
+	Depth     float64 // depth of the object in the cradle
+	Clearance float64 // clearance between the object and the cradle
+	Round     float64 // fillet radius for the block edges and the cradle lip
+	Tilt      float64 // tilt of the object about the x-axis (radians)
+}
+
+// Stand3D returns a stand with a cradle that holds an object.
+// The object is tilted about the x-axis and sits in the cradle with the bottom
+// of its bounding box on top of the base. The cradle is the offset negative of
+// the object, swept upwards so the object can be lifted out.
+// The object placement is returned with the stand.
+func Stand3D(object SDF3, k *StandParms) (SDF3, M44) {
+	if k.Wall <= 0 || k.Base <= 0 || k.Depth <= 0 {
+		panic("invalid stand size")
+	}
+	if k.Clearance < 0 {
+		panic("invalid clearance")
+	}
+	if k.Round < 0 || k.Round >= k.Wall {
+		panic("invalid round")
+	}
+
+	// place the object
+	m := RotateX(k.Tilt)
+	bb := m.MulBox(object.BoundingBox())
+	m = Translate3d(V3{-bb.Center().X, -bb.Center().Y, k.Base - bb.Min.Z}).Mul(m)
+	object = Transform3D(object, m)
+	bb = object.BoundingBox()
+
+	// base block
+	size := bb.Size().AddScalar(2 * (k.Wall + k.Clearance))
+	h := k.Base + k.Depth
+	block := Box3D(V3{size.X, size.Y, h}, k.Round)
+	block = Transform3D(block, Translate3d(V3{0, 0, 0.5 * h}))
+
+	// sweep the cradle upwards out of the block
+	cradle := Offset3D(object, k.Clearance)
+	steps := int(math.Ceil(k.Depth / Max(k.Clearance, 0.5)))
+	motion := func(t float64) M44 {
+		return Translate3d(V3{0, 0, t * k.Depth})
+	}
+	cradle = SweptVolume3D(cradle, motion, steps)
+
+	if k.Round > 0 {
+		return SmoothDifference3D(k.Round, block, cradle), m
+	}
+	return Difference3D(block, cradle), m
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,209 @@
+//-----------------------------------------------------------------------------
+/*
+
+2.5D Milling
+
+Generate G-code to mill an SDF2 profile from flat stock. The tool paths are
+contours of the distance field, so the tool radius offset is just a level
+set: d(p) = +r for an outside profile, d(p) = -r for an inside profile.
+Features smaller than the tool can't be cut and drop out of the contours.
+
+outside: cut around the outside of the profile (E.g. cut out a part)
+inside: cut around the inside of the profile (E.g. cut a hole)
+pocket: clear the whole profile with contours stepping in from the inside
+
+Each contour is cut in passes of at most StepDown until the full depth is
+reached. The top of the stock is at z = 0, the units are mm.
+
+Climb milling (with a clockwise spindle) keeps the material on the right of
+the tool, conventional milling keeps it on the left.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+//-----------------------------------------------------------------------------
+
+type MillParms struct {
+	Mode       string  // tool path: "outside", "inside", "pocket"
+	Tool       float64 // tool diameter
+	Depth      float64 // total depth of cut (+ve)
+	StepDown   float64 // maximum depth of cut per pass
+	StepOver   float64 // pocket step over (fraction of the tool diameter)
+	Resolution float64 // contour sampling step
+	SafeZ      float64 // z height for rapid moves
+	Feed       float64 // cutting feed rate (mm/min)
+	Plunge     float64 // plunge feed rate (mm/min)
+	Spindle    float64 // spindle speed (rpm), 0 for no spindle control
+	Climb      bool    // climb milling, else conventional milling
+}
+
+//-----------------------------------------------------------------------------
+// Tool Paths
+
+// Return the closed contours d(p) = level of an SDF2.
+func sdf2_contours(s SDF2, level, resolution float64) [][]V2 {
+	bb := s.BoundingBox()
+	// Enlarge the box so the contours are closed. The odd size keeps the sample
+	// points off axis aligned edges, marching squares misses contours on them.
+	bb = NewBox2(bb.Center(), bb.Size().AddScalar(2*Max(level, 0)+2.618*resolution))
+	paths := join_lines(MarchingSquares(Offset2D(s, level), bb, resolution), 1e-6*resolution)
+	var closed [][]V2
+	for _, p := range paths {
+		// open paths come from the edge of the box or a degenerate contour
+		if len(p) > 3 && p[0].Equals(p[len(p)-1], 1e-6*resolution) {
+			closed = append(closed, simplify_path(p, 1e-3*resolution))
+		}
+	}
+	return closed
+}
+
+// Remove the points of a path that are on the line between their neighbours.
+func simplify_path(p []V2, tolerance float64) []V2 {
+	out := []V2{p[0]}
+	for i := 1; i < len(p)-1; i++ {
+		a := out[len(out)-1]
+		ab := p[i+1].Sub(a)
+		l := ab.Length()
+		if l > 0 && Abs(ab.Cross(p[i].Sub(a)))/l < tolerance {
+			continue
+		}
+		out = append(out, p[i])
+	}
+	return append(out, p[len(p)-1])
+}
+
+// Orient a path so the material side (d(p) < level if inside is true) is on
+// the right (or left) of the tool.
+func orient_path(p []V2, s SDF2, level, resolution float64, inside, right bool) {
+	// use the longest segment to find the sides of the path
+	k := 0
+	for i := 1; i < len(p)-1; i++ {
+		if p[i+1].Sub(p[i]).Length2() > p[k+1].Sub(p[k]).Length2() {
+			k = i
+		}
+	}
+	t := p[k+1].Sub(p[k]).Normalize()
+	m := p[k].Add(p[k+1]).MulScalar(0.5)
+	left := m.Add(V2{-t.Y, t.X}.MulScalar(0.25 * resolution))
+	left_inside := s.Evaluate(left) < level
+	if (left_inside == inside) == right {
+		// reverse the path
+		for a, b := 0, len(p)-1; a < b; a, b = a+1, b-1 {
+			p[a], p[b] = p[b], p[a]
+		}
+	}
+}
+
+// MillPaths returns the 2D tool paths for milling an SDF2 profile.
+// Pocket paths are ordered from the inside out.
+func MillPaths(s SDF2, k *MillParms) ([][]V2, error) {
+	if k.Tool <= 0 || k.Resolution <= 0 {
+		return nil, fmt.Errorf("invalid tool diameter/resolution")
+	}
+	r := 0.5 * k.Tool
+	var paths [][]V2
+	switch k.Mode {
+	case "outside":
+		for _, p := range sdf2_contours(s, r, k.Resolution) {
+			// the material is inside the contour
+			orient_path(p, s, r, k.Resolution, true, k.Climb)
+			paths = append(paths, p)
+		}
+	case "inside":
+		for _, p := range sdf2_contours(s, -r, k.Resolution) {
+			// the material is outside the contour
+			orient_path(p, s, -r, k.Resolution, false, k.Climb)
+			paths = append(paths, p)
+		}
+	case "pocket":
+		if k.StepOver <= 0 || k.StepOver > 1 {
+			return nil, fmt.Errorf("invalid step over")
+		}
+		step := k.StepOver * k.Tool
+		// the contours vanish before this level
+		n := int(math.Ceil(s.BoundingBox().Size().MaxComponent()/step)) + 1
+		var levels [][][]V2
+		for i := 0; i < n; i++ {
+			level := -r - float64(i)*step
+			contours := sdf2_contours(s, level, k.Resolution)
+			if len(contours) == 0 {
+				break
+			}
+			for _, p := range contours {
+				orient_path(p, s, level, k.Resolution, false, k.Climb)
+			}
+			levels = append(levels, contours)
+		}
+		// finish on the wall
+		for i := len(levels) - 1; i >= 0; i-- {
+			paths = append(paths, levels[i]...)
+		}
+	default:
+		return nil, fmt.Errorf("unknown milling mode \"%s\"", k.Mode)
+	}
+	return paths, nil
+}
+
+//-----------------------------------------------------------------------------
+// G-code
+
+// WriteGCode writes the G-code for milling an SDF2 profile.
+func WriteGCode(w io.Writer, s SDF2, k *MillParms) error {
+	if k.Depth <= 0 || k.StepDown <= 0 || k.SafeZ <= 0 || k.Feed <= 0 || k.Plunge <= 0 {
+		return fmt.Errorf("invalid milling parameters")
+	}
+	paths, err := MillPaths(s, k)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "(%s milling, %.4f mm tool, %.4f mm deep)\n", k.Mode, k.Tool, k.Depth)
+	fmt.Fprintf(bw, "G21\nG90\n")
+	fmt.Fprintf(bw, "G0 Z%.4f\n", k.SafeZ)
+	if k.Spindle > 0 {
+		fmt.Fprintf(bw, "M3 S%.0f\n", k.Spindle)
+	}
+	passes := int(math.Ceil(k.Depth/k.StepDown - EPSILON))
+	for i := 1; i <= passes; i++ {
+		z := -k.Depth * float64(i) / float64(passes)
+		for _, p := range paths {
+			fmt.Fprintf(bw, "G0 X%.4f Y%.4f\n", p[0].X, p[0].Y)
+			fmt.Fprintf(bw, "G1 Z%.4f F%.1f\n", z, k.Plunge)
+			for j, v := range p[1:] {
+				if j == 0 {
+					fmt.Fprintf(bw, "G1 X%.4f Y%.4f F%.1f\n", v.X, v.Y, k.Feed)
+				} else {
+					fmt.Fprintf(bw, "G1 X%.4f Y%.4f\n", v.X, v.Y)
+				}
+			}
+			fmt.Fprintf(bw, "G0 Z%.4f\n", k.SafeZ)
+		}
+	}
+	if k.Spindle > 0 {
+		fmt.Fprintf(bw, "M5\n")
+	}
+	fmt.Fprintf(bw, "M2\n")
+	return bw.Flush()
+}
+
+// SaveGCode writes the G-code for milling an SDF2 profile to a file.
+func SaveGCode(path string, s SDF2, k *MillParms) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return WriteGCode(f, s, k)
+}
+
+//-----------------------------------------------------------------------------
@@ -3,6 +3,21 @@
 
 Involute Gears
 
+Spur, helical and internal (ring) gears from module/teeth/pressure angle.
+
+The gear profiles (SDF2) can have a bore and keyway. The gear solids (SDF3) are
+extruded (spur) or twist extruded (helical) and can have a hub on the top face.
+Helical gears with a +ve helix angle are right handed. A pair of external
+helical gears needs opposite hands, an internal gear and its pinion have the
+same hand.
+
+Bevel gears have their teeth on a cone and worm wheels have a throated rim,
+so neither is a simple extrusion. The straight bevel gear profile is scaled
+linearly towards the cone apex (each tooth is a cone), the tooth profile is
+the involute profile in the plane of the large end (Tredgold's approximation).
+The worm is a screw with a rack tooth profile. The worm wheel is a helical gear
+at the lead angle of the worm with its rim throated to wrap around the worm.
+
 */
 //-----------------------------------------------------------------------------
 
@@ -198,3 +213,328 @@ func (s *GearRackSDF2) BoundingBox() Box2 {
 }
 
 //-----------------------------------------------------------------------------
+
+//-----------------------------------------------------------------------------
+// Gear Generator
+
+type GearParms struct {
+	Teeth         int     // number of gear teeth
+	Module        float64 // pitch circle diameter / number of gear teeth
+	PressureAngle float64 // gear pressure angle (radians)
+	Backlash      float64 // backlash expressed as per-tooth distance at pitch circumference
+	Clearance     float64 // additional root clearance
+	Helix         float64 // helix angle (radians), 0 for spur gears, +ve for right hand
+	Width         float64 // face width of the gear
+	Bore          float64 // bore radius (0 for none)
+	Keyway        V2      // keyway width and depth past the bore (0 for none)
+	Hub           V2      // hub radius and height above the gear (0 for none)
+	Rim           float64 // ring gear wall thickness outside the tooth roots
+	Facets        int     // number of facets for involute flank (0 for the default)
+}
+
+// PitchRadius returns the pitch circle radius of the gear.
+func (k *GearParms) PitchRadius() float64 {
+	return float64(k.Teeth) * k.Module / 2.0
+}
+
+// Check the gear parameters.
+func (k *GearParms) check() {
+	if k.Teeth < 3 {
+		panic("invalid number of teeth")
+	}
+	if k.Module <= 0 || k.PressureAngle <= 0 || k.PressureAngle >= DtoR(45) {
+		panic("invalid gear module/pressure angle")
+	}
+	if k.Backlash < 0 || k.Clearance < 0 || k.Bore < 0 || k.Rim < 0 {
+		panic("invalid gear parameters")
+	}
+	if Abs(k.Helix) >= DtoR(60) {
+		panic("invalid helix angle")
+	}
+}
+
+func (k *GearParms) facets() int {
+	if k.Facets <= 0 {
+		return 10
+	}
+	return k.Facets
+}
+
+// Return the solid 2D profile of an external gear.
+func (k *GearParms) gear() SDF2 {
+	pitch_radius := k.PitchRadius()
+	base_radius := pitch_radius * math.Cos(k.PressureAngle)
+	outer_radius := pitch_radius + k.Module
+	root_radius := pitch_radius - k.Module - k.Clearance
+	tooth := InvoluteGearTooth(k.Teeth, k.Module, root_radius, base_radius, outer_radius, k.Backlash, k.facets())
+	return Union2D(RotateCopy2D(tooth, k.Teeth), Circle2D(root_radius))
+}
+
+// Return the bore cutter (with keyway) for a gear profile.
+func (k *GearParms) bore() SDF2 {
+	if k.Bore == 0 {
+		return nil
+	}
+	bore := Circle2D(k.Bore)
+	if k.Keyway.X > 0 && k.Keyway.Y > 0 {
+		l := k.Bore + k.Keyway.Y
+		key := Transform2D(Box2D(V2{l, k.Keyway.X}, 0), Translate2d(V2{0.5 * l, 0}))
+		bore = Union2D(bore, key)
+	}
+	return bore
+}
+
+// Return the gear solid for a profile with the helix twist.
+func (k *GearParms) extrude(profile SDF2) SDF3 {
+	if k.Width <= 0 {
+		panic("invalid gear width")
+	}
+	if k.Helix == 0 {
+		return Extrude3D(profile, k.Width)
+	}
+	// right hand: the teeth turn counter-clockwise moving up
+	twist := -k.Width * math.Tan(k.Helix) / k.PitchRadius()
+	return TwistExtrude3D(profile, k.Width, twist)
+}
+
+// SpurGear2D returns the 2D profile of an external gear with its bore and keyway.
+func SpurGear2D(k *GearParms) SDF2 {
+	k.check()
+	gear := k.gear()
+	if bore := k.bore(); bore != nil {
+		gear = Difference2D(gear, bore)
+	}
+	return gear
+}
+
+// RingGear2D returns the 2D profile of an internal (ring) gear.
+// The tooth spaces of the ring are the teeth of an external gear with the
+// addendum and dedendum swapped.
+func RingGear2D(k *GearParms) SDF2 {
+	k.check()
+	if k.Rim <= 0 {
+		panic("invalid ring gear rim")
+	}
+	pitch_radius := k.PitchRadius()
+	base_radius := pitch_radius * math.Cos(k.PressureAngle)
+	// the ring teeth tips are inside the pitch circle
+	tip_radius := pitch_radius - k.Module
+	root_radius := pitch_radius + k.Module + k.Clearance
+	// -ve backlash widens the spaces
+	space := InvoluteGearTooth(k.Teeth, k.Module, tip_radius, base_radius, root_radius, -k.Backlash, k.facets())
+	spaces := Union2D(RotateCopy2D(space, k.Teeth), Circle2D(tip_radius))
+	return Difference2D(Circle2D(root_radius+k.Rim), spaces)
+}
+
+// Gear3D returns an external spur or helical gear centered on z = 0.
+// The hub is on the top face of the gear.
+func Gear3D(k *GearParms) SDF3 {
+	k.check()
+	gear := k.gear()
+	s := k.extrude(gear)
+	if k.Hub.X > 0 && k.Hub.Y > 0 {
+		h := k.Hub.Y + 0.5*k.Width
+		hub := Cylinder3D(h, k.Hub.X, 0)
+		hub = Transform3D(hub, Translate3d(V3{0, 0, 0.5 * h}))
+		s = Union3D(s, hub)
+	}
+	if bore := k.bore(); bore != nil {
+		l := 2.0 * (k.Width + k.Hub.Y)
+		s = Difference3D(s, Extrude3D(bore, l))
+	}
+	return s
+}
+
+// RingGear3D returns an internal spur or helical gear centered on z = 0.
+func RingGear3D(k *GearParms) SDF3 {
+	return k.extrude(RingGear2D(k))
+}
+
+//-----------------------------------------------------------------------------
+// Bevel Gears
+
+type BevelGearParms struct {
+	Teeth         [2]int  // number of teeth on the gear pair
+	Module        float64 // module at the large end of the teeth
+	PressureAngle float64 // gear pressure angle (radians)
+	ShaftAngle    float64 // angle between the gear shafts (radians)
+	Face          float64 // face width along the cone
+	Backlash      float64 // backlash expressed as per-tooth distance at pitch circumference
+	Clearance     float64 // additional root clearance
+	Bore          float64 // bore radius (0 for none)
+	Facets        int     // number of facets for involute flank (0 for the default)
+}
+
+// PitchAngles returns the pitch cone angles of the gear pair.
+func (k *BevelGearParms) PitchAngles() (float64, float64) {
+	ratio := float64(k.Teeth[1]) / float64(k.Teeth[0])
+	d0 := math.Atan2(math.Sin(k.ShaftAngle), ratio+math.Cos(k.ShaftAngle))
+	return d0, k.ShaftAngle - d0
+}
+
+// ConeDistance returns the distance from the cone apex to the large end of the teeth.
+func (k *BevelGearParms) ConeDistance() float64 {
+	d0, _ := k.PitchAngles()
+	return 0.5 * float64(k.Teeth[0]) * k.Module / math.Sin(d0)
+}
+
+// Return a bevel gear with the pitch cone apex at the origin.
+// The gear axis is the z-axis with the large end below the apex.
+func (k *BevelGearParms) gear(teeth int, delta float64) SDF3 {
+	facets := k.Facets
+	if facets <= 0 {
+		facets = 10
+	}
+	pitch_radius := 0.5 * float64(teeth) * k.Module
+	base_radius := pitch_radius * math.Cos(k.PressureAngle)
+	// tooth depth is normal to the pitch cone
+	c := math.Cos(delta)
+	outer_radius := pitch_radius + k.Module*c
+	root_radius := pitch_radius - (k.Module+k.Clearance)*c
+	tooth := InvoluteGearTooth(teeth, k.Module, root_radius, base_radius, outer_radius, k.Backlash, facets)
+	profile := Union2D(RotateCopy2D(tooth, teeth), Circle2D(root_radius))
+	if k.Bore > 0 {
+		profile = Difference2D(profile, Circle2D(k.Bore))
+	}
+	// scale the profile towards the apex
+	a := k.ConeDistance()
+	h := k.Face * c
+	scale := 1.0 - k.Face/a
+	s := ScaleExtrude3D(profile, h, V2{scale, scale})
+	return Transform3D(s, Translate3d(V3{0, 0, 0.5*h - a*c}))
+}
+
+// BevelGears3D returns a meshing pair of straight bevel gears.
+// The pitch cone apexes are at the origin. The first gear axis is the z-axis,
+// the second gear axis is rotated by the shaft angle about the y-axis.
+func BevelGears3D(k *BevelGearParms) (SDF3, SDF3) {
+	if k.Teeth[0] < 3 || k.Teeth[1] < 3 {
+		panic("invalid number of teeth")
+	}
+	if k.Module <= 0 || k.PressureAngle <= 0 || k.PressureAngle >= DtoR(45) {
+		panic("invalid gear module/pressure angle")
+	}
+	if k.ShaftAngle <= 0 || k.ShaftAngle >= PI {
+		panic("invalid shaft angle")
+	}
+	if k.Face <= 0 || k.Face >= 0.5*k.ConeDistance() {
+		panic("invalid face width")
+	}
+	d0, d1 := k.PitchAngles()
+	g0 := k.gear(k.Teeth[0], d0)
+	g1 := k.gear(k.Teeth[1], d1)
+	// the teeth of the second gear meet at -x, put a tooth space of the first gear there
+	g0 = Transform3D(g0, RotateZ(PI+PI/float64(k.Teeth[0])))
+	g1 = Transform3D(g1, RotateY(k.ShaftAngle))
+	return g0, g1
+}
+
+//-----------------------------------------------------------------------------
+// Worm Gears
+
+type WormGearParms struct {
+	Module        float64 // axial module of the worm (transverse module of the wheel)
+	Starts        int     // number of thread starts on the worm
+	Teeth         int     // number of teeth on the worm wheel
+	Diameter      float64 // worm pitch diameter
+	PressureAngle float64 // gear pressure angle (radians)
+	Backlash      float64 // backlash expressed as per-tooth distance at pitch circumference
+	Clearance     float64 // additional root clearance
+	Length        float64 // length of the worm
+	Width         float64 // face width of the worm wheel
+}
+
+// LeadAngle returns the lead angle of the worm thread.
+func (k *WormGearParms) LeadAngle() float64 {
+	return math.Atan2(float64(k.Starts)*k.Module, k.Diameter)
+}
+
+// CenterDistance returns the distance between the worm and wheel axes.
+func (k *WormGearParms) CenterDistance() float64 {
+	return 0.5 * (k.Diameter + float64(k.Teeth)*k.Module)
+}
+
+func (k *WormGearParms) check() {
+	if k.Module <= 0 || k.Starts < 1 || k.Teeth < 3 {
+		panic("invalid worm gear parameters")
+	}
+	if k.PressureAngle <= 0 || k.PressureAngle >= DtoR(45) {
+		panic("invalid pressure angle")
+	}
+	if k.Diameter <= 2.0*(k.Module+k.Clearance) {
+		panic("invalid worm diameter")
+	}
+}
+
+// Worm3D returns a right hand worm centered on the origin with its axis on the z-axis.
+func Worm3D(k *WormGearParms) SDF3 {
+	k.check()
+	if k.Length <= 0 {
+		panic("invalid worm length")
+	}
+	// rack tooth profile in the axial plane
+	pitch := PI * k.Module
+	r := 0.5 * k.Diameter
+	t := 0.25*pitch - 0.25*k.Backlash
+	tan := math.Tan(k.PressureAngle)
+	x_tip := t - k.Module*tan
+	x_root := t + (k.Module+k.Clearance)*tan
+	r_tip := r + k.Module
+	r_root := r - k.Module - k.Clearance
+	if x_tip <= 0 || x_root >= 0.5*pitch {
+		panic("invalid worm tooth profile")
+	}
+	rack := NewPolygon()
+	rack.Add(pitch, 0)
+	rack.Add(pitch, r_root)
+	rack.Add(x_root, r_root)
+	rack.Add(x_tip, r_tip)
+	rack.Add(-x_tip, r_tip)
+	rack.Add(-x_root, r_root)
+	rack.Add(-pitch, r_root)
+	rack.Add(-pitch, 0)
+	return Screw3D(Polygon2D(rack.Vertices()), k.Length, pitch, k.Starts)
+}
+
+// WormWheel3D returns the worm wheel centered on the origin with its axis on the z-axis.
+func WormWheel3D(k *WormGearParms) SDF3 {
+	k.check()
+	g := GearParms{
+		Teeth:         k.Teeth,
+		Module:        k.Module,
+		PressureAngle: k.PressureAngle,
+		Backlash:      k.Backlash,
+		Clearance:     k.Clearance,
+		Helix:         k.LeadAngle(),
+		Width:         k.Width,
+	}
+	g.check()
+	// the blank is oversize, the throat trims the tooth tips
+	pitch_radius := g.PitchRadius()
+	base_radius := pitch_radius * math.Cos(g.PressureAngle)
+	root_radius := pitch_radius - g.Module - g.Clearance
+	tooth := InvoluteGearTooth(g.Teeth, g.Module, root_radius, base_radius, pitch_radius+2.0*g.Module, g.Backlash, g.facets())
+	wheel := g.extrude(Union2D(RotateCopy2D(tooth, g.Teeth), Circle2D(root_radius)))
+	// throat the rim around the worm
+	throat := Transform2D(Circle2D(0.5*k.Diameter-k.Module), Translate2d(V2{k.CenterDistance(), 0}))
+	return Difference3D(wheel, Revolve3D(throat))
+}
+
+// WormGears3D returns a meshing worm and worm wheel.
+// The wheel is centered on the origin with its axis on the z-axis.
+// The worm axis is parallel to the x-axis at y = center distance (and z = 0).
+func WormGears3D(k *WormGearParms) (SDF3, SDF3) {
+	worm := Worm3D(k)
+	// put a worm tooth in the wheel tooth space on the y-axis
+	pitch := PI * k.Module
+	m := Translate3d(V3{0, 0, 0.25 * pitch * float64(k.Starts)})
+	m = RotateY(0.5 * PI).Mul(m)
+	m = Translate3d(V3{0, k.CenterDistance(), 0}).Mul(m)
+	worm = Transform3D(worm, m)
+	// rotate the wheel to put a tooth space on the y-axis
+	a := TAU / float64(k.Teeth)
+	wheel := Transform3D(WormWheel3D(k), RotateZ(math.Mod(0.5*(PI-a), a)))
+	return worm, wheel
+}
+
+//-----------------------------------------------------------------------------
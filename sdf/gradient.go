@@ -0,0 +1,113 @@
+//-----------------------------------------------------------------------------
+/*
+
+Gradients and Normals
+
+The gradient of an SDF3 points away from the surface, on the surface it is
+the outward surface normal. SDFs can supply an analytic gradient, which is
+faster and more accurate than central differences (6 evaluations).
+
+Operators with an analytic gradient use the gradient of their children, or
+central differences with a step of eps for children without one.
+
+ProjectSurface moves a point onto the surface with Newton's method:
+p -= d * g / |g|^2. For an exact SDF near the surface this converges in a
+step or two.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"errors"
+	"math"
+)
+
+//-----------------------------------------------------------------------------
+
+// SDF3Gradient is implemented by SDF3s with an analytic gradient.
+type SDF3Gradient interface {
+	Gradient(p V3, eps float64) V3
+}
+
+// Gradient3D returns the gradient of an SDF3 at a point. eps is the step for
+// central differences if there is no analytic gradient.
+func Gradient3D(s SDF3, p V3, eps float64) V3 {
+	if sg, ok := s.(SDF3Gradient); ok {
+		return sg.Gradient(p, eps)
+	}
+	return numeric_gradient(s, p, eps)
+}
+
+// Return the gradient of an SDF3 by central differences.
+func numeric_gradient(s SDF3, p V3, eps float64) V3 {
+	return V3{
+		s.Evaluate(p.Add(V3{eps, 0, 0})) - s.Evaluate(p.Add(V3{-eps, 0, 0})),
+		s.Evaluate(p.Add(V3{0, eps, 0})) - s.Evaluate(p.Add(V3{0, -eps, 0})),
+		s.Evaluate(p.Add(V3{0, 0, eps})) - s.Evaluate(p.Add(V3{0, 0, -eps})),
+	}.DivScalar(2 * eps)
+}
+
+// Normal3D returns the unit normal of an SDF3 at a point (0 if the gradient is 0).
+func Normal3D(s SDF3, p V3, eps float64) V3 {
+	g := Gradient3D(s, p, eps)
+	if g.Length() == 0 {
+		return g
+	}
+	return g.Normalize()
+}
+
+// number of Newton iterations for ProjectSurface
+const PROJECT_ITERATIONS = 32
+
+// ProjectSurface returns the surface point near p. eps is the distance
+// tolerance and the step for central differences.
+func ProjectSurface(s SDF3, p V3, eps float64) (V3, error) {
+	for i := 0; i < PROJECT_ITERATIONS; i++ {
+		d := s.Evaluate(p)
+		if Abs(d) <= eps {
+			return p, nil
+		}
+		g := Gradient3D(s, p, eps)
+		g2 := g.Length2()
+		if g2 == 0 {
+			return p, errors.New("zero gradient")
+		}
+		p = p.Sub(g.MulScalar(d / g2))
+	}
+	return p, errors.New("no convergence")
+}
+
+//-----------------------------------------------------------------------------
+
+// Multiply a direction by the transpose of the matrix.
+// The gradient of f(Ap) is A^T grad f(Ap).
+func (a M44) mul_transpose_direction(b V3) V3 {
+	return V3{a.x00*b.X + a.x10*b.Y + a.x20*b.Z,
+		a.x01*b.X + a.x11*b.Y + a.x21*b.Z,
+		a.x02*b.X + a.x12*b.Y + a.x22*b.Z}
+}
+
+// Return the gradient of the exact box distance for d, the distance outside
+// each face. The signs of the faces are in k.
+func gradient_box(d, k V3) V3 {
+	if d.X > 0 || d.Y > 0 || d.Z > 0 {
+		return d.Max(V3{0, 0, 0}).Normalize().Mul(k)
+	}
+	// inside: the nearest face
+	if d.X >= d.Y && d.X >= d.Z {
+		return V3{k.X, 0, 0}
+	}
+	if d.Y >= d.Z {
+		return V3{0, k.Y, 0}
+	}
+	return V3{0, 0, k.Z}
+}
+
+// Return the sign of x (+1 for 0).
+func sign_nz(x float64) float64 {
+	return math.Copysign(1, x)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,167 @@
+//-----------------------------------------------------------------------------
+/*
+
+Heightmaps
+
+A solid with a flat base on the z = 0 plane and a top surface given by a
+height function. The xy extent is a rectangle centered on the origin.
+
+top = thickness + f(x, y)
+
+The height function can be any func(x, y) float64 (E.g. terrain, textures),
+or it can be sampled from a grayscale image (E.g. lithophanes).
+
+The distance field is bounded, not exact. The maximum slope of the height
+function is estimated by sampling so the distance is scaled down to avoid
+overshooting the surface on steep slopes.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"os"
+)
+
+//-----------------------------------------------------------------------------
+
+// HeightFunc returns the height of a surface at (x, y).
+type HeightFunc func(x, y float64) float64
+
+type HeightmapSDF3 struct {
+	f         HeightFunc
+	size      V2      // half size in x and y
+	thickness float64 // base thickness
+	k         float64 // distance scaling for the slope
+	bb        Box3
+}
+
+// number of samples (per axis) used to estimate the height range and slope
+const heightmap_samples = 128
+
+// Heightmap3D returns a solid with a top surface given by a height function.
+// size is the xy extent, the height function is evaluated over
+// x in [-size.X/2, size.X/2] and y in [-size.Y/2, size.Y/2] and must be >= 0.
+// thickness is added under the surface so the solid has a base.
+func Heightmap3D(f HeightFunc, size V2, thickness float64) SDF3 {
+	if size.X <= 0 || size.Y <= 0 || thickness < 0 {
+		panic("invalid heightmap parameters")
+	}
+	s := HeightmapSDF3{}
+	s.f = f
+	s.size = size.MulScalar(0.5)
+	s.thickness = thickness
+
+	// sample the height function for the range and maximum slope
+	n := heightmap_samples
+	dx := size.X / float64(n)
+	dy := size.Y / float64(n)
+	h := make([]float64, (n+1)*(n+1))
+	h_max := 0.0
+	for j := 0; j <= n; j++ {
+		for i := 0; i <= n; i++ {
+			z := f(-s.size.X+float64(i)*dx, -s.size.Y+float64(j)*dy)
+			if z < 0 {
+				panic("heightmap height is < 0")
+			}
+			h[j*(n+1)+i] = z
+			h_max = Max(h_max, z)
+		}
+	}
+	slope := 0.0
+	for j := 0; j <= n; j++ {
+		for i := 0; i <= n; i++ {
+			z := h[j*(n+1)+i]
+			if i < n {
+				slope = Max(slope, Abs(h[j*(n+1)+i+1]-z)/dx)
+			}
+			if j < n {
+				slope = Max(slope, Abs(h[(j+1)*(n+1)+i]-z)/dy)
+			}
+		}
+	}
+	// the gradient is the vector sum of the x and y slopes
+	slope *= math.Sqrt2
+	s.k = 1.0 / math.Sqrt(1.0+slope*slope)
+	// allow for peaks between the samples
+	h_max += slope * Max(dx, dy)
+	s.bb = Box3{V3{-s.size.X, -s.size.Y, 0}, V3{s.size.X, s.size.Y, thickness + h_max}}
+	return &s
+}
+
+// Return the minimum distance to a heightmap.
+func (s *HeightmapSDF3) Evaluate(p V3) float64 {
+	// clamp the height function domain to the xy extent
+	x := Clamp(p.X, -s.size.X, s.size.X)
+	y := Clamp(p.Y, -s.size.Y, s.size.Y)
+	top := (p.Z - (s.thickness + s.f(x, y))) * s.k
+	d := Max(top, -p.Z)
+	return Max(d, sdf_box2d(V2{p.X, p.Y}, s.size))
+}
+
+// Return the bounding box for a heightmap.
+func (s *HeightmapSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+// Image Heightmaps
+
+// ImageHeight returns a height function for a grayscale image.
+// The image is stretched to the xy extent (size) with the first image row
+// at +y. White is the full height, black is 0. For lithophanes set invert
+// so dark areas are thicker.
+func ImageHeight(img image.Image, size V2, height float64, invert bool) HeightFunc {
+	b := img.Bounds()
+	w := b.Dx()
+	h := b.Dy()
+	if w < 1 || h < 1 {
+		panic("empty image")
+	}
+	// convert to a grayscale level in [0,1]
+	level := make([]float64, w*h)
+	for j := 0; j < h; j++ {
+		for i := 0; i < w; i++ {
+			g := color.Gray16Model.Convert(img.At(b.Min.X+i, b.Min.Y+j)).(color.Gray16)
+			l := float64(g.Y) / 0xffff
+			if invert {
+				l = 1.0 - l
+			}
+			level[j*w+i] = l
+		}
+	}
+	pixel := func(i, j int) float64 {
+		i = int(Clamp(float64(i), 0, float64(w-1)))
+		j = int(Clamp(float64(j), 0, float64(h-1)))
+		return level[j*w+i]
+	}
+	return func(x, y float64) float64 {
+		// pixel centers, bilinear interpolation between them
+		u := (x/size.X+0.5)*float64(w) - 0.5
+		v := (0.5-y/size.Y)*float64(h) - 0.5
+		i := int(math.Floor(u))
+		j := int(math.Floor(v))
+		fu := u - float64(i)
+		fv := v - float64(j)
+		l0 := Mix(pixel(i, j), pixel(i+1, j), fu)
+		l1 := Mix(pixel(i, j+1), pixel(i+1, j+1), fu)
+		return height * Mix(l0, l1, fv)
+	}
+}
+
+// LoadImage reads an image file (E.g. a PNG).
+func LoadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,227 @@
+//-----------------------------------------------------------------------------
+/*
+
+Tool Holders
+
+Hex Bit Holders: A block with a grid of hex pockets for driver bits. The
+pockets are exact hexagons with a clearance across the flats, and they can be
+tilted back so the bits are easier to read and pick out. The block sits on
+the z = 0 plane, the front is the -y side.
+
+Screwdriver Racks: A wall mounted shelf with a row of holes for screwdriver
+shafts. The handles rest on the shelf. The holes can be tilted and have an
+optional slot to the front so a driver can be lifted out sideways.
+
+Wrench Rails: A wall mounted rail with a row of pegs for hanging wrenches by
+the ring end. The pegs are tilted up and have a ball end to retain the wrench.
+
+The racks and rails mount on the xz plane (the wall) and stick out along +y
+with z up. They have a screw hole at each end.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+
+// 1/4" hex bit (across flats)
+const HEX_BIT = 0.25 * MM_PER_INCH
+
+// Return the mounting holes at the ends of a rack. The holes go through the
+// back along the y-axis at x = +/- x and z = z.
+func holder_mounts(screw string, clearance, x, z, depth float64) SDF3 {
+	spec, err := ParseThread(screw)
+	if err != nil {
+		panic(err)
+	}
+	hole := Cylinder3D(2.0*depth, spec.Thread.Radius+clearance, 0)
+	hole = Transform3D(hole, RotateX(0.5*PI))
+	h0 := Transform3D(hole, Translate3d(V3{-x, 0, z}))
+	h1 := Transform3D(hole, Translate3d(V3{x, 0, z}))
+	return Union3D(h0, h1)
+}
+
+// Return the width of the end tabs for mounting screws.
+func holder_tab(screw string) float64 {
+	spec, err := ParseThread(screw)
+	if err != nil {
+		panic(err)
+	}
+	return 4.0 * spec.Thread.Radius
+}
+
+//-----------------------------------------------------------------------------
+// Hex Bit Holders
+
+type BitHolderParms struct {
+	Bit       float64 // bit size across the flats (E.g. HEX_BIT)
+	Grid      V2i     // number of pockets in x and y
+	Spacing   float64 // distance between pocket centers
+	Depth     float64 // pocket depth along the bit
+	Angle     float64 // tilt of the bits towards the back (radians)
+	Floor     float64 // material below the pockets
+	Clearance float64 // clearance across the flats
+}
+
+// BitHolder3D returns a block with a grid of hex bit pockets.
+func BitHolder3D(k *BitHolderParms) SDF3 {
+	if k.Bit <= 0 || k.Depth <= 0 || k.Floor <= 0 || k.Clearance < 0 {
+		panic("invalid bit holder parameters")
+	}
+	if k.Grid[0] < 1 || k.Grid[1] < 1 {
+		panic("invalid pocket grid")
+	}
+	if k.Angle < 0 || k.Angle >= DtoR(60) {
+		panic("invalid bit angle")
+	}
+	// across the corners
+	r := 0.5 * (k.Bit + k.Clearance) / math.Cos(DtoR(30))
+	if k.Spacing <= 2.0*r {
+		panic("bit spacing is too small")
+	}
+	sa, ca := math.Sincos(k.Angle)
+	n := V2{float64(k.Grid[0]), float64(k.Grid[1])}
+	// the pockets lean back, so the block is deeper than the grid
+	size := V3{n.X * k.Spacing, n.Y*k.Spacing + k.Depth*sa, k.Floor + k.Depth*ca + r*sa}
+	body := Box3D(size, 0)
+	body = Transform3D(body, Translate3d(V3{0, 0, 0.5 * size.Z}))
+	// the pocket is centered on the top face, a flat faces the front
+	pocket := Extrude3D(Polygon2D(Nagon(6, r)), 2.0*k.Depth)
+	pocket = Transform3D(pocket, RotateX(-k.Angle))
+	pockets := make([]SDF3, 0, k.Grid[0]*k.Grid[1])
+	for j := 0; j < k.Grid[1]; j++ {
+		for i := 0; i < k.Grid[0]; i++ {
+			x := (float64(i) - 0.5*(n.X-1)) * k.Spacing
+			y := (float64(j)-0.5*(n.Y-1))*k.Spacing + 0.5*k.Depth*sa
+			pockets = append(pockets, Transform3D(pocket, Translate3d(V3{x, y, size.Z})))
+		}
+	}
+	return Difference3D(body, Union3D(pockets...))
+}
+
+//-----------------------------------------------------------------------------
+// Screwdriver Racks
+
+type ScrewdriverRackParms struct {
+	Count     int     // number of screwdrivers
+	Spacing   float64 // distance between hole centers
+	Shaft     float64 // shaft hole diameter
+	Slot      float64 // width of the slot from the hole to the front (0 for none)
+	Angle     float64 // tilt of the holes away from the wall (radians)
+	Depth     float64 // shelf depth from the wall
+	Thickness float64 // shelf thickness
+	Back      V2      // back plate height (below the top of the shelf) and thickness
+	Screw     string  // mounting screw (E.g. "M4")
+	Clearance float64 // clearance on the holes
+}
+
+// ScrewdriverRack3D returns a wall mounted screwdriver rack.
+// The top of the shelf is at z = 0.
+func ScrewdriverRack3D(k *ScrewdriverRackParms) SDF3 {
+	if k.Count < 1 || k.Shaft <= 0 || k.Slot < 0 || k.Clearance < 0 {
+		panic("invalid screwdriver rack parameters")
+	}
+	if k.Angle < 0 || k.Angle >= DtoR(45) {
+		panic("invalid hole angle")
+	}
+	if k.Thickness <= 0 || k.Back.X <= k.Thickness || k.Back.Y <= 0 || k.Depth <= k.Back.Y {
+		panic("invalid shelf size")
+	}
+	r := 0.5*k.Shaft + k.Clearance
+	if k.Spacing <= 2.0*r || k.Slot > 2.0*r {
+		panic("invalid hole size")
+	}
+	if k.Depth-k.Back.Y <= 2.0*r {
+		panic("shelf is too shallow for the holes")
+	}
+	tab := holder_tab(k.Screw)
+	if k.Back.X-k.Thickness < tab {
+		panic("back plate is too small for the mounting screws")
+	}
+	w := float64(k.Count)*k.Spacing + 2.0*tab
+	shelf := Box3D(V3{w, k.Depth, k.Thickness}, 0)
+	shelf = Transform3D(shelf, Translate3d(V3{0, 0.5 * k.Depth, -0.5 * k.Thickness}))
+	back := Box3D(V3{w, k.Back.Y, k.Back.X}, 0)
+	back = Transform3D(back, Translate3d(V3{0, 0.5 * k.Back.Y, -0.5 * k.Back.X}))
+	s := Union3D(shelf, back)
+	// holes (and slots) centered on the shelf in front of the back plate
+	y := 0.5 * (k.Depth + k.Back.Y)
+	hole := Cylinder3D(4.0*k.Thickness, r, 0)
+	if k.Slot > 0 {
+		slot := Box3D(V3{k.Slot, k.Depth, 4.0 * k.Thickness}, 0)
+		slot = Transform3D(slot, Translate3d(V3{0, 0.5 * k.Depth, 0}))
+		hole = Union3D(hole, slot)
+	}
+	// the bottom of the hole swings away from the wall
+	hole = Transform3D(hole, RotateX(k.Angle))
+	holes := make([]SDF3, k.Count)
+	for i := range holes {
+		x := (float64(i) - 0.5*float64(k.Count-1)) * k.Spacing
+		holes[i] = Transform3D(hole, Translate3d(V3{x, y, -0.5 * k.Thickness}))
+	}
+	s = Difference3D(s, Union3D(holes...))
+	// mounting holes in the end tabs, below the shelf
+	z := -0.5 * (k.Back.X + k.Thickness)
+	mounts := holder_mounts(k.Screw, k.Clearance, 0.5*w-0.5*tab, z, k.Back.Y)
+	return Difference3D(s, mounts)
+}
+
+//-----------------------------------------------------------------------------
+// Wrench Rails
+
+type WrenchRailParms struct {
+	Count     int     // number of wrenches
+	Spacing   float64 // distance between pegs
+	Peg       V2      // peg diameter and length
+	Angle     float64 // tilt of the pegs upwards (radians)
+	Lip       float64 // radius of the ball at the end of the peg above the peg radius
+	Rail      V2      // rail height and thickness
+	Screw     string  // mounting screw (E.g. "M4")
+	Clearance float64 // clearance on the mounting holes
+}
+
+// WrenchRail3D returns a wall mounted rail with pegs for wrenches.
+// The rail is centered on z = 0.
+func WrenchRail3D(k *WrenchRailParms) SDF3 {
+	if k.Count < 1 || k.Peg.X <= 0 || k.Peg.Y <= 0 || k.Lip < 0 || k.Clearance < 0 {
+		panic("invalid wrench rail parameters")
+	}
+	if k.Angle < 0 || k.Angle >= DtoR(60) {
+		panic("invalid peg angle")
+	}
+	if k.Rail.X <= k.Peg.X || k.Rail.Y <= 0 {
+		panic("invalid rail size")
+	}
+	r := 0.5 * k.Peg.X
+	if k.Spacing <= 2.0*(r+k.Lip) {
+		panic("peg spacing is too small")
+	}
+	tab := holder_tab(k.Screw)
+	w := float64(k.Count)*k.Spacing + 2.0*tab
+	rail := Box3D(V3{w, k.Rail.Y, k.Rail.X}, 0)
+	rail = Transform3D(rail, Translate3d(V3{0, 0.5 * k.Rail.Y, 0}))
+	// the peg starts inside the rail and is tilted up from the y-axis
+	l := k.Peg.Y + k.Rail.Y
+	peg := Cylinder3D(l, r, 0)
+	peg = Transform3D(peg, Translate3d(V3{0, 0, 0.5 * l}))
+	if k.Lip > 0 {
+		ball := Sphere3D(r + k.Lip)
+		ball = Transform3D(ball, Translate3d(V3{0, 0, l}))
+		peg = Union3D(peg, ball)
+	}
+	peg = Transform3D(peg, RotateX(k.Angle-0.5*PI))
+	pegs := make([]SDF3, k.Count)
+	for i := range pegs {
+		x := (float64(i) - 0.5*float64(k.Count-1)) * k.Spacing
+		pegs[i] = Transform3D(peg, Translate3d(V3{x, 0, 0}))
+	}
+	// trim the pegs at the wall
+	s := Union3D(rail, Cut3D(Union3D(pegs...), V3{0, 0, 0}, V3{0, 1, 0}))
+	mounts := holder_mounts(k.Screw, k.Clearance, 0.5*w-0.5*tab, 0, k.Rail.Y)
+	return Difference3D(s, mounts)
+}
+
+//-----------------------------------------------------------------------------
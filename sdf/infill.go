@@ -0,0 +1,209 @@
+//-----------------------------------------------------------------------------
+/*
+
+Lattice Infill
+
+Lightweight parts: the interior of a solid is replaced by a periodic lattice
+and a solid skin is left on the surface.
+
+Lattices:
+
+gyroid: a triply periodic minimal surface (TPMS), thickened to a sheet
+Schwarz P: a TPMS, thickened to a sheet
+diamond (Schwarz D): a TPMS, thickened to a sheet
+lidinoid: a TPMS, thickened to a sheet
+honeycomb: hexagonal cells extruded along the z-axis
+cubic: square struts along the x, y and z axes
+
+The lattice functions are negative inside the lattice material. The TPMS
+distances are approximate, so the sheet thickness is nominal. The gradient of
+the lidinoid function varies more over the surface than the others, so its
+sheet thickness varies more.
+
+A lattice can also be used as a solid on its own (E.g. a heat exchanger
+core), Lattice3D trims it to a box.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+// Lattices
+
+// LatticeFunc returns the distance to a lattice (-ve inside the material).
+type LatticeFunc func(p V3) float64
+
+// Return x wrapped into the cell centered on 0.
+func lattice_wrap(x, cell float64) float64 {
+	return x - cell*math.Floor(x/cell+0.5)
+}
+
+// GyroidLattice returns a gyroid sheet lattice.
+func GyroidLattice(cell, thickness float64) LatticeFunc {
+	if cell <= 0 || thickness <= 0 {
+		panic("invalid lattice parameters")
+	}
+	k := TAU / cell
+	// the gradient of the gyroid function is about 1.5 near the surface
+	scale := 1.0 / (1.5 * k)
+	return func(p V3) float64 {
+		x, y, z := k*p.X, k*p.Y, k*p.Z
+		g := math.Sin(x)*math.Cos(y) + math.Sin(y)*math.Cos(z) + math.Sin(z)*math.Cos(x)
+		return Abs(g)*scale - 0.5*thickness
+	}
+}
+
+// SchwarzPLattice returns a Schwarz P sheet lattice.
+func SchwarzPLattice(cell, thickness float64) LatticeFunc {
+	if cell <= 0 || thickness <= 0 {
+		panic("invalid lattice parameters")
+	}
+	k := TAU / cell
+	// the gradient of the Schwarz P function is about sqrt(2) near the surface
+	scale := 1.0 / (math.Sqrt2 * k)
+	return func(p V3) float64 {
+		g := math.Cos(k*p.X) + math.Cos(k*p.Y) + math.Cos(k*p.Z)
+		return Abs(g)*scale - 0.5*thickness
+	}
+}
+
+// DiamondLattice returns a Schwarz D (diamond) sheet lattice.
+func DiamondLattice(cell, thickness float64) LatticeFunc {
+	if cell <= 0 || thickness <= 0 {
+		panic("invalid lattice parameters")
+	}
+	k := TAU / cell
+	// the gradient of the diamond function is about 1.5 near the surface
+	scale := 1.0 / (1.5 * k)
+	return func(p V3) float64 {
+		sx, cx := math.Sincos(k * p.X)
+		sy, cy := math.Sincos(k * p.Y)
+		sz, cz := math.Sincos(k * p.Z)
+		g := sx*sy*sz + sx*cy*cz + cx*sy*cz + cx*cy*sz
+		return Abs(g)*scale - 0.5*thickness
+	}
+}
+
+// LidinoidLattice returns a lidinoid sheet lattice.
+func LidinoidLattice(cell, thickness float64) LatticeFunc {
+	if cell <= 0 || thickness <= 0 {
+		panic("invalid lattice parameters")
+	}
+	k := TAU / cell
+	// the gradient of the lidinoid function is about 1.1 near the surface
+	scale := 1.0 / (1.1 * k)
+	return func(p V3) float64 {
+		sx, cx := math.Sincos(k * p.X)
+		sy, cy := math.Sincos(k * p.Y)
+		sz, cz := math.Sincos(k * p.Z)
+		s2x, c2x := math.Sincos(2 * k * p.X)
+		s2y, c2y := math.Sincos(2 * k * p.Y)
+		s2z, c2z := math.Sincos(2 * k * p.Z)
+		g := 0.5*(s2x*cy*sz+s2y*cz*sx+s2z*cx*sy) - 0.5*(c2x*c2y+c2y*c2z+c2z*c2x) + 0.15
+		return Abs(g)*scale - 0.5*thickness
+	}
+}
+
+// HoneycombLattice returns hexagonal cells (flat to flat size cell) along the z-axis.
+func HoneycombLattice(cell, thickness float64) LatticeFunc {
+	if cell <= 0 || thickness <= 0 || thickness >= cell {
+		panic("invalid lattice parameters")
+	}
+	// the hex centers are on two rectangular grids
+	s := V2{cell, cell * math.Sqrt(3)}
+	r := 0.5 * cell
+	hex := func(q V2) float64 {
+		q = q.Abs()
+		return Max(q.X, 0.5*q.X+0.5*math.Sqrt(3)*q.Y) - r
+	}
+	return func(p V3) float64 {
+		a := V2{lattice_wrap(p.X, s.X), lattice_wrap(p.Y, s.Y)}
+		b := V2{lattice_wrap(p.X-0.5*s.X, s.X), lattice_wrap(p.Y-0.5*s.Y, s.Y)}
+		// distance to the wall of the nearest cell
+		return -Min(hex(a), hex(b)) - 0.5*thickness
+	}
+}
+
+// CubicLattice returns square struts along the x, y and z axes.
+func CubicLattice(cell, thickness float64) LatticeFunc {
+	if cell <= 0 || thickness <= 0 || thickness >= cell {
+		panic("invalid lattice parameters")
+	}
+	t := V2{0.5 * thickness, 0.5 * thickness}
+	return func(p V3) float64 {
+		q := V3{lattice_wrap(p.X, cell), lattice_wrap(p.Y, cell), lattice_wrap(p.Z, cell)}
+		d := sdf_box2d(V2{q.X, q.Y}, t)
+		d = Min(d, sdf_box2d(V2{q.Y, q.Z}, t))
+		return Min(d, sdf_box2d(V2{q.Z, q.X}, t))
+	}
+}
+
+//-----------------------------------------------------------------------------
+// Lattice Solids
+
+type LatticeSDF3 struct {
+	lattice LatticeFunc
+	bb      Box3
+}
+
+// Lattice3D returns a lattice trimmed to a box.
+func Lattice3D(lattice LatticeFunc, size V3) SDF3 {
+	if size.X <= 0 || size.Y <= 0 || size.Z <= 0 {
+		panic("invalid lattice size")
+	}
+	s := LatticeSDF3{}
+	s.lattice = lattice
+	s.bb = NewBox3(V3{0, 0, 0}, size)
+	return &s
+}
+
+// Evaluate returns the minimum distance to a lattice solid.
+func (s *LatticeSDF3) Evaluate(p V3) float64 {
+	return Max(s.lattice(p), sdf_box3d(p, s.bb.Max))
+}
+
+// BoundingBox returns the bounding box of a lattice solid.
+func (s *LatticeSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+// Infill
+
+type InfillSDF3 struct {
+	sdf     SDF3
+	lattice LatticeFunc
+	skin    float64
+	bb      Box3
+}
+
+// Infill3D returns a solid with the interior replaced by a lattice.
+// The skin is a solid layer (of thickness skin) on the surface.
+func Infill3D(sdf SDF3, lattice LatticeFunc, skin float64) SDF3 {
+	if skin < 0 {
+		panic("invalid skin thickness")
+	}
+	s := InfillSDF3{}
+	s.sdf = sdf
+	s.lattice = lattice
+	s.skin = skin
+	s.bb = sdf.BoundingBox()
+	return &s
+}
+
+// Evaluate returns the minimum distance to an infilled solid.
+func (s *InfillSDF3) Evaluate(p V3) float64 {
+	d := s.sdf.Evaluate(p)
+	skin := Max(d, -d-s.skin)
+	return Min(skin, Max(d, s.lattice(p)))
+}
+
+// BoundingBox returns the bounding box of an infilled solid.
+func (s *InfillSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,120 @@
+//-----------------------------------------------------------------------------
+/*
+
+Interference Checking
+
+Check the fit of two mating solids (E.g. a button in a cavity). See also
+Interference (kinematics.go), a fast yes/no test used for collision detection.
+
+The intersection of the solids, f(p) = max(a(p), b(p)), is -ve where they
+overlap. The most -ve value is the depth of the deepest point in the overlap,
+so the penetration depth (the thickness of the overlap) is -2 min(f).
+
+If the solids don't overlap the minimum of f is at the midpoint of the closest
+approach, and f is half the gap, so the clearance is 2 min(f).
+
+f is sampled on a grid covering both solids and the minimum is refined with a
+compass search. Each connected region of overlapping samples is reported with
+its deepest point. The results are exact for exact SDFs. If the SDFs are a
+bound on the distance the penetration and clearance are under estimated.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "fmt"
+
+//-----------------------------------------------------------------------------
+
+type InterferenceReport struct {
+	Penetration float64 // maximum penetration depth (0 if the solids don't overlap)
+	Clearance   float64 // minimum clearance (0 if the solids overlap)
+	Position    V3      // location of the maximum penetration or minimum clearance
+	Overlaps    []V3    // the deepest point of each overlapping region
+}
+
+func (i *InterferenceReport) String() string {
+	if i.Penetration > 0 {
+		return fmt.Sprintf("penetration %.4f at %v (%d overlapping regions)", i.Penetration, i.Position, len(i.Overlaps))
+	}
+	return fmt.Sprintf("clearance %.4f at %v", i.Clearance, i.Position)
+}
+
+//-----------------------------------------------------------------------------
+
+// The intersection of two SDF3s, with a bounding box covering both.
+type interference_sdf3 struct {
+	a, b SDF3
+	bb   Box3
+}
+
+func (s *interference_sdf3) Evaluate(p V3) float64 {
+	return Max(s.a.Evaluate(p), s.b.Evaluate(p))
+}
+
+func (s *interference_sdf3) BoundingBox() Box3 {
+	return s.bb
+}
+
+// Return the minimum of f near p by compass search.
+func compass_min(f func(V3) float64, p V3, step, tolerance float64) (V3, float64) {
+	fp := f(p)
+	dirs := []V3{{1, 0, 0}, {-1, 0, 0}, {0, 1, 0}, {0, -1, 0}, {0, 0, 1}, {0, 0, -1}}
+	for step > tolerance {
+		moved := false
+		for _, d := range dirs {
+			q := p.Add(d.MulScalar(step))
+			if fq := f(q); fq < fp {
+				p, fp = q, fq
+				moved = true
+				break
+			}
+		}
+		if !moved {
+			step *= 0.5
+		}
+	}
+	return p, fp
+}
+
+// Interference3D returns the penetration depth or clearance between two solids.
+// The resolution is the grid sampling resolution, overlaps smaller than this may be missed.
+func Interference3D(a, b SDF3, resolution float64) *InterferenceReport {
+	if resolution <= 0 {
+		panic("invalid resolution")
+	}
+	s := interference_sdf3{a, b, a.BoundingBox().Extend(b.BoundingBox())}
+	g := new_print_grid(&s, resolution)
+	// the grid minimum
+	imin := 0
+	for i, d := range g.d {
+		if d < g.d[imin] {
+			imin = i
+		}
+	}
+	p, fmin := compass_min(s.Evaluate, g.position(imin), resolution, 1e-6*resolution)
+	r := InterferenceReport{Position: p}
+	if fmin < 0 {
+		r.Penetration = -2 * fmin
+	} else {
+		r.Clearance = 2 * fmin
+	}
+	// the overlapping regions
+	flag := make([]bool, len(g.d))
+	for i, d := range g.d {
+		flag[i] = d < 0
+	}
+	for _, region := range g.regions(flag) {
+		deepest := region[0]
+		for _, i := range region {
+			if g.d[i] < g.d[deepest] {
+				deepest = i
+			}
+		}
+		r.Overlaps = append(r.Overlaps, g.position(deepest))
+	}
+	return &r
+}
+
+//-----------------------------------------------------------------------------
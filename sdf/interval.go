@@ -0,0 +1,221 @@
+//-----------------------------------------------------------------------------
+/*
+
+Interval Evaluation
+
+An SDF3 can optionally return the range of its distance over a box. If the
+range doesn't contain 0 the box is entirely outside (or inside) the solid,
+and a renderer can skip it without looking inside.
+
+The range is computed with interval arithmetic: each operation on a value is
+replaced with the same operation on an interval that is guaranteed to hold
+all the results. The range may be larger than the true range, but it is never
+smaller.
+
+SDFs that don't implement EvaluateInterval get the range from the distance at
+the center of the box plus or minus the half diagonal. This assumes the SDF
+changes no faster than the distance, as the octree renderer already does.
+
+SDF2s work the same way (EvaluateInterval2), so linear extrusions and solids
+of revolution have an interval evaluation: the box is projected into the
+plane of the SDF2. For an SDF2 without its own interval evaluation the 2D
+center bound is still tighter than the 3D one.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+
+type Interval struct {
+	Lo, Hi float64 // lower and upper bound
+}
+
+// SDF3Interval is implemented by SDF3s that can evaluate their distance over a box.
+type SDF3Interval interface {
+	EvaluateInterval(b Box3) Interval
+}
+
+// EvaluateInterval returns the range of an SDF3 distance over a box.
+func EvaluateInterval(s SDF3, b Box3) Interval {
+	if si, ok := s.(SDF3Interval); ok {
+		return si.EvaluateInterval(b)
+	}
+	return lipschitz_interval(s, b)
+}
+
+// SDF2Interval is implemented by SDF2s that can evaluate their distance over a box.
+type SDF2Interval interface {
+	EvaluateInterval(b Box2) Interval
+}
+
+// EvaluateInterval2 returns the range of an SDF2 distance over a box.
+func EvaluateInterval2(s SDF2, b Box2) Interval {
+	if si, ok := s.(SDF2Interval); ok {
+		return si.EvaluateInterval(b)
+	}
+	d := s.Evaluate(b.Center())
+	r := 0.5 * b.Size().Length()
+	return Interval{d - r, d + r}
+}
+
+// Return the range of an SDF3 distance over a box from the center distance.
+func lipschitz_interval(s SDF3, b Box3) Interval {
+	return center_interval(s.Evaluate(b.Center()), b)
+}
+
+// Return the range of the distance over a box from the center distance d.
+func center_interval(d float64, b Box3) Interval {
+	r := 0.5 * b.Size().Length()
+	return Interval{d - r, d + r}
+}
+
+// Return the range of an SDF3 distance over a box given the (known) center
+// distance d. The range is the interval evaluation clipped to the center
+// distance bound.
+func evaluate_interval_center(s SDF3Interval, b Box3, d float64) Interval {
+	x := s.EvaluateInterval(b)
+	c := center_interval(d, b)
+	return Interval{Max(x.Lo, c.Lo), Min(x.Hi, c.Hi)}
+}
+
+// Return true if an SDF3 has a real interval evaluation. I.e. the range isn't
+// just the center distance fallback for the SDF3 and everything in it.
+func has_interval(s SDF3) bool {
+	switch s := s.(type) {
+	case *BoxSDF3, *SphereSDF3, *CylinderSDF3:
+		return true
+	case *TransformSDF3:
+		return has_interval(s.sdf)
+	case *ScaleUniformSDF3:
+		return has_interval(s.sdf)
+	case *ScaleNonUniformSDF3:
+		return has_interval(s.sdf)
+	case *OffsetSDF3:
+		return has_interval(s.sdf)
+	case *ShellSDF3:
+		return has_interval(s.sdf)
+	case *refined_interval_sdf3:
+		return has_interval(s.SDF3)
+	case *ExtrudeSDF3:
+		// the 2d range is better than the 3d center bound for any SDF2
+		return s.plain()
+	case *ExtrudeRoundedSDF3:
+		return true
+	case *SorSDF3:
+		return s.theta == 0
+	case *UnionSDF3:
+		if s.blended {
+			return false
+		}
+		for _, x := range s.sdf {
+			if has_interval(x) {
+				return true
+			}
+		}
+		return false
+	case *DifferenceSDF3:
+		return !s.blended && (has_interval(s.s0) || has_interval(s.s1))
+	case *IntersectionSDF3:
+		return !s.blended && (has_interval(s.s0) || has_interval(s.s1))
+	}
+	// other SDF3s with an interval evaluation
+	_, ok := s.(SDF3Interval)
+	return ok
+}
+
+// Return the x, y and z intervals of a box.
+func box_intervals(b Box3) (Interval, Interval, Interval) {
+	return Interval{b.Min.X, b.Max.X}, Interval{b.Min.Y, b.Max.Y}, Interval{b.Min.Z, b.Max.Z}
+}
+
+//-----------------------------------------------------------------------------
+
+// Excludes returns true if the interval doesn't contain x.
+func (a Interval) Excludes(x float64) bool {
+	return a.Lo > x || a.Hi < x
+}
+
+// Add adds two intervals.
+func (a Interval) Add(b Interval) Interval {
+	return Interval{a.Lo + b.Lo, a.Hi + b.Hi}
+}
+
+// AddScalar adds a scalar to an interval.
+func (a Interval) AddScalar(k float64) Interval {
+	return Interval{a.Lo + k, a.Hi + k}
+}
+
+// MulScalar multiplies an interval by a scalar.
+func (a Interval) MulScalar(k float64) Interval {
+	if k < 0 {
+		return Interval{a.Hi * k, a.Lo * k}
+	}
+	return Interval{a.Lo * k, a.Hi * k}
+}
+
+// Neg negates an interval.
+func (a Interval) Neg() Interval {
+	return Interval{-a.Hi, -a.Lo}
+}
+
+// Abs returns the absolute value of an interval.
+func (a Interval) Abs() Interval {
+	if a.Lo >= 0 {
+		return a
+	}
+	if a.Hi <= 0 {
+		return a.Neg()
+	}
+	return Interval{0, Max(-a.Lo, a.Hi)}
+}
+
+// Sqr returns the square of an interval.
+func (a Interval) Sqr() Interval {
+	a = a.Abs()
+	return Interval{a.Lo * a.Lo, a.Hi * a.Hi}
+}
+
+// Sqrt returns the square root of an interval (-ve values are taken as 0).
+func (a Interval) Sqrt() Interval {
+	return Interval{math.Sqrt(Max(a.Lo, 0)), math.Sqrt(Max(a.Hi, 0))}
+}
+
+// Min returns the minimum of two intervals.
+func (a Interval) Min(b Interval) Interval {
+	return Interval{Min(a.Lo, b.Lo), Min(a.Hi, b.Hi)}
+}
+
+// Max returns the maximum of two intervals.
+func (a Interval) Max(b Interval) Interval {
+	return Interval{Max(a.Lo, b.Lo), Max(a.Hi, b.Hi)}
+}
+
+// MinScalar returns the minimum of an interval and a scalar.
+func (a Interval) MinScalar(k float64) Interval {
+	return Interval{Min(a.Lo, k), Min(a.Hi, k)}
+}
+
+// MaxScalar returns the maximum of an interval and a scalar.
+func (a Interval) MaxScalar(k float64) Interval {
+	return Interval{Max(a.Lo, k), Max(a.Hi, k)}
+}
+
+//-----------------------------------------------------------------------------
+
+// The interval version of the exact box distance: |d+| + min(max(d), 0)
+// where d is the distance outside each face.
+func interval_box(d ...Interval) Interval {
+	outside := Interval{}
+	inside := d[0]
+	for _, x := range d {
+		outside = outside.Add(x.MaxScalar(0).Sqr())
+		inside = inside.Max(x)
+	}
+	return outside.Sqrt().Add(inside.MinScalar(0))
+}
+
+//-----------------------------------------------------------------------------
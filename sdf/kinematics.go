@@ -0,0 +1,325 @@
+//-----------------------------------------------------------------------------
+/*
+
+Kinematic Assemblies
+
+An assembly is a fixed base with links attached by joints. Each link is
+attached to the base or to a parent link. Joints are revolute (rotation
+about an axis) or prismatic (translation along an axis) with limits.
+
+The assembly can be posed with a set of joint values, and previewed at a
+sequence of joint positions to check the clearances through the motion.
+Each frame is rendered as an STL file, or as a PNG image of a slice
+through the assembly.
+
+A swept volume is the union of a part over a motion path. It can be
+subtracted from other parts to give clearance for the moving part.
+
+Collision detection finds the first time along a motion path where a moving
+part interferes with static geometry.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"math"
+)
+
+//-----------------------------------------------------------------------------
+// Joints
+
+type JointType int
+
+const (
+	REVOLUTE  JointType = iota // rotation about the joint axis
+	PRISMATIC                  // translation along the joint axis
+)
+
+type Joint struct {
+	Type     JointType
+	Origin   V3      // point on the joint axis
+	Axis     V3      // direction of the joint axis
+	Min, Max float64 // joint limits (radians or distance)
+}
+
+// Transform returns the joint transform for a joint value.
+// The value is clamped to the joint limits.
+func (j *Joint) Transform(x float64) M44 {
+	x = Clamp(x, j.Min, j.Max)
+	switch j.Type {
+	case REVOLUTE:
+		m := Translate3d(j.Origin.Negate())
+		m = Rotate3d(j.Axis, x).Mul(m)
+		return Translate3d(j.Origin).Mul(m)
+	case PRISMATIC:
+		return Translate3d(j.Axis.Normalize().MulScalar(x))
+	default:
+		panic("bad joint type")
+	}
+}
+
+//-----------------------------------------------------------------------------
+// Assemblies
+
+type Link struct {
+	sdf    SDF3   // link geometry (in the base frame at joint value 0)
+	joint  *Joint // joint to the parent
+	parent *Link  // parent link (nil for the base)
+	idx    int    // index of the joint value
+}
+
+type Assembly struct {
+	base  SDF3    // fixed base
+	links []*Link // moving links
+}
+
+// NewAssembly returns an assembly with a fixed base.
+func NewAssembly(base SDF3) *Assembly {
+	return &Assembly{base: base}
+}
+
+// AddLink adds a link to the assembly.
+// The joint is defined in the base frame at the parent's rest position.
+// A nil parent attaches the link to the base.
+func (a *Assembly) AddLink(s SDF3, j *Joint, parent *Link) *Link {
+	if j.Min > j.Max {
+		panic("bad joint limits")
+	}
+	l := &Link{s, j, parent, len(a.links)}
+	a.links = append(a.links, l)
+	return l
+}
+
+// Return the transform for a link with the given joint values.
+func (l *Link) transform(x []float64) M44 {
+	m := l.joint.Transform(x[l.idx])
+	if l.parent != nil {
+		m = l.parent.transform(x).Mul(m)
+	}
+	return m
+}
+
+// Pose returns the parts of the assembly (base first) for the joint values.
+func (a *Assembly) Pose(x []float64) []SDF3 {
+	if len(x) != len(a.links) {
+		panic("need a value for each joint")
+	}
+	parts := []SDF3{a.base}
+	for _, l := range a.links {
+		parts = append(parts, Transform3D(l.sdf, l.transform(x)))
+	}
+	return parts
+}
+
+// SDF3 returns the assembly as a single SDF3 for the joint values.
+func (a *Assembly) SDF3(x []float64) SDF3 {
+	return Union3D(a.Pose(x)...)
+}
+
+// Sweep returns n sets of joint values moving all joints from their
+// minimum to their maximum limit.
+func (a *Assembly) Sweep(n int) [][]float64 {
+	if n < 2 {
+		panic("need at least 2 frames")
+	}
+	frames := make([][]float64, n)
+	for i := range frames {
+		k := float64(i) / float64(n-1)
+		x := make([]float64, len(a.links))
+		for j, l := range a.links {
+			x[j] = Mix(l.joint.Min, l.joint.Max, k)
+		}
+		frames[i] = x
+	}
+	return frames
+}
+
+//-----------------------------------------------------------------------------
+// Swept Volumes
+
+// MotionFunc returns the transform for a part at time t in [0,1].
+type MotionFunc func(t float64) M44
+
+type SweptVolumeSDF3 struct {
+	sdf     SDF3  // moving part
+	inverse []M44 // inverse transforms at each step
+	bb      Box3  // bounding box
+}
+
+// SweptVolume3D returns the union of an SDF3 over a motion path.
+// The motion is sampled at steps+1 evenly spaced times in [0,1]. The steps
+// should be small enough relative to the part size that the positions overlap.
+func SweptVolume3D(sdf SDF3, motion MotionFunc, steps int) SDF3 {
+	if steps < 1 {
+		panic("need at least 1 step")
+	}
+	s := SweptVolumeSDF3{}
+	s.sdf = sdf
+	s.inverse = make([]M44, steps+1)
+	bb := sdf.BoundingBox()
+	for i := range s.inverse {
+		m := motion(float64(i) / float64(steps))
+		s.inverse[i] = m.Inverse()
+		if i == 0 {
+			s.bb = m.MulBox(bb)
+		} else {
+			s.bb = s.bb.Extend(m.MulBox(bb))
+		}
+	}
+	return &s
+}
+
+// Evaluate returns the minimum distance to the swept volume.
+func (s *SweptVolumeSDF3) Evaluate(p V3) float64 {
+	d := math.MaxFloat64
+	for _, m := range s.inverse {
+		d = Min(d, s.sdf.Evaluate(m.MulPosition(p)))
+	}
+	return d
+}
+
+// BoundingBox returns the bounding box of the swept volume.
+func (s *SweptVolumeSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+// Motion returns the motion of a link as its joint moves from the minimum to
+// maximum limit. Other joint values are set by x.
+func (l *Link) Motion(x []float64) MotionFunc {
+	return func(t float64) M44 {
+		y := append([]float64(nil), x...)
+		y[l.idx] = Mix(l.joint.Min, l.joint.Max, t)
+		return l.transform(y)
+	}
+}
+
+// Geometry returns the link geometry (at joint value 0).
+func (l *Link) Geometry() SDF3 {
+	return l.sdf
+}
+
+//-----------------------------------------------------------------------------
+// Collision Detection
+
+// Return a point where two SDF3s interfere within a box.
+// Boxes are subdivided down to the resolution. The intersection distance
+// is bounded by max(d0, d1), so boxes further away than this are skipped.
+func interference(s0, s1 SDF3, bb Box3, resolution float64) (V3, bool) {
+	c := bb.Center()
+	d := Max(s0.Evaluate(c), s1.Evaluate(c))
+	if d < 0 {
+		return c, true
+	}
+	size := bb.Size()
+	if d > 0.5*size.Length() || size.MaxComponent() < resolution {
+		return V3{}, false
+	}
+	// subdivide the box
+	for _, v := range bb.Vertices() {
+		if p, ok := interference(s0, s1, Box3{c.Min(v), c.Max(v)}, resolution); ok {
+			return p, true
+		}
+	}
+	return V3{}, false
+}
+
+// Interference returns a point where two SDF3s interfere (to the resolution).
+func Interference(s0, s1 SDF3, resolution float64) (V3, bool) {
+	bb0 := s0.BoundingBox()
+	bb1 := s1.BoundingBox()
+	bb := Box3{bb0.Min.Max(bb1.Min), bb0.Max.Min(bb1.Max)}
+	if bb.Min.X > bb.Max.X || bb.Min.Y > bb.Max.Y || bb.Min.Z > bb.Max.Z {
+		// the bounding boxes don't overlap
+		return V3{}, false
+	}
+	return interference(s0, s1, bb, resolution)
+}
+
+type Collision struct {
+	T float64 // time of the first interference
+	P V3      // point of interference
+}
+
+// FirstCollision returns the first interference between a moving part and
+// static geometry along a motion path, or nil if there is none.
+// The motion is sampled at steps+1 evenly spaced times in [0,1], and the time
+// of the first collision is refined between the samples.
+func FirstCollision(moving, static SDF3, motion MotionFunc, steps int, resolution float64) *Collision {
+	if steps < 1 {
+		panic("need at least 1 step")
+	}
+	check := func(t float64) (V3, bool) {
+		return Interference(Transform3D(moving, motion(t)), static, resolution)
+	}
+	t0 := 0.0
+	for i := 0; i <= steps; i++ {
+		t1 := float64(i) / float64(steps)
+		p, ok := check(t1)
+		if !ok {
+			t0 = t1
+			continue
+		}
+		if i == 0 {
+			return &Collision{0, p}
+		}
+		// bisect to find the first collision time
+		for j := 0; j < 20; j++ {
+			t := 0.5 * (t0 + t1)
+			if q, ok := check(t); ok {
+				t1, p = t, q
+			} else {
+				t0 = t
+			}
+		}
+		return &Collision{t1, p}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+// Previews
+
+// PreviewSTL renders the assembly as an STL file for each set of joint values.
+// The path is a format string for the frame number. E.g. "frame_%03d.stl"
+func (a *Assembly) PreviewSTL(path string, frames [][]float64, mesh_cells int) {
+	for i, x := range frames {
+		RenderSTL(a.SDF3(x), mesh_cells, fmt.Sprintf(path, i))
+	}
+}
+
+// PreviewPNG renders a slice of the assembly as a PNG file for each set of
+// joint values. The slice plane passes through p with normal n.
+// The path is a format string for the frame number. E.g. "frame_%03d.png"
+func (a *Assembly) PreviewPNG(path string, frames [][]float64, p, n V3, pixels V2i) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("no frames")
+	}
+	// use a common bounding box so the frames line up
+	slices := make([]SDF2, len(frames))
+	for i, x := range frames {
+		slices[i] = Slice2D(a.SDF3(x), p, n)
+	}
+	bb := slices[0].BoundingBox()
+	for _, s := range slices[1:] {
+		bb = bb.Extend(s.BoundingBox())
+	}
+	for i, s := range slices {
+		name := fmt.Sprintf(path, i)
+		fmt.Printf("rendering %s\n", name)
+		d, err := NewPNG(name, bb, pixels)
+		if err != nil {
+			return err
+		}
+		d.RenderSDF2(s)
+		err = d.Save()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,216 @@
+//-----------------------------------------------------------------------------
+/*
+
+Lab Mechanism Parts
+
+Parts for open hardware lab pumps.
+
+Peristaltic Rotors
+
+The rotor carries ball bearings as rollers. The rollers squeeze the tube
+against the housing, the housing radius is set by the tube wall thickness and
+the occlusion (how much further the walls are squeezed once they touch). The
+rotor body is two plates joined at the hub with a pocket for each roller,
+the rollers turn on axles (E.g. screws) through the plates. The rotor fits a
+motor shaft (with an optional D flat) and is held by a threaded set screw.
+
+Syringe Clamps
+
+A clamp block for a syringe barrel with a slot that locates the finger flange.
+The base has threaded holes for the clamp screws, the cap has clearance holes.
+The syringe axis is the x-axis, the flange is at the +x end.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+// Bearings
+
+type bearing_size struct {
+	bore  float64 // inside diameter
+	od    float64 // outside diameter
+	width float64 // width
+}
+
+var bearing_db = map[string]bearing_size{
+	"623": {3, 10, 4},
+	"624": {4, 13, 5},
+	"625": {5, 16, 5},
+	"688": {8, 16, 5},
+	"608": {8, 22, 7},
+}
+
+// Return the bearing dimensions.
+func bearing_lookup(name string) bearing_size {
+	b, ok := bearing_db[name]
+	if !ok {
+		panic("bearing not found")
+	}
+	return b
+}
+
+//-----------------------------------------------------------------------------
+// Peristaltic Rotors
+
+type PeristalticParms struct {
+	TubeID    float64 // tube inside diameter
+	TubeOD    float64 // tube outside diameter
+	Occlusion float64 // extra squeeze as a fraction of the tube walls (E.g. 0.15)
+	Rollers   int     // number of rollers
+	Bearing   string  // roller bearing (E.g. "623", "608")
+	Radius    float64 // radius of the roller axles
+	Shaft     float64 // motor shaft diameter
+	Flat      float64 // depth of the shaft D flat (0 for a round shaft)
+	SetScrew  string  // set screw thread (E.g. "M3")
+	Wall      float64 // plate thickness
+	Clearance float64 // clearance around the rollers, axles and shaft
+}
+
+// HousingRadius returns the inside radius of the pump housing.
+func (k *PeristalticParms) HousingRadius() float64 {
+	b := bearing_lookup(k.Bearing)
+	return k.Radius + 0.5*b.od + (k.TubeOD-k.TubeID)*(1.0-k.Occlusion)
+}
+
+// PeristalticRotor3D returns a peristaltic pump rotor centered on the origin.
+// The first roller is on the x-axis.
+func PeristalticRotor3D(k *PeristalticParms) SDF3 {
+	b := bearing_lookup(k.Bearing)
+	if k.TubeID <= 0 || k.TubeOD <= k.TubeID || k.Occlusion < 0 || k.Occlusion >= 0.5 {
+		panic("invalid tube parameters")
+	}
+	if k.Rollers < 2 || k.Wall <= 0 || k.Clearance < 0 {
+		panic("invalid rotor parameters")
+	}
+	if k.Shaft <= 0 || k.Flat < 0 || k.Flat >= 0.5*k.Shaft {
+		panic("invalid shaft")
+	}
+	// the rollers stick out past the rotor body
+	r_pocket := 0.5*b.od + k.Clearance
+	r_body := k.Radius + 0.5*b.bore + k.Wall
+	if r_body >= k.Radius+0.5*b.od {
+		panic("rotor wall is too thick for the bearing")
+	}
+	// the pockets must not meet or cut into the hub
+	if 2.0*k.Radius*math.Sin(PI/float64(k.Rollers)) <= 2.0*r_pocket {
+		panic("rollers are too close together")
+	}
+	if k.Radius-r_pocket <= 0.5*k.Shaft+k.Wall {
+		panic("roller radius is too small for the hub")
+	}
+	w := b.width + 2.0*k.Clearance
+	h := w + 2.0*k.Wall
+	body := Cylinder3D(h, r_body, 0)
+
+	// roller pockets and axles
+	pocket := Cylinder3D(w, r_pocket, 0)
+	axle := Cylinder3D(2.0*h, 0.5*b.bore+k.Clearance, 0)
+	roller := Transform3D(Union3D(pocket, axle), Translate3d(V3{k.Radius, 0, 0}))
+	cutters := []SDF3{RotateCopy3D(roller, k.Rollers)}
+
+	// motor shaft, the flat and set screw are between the first two rollers
+	r := 0.5*k.Shaft + k.Clearance
+	shaft := Circle2D(r)
+	if k.Flat > 0 {
+		flat := Transform2D(Box2D(V2{2.0 * r, 4.0 * r}, 0), Translate2d(V2{2.0*r - k.Flat, 0}))
+		shaft = Difference2D(shaft, flat)
+	}
+	hub := Extrude3D(shaft, 2.0*h)
+	if k.SetScrew != "" {
+		spec, err := ParseThread(k.SetScrew)
+		if err != nil {
+			panic(err)
+		}
+		spec.Internal = true
+		screw := Thread3D(spec, &ThreadParms{Length: r_body, LeadIn: 0.5 * spec.Thread.Pitch})
+		hub = Union3D(hub, Transform3D(screw, RotateY(0.5*PI)))
+	}
+	cutters = append(cutters, Transform3D(hub, RotateZ(PI/float64(k.Rollers))))
+
+	return Difference3D(body, Union3D(cutters...))
+}
+
+//-----------------------------------------------------------------------------
+// Syringe Clamps
+
+type syringe_size struct {
+	barrel float64 // barrel outside diameter
+	flange V2      // finger flange span and thickness
+}
+
+var syringe_db = map[string]syringe_size{
+	"1ml":  {6.7, V2{22, 1.6}},
+	"5ml":  {12.5, V2{24, 1.7}},
+	"10ml": {15.9, V2{32, 2.0}},
+	"20ml": {20.1, V2{36, 2.2}},
+	"60ml": {29.7, V2{48, 2.7}},
+}
+
+type SyringeParms struct {
+	Size      string  // syringe size (E.g. "10ml")
+	Screw     string  // clamp screw thread (E.g. "M3")
+	Wall      float64 // wall thickness
+	Clearance float64 // clearance around the barrel, flange and screws
+}
+
+// SyringeClamp3D returns the base and cap of a syringe clamp.
+// The base sits on the z = 0 plane, the cap is shown in position on the base.
+func SyringeClamp3D(k *SyringeParms) (SDF3, SDF3) {
+	sy, ok := syringe_db[k.Size]
+	if !ok {
+		panic("syringe size not found")
+	}
+	if k.Wall <= 0 || k.Clearance < 0 {
+		panic("invalid clamp parameters")
+	}
+	spec, err := ParseThread(k.Screw)
+	if err != nil {
+		panic(err)
+	}
+	rs := spec.Thread.Radius
+
+	rb := 0.5*sy.barrel + k.Clearance
+	// base and cap heights, the barrel axis is at z = h
+	h := rb + k.Wall
+	// clamp screws are beside the barrel
+	ys := rb + k.Wall + rs
+	width := Max(2.0*(ys+rs+k.Wall), sy.flange.X+2.0*(k.Clearance+k.Wall))
+	length := Max(24.0, 1.2*sy.barrel)
+	block := Box3D(V3{length, width, h}, 0)
+
+	// barrel cradle
+	cradle := Cylinder3D(2.0*length, rb, 0)
+	cradle = Transform3D(cradle, RotateY(0.5*PI))
+	cradle = Transform3D(cradle, Translate3d(V3{0, 0, h}))
+
+	// flange slot at the +x end, the flange span is across the clamp
+	// leave a floor under the slot so the base is one piece
+	t := sy.flange.Y + 2.0*k.Clearance
+	slot := Box3D(V3{t, sy.flange.X + 2.0*k.Clearance, 2.0 * (rb + 0.5*k.Wall)}, 0)
+	slot = Transform3D(slot, Translate3d(V3{0.5*length - k.Wall - 0.5*t, 0, h}))
+
+	base := Transform3D(block, Translate3d(V3{0, 0, 0.5 * h}))
+	cap := Transform3D(block, Translate3d(V3{0, 0, 1.5 * h}))
+
+	// screws: threaded in the base, clearance in the cap
+	spec.Internal = true
+	var threads, holes []SDF3
+	for _, x := range []float64{-0.25 * length, 0.25 * length} {
+		for _, y := range []float64{-ys, ys} {
+			thread := Thread3D(spec, &ThreadParms{Length: h - k.Wall, LeadIn: 0.5 * spec.Thread.Pitch})
+			threads = append(threads, Transform3D(thread, Translate3d(V3{x, y, k.Wall})))
+			hole := Cylinder3D(3.0*h, rs+k.Clearance, 0)
+			holes = append(holes, Transform3D(hole, Translate3d(V3{x, y, 1.5 * h})))
+		}
+	}
+
+	base = Difference3D(base, Union3D(append(threads, cradle, slot)...))
+	cap = Difference3D(cap, Union3D(append(holes, cradle, slot)...))
+	return base, cap
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,259 @@
+//-----------------------------------------------------------------------------
+/*
+
+Small Linear Algebra
+
+Solvers for the small linear systems used by spline fitting, NURBS and cam
+generation. The solvers return an error for bad sizes or singular matrices
+rather than panicking.
+
+Tridiagonal: row i of the matrix is (a[i], b[i], c[i]) on the sub, main and
+super diagonals. a[0] and c[n-1] are zero (or the corner elements for cyclic
+systems).
+
+Banded: row i of the matrix is stored as band[i][0:lower+upper+1], the
+elements from column i-lower to column i+upper. The elimination is not
+pivoted, it is stable for diagonally dominant and totally positive matrices
+(E.g. spline and B-spline interpolation).
+
+Least Squares: minimize |A.x - b| with a Householder QR factorization.
+
+*/
+//-----------------------------------------------------------------------------
+
+package linalg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+//-----------------------------------------------------------------------------
+
+// ErrSingular is returned when the matrix is singular (a zero pivot).
+var ErrSingular = errors.New("singular matrix")
+
+//-----------------------------------------------------------------------------
+// Tridiagonal
+
+// TriDiagonal solves the tridiagonal matrix equation m.x = d, returns x.
+// See: https://en.wikipedia.org/wiki/Tridiagonal_matrix_algorithm
+func TriDiagonal(a, b, c, d []float64) ([]float64, error) {
+	n := len(d)
+	if n == 0 || len(a) != n || len(b) != n || len(c) != n {
+		return nil, fmt.Errorf("bad sizes for tridiagonal matrix")
+	}
+	if a[0] != 0 || c[n-1] != 0 {
+		return nil, fmt.Errorf("bad values for tridiagonal matrix")
+	}
+	if b[0] == 0 {
+		return nil, ErrSingular
+	}
+	cp := make([]float64, n) // c-prime
+	x := make([]float64, n)  // d-prime -> x solution
+	// elimination
+	cp[0] = c[0] / b[0]
+	x[0] = d[0] / b[0]
+	for i := 1; i < n; i++ {
+		denom := b[i] - a[i]*cp[i-1]
+		if denom == 0 {
+			return nil, ErrSingular
+		}
+		cp[i] = c[i] / denom
+		x[i] = (d[i] - a[i]*x[i-1]) / denom
+	}
+	// back substitution
+	for i := n - 2; i >= 0; i-- {
+		x[i] -= cp[i] * x[i+1]
+	}
+	return x, nil
+}
+
+// TriDiagonalCyclic solves the cyclic tridiagonal matrix equation m.x = d, returns x.
+// a[0] and c[n-1] are the corner elements of the matrix.
+// See: https://en.wikipedia.org/wiki/Tridiagonal_matrix_algorithm#Variants
+func TriDiagonalCyclic(a, b, c, d []float64) ([]float64, error) {
+	n := len(d)
+	if len(a) != n || len(b) != n || len(c) != n {
+		return nil, fmt.Errorf("bad sizes for tridiagonal matrix")
+	}
+	if n < 3 {
+		return nil, fmt.Errorf("cyclic tridiagonal matrix needs at least 3 rows")
+	}
+	if b[0] == 0 {
+		return nil, ErrSingular
+	}
+	// Sherman-Morrison: m = t + u.v^T, where t is tridiagonal.
+	a0 := a[0]
+	cn := c[n-1]
+	gamma := -b[0]
+	ta := make([]float64, n)
+	tb := make([]float64, n)
+	tc := make([]float64, n)
+	copy(ta, a)
+	copy(tb, b)
+	copy(tc, c)
+	ta[0] = 0
+	tb[0] = b[0] - gamma
+	tb[n-1] = b[n-1] - a0*cn/gamma
+	tc[n-1] = 0
+	u := make([]float64, n)
+	u[0] = gamma
+	u[n-1] = cn
+	x, err := TriDiagonal(ta, tb, tc, d)
+	if err != nil {
+		return nil, err
+	}
+	q, err := TriDiagonal(ta, tb, tc, u)
+	if err != nil {
+		return nil, err
+	}
+	// v = [1, 0, ... 0, a0/gamma]
+	vx := x[0] + x[n-1]*a0/gamma
+	vq := q[0] + q[n-1]*a0/gamma
+	if 1+vq == 0 {
+		return nil, ErrSingular
+	}
+	k := vx / (1 + vq)
+	for i := range x {
+		x[i] -= k * q[i]
+	}
+	return x, nil
+}
+
+//-----------------------------------------------------------------------------
+// Banded
+
+// Banded solves the banded matrix equation m.x = d, returns x.
+// band[i] holds the elements of row i from column i-lower to column i+upper.
+func Banded(band [][]float64, lower, upper int, d []float64) ([]float64, error) {
+	n := len(d)
+	w := lower + upper + 1
+	if n == 0 || len(band) != n || lower < 0 || upper < 0 {
+		return nil, fmt.Errorf("bad sizes for banded matrix")
+	}
+	// work on a copy, column j of row i is at [i][j-i+lower]
+	m := make([][]float64, n)
+	for i := range band {
+		if len(band[i]) != w {
+			return nil, fmt.Errorf("bad row size for banded matrix")
+		}
+		m[i] = make([]float64, w)
+		copy(m[i], band[i])
+	}
+	x := make([]float64, n)
+	copy(x, d)
+	// elimination
+	for k := 0; k < n; k++ {
+		pivot := m[k][lower]
+		if pivot == 0 {
+			return nil, ErrSingular
+		}
+		for i := k + 1; i <= k+lower && i < n; i++ {
+			f := m[i][k-i+lower] / pivot
+			if f == 0 {
+				continue
+			}
+			for j := k; j <= k+upper && j < n; j++ {
+				m[i][j-i+lower] -= f * m[k][j-k+lower]
+			}
+			x[i] -= f * x[k]
+		}
+	}
+	// back substitution
+	for i := n - 1; i >= 0; i-- {
+		for j := i + 1; j <= i+upper && j < n; j++ {
+			x[i] -= m[i][j-i+lower] * x[j]
+		}
+		x[i] /= m[i][lower]
+	}
+	return x, nil
+}
+
+//-----------------------------------------------------------------------------
+// Least Squares
+
+// LeastSquares returns x minimizing |a.x - b| for an m x n matrix a (m >= n).
+// a is given as rows.
+func LeastSquares(a [][]float64, b []float64) ([]float64, error) {
+	m := len(a)
+	if m == 0 || len(b) != m {
+		return nil, fmt.Errorf("bad sizes for least squares")
+	}
+	n := len(a[0])
+	if n == 0 || n > m {
+		return nil, fmt.Errorf("bad sizes for least squares")
+	}
+	// work on copies
+	r := make([][]float64, m)
+	for i := range a {
+		if len(a[i]) != n {
+			return nil, fmt.Errorf("bad row size for least squares")
+		}
+		r[i] = make([]float64, n)
+		copy(r[i], a[i])
+	}
+	y := make([]float64, m)
+	copy(y, b)
+	// scale for the singular test
+	norm := 0.0
+	for i := range r {
+		for _, v := range r[i] {
+			norm = math.Max(norm, math.Abs(v))
+		}
+	}
+	// Householder QR: apply the reflections to y as we go
+	for k := 0; k < n; k++ {
+		s := 0.0
+		for i := k; i < m; i++ {
+			s += r[i][k] * r[i][k]
+		}
+		s = math.Sqrt(s)
+		if s <= 1e-12*norm {
+			return nil, ErrSingular
+		}
+		alpha := -math.Copysign(s, r[k][k])
+		// v = x - alpha.e1
+		v := make([]float64, m-k)
+		for i := k; i < m; i++ {
+			v[i-k] = r[i][k]
+		}
+		v[0] -= alpha
+		vv := 0.0
+		for _, x := range v {
+			vv += x * x
+		}
+		// apply I - 2vv^T/(v^Tv) to the remaining columns and y
+		for j := k; j < n; j++ {
+			f := 0.0
+			for i := k; i < m; i++ {
+				f += v[i-k] * r[i][j]
+			}
+			f = 2 * f / vv
+			for i := k; i < m; i++ {
+				r[i][j] -= f * v[i-k]
+			}
+		}
+		f := 0.0
+		for i := k; i < m; i++ {
+			f += v[i-k] * y[i]
+		}
+		f = 2 * f / vv
+		for i := k; i < m; i++ {
+			y[i] -= f * v[i-k]
+		}
+	}
+	// back substitution with the upper triangle
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		s := y[i]
+		for j := i + 1; j < n; j++ {
+			s -= r[i][j] * x[j]
+		}
+		x[i] = s / r[i][i]
+	}
+	return x, nil
+}
+
+//-----------------------------------------------------------------------------
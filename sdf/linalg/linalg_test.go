@@ -0,0 +1,130 @@
+//-----------------------------------------------------------------------------
+/*
+
+Linear Algebra Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package linalg
+
+import (
+	"math"
+	"testing"
+)
+
+//-----------------------------------------------------------------------------
+
+const tolerance = 1e-9
+
+func equal(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_TriDiagonal(t *testing.T) {
+	a := []float64{0, 1, 1, 1, 1}
+	b := []float64{2, 4, 4, 4, 2}
+	c := []float64{1, 1, 1, 1, 0}
+	d := []float64{0, 1, 2, 3, 4}
+	x, err := TriDiagonal(a, b, c, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x0 := []float64{-1.0 / 12.0, 1.0 / 6.0, 5.0 / 12.0, 1.0 / 6.0, 23.0 / 12.0}
+	if !equal(x, x0) {
+		t.Error("FAIL")
+	}
+	// errors, not panics
+	if _, err := TriDiagonal(a, b, c, d[:4]); err == nil {
+		t.Error("FAIL")
+	}
+	if _, err := TriDiagonal([]float64{0, 1}, []float64{1, 1}, []float64{1, 0}, []float64{1, 2}); err != ErrSingular {
+		t.Error("FAIL")
+	}
+	if _, err := TriDiagonalCyclic(a[:2], b[:2], c[:2], d[:2]); err == nil {
+		t.Error("FAIL")
+	}
+}
+
+func Test_Banded(t *testing.T) {
+	// tridiagonal as a band matrix
+	band := [][]float64{{0, 2, 1}, {1, 4, 1}, {1, 4, 1}, {1, 4, 1}, {1, 2, 0}}
+	d := []float64{0, 1, 2, 3, 4}
+	x, err := Banded(band, 1, 1, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x0 := []float64{-1.0 / 12.0, 1.0 / 6.0, 5.0 / 12.0, 1.0 / 6.0, 23.0 / 12.0}
+	if !equal(x, x0) {
+		t.Error("FAIL")
+	}
+	// pentadiagonal with an asymmetric band: check the residual
+	n := 7
+	band = make([][]float64, n)
+	for i := range band {
+		band[i] = []float64{1, -2, 8, 1.5}
+	}
+	d = []float64{1, 2, 3, 4, 5, 6, 7}
+	x, err = Banded(band, 2, 1, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		r := 0.0
+		for j := i - 2; j <= i+1; j++ {
+			if j >= 0 && j < n {
+				r += band[i][j-i+2] * x[j]
+			}
+		}
+		if math.Abs(r-d[i]) > tolerance {
+			t.Error("FAIL")
+		}
+	}
+	if _, err := Banded([][]float64{{0, 1}}, 1, 1, []float64{1}); err == nil {
+		t.Error("FAIL")
+	}
+	if _, err := Banded([][]float64{{0, 0, 1}, {1, 1, 0}}, 1, 1, []float64{1, 2}); err != ErrSingular {
+		t.Error("FAIL")
+	}
+}
+
+func Test_LeastSquares(t *testing.T) {
+	// square system
+	a := [][]float64{{2, 1}, {1, 3}}
+	x, err := LeastSquares(a, []float64{3, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(x, []float64{0.8, 1.4}) {
+		t.Error("FAIL")
+	}
+	// line fit: y = 1 + 2x with symmetric noise
+	a = [][]float64{{1, 0}, {1, 1}, {1, 2}, {1, 3}}
+	x, err = LeastSquares(a, []float64{1.1, 2.9, 5.1, 6.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// normal equations: [4 6; 6 14] x = [16, 33.8]
+	if !equal(x, []float64{1.06, 1.96}) {
+		t.Errorf("FAIL %v", x)
+	}
+	// rank deficient
+	if _, err := LeastSquares([][]float64{{1, 2}, {2, 4}, {3, 6}}, []float64{1, 2, 3}); err != ErrSingular {
+		t.Error("FAIL")
+	}
+	if _, err := LeastSquares([][]float64{{1, 2}}, []float64{1}); err == nil {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
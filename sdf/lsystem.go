@@ -0,0 +1,141 @@
+//-----------------------------------------------------------------------------
+/*
+
+L-Systems
+
+Lindenmayer systems for fractal trees, branching supports and decorative
+elements. The axiom is rewritten by the production rules and the result is
+interpreted by a 3D turtle to make a skeleton (see Skeleton3D).
+
+Turtle commands:
+
+F: move forward one step, adding a node and an edge
+f: move forward one step without an edge
++ -: turn left/right (about the up vector)
+& ^: pitch down/up (about the left vector)
+\ /: roll left/right (about the heading vector)
+|: turn around
+!: scale the radius (and step) by the taper factors
+[ ]: push/pop the turtle state (branches)
+
+Other characters are ignored by the turtle (they can be used in the rules).
+The turtle starts at the origin heading up the z-axis.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "strings"
+
+//-----------------------------------------------------------------------------
+
+type LSystem struct {
+	Axiom string          // initial string
+	Rules map[rune]string // production rules
+}
+
+// Expand returns the string after n rewrites of the axiom.
+func (l *LSystem) Expand(n int) string {
+	s := l.Axiom
+	for i := 0; i < n; i++ {
+		var b strings.Builder
+		for _, c := range s {
+			if r, ok := l.Rules[c]; ok {
+				b.WriteString(r)
+			} else {
+				b.WriteRune(c)
+			}
+		}
+		s = b.String()
+	}
+	return s
+}
+
+//-----------------------------------------------------------------------------
+// Turtle
+
+type TurtleParms struct {
+	Step        float64 // step length
+	Angle       float64 // turn angle (radians)
+	Radius      float64 // initial node radius
+	RadiusTaper float64 // radius scale for "!" (E.g. 0.7)
+	StepTaper   float64 // step scale for "!" (E.g. 0.8)
+}
+
+type turtle_state struct {
+	pos      V3
+	h, l, u  V3 // heading, left and up vectors
+	radius   float64
+	step     float64
+	node     int // current skeleton node
+	has_node bool
+}
+
+// Return a vector rotated about an axis.
+func turtle_rotate(v, axis V3, a float64) V3 {
+	return Rotate3d(axis, a).MulPosition(v)
+}
+
+// Skeleton interprets a string with a 3D turtle and returns the skeleton.
+func (k *TurtleParms) Skeleton(s string) *Skeleton {
+	if k.Step <= 0 || k.Radius <= 0 || k.RadiusTaper <= 0 || k.StepTaper <= 0 {
+		panic("invalid turtle parameters")
+	}
+	sk := NewSkeleton()
+	t := turtle_state{
+		h:      V3{0, 0, 1},
+		l:      V3{0, 1, 0},
+		u:      V3{-1, 0, 0},
+		radius: k.Radius,
+		step:   k.Step,
+	}
+	var stack []turtle_state
+	for _, c := range s {
+		switch c {
+		case 'F':
+			if !t.has_node {
+				t.node = sk.AddNode(t.pos, t.radius)
+				t.has_node = true
+			}
+			t.pos = t.pos.Add(t.h.MulScalar(t.step))
+			n := sk.AddNode(t.pos, t.radius)
+			sk.AddEdge(t.node, n)
+			t.node = n
+		case 'f':
+			t.pos = t.pos.Add(t.h.MulScalar(t.step))
+			t.has_node = false
+		case '+':
+			t.h, t.l = turtle_rotate(t.h, t.u, k.Angle), turtle_rotate(t.l, t.u, k.Angle)
+		case '-':
+			t.h, t.l = turtle_rotate(t.h, t.u, -k.Angle), turtle_rotate(t.l, t.u, -k.Angle)
+		case '&':
+			t.h, t.u = turtle_rotate(t.h, t.l, k.Angle), turtle_rotate(t.u, t.l, k.Angle)
+		case '^':
+			t.h, t.u = turtle_rotate(t.h, t.l, -k.Angle), turtle_rotate(t.u, t.l, -k.Angle)
+		case '\\':
+			t.l, t.u = turtle_rotate(t.l, t.h, k.Angle), turtle_rotate(t.u, t.h, k.Angle)
+		case '/':
+			t.l, t.u = turtle_rotate(t.l, t.h, -k.Angle), turtle_rotate(t.u, t.h, -k.Angle)
+		case '|':
+			t.h, t.l = t.h.Negate(), t.l.Negate()
+		case '!':
+			t.radius *= k.RadiusTaper
+			t.step *= k.StepTaper
+		case '[':
+			stack = append(stack, t)
+		case ']':
+			if len(stack) == 0 {
+				panic("unbalanced brackets")
+			}
+			t = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(sk.nodes) == 0 {
+		panic("the string draws nothing")
+	}
+	return sk
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,141 @@
+//-----------------------------------------------------------------------------
+/*
+
+Magnet and Pogo-Pin Pockets
+
+Pockets for neodymium magnets and housings for spring loaded (pogo) pins,
+E.g. for magnetic lids, charging docks and modular fixtures.
+
+Magnets are named by size in mm: "6x3" is a disc (diameter x height) and
+"5x5x5" or "10x5x2" is a cube or block (x, y and height). A glue gap adds
+clearance around and under the magnet. A retention lip narrows the opening so
+the magnet snaps in past it, the lip should be a few layers thick so it flexes.
+
+Pogo pins have a barrel with a flange (collar) at the tail. The pins go into
+the housing from behind, the flange seats in a recess and the shoulder takes
+the spring force.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"strconv"
+	"strings"
+)
+
+//-----------------------------------------------------------------------------
+// Magnets
+
+type MagnetParms struct {
+	Magnet    string  // magnet size, E.g. "6x3" (disc) or "5x5x5" (cube)
+	Clearance float64 // fit clearance around the magnet
+	Glue      float64 // glue gap around and under the magnet
+	Lip       V2      // retention lip overhang and height (0 for none)
+}
+
+// Return the shape and size of a magnet (diameter, diameter, height for discs).
+func magnet_size(name string) (bool, V3) {
+	f := strings.Split(strings.ToLower(strings.TrimSpace(name)), "x")
+	if len(f) != 2 && len(f) != 3 {
+		panic("invalid magnet size")
+	}
+	x := make([]float64, len(f))
+	for i := range f {
+		v, err := strconv.ParseFloat(f[i], 64)
+		if err != nil || v <= 0 {
+			panic("invalid magnet size")
+		}
+		x[i] = v
+	}
+	if len(x) == 2 {
+		return true, V3{x[0], x[0], x[1]}
+	}
+	return false, V3{x[0], x[1], x[2]}
+}
+
+// Return a disc or block cutter centered on the origin.
+func magnet_cutter(disc bool, size V3) SDF3 {
+	if disc {
+		return Cylinder3D(size.Z, 0.5*size.X, 0)
+	}
+	return Box3D(size, 0)
+}
+
+// MagnetPocket3D returns a cutter for a magnet pocket.
+// The face is the z = 0 plane, the part is below it.
+// The top of the magnet is flush with the face.
+func MagnetPocket3D(k *MagnetParms) SDF3 {
+	if k.Clearance < 0 || k.Glue < 0 || k.Lip.X < 0 || k.Lip.Y < 0 {
+		panic("invalid magnet pocket parameters")
+	}
+	disc, size := magnet_size(k.Magnet)
+	gap := k.Clearance + k.Glue
+	depth := size.Z + k.Glue
+	pocket := V3{size.X, size.Y, depth}.Add(V3{2.0 * gap, 2.0 * gap, 0})
+	if k.Lip.X == 0 || k.Lip.Y == 0 {
+		// extend above the face for a clean cut
+		return magnet_cutter(disc, V3{pocket.X, pocket.Y, 2.0 * depth})
+	}
+	if k.Lip.Y >= depth || 2.0*k.Lip.X >= Min(pocket.X, pocket.Y) {
+		panic("invalid retention lip")
+	}
+	// the pocket below the lip
+	s0 := magnet_cutter(disc, V3{pocket.X, pocket.Y, depth - k.Lip.Y})
+	s0 = Transform3D(s0, Translate3d(V3{0, 0, -0.5*(depth-k.Lip.Y) - k.Lip.Y}))
+	// the opening through the lip
+	opening := pocket.Sub(V3{2.0 * k.Lip.X, 2.0 * k.Lip.X, 0})
+	s1 := magnet_cutter(disc, V3{opening.X, opening.Y, 4.0 * k.Lip.Y})
+	return Union3D(s0, s1)
+}
+
+//-----------------------------------------------------------------------------
+// Pogo Pins
+
+type PogoPinParms struct {
+	Pins      int     // number of pins
+	Pitch     float64 // pin spacing
+	Barrel    float64 // barrel diameter
+	Length    float64 // barrel length from the flange to the face
+	Flange    V2      // flange diameter and thickness
+	Wall      float64 // housing wall thickness around the flanges
+	Clearance float64 // radial clearance on the barrel and flange
+}
+
+// PogoPin3D returns a housing and a cutter for the pin holes.
+// The face is the z = 0 plane, the housing is below it and the pins point up.
+// The pins are in a row along the x-axis centered on the origin.
+// The housing should be added to the part and the holes subtracted from the
+// result, so the holes go through the face.
+func PogoPin3D(k *PogoPinParms) (SDF3, SDF3) {
+	if k.Pins < 1 || k.Barrel <= 0 || k.Length <= 0 || k.Wall <= 0 || k.Clearance < 0 {
+		panic("invalid pogo pin parameters")
+	}
+	if k.Flange.X <= k.Barrel || k.Flange.Y <= 0 {
+		panic("invalid pogo pin flange")
+	}
+	rf := 0.5*k.Flange.X + k.Clearance
+	if k.Pins > 1 && k.Pitch < 2.0*rf {
+		panic("pogo pin pitch is too small")
+	}
+	rb := 0.5*k.Barrel + k.Clearance
+	h := k.Length + k.Flange.Y
+	w := float64(k.Pins-1) * k.Pitch
+	// housing
+	housing := Box3D(V3{w + 2.0*(rf+k.Wall), 2.0 * (rf + k.Wall), h}, 0)
+	housing = Transform3D(housing, Translate3d(V3{0, 0, -0.5 * h}))
+	// barrel through the face, flange recess open at the back
+	barrel := Cylinder3D(2.0*h, rb, 0)
+	flange := Cylinder3D(2.0*k.Flange.Y, rf, 0)
+	flange = Transform3D(flange, Translate3d(V3{0, 0, -h}))
+	hole := Union3D(barrel, flange)
+	holes := make([]SDF3, k.Pins)
+	for i := range holes {
+		x := float64(i)*k.Pitch - 0.5*w
+		holes[i] = Transform3D(hole, Translate3d(V3{x, 0, 0}))
+	}
+	return housing, Union3D(holes...)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,217 @@
+//-----------------------------------------------------------------------------
+/*
+
+Marble Runs
+
+Parametric ball track segments that join end to end.
+
+The track is a channel with a round bottom groove. Each segment starts at its
+input joint (the origin) with the ball travelling along the +x axis. The output
+joints give the position of the groove center and the heading of the ball
+where the next segment attaches. Use Attach to place a segment at the output
+joint of another segment.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+
+type TrackParms struct {
+	Ball      float64 // ball diameter
+	Clearance float64 // clearance between the ball and the groove
+	Wall      float64 // wall thickness below and beside the groove
+	Rail      float64 // height of the side walls above the groove center
+}
+
+type TrackJoint struct {
+	Position V3      // groove center at the joint
+	Heading  float64 // direction of ball travel (radians about the z-axis)
+}
+
+type TrackSegment struct {
+	SDF SDF3         // segment geometry
+	In  TrackJoint   // input joint
+	Out []TrackJoint // output joints
+}
+
+// Return the groove radius.
+func (k *TrackParms) groove() float64 {
+	return 0.5*k.Ball + k.Clearance
+}
+
+// Return the 2D solid and groove profiles of the track.
+// The profile is in the (across, up) plane with the groove center at the origin.
+func (k *TrackParms) profiles() (SDF2, SDF2) {
+	if k.Ball <= 0 || k.Clearance < 0 || k.Wall <= 0 || k.Rail < 0 {
+		panic("invalid track parameters")
+	}
+	r := k.groove()
+	w := 2.0 * (r + k.Wall)
+	h := r + k.Wall + k.Rail
+	solid := Transform2D(Box2D(V2{w, h}, 0), Translate2d(V2{0, 0.5*h - (r + k.Wall)}))
+	// open the groove above the ball center
+	top := Transform2D(Box2D(V2{2.0 * r, 2.0 * k.Rail}, 0), Translate2d(V2{0, k.Rail}))
+	return solid, Union2D(Circle2D(r), top)
+}
+
+// Return the 2D profile of the track.
+func (k *TrackParms) profile() SDF2 {
+	solid, groove := k.profiles()
+	return Difference2D(solid, groove)
+}
+
+// Return a straight extrusion of a profile along the x-axis from x = 0 to length.
+func track_extrude(profile SDF2, length float64) SDF3 {
+	s := Extrude3D(profile, length)
+	// map the extrusion (x, y, z) to (y, z, x)
+	s = Transform3D(s, Rotate3d(V3{1, 1, 1}, TAU/3))
+	return Transform3D(s, Translate3d(V3{0.5 * length, 0, 0}))
+}
+
+// Attach returns a copy of the segment placed with its input at a joint.
+func (t *TrackSegment) Attach(j TrackJoint) *TrackSegment {
+	m := Translate3d(t.In.Position.Negate())
+	m = RotateZ(j.Heading - t.In.Heading).Mul(m)
+	m = Translate3d(j.Position).Mul(m)
+	s := TrackSegment{}
+	s.SDF = Transform3D(t.SDF, m)
+	s.In = j
+	for _, o := range t.Out {
+		o.Position = m.MulPosition(o.Position)
+		o.Heading += j.Heading - t.In.Heading
+		s.Out = append(s.Out, o)
+	}
+	return &s
+}
+
+//-----------------------------------------------------------------------------
+
+// StraightTrack3D returns a straight track segment that drops over its length.
+func StraightTrack3D(k *TrackParms, length, drop float64) *TrackSegment {
+	if length <= 0 {
+		panic("invalid track length")
+	}
+	l := math.Sqrt(length*length + drop*drop)
+	s := track_extrude(k.profile(), l)
+	// tilt the track down
+	s = Transform3D(s, RotateY(math.Atan2(drop, length)))
+	return &TrackSegment{s, TrackJoint{}, []TrackJoint{{V3{length, 0, -drop}, 0}}}
+}
+
+// CurvedTrack3D returns a level track segment on a circular arc.
+// The track turns left for a +ve angle and right for a -ve angle.
+func CurvedTrack3D(k *TrackParms, radius, angle float64) *TrackSegment {
+	r := k.groove() + k.Wall
+	if radius <= r {
+		panic("invalid track radius")
+	}
+	if angle == 0 || Abs(angle) >= TAU {
+		panic("invalid track angle")
+	}
+	profile := Transform2D(k.profile(), Translate2d(V2{radius, 0}))
+	s := RevolveTheta3D(profile, Abs(angle))
+	// start at the origin heading along the x-axis
+	s = Transform3D(s, Translate3d(V3{0, radius, 0}).Mul(RotateZ(-0.5*PI)))
+	p := PolarToXY(radius, Abs(angle)-0.5*PI).Add(V2{0, radius})
+	if angle < 0 {
+		s = Transform3D(s, MirrorXZ())
+		p.Y = -p.Y
+	}
+	return &TrackSegment{s, TrackJoint{}, []TrackJoint{{V3{p.X, p.Y, 0}, angle}}}
+}
+
+// HelixTrack3D returns a track segment that spirals down counter-clockwise.
+// The track drops by pitch for each turn.
+func HelixTrack3D(k *TrackParms, radius, pitch, turns float64) *TrackSegment {
+	r := k.groove() + k.Wall
+	if radius <= r {
+		panic("invalid track radius")
+	}
+	if pitch <= r+k.Rail {
+		panic("helix pitch is too small for the track")
+	}
+	profile := Transform2D(k.profile(), Translate2d(V2{radius, 0}))
+	s := HelixExtrude3D(profile, -pitch, turns)
+	// start at the origin heading along the x-axis
+	m := Translate3d(V3{0, radius, 0}).Mul(RotateZ(-0.5 * PI))
+	s = Transform3D(s, m)
+	a := turns * TAU
+	p := PolarToXY(radius, a-0.5*PI).Add(V2{0, radius})
+	return &TrackSegment{s, TrackJoint{}, []TrackJoint{{V3{p.X, p.Y, -pitch * turns}, math.Mod(a, TAU)}}}
+}
+
+// FunnelTrack3D returns a funnel that the ball enters tangentially at the rim.
+// The ball spirals down and drops out through the hole at the bottom.
+// The output joint is at the hole with the ball dropping vertically.
+func FunnelTrack3D(k *TrackParms, radius, height float64) *TrackSegment {
+	hole := k.groove() + k.Clearance
+	if radius <= 2.0*hole || height <= 0 {
+		panic("invalid funnel size")
+	}
+	// funnel wall and rim
+	w := k.Wall
+	wall := NewPolygon()
+	wall.Add(hole, -height)
+	wall.Add(radius, 0)
+	wall.Add(radius, k.Rail)
+	wall.Add(radius+w, k.Rail)
+	wall.Add(radius+w, -w)
+	wall.Add(hole+w, -height-w)
+	s := Revolve3D(Polygon2D(wall.Vertices()))
+	// the rim is tangent to the input joint
+	s = Transform3D(s, Translate3d(V3{0, radius - k.groove(), 0}))
+	return &TrackSegment{s, TrackJoint{}, []TrackJoint{{V3{0, radius - k.groove(), -height}, 0}}}
+}
+
+// SwitchTrack3D returns a Y shaped track segment that splits the ball path,
+// and a flipper that sends alternate balls along each branch. The branches are
+// at +/- angle to the x-axis. The flipper pivots on a pin at the fork (the
+// pivot hole goes through the track floor).
+func SwitchTrack3D(k *TrackParms, length, angle float64) (*TrackSegment, SDF3) {
+	r := k.groove()
+	if length <= 4.0*r {
+		panic("invalid switch length")
+	}
+	if angle <= 0 || angle >= DtoR(45) {
+		panic("invalid switch angle")
+	}
+	solid2d, groove2d := k.profiles()
+	// the fork is half way along
+	fork := 0.5 * length
+	arm := fork / math.Cos(angle)
+	in_solid := track_extrude(solid2d, fork)
+	in_groove := track_extrude(groove2d, fork+r)
+	var solids, grooves []SDF3
+	solids = append(solids, in_solid)
+	grooves = append(grooves, Transform3D(in_groove, Translate3d(V3{-r, 0, 0})))
+	var out []TrackJoint
+	for _, a := range []float64{angle, -angle} {
+		m := Translate3d(V3{fork, 0, 0}).Mul(RotateZ(a))
+		solids = append(solids, Transform3D(track_extrude(solid2d, arm), m))
+		grooves = append(grooves, Transform3D(track_extrude(groove2d, arm+r), m))
+		p := PolarToXY(arm, a).Add(V2{fork, 0})
+		out = append(out, TrackJoint{V3{p.X, p.Y, 0}, a})
+	}
+	// flipper pivot hole
+	pin := 0.25 * r
+	hole := Cylinder3D(4.0*(r+k.Wall), pin+k.Clearance, 0)
+	hole = Transform3D(hole, Translate3d(V3{fork, 0, 0}))
+	grooves = append(grooves, hole)
+	s := Difference3D(Union3D(solids...), Union3D(grooves...))
+
+	// flipper: a vertical vane on a pivot pin (shown in the center position)
+	vane_l := 0.5 * arm
+	vane_h := r + k.Rail - k.Clearance
+	vane := Box3D(V3{vane_l, 0.5 * k.Wall, vane_h}, 0)
+	vane = Transform3D(vane, Translate3d(V3{0.5 * vane_l, 0, k.Rail - 0.5*vane_h}))
+	pivot := Cylinder3D(2.0*(r+k.Wall), pin, 0)
+	flipper := Transform3D(Union3D(vane, pivot), Translate3d(V3{fork, 0, 0}))
+
+	return &TrackSegment{s, TrackJoint{}, out}, flipper
+}
+
+//-----------------------------------------------------------------------------
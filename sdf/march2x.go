@@ -33,6 +33,7 @@ type dcache2 struct {
 	s          SDF2            // the SDF2 to be rendered
 	cache      map[V2i]float64 // cache of distances
 	lock       sync.RWMutex    // lock the the cache during reads/writes
+	shared     *samples2       // samples shared across renders (may be nil)
 }
 
 func new_dcache2(s SDF2, origin V2, resolution float64, n uint) *dcache2 {
@@ -74,10 +75,20 @@ func (dc *dcache2) evaluate(vi V2i) (V2, float64) {
 	if found {
 		return v, dist
 	}
+	// has a previous render evaluated it?
+	if dc.shared != nil {
+		if dist, found = dc.shared.read(v); found {
+			dc.write(vi, dist)
+			return v, dist
+		}
+	}
 	// evaluate the SDF2
 	dist = dc.s.Evaluate(v)
 	// write it to the cache
 	dc.write(vi, dist)
+	if dc.shared != nil {
+		dc.shared.write(v, dist)
+	}
 	return v, dist
 }
 
@@ -122,6 +133,11 @@ func (dc *dcache2) process_square(c *square, output chan<- *Line2_PP) {
 
 // MarchingSquares_Quadtree generates line segments for an SDF2 using quadtree subdivision.
 func MarchingSquares_Quadtree(s SDF2, resolution float64, output chan<- *Line2_PP) {
+	marching_squares_quadtree(s, resolution, output, nil)
+}
+
+// MarchingSquares_Quadtree with distance samples shared across renders.
+func marching_squares_quadtree(s SDF2, resolution float64, output chan<- *Line2_PP, shared *samples2) {
 	// Scale the bounding box about the center to make sure the boundaries
 	// aren't on the object surface.
 	bb := s.BoundingBox()
@@ -134,6 +150,7 @@ func MarchingSquares_Quadtree(s SDF2, resolution float64, output chan<- *Line2_P
 	levels := uint(math.Ceil(math.Log2(long_axis/resolution))) + 1
 	// create the distance cache
 	dc := new_dcache2(s, bb.Min, resolution, levels)
+	dc.shared = shared
 	// process the quadtree, start at the top level
 	dc.process_square(&square{V2i{0, 0}, levels - 1}, output)
 }
@@ -27,10 +27,11 @@ type LayerYZ struct {
 	steps V3i       // number of x,y,z steps
 	val0  []float64 // SDF values for x layer
 	val1  []float64 // SDF values for x + dx layer
+	batch BatchFunc // backend evaluation (nil for the CPU)
 }
 
 func NewLayerYZ(base, inc V3, steps V3i) *LayerYZ {
-	return &LayerYZ{base, inc, steps, nil, nil}
+	return &LayerYZ{base, inc, steps, nil, nil, nil}
 }
 
 // evalReq is used for processing evaluations in parallel.
@@ -80,6 +81,22 @@ func (l *LayerYZ) Evaluate(sdf SDF3, x int) {
 	var p V3
 	p.X = l.base.X + float64(x)*dx
 
+	if l.batch != nil {
+		// evaluate the whole layer with the backend
+		pts := make([]V3, 0, len(l.val1))
+		p.Y = l.base.Y
+		for y := 0; y < ny+1; y++ {
+			p.Z = l.base.Z
+			for z := 0; z < nz+1; z++ {
+				pts = append(pts, p)
+				p.Z += dz
+			}
+			p.Y += dy
+		}
+		l.batch(pts, l.val1)
+		return
+	}
+
 	// define the base struct for requesting evaluation
 	eReq := evalReq{
 		wg:  new(sync.WaitGroup),
@@ -140,6 +157,7 @@ func MarchingCubes(sdf SDF3, box Box3, step float64) []*Triangle3 {
 
 	// create the SDF layer cache
 	l := NewLayerYZ(base, inc, steps)
+	l.batch = compile_batch(sdf)
 	// evaluate the SDF for x = 0
 	l.Evaluate(sdf, 0)
 
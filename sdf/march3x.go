@@ -6,6 +6,14 @@ Marching Cubes Octree
 Convert an SDF3 to a triangle mesh.
 Uses octree space subdivision.
 
+A feature thinner than the resolution can fit between the corners of the
+smallest cubes and be lost. If the SDF3 has an interval evaluation, a smallest
+cube with all corners on the same side of the surface is split into smaller
+cubes (down to THIN_LEVELS below the resolution) when the interval doesn't
+rule out the surface. The split cubes are marched with the finer resolution,
+so a thin feature gets (finer) triangles. They don't always join the
+triangles of the neighbouring cubes exactly.
+
 */
 //-----------------------------------------------------------------------------
 
@@ -18,6 +26,9 @@ import (
 
 //-----------------------------------------------------------------------------
 
+// number of levels below the smallest cubes searched for thin features
+const THIN_LEVELS = 2
+
 type cube struct {
 	v V3i  // origin of cube as integers
 	n uint // level of cube, size = 1 << n
@@ -35,6 +46,9 @@ type dcache3 struct {
 	s          SDF3            // the SDF3 to be rendered
 	cache      map[V3i]float64 // cache of distances
 	lock       sync.RWMutex    // lock the the cache during reads/writes
+	shared     *samples3       // samples shared across renders (may be nil)
+	interval   bool            // the SDF3 has a real interval evaluation
+	batch      BatchFunc       // backend evaluation (nil for the CPU)
 }
 
 func new_dcache3(s SDF3, origin V3, resolution float64, n uint) *dcache3 {
@@ -46,6 +60,7 @@ func new_dcache3(s SDF3, origin V3, resolution float64, n uint) *dcache3 {
 		hdiag:      make([]float64, n),
 		s:          s,
 		cache:      make(map[V3i]float64),
+		interval:   has_interval(s),
 	}
 	// build a lut for cube half diagonal lengths
 	for i := range dc.hdiag {
@@ -78,10 +93,20 @@ func (dc *dcache3) evaluate(vi V3i) (V3, float64) {
 	if found {
 		return v, dist
 	}
+	// has a previous render evaluated it?
+	if dc.shared != nil {
+		if dist, found = dc.shared.read(v); found {
+			dc.write(vi, dist)
+			return v, dist
+		}
+	}
 	// evaluate the SDF3
 	dist = dc.s.Evaluate(v)
 	// write it to the cache
 	dc.write(vi, dist)
+	if dc.shared != nil {
+		dc.shared.write(v, dist)
+	}
 	return v, dist
 }
 
@@ -90,8 +115,26 @@ func (dc *dcache3) is_empty(c *cube) bool {
 	// evaluate the SDF3 at the center of the cube
 	s := 1 << (c.n - 1) // half side
 	_, d := dc.evaluate(c.v.AddScalar(s))
+	return dc.is_empty_center(c, d)
+}
+
+// is_empty_center returns true if the cube contains no SDF surface given the
+// distance d at the center of the cube.
+func (dc *dcache3) is_empty_center(c *cube, d float64) bool {
+	s := 1 << (c.n - 1) // half side
 	// compare to the center/corner distance
-	return Abs(d) >= dc.hdiag[c.n]
+	if Abs(d) >= dc.hdiag[c.n] {
+		return true
+	}
+	// Interval evaluation may prove the cube is empty or full. It isn't
+	// worth it for the smallest cubes, their corners are evaluated anyway.
+	// Without a real interval evaluation it can't do better than the test above.
+	if dc.interval && c.n > 1 {
+		v0 := dc.origin.Add(c.v.ToV3().MulScalar(dc.resolution))
+		v1 := v0.AddScalar(float64(2*s) * dc.resolution)
+		return evaluate_interval_center(dc.s.(SDF3Interval), Box3{v0, v1}, d).Excludes(0)
+	}
+	return false
 }
 
 // Process a cube. Generate triangles, or more cubes.
@@ -110,9 +153,7 @@ func (dc *dcache3) process_cube(c *cube, output chan<- *Triangle3) {
 			corners := [8]V3{c0, c1, c2, c3, c4, c5, c6, c7}
 			values := [8]float64{d0, d1, d2, d3, d4, d5, d6, d7}
 			// output the triangle(s) for this cube
-			for _, t := range mc_ToTriangles(corners, values, 0) {
-				output <- t
-			}
+			dc.leaf(corners, values, output)
 		} else {
 			// process the sub cubes
 			n := c.n - 1
@@ -130,10 +171,170 @@ func (dc *dcache3) process_cube(c *cube, output chan<- *Triangle3) {
 	}
 }
 
+// Output the triangles for a smallest cube.
+func (dc *dcache3) leaf(corners [8]V3, values [8]float64, output chan<- *Triangle3) {
+	triangles := mc_ToTriangles(corners, values, 0)
+	for _, t := range triangles {
+		output <- t
+	}
+	if len(triangles) == 0 && dc.interval {
+		// the surface may be between the corners
+		dc.thin_cube(corners[0], 2*dc.resolution, THIN_LEVELS, output)
+	}
+}
+
+// Look for a thin feature in a cube with all corners on the same side of the
+// surface. If the interval evaluation doesn't rule out the surface the cube is
+// split into 8 sub cubes, and they are marched (or split again).
+func (dc *dcache3) thin_cube(v0 V3, size float64, levels int, output chan<- *Triangle3) {
+	if levels == 0 || dc.s.(SDF3Interval).EvaluateInterval(Box3{v0, v0.AddScalar(size)}).Excludes(0) {
+		return
+	}
+	// cube_corners are in units of half the cube size
+	k := 0.25 * size
+	for _, ofs := range cube_corners {
+		c := v0.Add(ofs.ToV3().MulScalar(k))
+		var corners [8]V3
+		var values [8]float64
+		for i, x := range cube_corners {
+			corners[i] = c.Add(x.ToV3().MulScalar(k))
+			values[i] = dc.s.Evaluate(corners[i])
+		}
+		triangles := mc_ToTriangles(corners, values, 0)
+		for _, t := range triangles {
+			output <- t
+		}
+		if len(triangles) == 0 {
+			dc.thin_cube(c, 0.5*size, levels-1, output)
+		}
+	}
+}
+
+// corner offsets of the smallest cubes
+var cube_corners = [8]V3i{
+	{0, 0, 0}, {2, 0, 0}, {2, 2, 0}, {0, 2, 0},
+	{0, 0, 2}, {2, 0, 2}, {2, 2, 2}, {0, 2, 2},
+}
+
+// fill returns the distances at grid points. The uncached points are
+// evaluated as a batch with the backend and cached.
+func (dc *dcache3) fill(vs []V3i) []float64 {
+	dist := make([]float64, len(vs))
+	var todo []int           // indices of the points to evaluate
+	pending := map[V3i]int{} // index of a point to evaluate
+	var dups [][2]int        // repeated points to evaluate (index, first index)
+	var ps []V3
+	dc.lock.RLock()
+	for i, vi := range vs {
+		if d, found := dc.cache[vi]; found {
+			dist[i] = d
+			continue
+		}
+		if j, found := pending[vi]; found {
+			dups = append(dups, [2]int{i, j})
+			continue
+		}
+		pending[vi] = i
+		todo = append(todo, i)
+	}
+	dc.lock.RUnlock()
+	if len(todo) == 0 {
+		return dist
+	}
+	// has a previous render evaluated them?
+	n := 0
+	for _, i := range todo {
+		v := dc.origin.Add(vs[i].ToV3().MulScalar(dc.resolution))
+		if dc.shared != nil {
+			if d, found := dc.shared.read(v); found {
+				dist[i] = d
+				dc.write(vs[i], d)
+				continue
+			}
+		}
+		todo[n] = i
+		ps = append(ps, v)
+		n++
+	}
+	todo = todo[:n]
+	d := make([]float64, len(ps))
+	dc.batch(ps, d)
+	dc.lock.Lock()
+	for k, i := range todo {
+		dist[i] = d[k]
+		dc.cache[vs[i]] = d[k]
+	}
+	dc.lock.Unlock()
+	if dc.shared != nil {
+		for k, v := range ps {
+			dc.shared.write(v, d[k])
+		}
+	}
+	for _, x := range dups {
+		dist[x[0]] = dist[x[1]]
+	}
+	return dist
+}
+
+// Process the octree a level at a time. The distances needed for each level
+// (the cube centers, then the corners of the smallest cubes) are evaluated as
+// a batch with the backend. The cubes and triangles are the same as for
+// process_cube.
+func (dc *dcache3) process_batch(c *cube, output chan<- *Triangle3) {
+	cubes := []cube{*c}
+	for len(cubes) != 0 {
+		// the cube centers for the empty tests
+		vs := make([]V3i, len(cubes))
+		for i, c := range cubes {
+			vs[i] = c.v.AddScalar(1 << (c.n - 1))
+		}
+		dist := dc.fill(vs)
+		var next, leaves []cube
+		for i := range cubes {
+			c := &cubes[i]
+			if dc.is_empty_center(c, dist[i]) {
+				continue
+			}
+			if c.n == 1 {
+				leaves = append(leaves, *c)
+				continue
+			}
+			n := c.n - 1
+			s := 1 << n
+			for _, ofs := range []V3i{{0, 0, 0}, {s, 0, 0}, {s, s, 0}, {0, s, 0}, {0, 0, s}, {s, 0, s}, {s, s, s}, {0, s, s}} {
+				next = append(next, cube{c.v.Add(ofs), n})
+			}
+		}
+		// the corners of the smallest cubes
+		vs = make([]V3i, 0, 8*len(leaves))
+		for _, c := range leaves {
+			for _, ofs := range cube_corners {
+				vs = append(vs, c.v.Add(ofs))
+			}
+		}
+		dist = dc.fill(vs)
+		for i := range leaves {
+			var corners [8]V3
+			var values [8]float64
+			for j := range corners {
+				corners[j] = dc.origin.Add(vs[8*i+j].ToV3().MulScalar(dc.resolution))
+				values[j] = dist[8*i+j]
+			}
+			dc.leaf(corners, values, output)
+		}
+		cubes = next
+	}
+}
+
 //-----------------------------------------------------------------------------
 
 // MarchingCubes_Octree generates a triangle mesh for an SDF3 using octree subdivision.
 func MarchingCubes_Octree(s SDF3, resolution float64, output chan<- *Triangle3) {
+	marching_cubes_octree(s, resolution, output, nil)
+}
+
+// MarchingCubes_Octree with distance samples shared across renders.
+func marching_cubes_octree(s SDF3, resolution float64, output chan<- *Triangle3, shared *samples3) {
 	// Scale the bounding box about the center to make sure the boundaries
 	// aren't on the object surface.
 	bb := s.BoundingBox()
@@ -146,8 +347,13 @@ func MarchingCubes_Octree(s SDF3, resolution float64, output chan<- *Triangle3)
 	levels := uint(math.Ceil(math.Log2(long_axis/resolution))) + 1
 	// create the distance cache
 	dc := new_dcache3(s, bb.Min, resolution, levels)
+	dc.shared = shared
 	// process the octree, start at the top level
-	dc.process_cube(&cube{V3i{0, 0, 0}, levels - 1}, output)
+	if dc.batch = compile_batch(s); dc.batch != nil {
+		dc.process_batch(&cube{V3i{0, 0, 0}, levels - 1}, output)
+	} else {
+		dc.process_cube(&cube{V3i{0, 0, 0}, levels - 1}, output)
+	}
 }
 
 //-----------------------------------------------------------------------------
@@ -0,0 +1,214 @@
+//-----------------------------------------------------------------------------
+/*
+
+Mass Properties
+
+Volume, surface area, center of mass and moments of inertia of an SDF3, so
+they can be checked before a part is made. The density is uniform (1), for
+the mass multiply the volume (and the inertia) by the material density.
+
+The integrals are over the bounding box of the SDF3 (enlarged by the width of
+the area kernel):
+
+Volume: the integral of the inside indicator (d < 0).
+Area: the integral of a narrow kernel of the distance (the co-area formula).
+This needs |grad d| = 1 near the surface, so it is only good for SDFs that
+are exact near the surface.
+Inertia: the inertia tensor about the center of mass.
+
+Grid: midpoint quadrature on a regular grid of cells. The inside fraction of
+each cell is approximated from the distance at the cell center, which is much
+better than a hard inside/outside test. The error estimate is the difference
+from the result on a grid with half the cells.
+
+Monte Carlo: uniform random samples in the bounding box. The error estimate
+is the standard error (1 sigma) of the sample mean.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+//-----------------------------------------------------------------------------
+
+// number of cells on the longest axis for Volume, Area and Centroid
+const MASS_CELLS = 100
+
+type MassProperties struct {
+	Volume      float64 // volume
+	VolumeError float64 // volume error estimate
+	Area        float64 // surface area
+	AreaError   float64 // surface area error estimate
+	Centroid    V3      // center of mass
+	Inertia     V3      // moments of inertia about the center of mass (Ixx, Iyy, Izz)
+	Product     V3      // products of inertia about the center of mass (Ixy, Ixz, Iyz)
+	Samples     int     // number of SDF evaluations
+}
+
+func (m *MassProperties) String() string {
+	return fmt.Sprintf("volume %.4g (+/- %.2g), area %.4g (+/- %.2g), centroid %v, inertia %v, product %v",
+		m.Volume, m.VolumeError, m.Area, m.AreaError, m.Centroid, m.Inertia, m.Product)
+}
+
+//-----------------------------------------------------------------------------
+
+// weighted sums of the integrands
+type mass_sum struct {
+	v, a       float64 // volume, area
+	x          V3      // 1st moments
+	xx, yy, zz float64 // 2nd moments
+	xy, xz, yz float64
+	n          int // samples
+}
+
+// Add a sample point with volume and area weights.
+func (m *mass_sum) add(p V3, v, a float64) {
+	m.n++
+	m.a += a
+	if v == 0 {
+		return
+	}
+	m.v += v
+	m.x = m.x.Add(p.MulScalar(v))
+	m.xx += v * p.X * p.X
+	m.yy += v * p.Y * p.Y
+	m.zz += v * p.Z * p.Z
+	m.xy += v * p.X * p.Y
+	m.xz += v * p.X * p.Z
+	m.yz += v * p.Y * p.Z
+}
+
+// Return the mass properties for the sums.
+func (m *mass_sum) properties() *MassProperties {
+	r := MassProperties{Volume: m.v, Area: m.a, Samples: m.n}
+	if m.v == 0 {
+		return &r
+	}
+	c := m.x.DivScalar(m.v)
+	r.Centroid = c
+	// 2nd moments about the center of mass (parallel axis theorem)
+	xx := m.xx - m.v*c.X*c.X
+	yy := m.yy - m.v*c.Y*c.Y
+	zz := m.zz - m.v*c.Z*c.Z
+	r.Inertia = V3{yy + zz, xx + zz, xx + yy}
+	r.Product = V3{
+		-(m.xy - m.v*c.X*c.Y),
+		-(m.xz - m.v*c.X*c.Z),
+		-(m.yz - m.v*c.Y*c.Z),
+	}
+	return &r
+}
+
+//-----------------------------------------------------------------------------
+// Grid Quadrature
+
+// Return the mass property sums on a grid with cells on the longest axis.
+func mass_grid(s SDF3, cells int) *mass_sum {
+	bb := s.BoundingBox()
+	h := bb.Size().MaxComponent() / float64(cells)
+	// area kernel width
+	w := 2 * h
+	bb = Box3{bb.Min.SubScalar(w), bb.Max.AddScalar(w)}
+	n := bb.Size().DivScalar(h).Ceil().ToV3i()
+	// center the grid on the box
+	base := bb.Center().Sub(n.ToV3().MulScalar(0.5 * h)).AddScalar(0.5 * h)
+	dv := h * h * h
+	var m mass_sum
+	for i := 0; i < n[0]; i++ {
+		for j := 0; j < n[1]; j++ {
+			for k := 0; k < n[2]; k++ {
+				p := base.Add(V3{float64(i), float64(j), float64(k)}.MulScalar(h))
+				d := s.Evaluate(p)
+				// inside fraction of the cell
+				v := Clamp(0.5-d/h, 0, 1) * dv
+				// area kernel (integral = 1)
+				a := Max(1-Abs(d)/w, 0) / w * dv
+				m.add(p, v, a)
+			}
+		}
+	}
+	return &m
+}
+
+// MassGrid3D returns the mass properties of an SDF3 using grid quadrature
+// with cells on the longest axis of the bounding box.
+func MassGrid3D(s SDF3, cells int) *MassProperties {
+	if cells < 2 {
+		panic("invalid number of cells")
+	}
+	fine := mass_grid(s, cells)
+	coarse := mass_grid(s, cells/2)
+	r := fine.properties()
+	r.VolumeError = Abs(fine.v - coarse.v)
+	r.AreaError = Abs(fine.a - coarse.a)
+	r.Samples += coarse.n
+	return r
+}
+
+//-----------------------------------------------------------------------------
+// Monte Carlo
+
+// MassMonteCarlo3D returns the mass properties of an SDF3 using random
+// samples in the bounding box.
+func MassMonteCarlo3D(s SDF3, samples int) *MassProperties {
+	if samples < 2 {
+		panic("invalid number of samples")
+	}
+	bb := s.BoundingBox()
+	size := bb.Size()
+	// area kernel width: a few times the mean sample spacing
+	h := 2 * math.Cbrt(size.X*size.Y*size.Z/float64(samples))
+	bb = Box3{bb.Min.SubScalar(h), bb.Max.AddScalar(h)}
+	size = bb.Size()
+	vbb := size.X * size.Y * size.Z
+	w := vbb / float64(samples)
+	rnd := rand.New(rand.NewSource(1))
+	var m mass_sum
+	inside := 0
+	a2 := 0.0
+	for i := 0; i < samples; i++ {
+		p := bb.Min.Add(size.Mul(V3{rnd.Float64(), rnd.Float64(), rnd.Float64()}))
+		d := s.Evaluate(p)
+		v := 0.0
+		if d < 0 {
+			v = w
+			inside++
+		}
+		a := Max(1-Abs(d)/h, 0) / h
+		a2 += a * a
+		m.add(p, v, a*w)
+	}
+	r := m.properties()
+	n := float64(samples)
+	f := float64(inside) / n
+	r.VolumeError = vbb * math.Sqrt(f*(1-f)/n)
+	// standard error of the mean kernel value
+	ma := m.a / vbb
+	r.AreaError = vbb * math.Sqrt(Max(a2/n-ma*ma, 0)/n)
+	return r
+}
+
+//-----------------------------------------------------------------------------
+
+// Volume returns the volume of an SDF3.
+func Volume(s SDF3) float64 {
+	return mass_grid(s, MASS_CELLS).properties().Volume
+}
+
+// Area returns the surface area of an SDF3.
+func Area(s SDF3) float64 {
+	return mass_grid(s, MASS_CELLS).properties().Area
+}
+
+// Centroid returns the center of mass of an SDF3.
+func Centroid(s SDF3) V3 {
+	return mass_grid(s, MASS_CELLS).properties().Centroid
+}
+
+//-----------------------------------------------------------------------------
@@ -115,7 +115,7 @@ func Translate2d(v V2) M33 {
 }
 
 // Scale3d returns a 4x4 scaling matrix.
-// Scaling does not preserve distance. See: ScaleUniform3D()
+// Scaling does not preserve distance. See: ScaleUniform3D(), ScaleNonUniform3D()
 func Scale3d(v V3) M44 {
 	return M44{
 		v.X, 0, 0, 0,
@@ -125,7 +125,7 @@ func Scale3d(v V3) M44 {
 }
 
 // Scale2d returns a 3x3 scaling matrix.
-// Scaling does not preserve distance. See: ScaleUniform2D()
+// Scaling does not preserve distance. See: ScaleUniform2D(), ScaleNonUniform2D()
 func Scale2d(v V2) M33 {
 	return M33{
 		v.X, 0, 0,
@@ -161,7 +161,6 @@ func RotateZ(a float64) M44 {
 	return Rotate3d(V3{0, 0, 1}, a)
 }
 
-// TODO - generalise for other mirror planes
 // Mirror across the YZ plane
 func MirrorYZ() M44 {
 	return M44{
@@ -171,6 +170,47 @@ func MirrorYZ() M44 {
 		0, 0, 0, 1}
 }
 
+// Mirror across the XZ plane
+func MirrorXZ() M44 {
+	return M44{
+		1, 0, 0, 0,
+		0, -1, 0, 0,
+		0, 0, 1, 0,
+		0, 0, 0, 1}
+}
+
+// Mirror across the XY plane
+func MirrorXY() M44 {
+	return M44{
+		1, 0, 0, 0,
+		0, 1, 0, 0,
+		0, 0, -1, 0,
+		0, 0, 0, 1}
+}
+
+// MirrorPlane3d returns a matrix to mirror across a plane.
+// The plane is given by a point on the plane and the plane normal.
+func MirrorPlane3d(p, n V3) M44 {
+	n = n.Normalize()
+	d := 2.0 * n.Dot(p)
+	return M44{
+		1 - 2*n.X*n.X, -2 * n.X * n.Y, -2 * n.X * n.Z, d * n.X,
+		-2 * n.Y * n.X, 1 - 2*n.Y*n.Y, -2 * n.Y * n.Z, d * n.Y,
+		-2 * n.Z * n.X, -2 * n.Z * n.Y, 1 - 2*n.Z*n.Z, d * n.Z,
+		0, 0, 0, 1}
+}
+
+// MirrorLine2d returns a matrix to mirror across a line.
+// The line is given by a point on the line and the line normal.
+func MirrorLine2d(p, n V2) M33 {
+	n = n.Normalize()
+	d := 2.0 * n.Dot(p)
+	return M33{
+		1 - 2*n.X*n.X, -2 * n.X * n.Y, d * n.X,
+		-2 * n.Y * n.X, 1 - 2*n.Y*n.Y, d * n.Y,
+		0, 0, 1}
+}
+
 // Return an orthographic 2d rotation matrix (right hand rule)
 func Rotate2d(a float64) M33 {
 	s := math.Sin(a)
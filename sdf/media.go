@@ -0,0 +1,129 @@
+//-----------------------------------------------------------------------------
+/*
+
+Media Holders
+
+Cutters for pockets that hold memory cards, USB plugs and coin cells, E.g. in
+an enclosure lid or a desk organizer.
+
+Card Slots: The card stands on edge in a slot. The slot narrows towards the
+bottom by the grip amount, the card flexes slightly and is held by friction.
+The top of the slot has a chamfered lead-in and an optional finger notch at
+the center so the card can be pinched and pulled out.
+
+Coin Cells: A round pocket with finger cutouts on opposite sides of the cell.
+
+The face is the z = 0 plane, the part is below it.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+//-----------------------------------------------------------------------------
+// Card Slots
+
+// Card and plug sizes (width, thickness, length).
+var media_db = map[string]V3{
+	"SD":      {24, 2.1, 32},
+	"microSD": {11, 1.0, 15},
+	"USB-A":   {12, 4.5, 12},
+	"USB-C":   {8.4, 2.6, 6.7},
+}
+
+type CardSlotParms struct {
+	Card      string  // card or plug name, E.g. "SD", "microSD", "USB-A"
+	Depth     float64 // slot depth (0 for half the card length)
+	Clearance float64 // clearance around the card
+	Grip      float64 // narrowing of the slot at the bottom (friction fit)
+	Chamfer   float64 // lead-in chamfer at the top of the slot
+	Notch     float64 // radius of the finger notch (0 for none)
+}
+
+// CardSlot3D returns a cutter for a slot that holds a card on edge.
+// The card thickness is along the x-axis and the width along the y-axis.
+func CardSlot3D(k *CardSlotParms) SDF3 {
+	size, ok := media_db[k.Card]
+	if !ok {
+		panic("unknown card type")
+	}
+	if k.Depth < 0 || k.Clearance < 0 || k.Grip < 0 || k.Chamfer < 0 || k.Notch < 0 {
+		panic("invalid card slot parameters")
+	}
+	depth := k.Depth
+	if depth == 0 {
+		depth = 0.5 * size.Z
+	}
+	// half thickness at the top and bottom of the slot
+	t0 := 0.5*size.Y + k.Clearance
+	t1 := t0 - 0.5*k.Grip
+	if t1 <= 0 || k.Chamfer >= depth {
+		panic("invalid card slot grip or chamfer")
+	}
+	// the (thickness, depth) profile, extended above the face
+	ext := k.Chamfer + 1.0
+	var profile []V2
+	if k.Chamfer > 0 {
+		profile = []V2{
+			{-t1, -depth}, {t1, -depth}, {t0, -k.Chamfer}, {t0 + k.Chamfer, 0},
+			{t0 + k.Chamfer, ext}, {-t0 - k.Chamfer, ext}, {-t0 - k.Chamfer, 0}, {-t0, -k.Chamfer},
+		}
+	} else {
+		profile = []V2{{-t1, -depth}, {t1, -depth}, {t0, 0}, {t0, ext}, {-t0, ext}, {-t0, 0}}
+	}
+	s := Extrude3D(Polygon2D(profile), size.X+2.0*k.Clearance)
+	// map the profile depth onto the z-axis
+	s = Transform3D(s, RotateX(0.5*PI))
+	if k.Notch > 0 {
+		s = Union3D(s, Sphere3D(k.Notch))
+	}
+	return s
+}
+
+//-----------------------------------------------------------------------------
+// Coin Cells
+
+// Coin cell sizes (diameter, thickness).
+var coin_cell_db = map[string]V2{
+	"CR2032": {20, 3.2},
+	"CR2025": {20, 2.5},
+	"CR2016": {20, 1.6},
+	"CR1632": {16, 3.2},
+	"CR1220": {12.5, 2.0},
+	"LR44":   {11.6, 5.4},
+}
+
+type CoinCellParms struct {
+	Cell      string  // cell name, E.g. "CR2032"
+	Depth     float64 // pocket depth (0 for the cell thickness)
+	Clearance float64 // radial clearance around the cell
+	Finger    float64 // diameter of the finger cutouts (0 for none)
+}
+
+// CoinCellPocket3D returns a cutter for a coin cell pocket.
+// The finger cutouts are on the x-axis.
+func CoinCellPocket3D(k *CoinCellParms) SDF3 {
+	size, ok := coin_cell_db[k.Cell]
+	if !ok {
+		panic("unknown coin cell type")
+	}
+	if k.Depth < 0 || k.Clearance < 0 || k.Finger < 0 {
+		panic("invalid coin cell pocket parameters")
+	}
+	depth := k.Depth
+	if depth == 0 {
+		depth = size.Y
+	}
+	r := 0.5*size.X + k.Clearance
+	// extend above the face for a clean cut
+	s := Cylinder3D(2.0*depth, r, 0)
+	if k.Finger > 0 {
+		finger := Cylinder3D(2.0*depth, 0.5*k.Finger, 0)
+		f0 := Transform3D(finger, Translate3d(V3{-r, 0, 0}))
+		f1 := Transform3D(finger, Translate3d(V3{r, 0, 0}))
+		s = Union3D(s, f0, f1)
+	}
+	return s
+}
+
+//-----------------------------------------------------------------------------
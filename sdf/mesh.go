@@ -0,0 +1,334 @@
+//-----------------------------------------------------------------------------
+/*
+
+Triangle Meshes
+
+An indexed triangle mesh for post-processing rendered output without external
+tools. E.g. load the upper and lower halves of a part, move them apart and
+save them as a single plated STL file.
+
+The vertices of the triangles are welded (vertices with the same float32
+coordinates are shared) so the mesh has a vertex normal for each vertex. The
+vertex normal is the area weighted average of the adjoining face normals.
+A mesh rendered from an SDF3 uses the SDF3 normal at each vertex, which is
+smooth across the faces.
+
+Triangle vertices are counter-clockwise when viewed from the outside.
+
+Merge concatenates meshes, the solids can overlap (E.g. parts on a plate).
+Union, Difference and Intersect are boolean operations on the solids. The
+meshes are voxelized (see Voxelize3D), the voxel grids are combined and the
+result is re-meshed with marching cubes, so the result is accurate to about
+the cell size and sharp edges are rounded off at that scale. Boolean meshes
+must be closed, the inside of a mesh is found by counting the surface
+crossings along a line parallel to the z axis. Where the SDFs of the parts
+are available a boolean on the SDFs is more accurate.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+//-----------------------------------------------------------------------------
+
+type Mesh struct {
+	Vertex []V3     // vertices
+	Index  [][3]int // triangle vertex indices
+	Normal []V3     // vertex normals
+}
+
+// NewMesh returns an indexed mesh for a set of triangles.
+func NewMesh(triangles []*Triangle3) *Mesh {
+	m := Mesh{}
+	index := make(map[[3]float32]int)
+	vertex := func(v V3) int {
+		k := [3]float32{float32(v.X), float32(v.Y), float32(v.Z)}
+		if i, ok := index[k]; ok {
+			return i
+		}
+		i := len(m.Vertex)
+		index[k] = i
+		m.Vertex = append(m.Vertex, v)
+		return i
+	}
+	for _, t := range triangles {
+		idx := [3]int{vertex(t.V[0]), vertex(t.V[1]), vertex(t.V[2])}
+		if idx[0] == idx[1] || idx[1] == idx[2] || idx[2] == idx[0] {
+			// degenerate triangle
+			continue
+		}
+		m.Index = append(m.Index, idx)
+	}
+	m.normals()
+	return &m
+}
+
+// Work out the vertex normals.
+func (m *Mesh) normals() {
+	m.Normal = make([]V3, len(m.Vertex))
+	for _, t := range m.Index {
+		v0 := m.Vertex[t[0]]
+		// the cross product length is twice the triangle area
+		n := m.Vertex[t[1]].Sub(v0).Cross(m.Vertex[t[2]].Sub(v0))
+		for _, i := range t {
+			m.Normal[i] = m.Normal[i].Add(n)
+		}
+	}
+	for i, n := range m.Normal {
+		if n.Length() > 0 {
+			m.Normal[i] = n.Normalize()
+		}
+	}
+}
+
+// RenderMesh renders an SDF3 as a triangle mesh (octree sampling).
+func RenderMesh(
+	s SDF3, //sdf3 to render
+	mesh_cells int, //number of cells on the longest axis. e.g 200
+) *Mesh {
+	resolution := s.BoundingBox().Size().MaxComponent() / float64(mesh_cells)
+	m := NewMesh(collect_triangles(s, resolution))
+	m.SetNormals(s, 1e-3*resolution)
+	return m
+}
+
+// SetNormals sets the vertex normals from the normals of an SDF3.
+// eps is the step for central differences if there is no analytic gradient.
+func (m *Mesh) SetNormals(s SDF3, eps float64) {
+	for i, v := range m.Vertex {
+		m.Normal[i] = Normal3D(s, v, eps)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// Triangles returns the triangles of a mesh.
+func (m *Mesh) Triangles() []*Triangle3 {
+	t := make([]*Triangle3, len(m.Index))
+	for i, idx := range m.Index {
+		t[i] = NewTriangle3(m.Vertex[idx[0]], m.Vertex[idx[1]], m.Vertex[idx[2]])
+	}
+	return t
+}
+
+// BoundingBox returns the bounding box of a mesh.
+func (m *Mesh) BoundingBox() Box3 {
+	if len(m.Vertex) == 0 {
+		return Box3{}
+	}
+	return Box3{V3Set(m.Vertex).Min(), V3Set(m.Vertex).Max()}
+}
+
+// Transform returns a mesh transformed by a matrix.
+func (m *Mesh) Transform(a M44) *Mesh {
+	t := Mesh{}
+	t.Vertex = make([]V3, len(m.Vertex))
+	for i, v := range m.Vertex {
+		t.Vertex[i] = a.MulPosition(v)
+	}
+	t.Index = make([][3]int, len(m.Index))
+	copy(t.Index, m.Index)
+	if a.Determinant() < 0 {
+		// a mirroring transform, keep the triangles counter-clockwise
+		for i := range t.Index {
+			t.Index[i][1], t.Index[i][2] = t.Index[i][2], t.Index[i][1]
+		}
+	}
+	t.normals()
+	return &t
+}
+
+// Merge returns a mesh with the triangles of this mesh and other meshes.
+func (m *Mesh) Merge(meshes ...*Mesh) *Mesh {
+	t := Mesh{}
+	for _, x := range append([]*Mesh{m}, meshes...) {
+		base := len(t.Vertex)
+		t.Vertex = append(t.Vertex, x.Vertex...)
+		t.Normal = append(t.Normal, x.Normal...)
+		for _, idx := range x.Index {
+			t.Index = append(t.Index, [3]int{idx[0] + base, idx[1] + base, idx[2] + base})
+		}
+	}
+	return &t
+}
+
+//-----------------------------------------------------------------------------
+// Booleans
+
+// Voxelize returns a narrow band voxel grid for a closed mesh.
+func (m *Mesh) Voxelize(cell float64) *VoxelSDF3 {
+	if cell <= 0 {
+		panic("invalid cell size")
+	}
+	v := new_voxels(cell, VOXEL_BAND*cell, m.BoundingBox())
+	// distance to the surface for the voxels within the band
+	dist := make(map[V3i]float64)
+	// blocks with voxels within the band
+	blocks := make(map[V3i]bool)
+	// crossings of the voxel columns (parallel to the z axis) with the surface
+	cross := make(map[[2]int][]float64)
+	// offset the columns so they miss the edges and vertices of a grid aligned mesh
+	off := V2{0.3183e-3, 0.2718e-3}.MulScalar(cell)
+	lattice := func(p V3, round func(float64) float64) V3i {
+		p = p.DivScalar(cell)
+		return V3i{int(round(p.X)), int(round(p.Y)), int(round(p.Z))}
+	}
+	for _, idx := range m.Index {
+		t := Triangle3{[3]V3{m.Vertex[idx[0]], m.Vertex[idx[1]], m.Vertex[idx[2]]}}
+		tmin := V3Set(t.V[:]).Min()
+		tmax := V3Set(t.V[:]).Max()
+		lo := lattice(tmin.SubScalar(v.band), math.Ceil)
+		hi := lattice(tmax.AddScalar(v.band), math.Floor)
+		for k := lo[2]; k <= hi[2]; k++ {
+			for j := lo[1]; j <= hi[1]; j++ {
+				for i := lo[0]; i <= hi[0]; i++ {
+					x := V3i{i, j, k}
+					p := x.ToV3().MulScalar(cell)
+					d := p.Sub(t.closest(p)).Length()
+					if d >= v.band {
+						continue
+					}
+					if d0, ok := dist[x]; !ok || d < d0 {
+						dist[x] = d
+					}
+					blocks[V3i{floor_div(i, VOXEL_BLOCK), floor_div(j, VOXEL_BLOCK), floor_div(k, VOXEL_BLOCK)}] = true
+				}
+			}
+		}
+		lo = lattice(tmin, math.Floor)
+		hi = lattice(tmax, math.Ceil)
+		for j := lo[1]; j <= hi[1]; j++ {
+			for i := lo[0]; i <= hi[0]; i++ {
+				q := V2{float64(i) * cell, float64(j) * cell}.Add(off)
+				if z, ok := t.z_crossing(q); ok {
+					c := [2]int{i, j}
+					cross[c] = append(cross[c], z)
+				}
+			}
+		}
+	}
+	for _, c := range cross {
+		sort.Float64s(c)
+	}
+	// a voxel is inside if the column above it crosses the surface an odd number of times
+	sign := func(x V3i) float64 {
+		c := cross[[2]int{x[0], x[1]}]
+		if (len(c)-sort.SearchFloat64s(c, float64(x[2])*cell))%2 == 1 {
+			return -1
+		}
+		return 1
+	}
+	v.build(
+		func(b V3i) (float64, bool) {
+			if blocks[b] {
+				return 0, false
+			}
+			// the block is all inside or all outside
+			c := V3i{b[0]*VOXEL_BLOCK + VOXEL_BLOCK/2, b[1]*VOXEL_BLOCK + VOXEL_BLOCK/2, b[2]*VOXEL_BLOCK + VOXEL_BLOCK/2}
+			return sign(c) * v.band, true
+		},
+		func(x V3i) float64 {
+			d, ok := dist[x]
+			if !ok {
+				d = v.band
+			}
+			return sign(x) * d
+		},
+	)
+	return v
+}
+
+// Return the mesh of a voxel grid.
+func remesh(v *VoxelSDF3) *Mesh {
+	return NewMesh(collect_triangles(v, v.Cell()))
+}
+
+// Union returns the boolean union of closed meshes.
+// The result is accurate to about the cell size.
+func (m *Mesh) Union(cell float64, meshes ...*Mesh) *Mesh {
+	v := m.Voxelize(cell)
+	for _, x := range meshes {
+		v = VoxelUnion3D(v, x.Voxelize(cell))
+	}
+	return remesh(v)
+}
+
+// Difference returns the boolean difference of closed meshes (m - x).
+// The result is accurate to about the cell size.
+func (m *Mesh) Difference(cell float64, x *Mesh) *Mesh {
+	return remesh(VoxelDifference3D(m.Voxelize(cell), x.Voxelize(cell)))
+}
+
+// Intersect returns the boolean intersection of closed meshes.
+// The result is accurate to about the cell size.
+func (m *Mesh) Intersect(cell float64, x *Mesh) *Mesh {
+	return remesh(VoxelIntersect3D(m.Voxelize(cell), x.Voxelize(cell)))
+}
+
+//-----------------------------------------------------------------------------
+// STL and 3MF
+
+// SaveSTL writes a mesh to an STL file.
+func (m *Mesh) SaveSTL(path string) error {
+	return SaveSTLWithMetadata(path, m.Triangles(), nil)
+}
+
+// SaveSTLWithMetadata writes a mesh to an STL file with the metadata in the header.
+func (m *Mesh) SaveSTLWithMetadata(path string, meta *Metadata) error {
+	return SaveSTLWithMetadata(path, m.Triangles(), meta)
+}
+
+// Save3MF writes a mesh to a 3MF file with the metadata in the model.
+func (m *Mesh) Save3MF(path string, meta *Metadata) error {
+	return Save3MF(path, m.Triangles(), meta)
+}
+
+// LoadSTL reads a mesh from a binary STL file.
+func LoadSTL(path string) (*Mesh, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewReader(f)
+	var hdr STLHeader
+	if err := binary.Read(buf, binary.LittleEndian, &hdr); err != nil {
+		return nil, err
+	}
+	// check the count before allocating the triangles
+	if 84+50*int64(hdr.Count) > fi.Size() {
+		return nil, fmt.Errorf("%s: short STL file (ascii STL is not supported)", path)
+	}
+	triangles := make([]*Triangle3, hdr.Count)
+	var d STLTriangle
+	for i := range triangles {
+		if err := binary.Read(buf, binary.LittleEndian, &d); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, fmt.Errorf("%s: short STL file (ascii STL is not supported)", path)
+			}
+			return nil, err
+		}
+		triangles[i] = NewTriangle3(
+			V3{float64(d.Vertex1[0]), float64(d.Vertex1[1]), float64(d.Vertex1[2])},
+			V3{float64(d.Vertex2[0]), float64(d.Vertex2[1]), float64(d.Vertex2[2])},
+			V3{float64(d.Vertex3[0]), float64(d.Vertex3[1]), float64(d.Vertex3[2])},
+		)
+	}
+	return NewMesh(triangles), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,169 @@
+//-----------------------------------------------------------------------------
+/*
+
+Metaballs
+
+Blobby shapes from a sum of field sources (E.g. hand grips, character blanks).
+Each source has a radius of influence (R) and a strength (s). The field at
+distance d from a source is:
+
+f(d) = s * (1 - (d/R)^2)^3 for d < R, else 0
+
+The surface is where the summed field equals the threshold. Sources can be
+points, line segments or planes. Line segments can be tapered, with a
+different radius of influence at each end. A -ve strength subtracts from the
+field.
+
+Planes have no bounded extent, they blend with the other sources but the
+solid is limited to the bounding box of the point and line sources.
+
+The distance field is bounded (the field difference divided by the maximum
+field gradient), it is not exact.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+
+const (
+	meta_point = iota
+	meta_line
+	meta_plane
+)
+
+type MetaSource struct {
+	kind     int
+	p0, p1   V3      // point, line segment, point on the plane
+	n        V3      // plane normal
+	radius   float64 // radius of influence
+	r1       float64 // radius of influence at p1 (tapered lines)
+	strength float64 // field strength
+}
+
+// MetaPoint returns a point field source.
+func MetaPoint(center V3, radius, strength float64) *MetaSource {
+	return &MetaSource{kind: meta_point, p0: center, radius: radius, strength: strength}
+}
+
+// MetaLine returns a line segment field source.
+func MetaLine(p0, p1 V3, radius, strength float64) *MetaSource {
+	return &MetaSource{kind: meta_line, p0: p0, p1: p1, radius: radius, r1: radius, strength: strength}
+}
+
+// MetaTaperedLine returns a line segment field source with a radius of
+// influence r0 at p0 and r1 at p1.
+func MetaTaperedLine(p0, p1 V3, r0, r1, strength float64) *MetaSource {
+	return &MetaSource{kind: meta_line, p0: p0, p1: p1, radius: r0, r1: r1, strength: strength}
+}
+
+// MetaPlane returns a plane field source.
+func MetaPlane(point, normal V3, radius, strength float64) *MetaSource {
+	return &MetaSource{kind: meta_plane, p0: point, n: normal.Normalize(), radius: radius, strength: strength}
+}
+
+// Return the distance from a point to the source and the radius of influence.
+func (m *MetaSource) distance(p V3) (float64, float64) {
+	switch m.kind {
+	case meta_line:
+		v := m.p1.Sub(m.p0)
+		t := Clamp(p.Sub(m.p0).Dot(v)/v.Length2(), 0, 1)
+		return p.Sub(m.p0.Add(v.MulScalar(t))).Length(), Mix(m.radius, m.r1, t)
+	case meta_plane:
+		return Abs(p.Sub(m.p0).Dot(m.n)), m.radius
+	}
+	return p.Sub(m.p0).Length(), m.radius
+}
+
+// Return the smallest and largest radius of influence.
+func (m *MetaSource) radii() (float64, float64) {
+	if m.kind == meta_line {
+		return Min(m.radius, m.r1), Max(m.radius, m.r1)
+	}
+	return m.radius, m.radius
+}
+
+// Return the field of the source at a point.
+func (m *MetaSource) field(p V3) float64 {
+	d, r := m.distance(p)
+	x := d / r
+	if x >= 1 {
+		return 0
+	}
+	k := 1.0 - x*x
+	return m.strength * k * k * k
+}
+
+//-----------------------------------------------------------------------------
+
+type MetaballsSDF3 struct {
+	sources   []*MetaSource
+	threshold float64
+	k         float64 // 1 / maximum field gradient
+	center    V3      // bounding box center
+	size      V3      // bounding box half size
+	bb        Box3
+}
+
+// Metaballs3D returns the blended surface of a set of field sources.
+func Metaballs3D(threshold float64, sources ...*MetaSource) SDF3 {
+	if threshold <= 0 {
+		panic("invalid threshold")
+	}
+	s := MetaballsSDF3{}
+	s.threshold = threshold
+	// the maximum slope of the field function is 6x(1-x^2)^2 at x = 1/sqrt(5)
+	slope := 96.0 / (25.0 * math.Sqrt(5))
+	g := 0.0
+	var bb *Box3
+	for _, m := range sources {
+		r_min, r_max := m.radii()
+		if r_min <= 0 {
+			panic("invalid radius of influence")
+		}
+		s.sources = append(s.sources, m)
+		g += slope * Abs(m.strength) / r_min
+		if m.kind == meta_plane || m.strength <= 0 {
+			continue
+		}
+		r := V3{r_max, r_max, r_max}
+		b := Box3{m.p0.Sub(r), m.p0.Add(r)}
+		if m.kind == meta_line {
+			b = b.Extend(Box3{m.p1.Sub(r), m.p1.Add(r)})
+		}
+		if bb == nil {
+			bb = &b
+		} else {
+			*bb = bb.Extend(b)
+		}
+	}
+	if bb == nil {
+		panic("metaballs need a +ve point or line source")
+	}
+	s.k = 1.0 / g
+	s.bb = *bb
+	s.center = bb.Center()
+	s.size = bb.Size().MulScalar(0.5)
+	return &s
+}
+
+// Return the minimum distance to the metaballs.
+func (s *MetaballsSDF3) Evaluate(p V3) float64 {
+	f := 0.0
+	for _, m := range s.sources {
+		f += m.field(p)
+	}
+	d := (s.threshold - f) * s.k
+	// limit the solid to the bounding box
+	return Max(d, sdf_box3d(p.Sub(s.center), s.size))
+}
+
+// Return the bounding box for the metaballs.
+func (s *MetaballsSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,117 @@
+//-----------------------------------------------------------------------------
+/*
+
+Model Metadata
+
+Record how a part was generated in the files exported for it. The metadata
+is the serialized (JSON) configuration of the model, a version string and a
+timestamp.
+
+STL: The 80 byte binary header holds a summary line (version, timestamp and
+a hash of the configuration) and as much of the configuration as will fit.
+The hash identifies the configuration when it is too long for the header.
+
+SVG: A comment (at the start of the file) holds the summary line and the
+configuration. Set SliceParms.Metadata for the SVG slices of SliceStack.
+
+3MF: The model part has metadata elements for the version, timestamp, summary
+line and configuration (see Save3MF).
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+//-----------------------------------------------------------------------------
+
+type Metadata struct {
+	Config  string    // serialized configuration (JSON)
+	Version string    // version of the generating program
+	Time    time.Time // generation time
+}
+
+// NewMetadata returns the metadata for a model configuration (E.g. a parameter
+// structure). The configuration is serialized as JSON.
+func NewMetadata(config interface{}, version string) (*Metadata, error) {
+	b, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Metadata{
+		Config:  string(b),
+		Version: version,
+		Time:    time.Now().UTC(),
+	}, nil
+}
+
+// Hash returns a short hash of the configuration.
+func (m *Metadata) Hash() string {
+	h := sha256.Sum256([]byte(m.Config))
+	return hex.EncodeToString(h[:8])
+}
+
+// Summary returns a single line summary of the metadata.
+func (m *Metadata) Summary() string {
+	return fmt.Sprintf("sdfx %s %s config:%s", m.Version, m.Time.Format(time.RFC3339), m.Hash())
+}
+
+// String returns the summary and configuration.
+func (m *Metadata) String() string {
+	return m.Summary() + "\n" + m.Config
+}
+
+//-----------------------------------------------------------------------------
+// STL
+
+// Return the STL header for the metadata.
+// Note: binary STL headers must not start with "solid".
+func (m *Metadata) stl_header() [80]uint8 {
+	var h [80]uint8
+	if m != nil {
+		copy(h[:], m.Summary()+" "+m.Config)
+	}
+	return h
+}
+
+// ReadSTLHeader returns the text in the header of a binary STL file.
+func ReadSTLHeader(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hdr := STLHeader{}
+	if err := binary.Read(f, binary.LittleEndian, &hdr); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(hdr.Text[:]), "\x00"), nil
+}
+
+//-----------------------------------------------------------------------------
+// SVG
+
+// Return the SVG comment for the metadata.
+// Note: "--" isn't allowed within an XML comment.
+func (m *Metadata) svg_comment() string {
+	if m == nil {
+		return ""
+	}
+	t := m.String()
+	for strings.Contains(t, "--") {
+		t = strings.ReplaceAll(t, "--", "- -")
+	}
+	return "<!-- " + t + " -->\n"
+}
+
+//-----------------------------------------------------------------------------
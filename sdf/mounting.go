@@ -0,0 +1,146 @@
+//-----------------------------------------------------------------------------
+/*
+
+Wall Mounting Features
+
+Cutters for hanging flat-backed parts (signs, racks, boxes) on a wall. The
+back face of the part is the z = 0 plane, the part is above it and y is up
+(use WallAnchor to place a feature on another face).
+
+Keyhole Slots: The screw head goes into the entry hole at the origin and the
+part drops down so the head slides along the slot behind a lip. The part
+hangs with the screw at the top of the slot, (0, length).
+
+Sawtooth Hangers: A pocket in the back with a row of notches along the top
+edge. A nail in the wall sits in one of the notches, so the part can be
+levelled by picking a notch. The notches are at y = height + tooth.
+
+Drywall Anchor Holes: Screw holes through the part sized for the screws that
+go with common drywall anchors, with an optional 90 degree countersink on the
+front face.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+//-----------------------------------------------------------------------------
+// Keyhole Slots
+
+type KeyholeParms struct {
+	Screw     V2      // screw head and shaft diameter
+	Length    float64 // slot length (0 for the head diameter)
+	Lip       float64 // thickness of the lip behind the screw head
+	Depth     float64 // depth of the keyhole into the back
+	Clearance float64 // clearance on the head and shaft
+}
+
+// KeyholeSlot3D returns a cutter for a keyhole slot.
+func KeyholeSlot3D(k *KeyholeParms) SDF3 {
+	if k.Screw.Y <= 0 || k.Screw.X <= k.Screw.Y || k.Clearance < 0 || k.Length < 0 {
+		panic("invalid keyhole parameters")
+	}
+	if k.Lip <= 0 || k.Depth <= k.Lip {
+		panic("invalid keyhole depth")
+	}
+	head := k.Screw.X + 2.0*k.Clearance
+	shaft := k.Screw.Y + 2.0*k.Clearance
+	length := k.Length
+	if length == 0 {
+		length = k.Screw.X
+	}
+	// entry hole and the shaft slot, through the lip
+	entry := Cylinder3D(2.0*k.Depth, 0.5*head, 0)
+	slot := Box3D(V3{shaft, length, 2.0 * k.Depth}, 0)
+	slot = Transform3D(slot, Translate3d(V3{0, 0.5 * length, 0}))
+	// the head channel behind the lip, rounded at the top
+	h := k.Depth - k.Lip
+	end := Transform3D(Cylinder3D(h, 0.5*head, 0), Translate3d(V3{0, 0.5 * length, 0}))
+	channel := Union3D(Box3D(V3{head, length, h}, 0), end)
+	channel = Transform3D(channel, Translate3d(V3{0, 0.5 * length, k.Lip + 0.5*h}))
+	return Union3D(entry, slot, channel)
+}
+
+//-----------------------------------------------------------------------------
+// Sawtooth Hangers
+
+type SawtoothParms struct {
+	Width  float64 // pocket width
+	Height float64 // pocket height (below the notches)
+	Depth  float64 // pocket depth into the back
+	Teeth  int     // number of notches
+	Tooth  float64 // notch height
+}
+
+// SawtoothHanger3D returns a cutter for a sawtooth hanger pocket.
+// The pocket is centered on the y-axis with the bottom edge on the x-axis.
+func SawtoothHanger3D(k *SawtoothParms) SDF3 {
+	if k.Width <= 0 || k.Height <= 0 || k.Depth <= 0 || k.Tooth <= 0 {
+		panic("invalid sawtooth hanger size")
+	}
+	if k.Teeth < 1 {
+		panic("invalid number of teeth")
+	}
+	w := 0.5 * k.Width
+	pitch := k.Width / float64(k.Teeth)
+	// bottom edge, then the notches from right to left
+	profile := []V2{{-w, 0}, {w, 0}}
+	for i := 0; i <= k.Teeth; i++ {
+		x := w - float64(i)*pitch
+		profile = append(profile, V2{x, k.Height})
+		if i < k.Teeth {
+			profile = append(profile, V2{x - 0.5*pitch, k.Height + k.Tooth})
+		}
+	}
+	// extend below the back for a clean cut
+	return Extrude3D(Polygon2D(profile), 2.0*k.Depth)
+}
+
+//-----------------------------------------------------------------------------
+// Drywall Anchor Holes
+
+// Drywall anchor screw sizes (shaft, countersunk head diameter).
+var anchor_screw_db = map[string]V2{
+	"#6":  {3.5, 7.0},
+	"#8":  {4.2, 8.2},
+	"#10": {4.8, 9.5},
+	"M4":  {4.0, 8.0},
+	"M5":  {5.0, 10.0},
+}
+
+type DrywallHoleParms struct {
+	Screw       string  // anchor screw size, E.g. "#8", "M4"
+	Thickness   float64 // part thickness at the hole
+	Clearance   float64 // clearance on the screw shaft and head
+	Countersink bool    // countersink the head on the front face
+}
+
+// DrywallHole3D returns a cutter for a screw hole through a part.
+func DrywallHole3D(k *DrywallHoleParms) SDF3 {
+	size, ok := anchor_screw_db[k.Screw]
+	if !ok {
+		panic("unknown anchor screw size")
+	}
+	if k.Thickness <= 0 || k.Clearance < 0 {
+		panic("invalid drywall hole parameters")
+	}
+	r0 := 0.5*size.X + k.Clearance
+	r1 := 0.5*size.Y + k.Clearance
+	// extend past both faces for a clean cut
+	s := Cylinder3D(2.0*k.Thickness, r0, 0)
+	s = Transform3D(s, Translate3d(V3{0, 0, 0.5 * k.Thickness}))
+	if k.Countersink {
+		// 90 degrees, the head is flush with the front face
+		h := r1 - r0
+		if h >= k.Thickness {
+			panic("part is too thin for the countersink")
+		}
+		ext := 1.0
+		cs := Cone3D(h+ext, r0, r1+ext, 0)
+		cs = Transform3D(cs, Translate3d(V3{0, 0, k.Thickness - h + 0.5*(h+ext)}))
+		s = Union3D(s, cs)
+	}
+	return s
+}
+
+//-----------------------------------------------------------------------------
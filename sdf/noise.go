@@ -0,0 +1,132 @@
+//-----------------------------------------------------------------------------
+/*
+
+Procedural Noise
+
+Gradient noise (Ken Perlin's improved noise) for organic textures and
+controlled surface roughness. Use with Displace3D.
+
+The noise is 0 at the integer lattice points and is about [-1,1] between them.
+Fractal noise sums octaves of the noise at doubling frequencies and halving
+amplitudes.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"math/rand"
+)
+
+//-----------------------------------------------------------------------------
+
+type Noise struct {
+	perm [512]int
+}
+
+// NewNoise returns a noise generator. The seed selects the noise pattern.
+func NewNoise(seed int64) *Noise {
+	n := Noise{}
+	p := rand.New(rand.NewSource(seed)).Perm(256)
+	for i := range n.perm {
+		n.perm[i] = p[i&255]
+	}
+	return &n
+}
+
+// quintic fade curve
+func noise_fade(t float64) float64 {
+	return t * t * t * (t*(t*6.0-15.0) + 10.0)
+}
+
+// dot product of the position with one of 12 cube edge gradients
+func noise_grad(hash int, x, y, z float64) float64 {
+	h := hash & 15
+	u := y
+	if h < 8 {
+		u = x
+	}
+	v := z
+	if h < 4 {
+		v = y
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
+
+// Perlin returns the gradient noise at a point.
+func (n *Noise) Perlin(p V3) float64 {
+	fx := math.Floor(p.X)
+	fy := math.Floor(p.Y)
+	fz := math.Floor(p.Z)
+	xi := int(fx) & 255
+	yi := int(fy) & 255
+	zi := int(fz) & 255
+	x := p.X - fx
+	y := p.Y - fy
+	z := p.Z - fz
+	u := noise_fade(x)
+	v := noise_fade(y)
+	w := noise_fade(z)
+
+	// hash the cube corners
+	pm := &n.perm
+	a := pm[xi] + yi
+	aa := pm[a] + zi
+	ab := pm[a+1] + zi
+	b := pm[xi+1] + yi
+	ba := pm[b] + zi
+	bb := pm[b+1] + zi
+
+	// blend the corner gradients
+	x0 := Mix(noise_grad(pm[aa], x, y, z), noise_grad(pm[ba], x-1, y, z), u)
+	x1 := Mix(noise_grad(pm[ab], x, y-1, z), noise_grad(pm[bb], x-1, y-1, z), u)
+	x2 := Mix(noise_grad(pm[aa+1], x, y, z-1), noise_grad(pm[ba+1], x-1, y, z-1), u)
+	x3 := Mix(noise_grad(pm[ab+1], x, y-1, z-1), noise_grad(pm[bb+1], x-1, y-1, z-1), u)
+	return Mix(Mix(x0, x1, v), Mix(x2, x3, v), w)
+}
+
+// Fractal returns fractal (fBm) noise at a point.
+// The result is normalized to about [-1,1].
+func (n *Noise) Fractal(p V3, octaves int) float64 {
+	if octaves < 1 {
+		panic("invalid octaves")
+	}
+	sum := 0.0
+	amplitude := 1.0
+	total := 0.0
+	for i := 0; i < octaves; i++ {
+		sum += amplitude * n.Perlin(p)
+		total += amplitude
+		p = p.MulScalar(2.0)
+		amplitude *= 0.5
+	}
+	return sum / total
+}
+
+//-----------------------------------------------------------------------------
+
+// NoiseDisplacement returns a displacement function for Displace3D.
+// scale is the feature size, amplitude is the maximum displacement.
+// The displacement gradient is roughly 2 * amplitude / scale.
+func NoiseDisplacement(seed int64, scale, amplitude float64, octaves int) DisplaceFunc {
+	if scale <= 0 {
+		panic("invalid noise scale")
+	}
+	n := NewNoise(seed)
+	k := 1.0 / scale
+	return func(p V3) float64 {
+		return amplitude * n.Fractal(p.MulScalar(k), octaves)
+	}
+}
+
+//-----------------------------------------------------------------------------
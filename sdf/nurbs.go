@@ -0,0 +1,318 @@
+//-----------------------------------------------------------------------------
+/*
+
+NURBS Curves
+
+Non-Uniform Rational B-Splines. Unlike cubic splines these can exactly
+represent conic sections (circles, ellipses, etc.).
+
+The curve is evaluated with the de Boor algorithm using homogeneous control
+points (w*x, w*y, w). The derivative is evaluated using the derivative
+control points of the homogeneous curve.
+
+Closed curves have an inside and an outside. The sign of the distance is
+found from the side of the curve tangent at the closest point.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+
+type NURBS struct {
+	degree int       // curve degree
+	knot   []float64 // knot vector
+	h      []V3      // homogeneous control points
+	dknot  []float64 // knot vector for the derivative curve
+	dh     []V3      // homogeneous control points for the derivative curve
+}
+
+// Evaluate a B-spline curve using the de Boor algorithm.
+// knot[k] <= t < knot[k+1], p is the degree.
+func deboor(k, p int, t float64, knot []float64, ctrl []V3) V3 {
+	d := make([]V3, p+1)
+	for j := 0; j <= p; j++ {
+		d[j] = ctrl[j+k-p]
+	}
+	for r := 1; r <= p; r++ {
+		for j := p; j >= r; j-- {
+			alpha := (t - knot[j+k-p]) / (knot[j+1+k-r] - knot[j+k-p])
+			d[j] = d[j-1].MulScalar(1 - alpha).Add(d[j].MulScalar(alpha))
+		}
+	}
+	return d[p]
+}
+
+// Return the curve value at t (knot[k] <= t <= knot[k+1]).
+func (c *NURBS) f0(k int, t float64) V2 {
+	h := deboor(k, c.degree, t, c.knot, c.h)
+	return V2{h.X / h.Z, h.Y / h.Z}
+}
+
+// Return the 1st derivative of the curve at t (knot[k] <= t <= knot[k+1]).
+func (c *NURBS) f1(k int, t float64) V2 {
+	h := deboor(k, c.degree, t, c.knot, c.h)
+	dh := deboor(k-1, c.degree-1, t, c.dknot, c.dh)
+	p := V2{h.X / h.Z, h.Y / h.Z}
+	return V2{dh.X, dh.Y}.Sub(p.MulScalar(dh.Z)).DivScalar(h.Z)
+}
+
+// Return the knot span index for t.
+func (c *NURBS) span(t float64) int {
+	n := len(c.h) - 1
+	if t >= c.knot[n+1] {
+		// use the last non-empty span
+		k := n
+		for c.knot[k] == c.knot[k+1] {
+			k--
+		}
+		return k
+	}
+	k := c.degree
+	for t >= c.knot[k+1] {
+		k++
+	}
+	return k
+}
+
+// Evaluate returns the curve value at t.
+func (c *NURBS) Evaluate(t float64) V2 {
+	return c.f0(c.span(t), t)
+}
+
+// Domain returns the parameter range of the curve.
+func (c *NURBS) Domain() (float64, float64) {
+	return c.knot[c.degree], c.knot[len(c.h)]
+}
+
+// NewNURBS returns a NURBS curve.
+// Weights default to 1 (a non-rational B-spline) if nil.
+// The knot vector defaults to a clamped uniform vector if nil.
+func NewNURBS(
+	degree int, // curve degree
+	ctrl []V2, // control points
+	weight []float64, // control point weights
+	knot []float64, // knot vector
+) *NURBS {
+	n := len(ctrl)
+	if degree < 1 {
+		panic("invalid nurbs degree")
+	}
+	if n <= degree {
+		panic("not enough nurbs control points")
+	}
+	if weight == nil {
+		weight = make([]float64, n)
+		for i := range weight {
+			weight[i] = 1
+		}
+	}
+	if len(weight) != n {
+		panic("nurbs weights must match control points")
+	}
+	if knot == nil {
+		// clamped uniform knot vector
+		knot = make([]float64, n+degree+1)
+		m := n - degree
+		for i := range knot {
+			knot[i] = Clamp(float64(i-degree)/float64(m), 0, 1)
+		}
+	}
+	if len(knot) != n+degree+1 {
+		panic("bad nurbs knot vector length")
+	}
+	for i := 1; i < len(knot); i++ {
+		if knot[i] < knot[i-1] {
+			panic("nurbs knot vector must be non-decreasing")
+		}
+	}
+	if knot[degree] >= knot[n] {
+		panic("empty nurbs parameter range")
+	}
+
+	c := NURBS{}
+	c.degree = degree
+	c.knot = knot
+	c.h = make([]V3, n)
+	for i, v := range ctrl {
+		w := weight[i]
+		if w <= 0 {
+			panic("nurbs weights must be > 0")
+		}
+		c.h[i] = V3{v.X * w, v.Y * w, w}
+	}
+	// derivative control points
+	c.dknot = knot[1 : len(knot)-1]
+	c.dh = make([]V3, n-1)
+	for i := range c.dh {
+		dk := knot[i+degree+1] - knot[i+1]
+		if dk != 0 {
+			c.dh[i] = c.h[i+1].Sub(c.h[i]).MulScalar(float64(degree) / dk)
+		}
+	}
+	return &c
+}
+
+//-----------------------------------------------------------------------------
+
+// non-empty knot span of a NURBS curve
+type nurbs_span struct {
+	k      int     // knot index
+	t0, t1 float64 // parameter range
+	p0     V2      // curve value at t0
+	bb     Box2    // bounding box (of the span control points)
+}
+
+// A knot span of a NURBS curve as a Curve2.
+type nurbs_curve struct {
+	c *NURBS
+	s *nurbs_span
+}
+
+func (n nurbs_curve) f0(t float64) V2 {
+	return n.c.f0(n.s.k, t)
+}
+
+func (n nurbs_curve) f1(t float64) V2 {
+	return n.c.f1(n.s.k, t)
+}
+
+func (n nurbs_curve) domain() (float64, float64) {
+	return n.s.t0, n.s.t1
+}
+
+// The span control points bound the curve on any sub-interval of the span.
+func (n nurbs_curve) bound(t0, t1 float64) Box2 {
+	return n.s.bb
+}
+
+// Return the minimum distance squared between a point and a span of the curve,
+// and the t value at which it occurs.
+func (c *NURBS) min_distance2(s *nurbs_span, p V2) (float64, float64) {
+	return CurveMinDistance2(nurbs_curve{c, s}, p)
+}
+
+//-----------------------------------------------------------------------------
+
+type NURBSSDF2 struct {
+	curve  *NURBS       // nurbs curve
+	span   []nurbs_span // non-empty knot spans
+	closed bool         // is the curve closed?
+	ccw    bool         // is a closed curve counter-clockwise?
+	bb     Box2         // bounding box
+}
+
+// NURBS2D returns an SDF2 for a NURBS curve.
+// If the curve is closed the SDF is -ve on the inside.
+func NURBS2D(
+	degree int, // curve degree
+	ctrl []V2, // control points
+	weight []float64, // control point weights (nil for all 1)
+	knot []float64, // knot vector (nil for clamped uniform)
+) SDF2 {
+	s := NURBSSDF2{}
+	s.curve = NewNURBS(degree, ctrl, weight, knot)
+	c := s.curve
+	p := c.degree
+	for k := p; k < len(c.h); k++ {
+		if c.knot[k] == c.knot[k+1] {
+			continue
+		}
+		// the curve lies within the convex hull of the span control points
+		bb := Box2{ctrl[k-p], ctrl[k-p]}
+		for _, v := range ctrl[k-p+1 : k+1] {
+			bb = bb.Extend(Box2{v, v})
+		}
+		t0 := c.knot[k]
+		s.span = append(s.span, nurbs_span{k, t0, c.knot[k+1], c.f0(k, t0), bb})
+	}
+	// work out the overall bounding box
+	s.bb = s.span[0].bb
+	for i := 1; i < len(s.span); i++ {
+		s.bb = s.bb.Extend(s.span[i].bb)
+	}
+	// closed curves have an inside and an outside
+	t0, t1 := c.Domain()
+	s.closed = c.Evaluate(t0).Equals(c.Evaluate(t1), TOLERANCE)
+	if s.closed {
+		// work out the orientation from the signed area
+		v := s.Polygonize(len(s.span)*SPLINE_SAMPLES + 1).Vertices()
+		area := 0.0
+		for i := 0; i < len(v)-1; i++ {
+			area += v[i].Cross(v[i+1])
+		}
+		s.ccw = area > 0
+	}
+	return &s
+}
+
+// Return true if a point is inside a closed curve.
+// The closest point on the curve is at t on the i-th span.
+func (s *NURBSSDF2) inside(i int, t float64, p V2) bool {
+	c := s.curve
+	sp := &s.span[i]
+	n := len(s.span)
+	// At a span joint the curve may have a corner, so use the sum of
+	// the tangents on each side of the joint.
+	tangent := c.f1(sp.k, t).Normalize()
+	if t == sp.t0 {
+		prev := &s.span[(i+n-1)%n]
+		tangent = tangent.Add(c.f1(prev.k, prev.t1).Normalize())
+	} else if t == sp.t1 {
+		next := &s.span[(i+1)%n]
+		tangent = tangent.Add(c.f1(next.k, next.t0).Normalize())
+	}
+	// the inside is on the left of a counter-clockwise curve
+	left := tangent.Cross(p.Sub(c.f0(sp.k, t))) > 0
+	return left == s.ccw
+}
+
+// Evaluate returns the minimum distance to the NURBS curve.
+func (s *NURBSSDF2) Evaluate(p V2) float64 {
+	// The span boxes are not tight, so use the span start points
+	// (rather than the box) for the upper bound on the distance.
+	vs := make([]float64, len(s.span))
+	dmax := math.MaxFloat64
+	for i := range s.span {
+		vs[i] = s.span[i].bb.MinMaxDist2(p).X
+		// the closest span is no further away than this
+		dmax = Min(dmax, s.span[i].p0.Sub(p).Length2())
+	}
+	dmin, imin, tmin := math.MaxFloat64, 0, 0.0
+	for i := range s.span {
+		// skip spans that can't be the closest
+		if vs[i] > dmax || vs[i] > dmin {
+			continue
+		}
+		if d, t := s.curve.min_distance2(&s.span[i], p); d < dmin {
+			dmin, imin, tmin = d, i, t
+		}
+	}
+	d := math.Sqrt(dmin)
+	if s.closed && s.inside(imin, tmin, p) {
+		// p is inside the closed curve
+		return -d
+	}
+	return d
+}
+
+// BoundingBox returns the bounding box of the NURBS curve.
+func (s *NURBSSDF2) BoundingBox() Box2 {
+	return s.bb
+}
+
+// Polygonize returns a polygon approximating the NURBS curve.
+func (s *NURBSSDF2) Polygonize(n int) *Polygon {
+	p := NewPolygon()
+	t0, t1 := s.curve.Domain()
+	dt := (t1 - t0) / float64(n-1)
+	for i := 0; i < n; i++ {
+		p.AddV2(s.curve.Evaluate(t0 + float64(i)*dt))
+	}
+	return p
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,184 @@
+//-----------------------------------------------------------------------------
+/*
+
+OpenCL Kernels
+
+Compile an SDF3 into the source of an OpenCL kernel. The kernel evaluates the
+SDF3 at a batch of points, one work item per point:
+
+	__kernel void sdf(__global const float *p, __global float *out, const int n)
+
+p holds the x, y, z of each point and out is the distance at each point. Each
+node of the CSG tree becomes a few lines of straight-line code, the kernel has
+no branches other than the bounds check.
+
+The same nodes as the CPU backend are supported (box, sphere, cylinder,
+transforms, scaling, union, difference, intersection, offset and shell), but
+not the blended booleans (the blend is a Go function). The kernel works in
+single precision, so the distances are within float precision of Evaluate.
+
+The kernel source doesn't need a GPU, the OpenCL backend that builds and runs
+it is in the sdf/opencl package (build with -tags opencl).
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//-----------------------------------------------------------------------------
+
+// the name of the kernel function
+const OPENCL_KERNEL = "sdf"
+
+// helper functions for the kernel
+const opencl_prelude = `float sdf_box3d(float3 p, float3 s) {
+	float3 d = fabs(p) - s;
+	return length(max(d, (float3)(0.0f))) + min(max(d.x, max(d.y, d.z)), 0.0f);
+}
+
+float sdf_box2d(float2 p, float2 s) {
+	float2 d = fabs(p) - s;
+	return length(max(d, (float2)(0.0f))) + min(max(d.x, d.y), 0.0f);
+}
+
+`
+
+// OpenCLSource returns the OpenCL source of a kernel that evaluates an SDF3.
+func OpenCLSource(s SDF3) (string, error) {
+	g := opencl_gen{}
+	d, err := g.node(s, "v0")
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString(opencl_prelude)
+	fmt.Fprintf(&b, "__kernel void %s(__global const float *p, __global float *out, const int n) {\n", OPENCL_KERNEL)
+	b.WriteString("\tint i = get_global_id(0);\n")
+	b.WriteString("\tif (i >= n) {\n\t\treturn;\n\t}\n")
+	b.WriteString("\tfloat3 v0 = vload3(i, p);\n")
+	b.WriteString(g.body.String())
+	fmt.Fprintf(&b, "\tout[i] = %s;\n}\n", d)
+	return b.String(), nil
+}
+
+//-----------------------------------------------------------------------------
+
+// opencl_gen generates the kernel body a node at a time.
+type opencl_gen struct {
+	body strings.Builder
+	n    int // number of variables
+}
+
+// Add a variable to the kernel body and return its name.
+func (g *opencl_gen) variable(typ, format string, args ...interface{}) string {
+	g.n++
+	v := fmt.Sprintf("v%d", g.n)
+	fmt.Fprintf(&g.body, "\t%s %s = %s;\n", typ, v, fmt.Sprintf(format, args...))
+	return v
+}
+
+// Return an OpenCL float literal.
+func cl_float(x float64) string {
+	return "(" + strconv.FormatFloat(x, 'e', -1, 32) + "f)"
+}
+
+// Return an OpenCL float3 literal.
+func cl_float3(v V3) string {
+	return fmt.Sprintf("(float3)(%s, %s, %s)", cl_float(v.X), cl_float(v.Y), cl_float(v.Z))
+}
+
+// Generate the code for a node evaluated at point p, return the distance variable.
+func (g *opencl_gen) node(s SDF3, p string) (string, error) {
+	switch s := s.(type) {
+	case *BoxSDF3:
+		return g.variable("float", "sdf_box3d(%s, %s) - %s", p, cl_float3(s.size), cl_float(s.round)), nil
+	case *SphereSDF3:
+		return g.variable("float", "length(%s) - %s", p, cl_float(s.radius)), nil
+	case *CylinderSDF3:
+		return g.variable("float", "sdf_box2d((float2)(length(%s.xy), %s.z), (float2)(%s, %s)) - %s",
+			p, p, cl_float(s.radius), cl_float(s.height), cl_float(s.round)), nil
+	case *TransformSDF3:
+		m := s.inverse
+		q := g.variable("float3", "(float3)(dot(%s, %s) + %s, dot(%s, %s) + %s, dot(%s, %s) + %s)",
+			p, cl_float3(V3{m.x00, m.x01, m.x02}), cl_float(m.x03),
+			p, cl_float3(V3{m.x10, m.x11, m.x12}), cl_float(m.x13),
+			p, cl_float3(V3{m.x20, m.x21, m.x22}), cl_float(m.x23))
+		return g.node(s.sdf, q)
+	case *ScaleUniformSDF3:
+		d, err := g.node(s.sdf, g.variable("float3", "%s * %s", p, cl_float(s.inv_k)))
+		if err != nil {
+			return "", err
+		}
+		return g.variable("float", "%s * %s", d, cl_float(s.k)), nil
+	case *ScaleNonUniformSDF3:
+		d, err := g.node(s.sdf, g.variable("float3", "%s * %s", p, cl_float3(s.inv_k)))
+		if err != nil {
+			return "", err
+		}
+		return g.variable("float", "%s * %s", d, cl_float(s.k_min)), nil
+	case *UnionSDF3:
+		if s.blended {
+			break
+		}
+		d, err := g.node(s.sdf[0], p)
+		if err != nil {
+			return "", err
+		}
+		for _, x := range s.sdf[1:] {
+			dx, err := g.node(x, p)
+			if err != nil {
+				return "", err
+			}
+			d = g.variable("float", "min(%s, %s)", d, dx)
+		}
+		return d, nil
+	case *DifferenceSDF3:
+		if s.blended {
+			break
+		}
+		return g.pair(s.s0, s.s1, p, "max(%s, -%s)")
+	case *IntersectionSDF3:
+		if s.blended {
+			break
+		}
+		return g.pair(s.s0, s.s1, p, "max(%s, %s)")
+	case *OffsetSDF3:
+		d, err := g.node(s.sdf, p)
+		if err != nil {
+			return "", err
+		}
+		return g.variable("float", "%s - %s", d, cl_float(s.offset)), nil
+	case *ShellSDF3:
+		d, err := g.node(s.sdf, p)
+		if err != nil {
+			return "", err
+		}
+		return g.variable("float", "fabs(%s) - %s", d, cl_float(s.delta)), nil
+	case *refined_sdf3:
+		return g.node(s.SDF3, p)
+	case *refined_interval_sdf3:
+		return g.node(s.SDF3, p)
+	}
+	return "", fmt.Errorf("opencl: unsupported node %T", s)
+}
+
+// Generate the code for a node that combines the distances of two nodes.
+func (g *opencl_gen) pair(s0, s1 SDF3, p, format string) (string, error) {
+	d0, err := g.node(s0, p)
+	if err != nil {
+		return "", err
+	}
+	d1, err := g.node(s1, p)
+	if err != nil {
+		return "", err
+	}
+	return g.variable("float", format, d0, d1), nil
+}
+
+//-----------------------------------------------------------------------------
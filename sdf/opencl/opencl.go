@@ -0,0 +1,249 @@
+//go:build opencl
+// +build opencl
+
+//-----------------------------------------------------------------------------
+/*
+
+OpenCL Backend
+
+Evaluate SDF3 samples on a GPU. The CSG tree is compiled into an OpenCL
+kernel (see sdf.OpenCLSource) and each batch of the renderer is evaluated by
+the kernel, one work item per point.
+
+This package needs cgo and the OpenCL headers and library, so it is only
+built with the opencl build tag:
+
+	go build -tags opencl
+
+Install the backend if there is a GPU, otherwise the renderer stays on the
+CPU:
+
+	if b, err := opencl.NewBackend(); err == nil {
+		sdf.SetBackend(b)
+	}
+
+An SDF3 the kernel can't express (E.g. a blended union or a primitive without
+a kernel) fails to compile and the renderer falls back to the CPU. A batch
+that fails on the device is evaluated on the CPU.
+
+*/
+//-----------------------------------------------------------------------------
+
+package opencl
+
+/*
+#cgo darwin LDFLAGS: -framework OpenCL
+#cgo !darwin LDFLAGS: -lOpenCL
+#define CL_TARGET_OPENCL_VERSION 120
+#ifdef __APPLE__
+#include <OpenCL/opencl.h>
+#else
+#include <CL/cl.h>
+#endif
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// Backend evaluates SDF3 batches on an OpenCL device.
+type Backend struct {
+	device C.cl_device_id
+	ctx    C.cl_context
+	queue  C.cl_command_queue
+	name   string
+	lock   sync.Mutex // the queue is shared by the kernels
+}
+
+// Return an error for an OpenCL status code.
+func cl_error(what string, status C.cl_int) error {
+	return fmt.Errorf("opencl: %s failed (%d)", what, int(status))
+}
+
+// NewBackend returns a backend for the first GPU of the first platform
+// that has one.
+func NewBackend() (*Backend, error) {
+	var n C.cl_uint
+	if status := C.clGetPlatformIDs(0, nil, &n); status != C.CL_SUCCESS || n == 0 {
+		return nil, fmt.Errorf("opencl: no platforms")
+	}
+	platforms := make([]C.cl_platform_id, n)
+	if status := C.clGetPlatformIDs(n, &platforms[0], nil); status != C.CL_SUCCESS {
+		return nil, cl_error("clGetPlatformIDs", status)
+	}
+	b := Backend{}
+	for _, p := range platforms {
+		if C.clGetDeviceIDs(p, C.CL_DEVICE_TYPE_GPU, 1, &b.device, nil) == C.CL_SUCCESS {
+			break
+		}
+		b.device = nil
+	}
+	if b.device == nil {
+		return nil, fmt.Errorf("opencl: no gpu devices")
+	}
+	var status C.cl_int
+	b.ctx = C.clCreateContext(nil, 1, &b.device, nil, nil, &status)
+	if status != C.CL_SUCCESS {
+		return nil, cl_error("clCreateContext", status)
+	}
+	b.queue = C.clCreateCommandQueue(b.ctx, b.device, 0, &status)
+	if status != C.CL_SUCCESS {
+		C.clReleaseContext(b.ctx)
+		return nil, cl_error("clCreateCommandQueue", status)
+	}
+	// device name
+	var name [256]C.char
+	C.clGetDeviceInfo(b.device, C.CL_DEVICE_NAME, C.size_t(len(name)), unsafe.Pointer(&name[0]), nil)
+	b.name = "opencl " + C.GoString(&name[0])
+	return &b, nil
+}
+
+// Name returns the name of the backend (and device).
+func (b *Backend) Name() string {
+	return b.name
+}
+
+// Close releases the OpenCL context.
+func (b *Backend) Close() {
+	C.clReleaseCommandQueue(b.queue)
+	C.clReleaseContext(b.ctx)
+}
+
+//-----------------------------------------------------------------------------
+
+// kernel is a compiled SDF3 and its buffers.
+type kernel struct {
+	b       *Backend
+	program C.cl_program
+	kernel  C.cl_kernel
+	n       int      // size of the device buffers (points)
+	in, out C.cl_mem // device buffers
+	p, d    []float32
+}
+
+// Compile builds the kernel for an SDF3.
+func (b *Backend) Compile(s sdf.SDF3) (sdf.BatchFunc, error) {
+	src, err := sdf.OpenCLSource(s)
+	if err != nil {
+		return nil, err
+	}
+	k := &kernel{b: b}
+	csrc := C.CString(src)
+	defer C.free(unsafe.Pointer(csrc))
+	var status C.cl_int
+	k.program = C.clCreateProgramWithSource(b.ctx, 1, &csrc, nil, &status)
+	if status != C.CL_SUCCESS {
+		return nil, cl_error("clCreateProgramWithSource", status)
+	}
+	if status = C.clBuildProgram(k.program, 1, &b.device, nil, nil, nil); status != C.CL_SUCCESS {
+		err := fmt.Errorf("opencl: build failed: %s", k.build_log())
+		C.clReleaseProgram(k.program)
+		return nil, err
+	}
+	cname := C.CString(sdf.OPENCL_KERNEL)
+	defer C.free(unsafe.Pointer(cname))
+	k.kernel = C.clCreateKernel(k.program, cname, &status)
+	if status != C.CL_SUCCESS {
+		C.clReleaseProgram(k.program)
+		return nil, cl_error("clCreateKernel", status)
+	}
+	runtime.SetFinalizer(k, (*kernel).release)
+	return func(p []sdf.V3, out []float64) {
+		if err := k.run(p, out); err != nil {
+			// fall back to the CPU
+			for i := range p {
+				out[i] = s.Evaluate(p[i])
+			}
+		}
+	}, nil
+}
+
+// Return the build log of the program.
+func (k *kernel) build_log() string {
+	var n C.size_t
+	C.clGetProgramBuildInfo(k.program, k.b.device, C.CL_PROGRAM_BUILD_LOG, 0, nil, &n)
+	if n == 0 {
+		return ""
+	}
+	log := make([]byte, n)
+	C.clGetProgramBuildInfo(k.program, k.b.device, C.CL_PROGRAM_BUILD_LOG, n, unsafe.Pointer(&log[0]), nil)
+	return string(log[:n-1])
+}
+
+// Release the device buffers.
+func (k *kernel) release_buffers() {
+	if k.n != 0 {
+		C.clReleaseMemObject(k.in)
+		C.clReleaseMemObject(k.out)
+		k.n = 0
+	}
+}
+
+// Release the kernel.
+func (k *kernel) release() {
+	k.release_buffers()
+	C.clReleaseKernel(k.kernel)
+	C.clReleaseProgram(k.program)
+}
+
+// Evaluate a batch of points on the device.
+func (k *kernel) run(p []sdf.V3, out []float64) error {
+	n := len(p)
+	if n == 0 {
+		return nil
+	}
+	b := k.b
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	// the buffers are reused while they are large enough
+	if k.n < n {
+		k.release_buffers()
+		var status C.cl_int
+		k.in = C.clCreateBuffer(b.ctx, C.CL_MEM_READ_ONLY, C.size_t(12*n), nil, &status)
+		if status != C.CL_SUCCESS {
+			return cl_error("clCreateBuffer", status)
+		}
+		k.out = C.clCreateBuffer(b.ctx, C.CL_MEM_WRITE_ONLY, C.size_t(4*n), nil, &status)
+		if status != C.CL_SUCCESS {
+			C.clReleaseMemObject(k.in)
+			return cl_error("clCreateBuffer", status)
+		}
+		k.n = n
+		k.p = make([]float32, 3*n)
+		k.d = make([]float32, n)
+	}
+	for i, v := range p {
+		k.p[3*i] = float32(v.X)
+		k.p[3*i+1] = float32(v.Y)
+		k.p[3*i+2] = float32(v.Z)
+	}
+	if status := C.clEnqueueWriteBuffer(b.queue, k.in, C.CL_TRUE, 0, C.size_t(12*n), unsafe.Pointer(&k.p[0]), 0, nil, nil); status != C.CL_SUCCESS {
+		return cl_error("clEnqueueWriteBuffer", status)
+	}
+	cn := C.cl_int(n)
+	C.clSetKernelArg(k.kernel, 0, C.size_t(unsafe.Sizeof(k.in)), unsafe.Pointer(&k.in))
+	C.clSetKernelArg(k.kernel, 1, C.size_t(unsafe.Sizeof(k.out)), unsafe.Pointer(&k.out))
+	C.clSetKernelArg(k.kernel, 2, C.size_t(unsafe.Sizeof(cn)), unsafe.Pointer(&cn))
+	global := C.size_t(n)
+	if status := C.clEnqueueNDRangeKernel(b.queue, k.kernel, 1, nil, &global, nil, 0, nil, nil); status != C.CL_SUCCESS {
+		return cl_error("clEnqueueNDRangeKernel", status)
+	}
+	if status := C.clEnqueueReadBuffer(b.queue, k.out, C.CL_TRUE, 0, C.size_t(4*n), unsafe.Pointer(&k.d[0]), 0, nil, nil); status != C.CL_SUCCESS {
+		return cl_error("clEnqueueReadBuffer", status)
+	}
+	for i := range out[:n] {
+		out[i] = float64(k.d[i])
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,109 @@
+//-----------------------------------------------------------------------------
+/*
+
+Handed Parts
+
+A part is built from a body and features that are added to or subtracted
+from it. Some features are handed (E.g. threads, text). Mirroring the part
+should move these features to their mirrored position, but should not
+mirror the features themselves. A right hand thread should stay a right
+hand thread and text should stay readable.
+
+Handed features are defined at the origin and placed with a rigid
+transform. When the part is mirrored the feature is mirrored twice, once
+about its own YZ plane and then by the part mirror. The result is a
+rotation, so the feature keeps its handedness. Handed features should be
+symmetric about their own YZ plane apart from their handedness (E.g. a
+thread along the z-axis, or text centered on the origin).
+
+The mirror transforms preserve distance, so clearances and tolerances
+built into the features are unchanged.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+//-----------------------------------------------------------------------------
+
+type part_feature struct {
+	sdf    SDF3 // feature
+	m      M44  // feature placement
+	handed bool // is the feature handed?
+}
+
+type Part struct {
+	add []part_feature // features added to the part
+	sub []part_feature // features subtracted from the part
+}
+
+// NewPart returns an empty part.
+func NewPart() *Part {
+	return &Part{}
+}
+
+// Add adds a feature to the part.
+func (p *Part) Add(s SDF3) {
+	p.add = append(p.add, part_feature{s, Identity3d(), false})
+}
+
+// AddHanded adds a handed feature to the part, placed with the m transform.
+func (p *Part) AddHanded(s SDF3, m M44) {
+	p.add = append(p.add, part_feature{s, m, true})
+}
+
+// Sub subtracts a feature from the part.
+func (p *Part) Sub(s SDF3) {
+	p.sub = append(p.sub, part_feature{s, Identity3d(), false})
+}
+
+// SubHanded subtracts a handed feature from the part, placed with the m transform.
+func (p *Part) SubHanded(s SDF3, m M44) {
+	p.sub = append(p.sub, part_feature{s, m, true})
+}
+
+// Return the SDF3 for a list of part features.
+func part_features(features []part_feature) SDF3 {
+	s := make([]SDF3, len(features))
+	for i, f := range features {
+		s[i] = Transform3D(f.sdf, f.m)
+	}
+	return Union3D(s...)
+}
+
+// SDF3 returns the part as an SDF3.
+func (p *Part) SDF3() SDF3 {
+	s := part_features(p.add)
+	if s == nil {
+		panic("part has no added features")
+	}
+	if len(p.sub) == 0 {
+		return s
+	}
+	return Difference3D(s, part_features(p.sub))
+}
+
+// Return the mirrored features.
+func mirror_features(features []part_feature, m M44) []part_feature {
+	mirrored := make([]part_feature, len(features))
+	for i, f := range features {
+		if f.handed {
+			// mirror the feature about its own YZ plane, then by the part mirror
+			mirrored[i] = part_feature{f.sdf, m.Mul(f.m).Mul(MirrorYZ()), true}
+		} else {
+			mirrored[i] = part_feature{f.sdf, m.Mul(f.m), false}
+		}
+	}
+	return mirrored
+}
+
+// MirrorPart returns a mirrored part. m is a mirror transform (E.g. MirrorYZ()).
+// Handed features are placed at their mirrored position but keep their handedness.
+func MirrorPart(p *Part, m M44) *Part {
+	return &Part{
+		add: mirror_features(p.add, m),
+		sub: mirror_features(p.sub, m),
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,359 @@
+//-----------------------------------------------------------------------------
+/*
+
+Pipe Threads, Test Plugs and Pressure Caps
+
+Pipe threads are looked up by name (E.g. "npt_1/2", "bsp_1/2", "bspt_1/2", "ght_3/4").
+
+NPT (ASME B1.20.1): 60 degree thread with a 1:16 taper on the diameter.
+Dimensions are in inches and are derived from the pipe outside diameter (D)
+and threads per inch (n):
+
+pitch diameter at the small end: E0 = D - (0.05D + 1.1)/n
+effective thread length: L2 = (0.8D + 6.8)/n
+
+BSPP (ISO 228-1, "G" threads): 55 degree Whitworth thread, parallel.
+Dimensions are in mm. Parallel threads don't seal on the thread, so plugs
+and caps use an o-ring (or a flat gasket) as a face seal.
+
+BSPT (ISO 7-1, "R" threads): 55 degree Whitworth thread with a 1:16 taper on
+the diameter. The basic diameter is at the gauge plane, a gauge length from
+the small end. Dimensions are in mm.
+
+GHT (ANSI B1.20.7, 3/4-11.5 NH): Garden hose thread. 60 degree thread,
+parallel, sealed with a washer in the female coupling. Dimensions are in inches.
+
+Plugs have the underside of the head on the z = 0 plane with the thread
+running up from z = 0. Caps have the closed end on the z = 0 plane with the
+opening at the top.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+// Pipe Thread Database
+
+type PipeThread struct {
+	Name   string  // name of pipe thread
+	Radius float64 // major radius (at the small end for tapered threads)
+	Pitch  float64 // thread to thread distance
+	Taper  float64 // change of radius per unit length (0 for parallel threads)
+	Length float64 // effective thread length
+	Form   string  // thread form: "npt", "whitworth", "nh"
+	Units  string  // "inch" or "mm"
+}
+
+type PipeThreadDatabase map[string]*PipeThread
+
+var pipe_db = Init_PipeThreadLookup()
+
+// NPTAdd adds an NPT pipe thread to the database.
+func (m PipeThreadDatabase) NPTAdd(
+	name string, // thread name
+	od float64, // pipe outside diameter
+	tpi float64, // threads per inch
+) {
+	t := PipeThread{}
+	t.Name = name
+	t.Pitch = 1.0 / tpi
+	e0 := od - (0.05*od+1.1)*t.Pitch
+	// truncated 60 degree thread: 0.8p deep
+	t.Radius = 0.5*e0 + 0.4*t.Pitch
+	t.Taper = 1.0 / 32.0
+	t.Length = (0.8*od + 6.8) * t.Pitch
+	t.Form = "npt"
+	t.Units = "inch"
+	m[name] = &t
+}
+
+// BSPAdd adds a BSP parallel pipe thread to the database.
+func (m PipeThreadDatabase) BSPAdd(
+	name string, // thread name
+	diameter float64, // major diameter
+	tpi float64, // threads per inch
+	length float64, // useful thread length
+) {
+	t := PipeThread{}
+	t.Name = name
+	t.Radius = diameter / 2.0
+	t.Pitch = MM_PER_INCH / tpi
+	t.Length = length
+	t.Form = "whitworth"
+	t.Units = "mm"
+	m[name] = &t
+}
+
+// BSPTAdd adds a BSP tapered pipe thread to the database.
+func (m PipeThreadDatabase) BSPTAdd(
+	name string, // thread name
+	diameter float64, // major diameter at the gauge plane
+	tpi float64, // threads per inch
+	gauge float64, // gauge length
+	length float64, // useful thread length
+) {
+	t := PipeThread{}
+	t.Name = name
+	t.Taper = 1.0 / 32.0
+	t.Radius = diameter/2.0 - gauge*t.Taper
+	t.Pitch = MM_PER_INCH / tpi
+	t.Length = length
+	t.Form = "whitworth"
+	t.Units = "mm"
+	m[name] = &t
+}
+
+// GHTAdd adds a garden hose thread to the database.
+func (m PipeThreadDatabase) GHTAdd(
+	name string, // thread name
+	diameter float64, // major diameter
+	tpi float64, // threads per inch
+	length float64, // thread length
+) {
+	t := PipeThread{}
+	t.Name = name
+	t.Radius = diameter / 2.0
+	t.Pitch = 1.0 / tpi
+	t.Length = length
+	t.Form = "nh"
+	t.Units = "inch"
+	m[name] = &t
+}
+
+func Init_PipeThreadLookup() PipeThreadDatabase {
+	m := make(PipeThreadDatabase)
+	// NPT
+	m.NPTAdd("npt_1/16", 0.3125, 27)
+	m.NPTAdd("npt_1/8", 0.405, 27)
+	m.NPTAdd("npt_1/4", 0.540, 18)
+	m.NPTAdd("npt_3/8", 0.675, 18)
+	m.NPTAdd("npt_1/2", 0.840, 14)
+	m.NPTAdd("npt_3/4", 1.050, 14)
+	m.NPTAdd("npt_1", 1.315, 11.5)
+	m.NPTAdd("npt_1-1/4", 1.660, 11.5)
+	m.NPTAdd("npt_1-1/2", 1.900, 11.5)
+	m.NPTAdd("npt_2", 2.375, 11.5)
+	// BSPP (G)
+	m.BSPAdd("bsp_1/16", 7.723, 28, 6.5)
+	m.BSPAdd("bsp_1/8", 9.728, 28, 6.5)
+	m.BSPAdd("bsp_1/4", 13.157, 19, 9.7)
+	m.BSPAdd("bsp_3/8", 16.662, 19, 10.1)
+	m.BSPAdd("bsp_1/2", 20.955, 14, 13.2)
+	m.BSPAdd("bsp_3/4", 26.441, 14, 14.5)
+	m.BSPAdd("bsp_1", 33.249, 11, 16.8)
+	m.BSPAdd("bsp_1-1/4", 41.910, 11, 19.1)
+	m.BSPAdd("bsp_1-1/2", 47.803, 11, 19.1)
+	m.BSPAdd("bsp_2", 59.614, 11, 23.4)
+	// BSPT (R)
+	m.BSPTAdd("bspt_1/16", 7.723, 28, 4.0, 6.5)
+	m.BSPTAdd("bspt_1/8", 9.728, 28, 4.0, 6.5)
+	m.BSPTAdd("bspt_1/4", 13.157, 19, 6.0, 9.7)
+	m.BSPTAdd("bspt_3/8", 16.662, 19, 6.4, 10.1)
+	m.BSPTAdd("bspt_1/2", 20.955, 14, 8.2, 13.2)
+	m.BSPTAdd("bspt_3/4", 26.441, 14, 9.5, 14.5)
+	m.BSPTAdd("bspt_1", 33.249, 11, 10.4, 16.8)
+	m.BSPTAdd("bspt_1-1/4", 41.910, 11, 12.7, 19.1)
+	m.BSPTAdd("bspt_1-1/2", 47.803, 11, 12.7, 19.1)
+	m.BSPTAdd("bspt_2", 59.614, 11, 15.9, 23.4)
+	// GHT
+	m.GHTAdd("ght_3/4", 1.0625, 11.5, 0.5)
+	return m
+}
+
+// PipeThreadLookup returns the parameters for a pipe thread by name.
+func PipeThreadLookup(name string) *PipeThread {
+	t, ok := pipe_db[name]
+	if !ok {
+		panic("pipe thread name not found")
+	}
+	return t
+}
+
+// Depth returns the depth of the thread.
+func (t *PipeThread) Depth() float64 {
+	if t.Form == "whitworth" {
+		return 0.640327 * t.Pitch
+	}
+	return 0.8 * t.Pitch
+}
+
+// Return the 2d thread profile at a radius.
+func (t *PipeThread) profile(radius float64, internal bool) SDF2 {
+	switch t.Form {
+	case "npt", "nh":
+		if internal {
+			return ISOThread(radius, t.Pitch, "internal")
+		}
+		return ISOThread(radius, t.Pitch, "external")
+	case "whitworth":
+		return WhitworthThread(radius, t.Pitch)
+	}
+	panic("unknown pipe thread form")
+}
+
+// PipeThread3D returns a pipe thread from z = 0 to z = length.
+// External threads are the threaded pipe with the small end at z = length.
+// Internal threads are a cutter with the small end at z = 0, it extends
+// above z = length to give a clean cut.
+// The offset is added to the thread radius (+ve is larger).
+func PipeThread3D(t *PipeThread, internal bool, length, offset float64) SDF3 {
+	if length <= 0 {
+		panic("invalid thread length")
+	}
+	if !internal {
+		r := t.Radius + offset + 0.5*length*t.Taper
+		s := TaperScrew3D(t.profile(r, false), length, t.Pitch, t.Taper, 1)
+		return Transform3D(s, Translate3d(V3{0, 0, 0.5 * length}))
+	}
+	l := length + 2.0*t.Pitch
+	r := t.Radius + offset + 0.5*l*t.Taper
+	s := TaperScrew3D(t.profile(r, true), l, t.Pitch, -t.Taper, 1)
+	return Transform3D(s, Translate3d(V3{0, 0, 0.5 * l}))
+}
+
+//-----------------------------------------------------------------------------
+// O-Ring Grooves
+
+type ORingParms struct {
+	Section float64 // o-ring cross section diameter
+	Squeeze float64 // compression as a fraction of the section (E.g. 0.25)
+}
+
+// Groove returns the width and depth of a static face seal groove.
+// The width leaves room for the o-ring to spread when it is squeezed.
+func (k *ORingParms) Groove() (float64, float64) {
+	if k.Section <= 0 || k.Squeeze <= 0 || k.Squeeze >= 0.5 {
+		panic("invalid o-ring parameters")
+	}
+	return 1.35 * k.Section, (1.0 - k.Squeeze) * k.Section
+}
+
+// FaceGroove3D returns a cutter for a face seal groove in a face on the z = 0 plane.
+// The groove is below z = 0 with an inner radius r.
+func FaceGroove3D(k *ORingParms, r float64) SDF3 {
+	w, d := k.Groove()
+	groove := Transform2D(Box2D(V2{w, 2.0 * d}, 0), Translate2d(V2{r + 0.5*w, 0}))
+	return Revolve3D(groove)
+}
+
+//-----------------------------------------------------------------------------
+// Test Plugs
+
+type PipePlugParms struct {
+	Thread    string      // pipe thread name (E.g. "npt_1/2", "bsp_1/2")
+	Style     string      // head style: "hex", "square", "socket"
+	Length    float64     // thread length (0 for the standard length)
+	Tolerance float64     // radial clearance on the thread
+	ORing     *ORingParms // face seal under the head (nil for none)
+}
+
+// PipePlug3D returns a threaded plug for testing pipe fittings.
+func PipePlug3D(k *PipePlugParms) SDF3 {
+	t := PipeThreadLookup(k.Thread)
+	if k.Tolerance < 0 {
+		panic("invalid tolerance")
+	}
+	l := k.Length
+	if l == 0 {
+		l = t.Length
+	}
+	if l < 0 {
+		panic("invalid plug length")
+	}
+	// the large end of the thread is under the head
+	r := t.Radius + l*t.Taper
+	// the head covers the seal
+	seal := r
+	var groove SDF3
+	if k.ORing != nil {
+		w, _ := k.ORing.Groove()
+		groove = FaceGroove3D(k.ORing, r+0.1*k.ORing.Section)
+		seal = r + 0.1*k.ORing.Section + w
+	}
+	rh := Max(1.2*r, seal+0.1*r)
+	h := 0.6 * r
+
+	var head SDF3
+	switch k.Style {
+	case "hex":
+		head = HexHead3D(rh/math.Cos(DtoR(30)), h, "b")
+	case "square":
+		head = Box3D(V3{2.0 * rh, 2.0 * rh, h}, 0.05*h)
+	case "socket":
+		head = Cylinder3D(h, rh, 0.05*h)
+		head = Difference3D(head, Transform3D(hex_socket(r, 0.6*h), Translate3d(V3{0, 0, -0.5 * h})))
+	default:
+		panic("unknown plug head style")
+	}
+	head = Transform3D(head, Translate3d(V3{0, 0, -0.5 * h}))
+	if groove != nil {
+		head = Difference3D(head, groove)
+	}
+
+	thread := PipeThread3D(t, false, l, -k.Tolerance)
+	return Union3D(head, thread)
+}
+
+//-----------------------------------------------------------------------------
+// Pressure Caps
+
+type PipeCapParms struct {
+	Thread    string      // pipe thread name (E.g. "npt_1/2", "bsp_1/2")
+	Style     string      // outside style: "hex", "round", "knurl"
+	Length    float64     // thread length (0 for the standard length)
+	Wall      float64     // wall thickness around the thread and at the closed end
+	Tolerance float64     // radial clearance on the thread
+	ORing     *ORingParms // face seal against the end of the pipe (nil for none)
+}
+
+// PipeCap3D returns a threaded cap for closing off a pipe under pressure.
+func PipeCap3D(k *PipeCapParms) SDF3 {
+	t := PipeThreadLookup(k.Thread)
+	if k.Tolerance < 0 {
+		panic("invalid tolerance")
+	}
+	if k.Wall <= 0 {
+		panic("invalid cap wall")
+	}
+	l := k.Length
+	if l == 0 {
+		l = t.Length
+	}
+	if l < 0 {
+		panic("invalid cap length")
+	}
+	r := t.Radius + l*t.Taper + k.Tolerance
+	ro := r + k.Wall
+	h := l + k.Wall
+
+	var body SDF3
+	switch k.Style {
+	case "hex":
+		body = HexHead3D(ro/math.Cos(DtoR(30)), h, "t")
+	case "round":
+		body = Cylinder3D(h, ro, 0.1*k.Wall)
+	case "knurl":
+		body = KnurledHead3D(ro, h, ro*0.25)
+	default:
+		panic("unknown cap style")
+	}
+	body = Transform3D(body, Translate3d(V3{0, 0, 0.5 * h}))
+
+	cutters := []SDF3{PipeThread3D(t, true, l, k.Tolerance)}
+	if k.ORing != nil {
+		// the groove is under the end of the pipe wall
+		w, _ := k.ORing.Groove()
+		ri := t.Radius - t.Depth() - w
+		if ri <= 0 {
+			panic("o-ring is too large for the pipe")
+		}
+		cutters = append(cutters, FaceGroove3D(k.ORing, ri))
+	}
+	cutter := Transform3D(Union3D(cutters...), Translate3d(V3{0, 0, k.Wall}))
+	return Difference3D(body, cutter)
+}
+
+//-----------------------------------------------------------------------------
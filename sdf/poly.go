@@ -28,6 +28,7 @@ type PV struct {
 	vertex   V2      // vertex coordinates
 	facets   int     // number of polygon facets to create when smoothing
 	radius   float64 // radius of smoothing (0 == none)
+	ctrl     []V2    // bezier control points (before the vertex)
 }
 
 type PVType int
@@ -37,6 +38,7 @@ const (
 	HIDE                 // hide the line segment in rendering
 	SMOOTH               // smooth the vertex
 	ARC                  // replace the line segment with an arc
+	BEZIER               // replace the line segment with a bezier curve
 )
 
 //-----------------------------------------------------------------------------
@@ -185,6 +187,50 @@ func (p *Polygon) create_arcs() {
 	}
 }
 
+//-----------------------------------------------------------------------------
+// convert line segments to bezier curves
+
+// Replace a line segment with a bezier curve.
+func (p *Polygon) bezier_vertex(i int) bool {
+	// check the vertex
+	v := &p.vlist[i]
+	if v.vtype != BEZIER {
+		return false
+	}
+	// now it's a normal vertex
+	v.vtype = NORMAL
+	// check for the previous vertex
+	pv := p.prev_vertex(i)
+	if pv == nil {
+		return false
+	}
+	// the curve runs from the previous vertex, through the control points to this vertex
+	points := append([]V2{pv.vertex}, v.ctrl...)
+	points = append(points, v.vertex)
+	v.ctrl = nil
+	// adaptively sample the curve
+	b := Polygon{}
+	NewBezierSpline(points).Sample(&b, 0, 1, points[0], points[len(points)-1], 0)
+	// the first and last samples are the existing endpoints
+	vlist := b.vlist[1 : len(b.vlist)-1]
+	// insert the new vertices between the curve endpoints
+	p.vlist = append(p.vlist[:i], append(vlist, p.vlist[i:]...)...)
+	return true
+}
+
+// Convert polygon line segments to bezier curves.
+func (p *Polygon) create_beziers() {
+	done := false
+	for done == false {
+		done = true
+		for i := range p.vlist {
+			if p.bezier_vertex(i) {
+				done = false
+			}
+		}
+	}
+}
+
 //-----------------------------------------------------------------------------
 // vertex smoothing
 
@@ -262,6 +308,9 @@ func (p *Polygon) relative_to_absolute() {
 				panic("relative vertex needs an absolute reference")
 			}
 			v.vertex = v.vertex.Add(pv.vertex)
+			for j := range v.ctrl {
+				v.ctrl[j] = v.ctrl[j].Add(pv.vertex)
+			}
 			v.relative = false
 		}
 	}
@@ -272,6 +321,7 @@ func (p *Polygon) relative_to_absolute() {
 func (p *Polygon) fixups() {
 	p.relative_to_absolute()
 	p.create_arcs()
+	p.create_beziers()
 	p.smooth_vertices()
 }
 
@@ -314,6 +364,21 @@ func (p *Polygon) Add(x, y float64) *PV {
 	return p.AddV2(V2{x, y})
 }
 
+// AddBezier adds a bezier curve from the prior vertex to a polygon.
+// The final point is the curve endpoint, the others are control points.
+// 2 points give a quadratic curve, 3 points give a cubic curve.
+// The curve is flattened to line segments with adaptive sampling.
+func (p *Polygon) AddBezier(x ...V2) *PV {
+	if len(x) < 2 || len(x) > 4 {
+		panic("bad number of bezier points")
+	}
+	n := len(x) - 1
+	v := p.AddV2(x[n])
+	v.ctrl = append([]V2(nil), x[:n]...)
+	v.vtype = BEZIER
+	return v
+}
+
 // Drop the last vertex from the list.
 func (p *Polygon) Drop() {
 	p.vlist = p.vlist[:len(p.vlist)-1]
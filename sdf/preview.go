@@ -0,0 +1,216 @@
+//-----------------------------------------------------------------------------
+/*
+
+Live Preview
+
+A small web viewer for design iteration. The model is built by a function of
+named parameters, each parameter is shown as a slider. When a slider moves
+the model is rebuilt and raymarched to a shaded image, there is no mesh
+rendering, so a tweak takes a fraction of the time of an STL render.
+
+	p := NewPreview(func(v map[string]float64) SDF3 {
+		return Box3D(V3{v["width"], 10, 10}, v["round"])
+	})
+	p.Parameter("width", 10, 50, 20)
+	p.Parameter("round", 0, 4, 1)
+	log.Fatal(p.ListenAndServe(":8000"))
+
+Then browse to http://localhost:8000. The view can be orbited with the
+azimuth and elevation sliders.
+
+The image is an orthographic view (see RenderImage) that looks at the center
+of the bounding box, the light is at the camera. A build function that panics
+(E.g. on invalid parameters) gives an error message instead of an image.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"html/template"
+	"image/png"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+//-----------------------------------------------------------------------------
+
+// PreviewParm is a model parameter shown as a slider.
+type PreviewParm struct {
+	Name     string
+	Min, Max float64
+	Value    float64 // initial value
+}
+
+// Preview serves a live preview of a parametric model.
+type Preview struct {
+	build  func(v map[string]float64) SDF3
+	parms  []PreviewParm
+	pixels int // image size
+
+	mutex sync.Mutex
+	key   string // parameter values of the last model
+	sdf   SDF3   // last model
+}
+
+// NewPreview returns a preview for a model build function.
+func NewPreview(build func(v map[string]float64) SDF3) *Preview {
+	if build == nil {
+		panic("nil build function")
+	}
+	return &Preview{
+		build:  build,
+		pixels: 400,
+	}
+}
+
+// Parameter registers a model parameter.
+func (p *Preview) Parameter(name string, min, max, value float64) {
+	if min >= max || value < min || value > max {
+		panic("invalid parameter range")
+	}
+	for _, x := range p.parms {
+		if x.Name == name {
+			panic("duplicate parameter name")
+		}
+	}
+	p.parms = append(p.parms, PreviewParm{name, min, max, value})
+}
+
+// SetPixels sets the size of the preview image.
+func (p *Preview) SetPixels(pixels int) {
+	if pixels <= 0 {
+		panic("invalid image size")
+	}
+	p.pixels = pixels
+}
+
+//-----------------------------------------------------------------------------
+
+// Return the model for a set of parameter values.
+// The model is only rebuilt when the values change.
+func (p *Preview) model(v map[string]float64) (s SDF3, err error) {
+	key := fmt.Sprint(v)
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.sdf != nil && key == p.key {
+		return p.sdf, nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			s, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+	s = p.build(v)
+	if s == nil {
+		return nil, fmt.Errorf("no model")
+	}
+	p.key, p.sdf = key, s
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
+
+var preview_page = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html><head><title>sdfx preview</title></head>
+<body style="font-family: sans-serif">
+<div style="display: flex">
+<div><img id="view" width="{{.Pixels}}" height="{{.Pixels}}"><p id="error" style="color: red"></p></div>
+<form id="parms" style="margin-left: 1em">
+{{range .Parms}}<p>{{.Name}} <output>{{.Value}}</output><br>
+<input type="range" name="{{.Name}}" min="{{.Min}}" max="{{.Max}}" value="{{.Value}}" step="any"></p>
+{{end}}<p>azimuth <output>-60</output><br>
+<input type="range" name="_az" min="-180" max="180" value="-60" step="1"></p>
+<p>elevation <output>30</output><br>
+<input type="range" name="_el" min="-90" max="90" value="30" step="1"></p>
+</form>
+</div>
+<script>
+const form = document.getElementById("parms");
+const view = document.getElementById("view");
+const error = document.getElementById("error");
+let busy = false, dirty = false;
+function update() {
+	if (busy) { dirty = true; return; }
+	busy = true; dirty = false;
+	// one request: an image, or the error message
+	fetch("image?" + new URLSearchParams(new FormData(form)))
+		.then(r => r.ok ? r.blob().then(show) : r.text().then(t => { error.textContent = t; }))
+		.catch(e => { error.textContent = e; })
+		.finally(done);
+}
+function show(blob) {
+	if (view.src) URL.revokeObjectURL(view.src);
+	view.src = URL.createObjectURL(blob);
+	error.textContent = "";
+}
+function done() {
+	busy = false;
+	if (dirty) update();
+}
+form.oninput = function(e) {
+	e.target.previousElementSibling.previousElementSibling.textContent = Number(e.target.value).toPrecision(4);
+	update();
+};
+update();
+</script>
+</body></html>
+`))
+
+// ServeHTTP serves the preview page and images.
+func (p *Preview) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		err := preview_page.Execute(w, struct {
+			Pixels int
+			Parms  []PreviewParm
+		}{p.pixels, p.parms})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	case "/image":
+		p.serve_image(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Serve a preview image for the parameter values in the query.
+func (p *Preview) serve_image(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	// query value, clamped to a range
+	get := func(name string, min, max, value float64) float64 {
+		x, err := strconv.ParseFloat(q.Get(name), 64)
+		if err != nil {
+			return value
+		}
+		return Clamp(x, min, max)
+	}
+	v := make(map[string]float64)
+	for _, x := range p.parms {
+		v[x.Name] = get(x.Name, x.Min, x.Max, x.Value)
+	}
+	s, err := p.model(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	az := DtoR(get("_az", -180, 180, -60))
+	el := DtoR(get("_el", -90, 90, 30))
+	img := RenderImage(s, ViewCamera(s.BoundingBox(), az, el, 0, V2i{p.pixels, p.pixels}), nil)
+	w.Header().Set("Content-Type", "image/png")
+	if err := png.Encode(w, img); err != nil {
+		log.Printf("preview: %s", err)
+	}
+}
+
+// ListenAndServe serves the preview on an address.
+func (p *Preview) ListenAndServe(addr string) error {
+	return http.ListenAndServe(addr, p)
+}
+
+//-----------------------------------------------------------------------------
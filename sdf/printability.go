@@ -0,0 +1,286 @@
+//-----------------------------------------------------------------------------
+/*
+
+Printability Checks
+
+Scan a solid for features that are hard to 3d print, and report where they are.
+The SDF3 is sampled on a grid, flagged grid cells are grouped into connected
+regions and each region is reported once.
+
+Thin walls: The distance to the surface peaks on the mid surface of a wall.
+Inside samples that are a local maximum of the depth (|d|) are on the mid
+surface, the wall thickness there is about 2|d|. The resolution should be less
+than half the minimum wall thickness or thin walls may have no inside samples.
+
+Overhangs: Surface samples with a downward facing normal. The overhang angle is
+measured from the vertical: a vertical wall is 0 degrees, a flat ceiling is 90
+degrees. The bottom of the part (on the build plate) is ignored. Samples with
+a small distance gradient are ignored, they aren't on a real surface.
+
+Trapped volumes: Empty space that is not connected to the outside of the part.
+E.g. uncured resin or unremovable support inside a closed cavity. Passages
+narrower than the resolution may not be seen.
+
+The checks assume the SDF is a reasonable distance function near the surface.
+A bound on the distance (E.g. smooth unions) under estimates wall thickness.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"math"
+)
+
+//-----------------------------------------------------------------------------
+
+type PrintParms struct {
+	MinWall     float64 // minimum wall thickness
+	MaxOverhang float64 // maximum overhang angle from vertical (degrees)
+	Resolution  float64 // grid sampling resolution
+}
+
+type PrintIssue struct {
+	Kind     string  // "wall", "overhang", "trapped"
+	Position V3      // location of the worst case
+	Value    float64 // wall thickness, overhang angle (degrees), trapped volume
+	Cells    int     // number of grid cells in the region
+}
+
+func (i *PrintIssue) String() string {
+	switch i.Kind {
+	case "wall":
+		return fmt.Sprintf("thin wall %.3f at %v (%d cells)", i.Value, i.Position, i.Cells)
+	case "overhang":
+		return fmt.Sprintf("overhang %.1f degrees at %v (%d cells)", i.Value, i.Position, i.Cells)
+	case "trapped":
+		return fmt.Sprintf("trapped volume %.3f at %v (%d cells)", i.Value, i.Position, i.Cells)
+	}
+	return fmt.Sprintf("%s %f at %v (%d cells)", i.Kind, i.Value, i.Position, i.Cells)
+}
+
+//-----------------------------------------------------------------------------
+
+// distance samples on a grid
+type print_grid struct {
+	s    SDF3
+	base V3      // position of cell 0,0,0
+	res  float64 // resolution
+	n    V3i     // grid size
+	d    []float64
+}
+
+func new_print_grid(s SDF3, res float64) *print_grid {
+	g := print_grid{s: s, res: res}
+	bb := s.BoundingBox()
+	// a border of cells so the outside of the part is connected
+	g.n = bb.Size().DivScalar(res).Ceil().ToV3i().AddScalar(3)
+	g.base = bb.Center().Sub(g.n.ToV3().SubScalar(1).MulScalar(0.5 * res))
+	g.d = make([]float64, g.n[0]*g.n[1]*g.n[2])
+	for i := range g.d {
+		g.d[i] = s.Evaluate(g.position(i))
+	}
+	return &g
+}
+
+// Return the grid coordinates of a cell.
+func (g *print_grid) coord(i int) V3i {
+	return V3i{i % g.n[0], (i / g.n[0]) % g.n[1], i / (g.n[0] * g.n[1])}
+}
+
+// Return the cell index for grid coordinates (-1 if outside the grid).
+func (g *print_grid) index(c V3i) int {
+	if c[0] < 0 || c[1] < 0 || c[2] < 0 || c[0] >= g.n[0] || c[1] >= g.n[1] || c[2] >= g.n[2] {
+		return -1
+	}
+	return c[0] + g.n[0]*(c[1]+g.n[1]*c[2])
+}
+
+// Return the position of a cell.
+func (g *print_grid) position(i int) V3 {
+	return g.base.Add(g.coord(i).ToV3().MulScalar(g.res))
+}
+
+// Return the neighbours of a cell (6 or 26 connected).
+func (g *print_grid) neighbours(i int, all bool) []int {
+	c := g.coord(i)
+	var nb []int
+	for dz := -1; dz <= 1; dz++ {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				k := Abs(float64(dx)) + Abs(float64(dy)) + Abs(float64(dz))
+				if k == 0 || (!all && k != 1) {
+					continue
+				}
+				if j := g.index(c.Add(V3i{dx, dy, dz})); j >= 0 {
+					nb = append(nb, j)
+				}
+			}
+		}
+	}
+	return nb
+}
+
+// Return the connected regions (26 connected) of the flagged cells.
+func (g *print_grid) regions(flag []bool) [][]int {
+	seen := make([]bool, len(flag))
+	var regions [][]int
+	for i := range flag {
+		if !flag[i] || seen[i] {
+			continue
+		}
+		seen[i] = true
+		region := []int{i}
+		for k := 0; k < len(region); k++ {
+			for _, j := range g.neighbours(region[k], true) {
+				if flag[j] && !seen[j] {
+					seen[j] = true
+					region = append(region, j)
+				}
+			}
+		}
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+// Return the distance gradient at a point.
+func (g *print_grid) gradient(p V3) V3 {
+	h := 1e-3 * g.res
+	return V3{
+		g.s.Evaluate(p.Add(V3{h, 0, 0})) - g.s.Evaluate(p.Add(V3{-h, 0, 0})),
+		g.s.Evaluate(p.Add(V3{0, h, 0})) - g.s.Evaluate(p.Add(V3{0, -h, 0})),
+		g.s.Evaluate(p.Add(V3{0, 0, h})) - g.s.Evaluate(p.Add(V3{0, 0, -h})),
+	}.DivScalar(2 * h)
+}
+
+//-----------------------------------------------------------------------------
+
+// Return the thin wall regions.
+func (g *print_grid) thin_walls(min_wall float64) []*PrintIssue {
+	flag := make([]bool, len(g.d))
+	for i, d := range g.d {
+		if d >= 0 || -2*d >= min_wall {
+			continue
+		}
+		// is this a local maximum of the depth?
+		flag[i] = true
+		for _, j := range g.neighbours(i, true) {
+			if g.d[j] < d {
+				flag[i] = false
+				break
+			}
+		}
+	}
+	var issues []*PrintIssue
+	for _, r := range g.regions(flag) {
+		issue := PrintIssue{Kind: "wall", Value: math.MaxFloat64, Cells: len(r)}
+		for _, i := range r {
+			if t := -2 * g.d[i]; t < issue.Value {
+				issue.Value = t
+				issue.Position = g.position(i)
+			}
+		}
+		issues = append(issues, &issue)
+	}
+	return issues
+}
+
+// Return the overhang regions.
+func (g *print_grid) overhangs(max_overhang float64) []*PrintIssue {
+	limit := -math.Sin(DtoR(max_overhang))
+	// the build plate
+	zmin := g.s.BoundingBox().Min.Z + g.res
+	flag := make([]bool, len(g.d))
+	angle := make(map[int]float64)
+	position := make(map[int]V3)
+	for i, d := range g.d {
+		// samples near the surface
+		if Abs(d) > 0.5*g.res {
+			continue
+		}
+		p := g.position(i)
+		n := g.gradient(p)
+		if n.Length() < 0.5 {
+			// Not a real surface. E.g. the zero distance where the
+			// faces of two unioned solids touch.
+			continue
+		}
+		n = n.Normalize()
+		// the closest surface point
+		p = p.Sub(n.MulScalar(d))
+		if n.Z < limit && p.Z > zmin {
+			flag[i] = true
+			angle[i] = RtoD(math.Asin(Clamp(-n.Z, -1, 1)))
+			position[i] = p
+		}
+	}
+	var issues []*PrintIssue
+	for _, r := range g.regions(flag) {
+		issue := PrintIssue{Kind: "overhang", Cells: len(r)}
+		for _, i := range r {
+			if angle[i] > issue.Value {
+				issue.Value = angle[i]
+				issue.Position = position[i]
+			}
+		}
+		issues = append(issues, &issue)
+	}
+	return issues
+}
+
+// Return the trapped volume regions.
+func (g *print_grid) trapped() []*PrintIssue {
+	// flood fill the empty cells from the corner of the grid (always outside)
+	outside := make([]bool, len(g.d))
+	outside[0] = true
+	fill := []int{0}
+	for len(fill) != 0 {
+		i := fill[len(fill)-1]
+		fill = fill[:len(fill)-1]
+		for _, j := range g.neighbours(i, false) {
+			if g.d[j] > 0 && !outside[j] {
+				outside[j] = true
+				fill = append(fill, j)
+			}
+		}
+	}
+	flag := make([]bool, len(g.d))
+	for i, d := range g.d {
+		flag[i] = d > 0 && !outside[i]
+	}
+	var issues []*PrintIssue
+	for _, r := range g.regions(flag) {
+		// report the center of the region
+		c := V3{}
+		for _, i := range r {
+			c = c.Add(g.position(i))
+		}
+		v := g.res * g.res * g.res
+		issues = append(issues, &PrintIssue{"trapped", c.DivScalar(float64(len(r))), float64(len(r)) * v, len(r)})
+	}
+	return issues
+}
+
+//-----------------------------------------------------------------------------
+
+// Printability returns the thin walls, overhangs and trapped volumes of an SDF3.
+// A zero MinWall or MaxOverhang skips that check.
+func Printability(s SDF3, k *PrintParms) []*PrintIssue {
+	if k.Resolution <= 0 || k.MinWall < 0 || k.MaxOverhang < 0 || k.MaxOverhang > 90 {
+		panic("invalid printability parameters")
+	}
+	g := new_print_grid(s, k.Resolution)
+	var issues []*PrintIssue
+	if k.MinWall > 0 {
+		issues = append(issues, g.thin_walls(k.MinWall)...)
+	}
+	if k.MaxOverhang > 0 {
+		issues = append(issues, g.overhangs(k.MaxOverhang)...)
+	}
+	return append(issues, g.trapped()...)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,234 @@
+//-----------------------------------------------------------------------------
+/*
+
+Raymarched Images
+
+Render a shaded image of an SDF3 by sphere tracing: march along each camera
+ray by the distance to the surface until the ray hits the surface. There's
+no mesh, so this is a quick way to get documentation images of a model, or
+to compare the output of the examples against reference images in CI.
+
+The shading is lambertian with an ambient term, a light direction (or a
+headlight at the camera) and optional ambient occlusion. Ambient occlusion
+samples the distance field along the surface normal, the surface is darker
+where other parts of the model are close to it (E.g. in corners and pockets).
+
+A camera with a zero field of view is orthographic, the view size is then the
+height of the view.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+)
+
+//-----------------------------------------------------------------------------
+
+// maximum number of steps along a ray
+const RAYMARCH_STEPS = 256
+
+// number of ambient occlusion samples
+const AO_SAMPLES = 5
+
+type Camera struct {
+	Eye    V3      // camera position
+	Target V3      // point the camera looks at
+	Up     V3      // up direction
+	Fov    float64 // vertical field of view (radians), 0 for orthographic
+	Size   float64 // view height for an orthographic camera
+	Pixels V2i     // image size
+}
+
+type Lighting struct {
+	Light      V3      // direction towards the light (zero for a headlight)
+	Ambient    float64 // ambient light level (0..1)
+	Diffuse    float64 // diffuse light level (0..1)
+	AO         float64 // ambient occlusion distance (0 for none)
+	Color      V3      // surface color (rgb 0..1)
+	Background V3      // background color (rgb 0..1)
+}
+
+// DefaultLighting is a light gray model on a dark background with a headlight.
+var DefaultLighting = Lighting{
+	Ambient:    0.25,
+	Diffuse:    0.75,
+	Color:      V3{0.9, 0.9, 0.9},
+	Background: V3{0.125, 0.125, 0.125},
+}
+
+// ViewCamera returns a camera looking at the center of a bounding box from
+// an azimuth and elevation (radians). The box fits the view.
+func ViewCamera(bb Box3, az, el, fov float64, pixels V2i) *Camera {
+	if pixels[0] <= 0 || pixels[1] <= 0 {
+		panic("invalid image size")
+	}
+	if fov < 0 || fov >= PI {
+		panic("invalid field of view")
+	}
+	center := bb.Center()
+	radius := 0.5 * bb.Size().Length()
+	sa, ca := math.Sincos(az)
+	se, ce := math.Sincos(el)
+	dir := V3{ce * ca, ce * sa, se}
+	// up is +z, unless we're looking along the z-axis
+	up := V3{0, 0, 1}
+	if Abs(se) > 1-EPSILON {
+		up = V3{-ca, -sa, 0}.MulScalar(Sign(se))
+	}
+	// fit the bounding sphere to the smaller of the view height and width
+	k := Min(1, float64(pixels[0])/float64(pixels[1]))
+	c := Camera{Target: center, Up: up, Fov: fov, Pixels: pixels}
+	if fov == 0 {
+		c.Size = 2.0 * radius / k
+		c.Eye = center.Add(dir.MulScalar(2.0 * radius))
+	} else {
+		// half angle of the view in the smaller direction
+		a := math.Atan(k * math.Tan(0.5*fov))
+		c.Eye = center.Add(dir.MulScalar(radius / math.Sin(a)))
+	}
+	return &c
+}
+
+//-----------------------------------------------------------------------------
+
+// Return the ambient occlusion (1 is unoccluded) at a surface point.
+func ambient_occlusion(s SDF3, p, n V3, dist float64) float64 {
+	occ := 0.0
+	w := 1.0
+	for i := 1; i <= AO_SAMPLES; i++ {
+		h := dist * float64(i) / AO_SAMPLES
+		occ += w * (h - s.Evaluate(p.Add(n.MulScalar(h)))) / dist
+		w *= 0.5
+	}
+	return Clamp(1-occ, 0, 1)
+}
+
+// RenderImage returns a raymarched image of an SDF3.
+func RenderImage(s SDF3, c *Camera, l *Lighting) *image.RGBA {
+	if l == nil {
+		l = &DefaultLighting
+	}
+	nx, ny := c.Pixels[0], c.Pixels[1]
+	if nx <= 0 || ny <= 0 {
+		panic("invalid image size")
+	}
+	// camera frame
+	f := c.Target.Sub(c.Eye).Normalize()
+	r := f.Cross(c.Up)
+	if r.Length() < EPSILON {
+		panic("camera up is along the view direction")
+	}
+	r = r.Normalize()
+	u := r.Cross(f)
+	aspect := float64(nx) / float64(ny)
+	// half the view height at the target
+	h := 0.5 * c.Size
+	if c.Fov > 0 {
+		h = math.Tan(0.5*c.Fov) * c.Target.Sub(c.Eye).Length()
+	}
+	eps := h / float64(ny)
+	// march the rays that hit the bounding sphere
+	bb := s.BoundingBox()
+	center := bb.Center()
+	radius := 0.5*bb.Size().Length() + eps
+	light := l.Light
+	if light.Length() > 0 {
+		light = light.Normalize()
+	}
+	background := rgba(l.Background)
+
+	img := image.NewRGBA(image.Rect(0, 0, nx, ny))
+	rows := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range rows {
+				y := 1 - 2*(float64(j)+0.5)/float64(ny)
+				for i := 0; i < nx; i++ {
+					x := (2*(float64(i)+0.5)/float64(nx) - 1) * aspect
+					// ray origin and direction
+					ro, rd := c.Eye, f
+					if c.Fov > 0 {
+						t := math.Tan(0.5 * c.Fov)
+						rd = f.Add(r.MulScalar(x * t)).Add(u.MulScalar(y * t)).Normalize()
+					} else {
+						ro = ro.Add(r.MulScalar(x * h)).Add(u.MulScalar(y * h))
+					}
+					// clip the ray to the bounding sphere
+					oc := ro.Sub(center)
+					b := oc.Dot(rd)
+					disc := b*b - oc.Length2() + radius*radius
+					img.SetRGBA(i, j, background)
+					if disc < 0 {
+						continue
+					}
+					t, t1 := Max(0, -b-math.Sqrt(disc)), -b+math.Sqrt(disc)
+					for n := 0; n < RAYMARCH_STEPS && t < t1; n++ {
+						p := ro.Add(rd.MulScalar(t))
+						d := s.Evaluate(p)
+						if d < eps {
+							img.SetRGBA(i, j, rgba(l.Color.MulScalar(shade(s, p, rd, light, eps, l))))
+							break
+						}
+						t += d
+					}
+				}
+			}
+		}()
+	}
+	for j := 0; j < ny; j++ {
+		rows <- j
+	}
+	close(rows)
+	wg.Wait()
+	return img
+}
+
+// Return the light level at a surface point.
+func shade(s SDF3, p, rd, light V3, eps float64, l *Lighting) float64 {
+	n := Normal3D(s, p, eps)
+	if light.Length() == 0 {
+		// headlight
+		light = rd.Negate()
+	}
+	k := l.Ambient + l.Diffuse*Max(0, n.Dot(light))
+	if l.AO > 0 {
+		k *= ambient_occlusion(s, p, n, l.AO)
+	}
+	return Clamp(k, 0, 1)
+}
+
+// Convert an rgb (0..1) color to RGBA.
+func rgba(c V3) color.RGBA {
+	r := uint8(255 * Clamp(c.X, 0, 1))
+	g := uint8(255 * Clamp(c.Y, 0, 1))
+	b := uint8(255 * Clamp(c.Z, 0, 1))
+	return color.RGBA{r, g, b, 255}
+}
+
+// RenderPNG renders a raymarched image of an SDF3 to a PNG file.
+func RenderPNG(s SDF3, c *Camera, l *Lighting, path string) error {
+	img := RenderImage(s, c, l)
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//-----------------------------------------------------------------------------
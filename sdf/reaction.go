@@ -0,0 +1,189 @@
+//-----------------------------------------------------------------------------
+/*
+
+Reaction-Diffusion Patterns
+
+Organic (Turing) patterns from a Gray-Scott reaction-diffusion simulation.
+The simulation runs on a periodic 2D or 3D grid, the result tiles in every
+direction. The pattern can be used as a displacement (Displace3D) or as a
+perforation mask (a LatticeFunc for Infill3D).
+
+Gray-Scott parameters:
+
+u, v: chemical concentrations (the pattern is v)
+Du, Dv: diffusion rates (E.g. 0.16, 0.08)
+Feed: feed rate of u (E.g. 0.035 labyrinth, 0.055 spots)
+Kill: kill rate of v (E.g. 0.065 labyrinth, 0.062 spots)
+
+The initial state is seeded at random positions from the seed value so the
+pattern is repeatable.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"math/rand"
+)
+
+//-----------------------------------------------------------------------------
+
+type ReactionDiffusionParms struct {
+	Size   V3i     // grid size (Size[2] = 1 for a 2D pattern)
+	Du, Dv float64 // diffusion rates
+	Feed   float64 // feed rate
+	Kill   float64 // kill rate
+	Steps  int     // number of simulation steps
+	Spots  int     // number of initial seed spots
+	Seed   int64   // random seed
+}
+
+type ReactionDiffusion struct {
+	n V3i
+	v []float64 // pattern values [0,1]
+}
+
+// Return the index of a grid point (wrapped to the grid).
+func (r *ReactionDiffusion) index(i, j, k int) int {
+	n := r.n
+	i = ((i % n[0]) + n[0]) % n[0]
+	j = ((j % n[1]) + n[1]) % n[1]
+	k = ((k % n[2]) + n[2]) % n[2]
+	return (k*n[1]+j)*n[0] + i
+}
+
+// NewReactionDiffusion runs a reaction-diffusion simulation and returns the pattern.
+func NewReactionDiffusion(k *ReactionDiffusionParms) *ReactionDiffusion {
+	n := k.Size
+	if n[0] < 4 || n[1] < 4 || n[2] < 1 {
+		panic("invalid grid size")
+	}
+	if k.Du <= 0 || k.Dv <= 0 || k.Feed <= 0 || k.Kill <= 0 || k.Steps < 0 || k.Spots < 1 {
+		panic("invalid reaction-diffusion parameters")
+	}
+	// the explicit update is unstable for large diffusion rates
+	dims := 2
+	if n[2] > 1 {
+		dims = 3
+	}
+	if Max(k.Du, k.Dv) > 0.5/float64(dims) {
+		panic("diffusion rate is too large")
+	}
+	r := ReactionDiffusion{n: n}
+	size := n[0] * n[1] * n[2]
+	u := make([]float64, size)
+	v := make([]float64, size)
+	for i := range u {
+		u[i] = 1
+	}
+
+	// seed spots
+	rnd := rand.New(rand.NewSource(k.Seed))
+	for s := 0; s < k.Spots; s++ {
+		ci, cj, ck := rnd.Intn(n[0]), rnd.Intn(n[1]), rnd.Intn(n[2])
+		dk := 1
+		if dims == 3 {
+			dk = 3
+		}
+		for dz := 0; dz < dk; dz++ {
+			for dy := 0; dy < 3; dy++ {
+				for dx := 0; dx < 3; dx++ {
+					x := r.index(ci+dx, cj+dy, ck+dz)
+					u[x] = 0.5
+					v[x] = 0.25 + 0.05*rnd.Float64()
+				}
+			}
+		}
+	}
+
+	// simulate
+	u1 := make([]float64, size)
+	v1 := make([]float64, size)
+	for step := 0; step < k.Steps; step++ {
+		for z := 0; z < n[2]; z++ {
+			for y := 0; y < n[1]; y++ {
+				for x := 0; x < n[0]; x++ {
+					c := r.index(x, y, z)
+					nb := [6]int{
+						r.index(x-1, y, z), r.index(x+1, y, z),
+						r.index(x, y-1, z), r.index(x, y+1, z),
+						r.index(x, y, z-1), r.index(x, y, z+1),
+					}
+					lu := -float64(2*dims) * u[c]
+					lv := -float64(2*dims) * v[c]
+					for _, i := range nb[:2*dims] {
+						lu += u[i]
+						lv += v[i]
+					}
+					uvv := u[c] * v[c] * v[c]
+					u1[c] = u[c] + k.Du*lu - uvv + k.Feed*(1-u[c])
+					v1[c] = v[c] + k.Dv*lv + uvv - (k.Feed+k.Kill)*v[c]
+				}
+			}
+		}
+		u, u1 = u1, u
+		v, v1 = v1, v
+	}
+
+	// normalize to [0,1]
+	v_min, v_max := v[0], v[0]
+	for _, x := range v {
+		v_min = Min(v_min, x)
+		v_max = Max(v_max, x)
+	}
+	scale := 0.0
+	if v_max > v_min {
+		scale = 1.0 / (v_max - v_min)
+	}
+	r.v = make([]float64, size)
+	for i, x := range v {
+		r.v[i] = (x - v_min) * scale
+	}
+	return &r
+}
+
+// Sample returns the pattern value [0,1] at a point. The grid cells are cell
+// units in size, the pattern repeats. 2D patterns ignore p.Z.
+func (r *ReactionDiffusion) Sample(p V3, cell float64) float64 {
+	x := p.X / cell
+	y := p.Y / cell
+	z := 0.0
+	if r.n[2] > 1 {
+		z = p.Z / cell
+	}
+	i, j, k := int(math.Floor(x)), int(math.Floor(y)), int(math.Floor(z))
+	fx, fy, fz := x-float64(i), y-float64(j), z-float64(k)
+	// trilinear interpolation
+	c := func(di, dj, dk int) float64 {
+		return r.v[r.index(i+di, j+dj, k+dk)]
+	}
+	c0 := Mix(Mix(c(0, 0, 0), c(1, 0, 0), fx), Mix(c(0, 1, 0), c(1, 1, 0), fx), fy)
+	c1 := Mix(Mix(c(0, 0, 1), c(1, 0, 1), fx), Mix(c(0, 1, 1), c(1, 1, 1), fx), fy)
+	return Mix(c0, c1, fz)
+}
+
+// Displacement returns a displacement function for Displace3D.
+// The displacement is amplitude * the pattern value.
+func (r *ReactionDiffusion) Displacement(cell, amplitude float64) DisplaceFunc {
+	if cell <= 0 {
+		panic("invalid cell size")
+	}
+	return func(p V3) float64 {
+		return amplitude * r.Sample(p, cell)
+	}
+}
+
+// Lattice returns a perforation mask for Infill3D. The material is where the
+// pattern value is greater than level. The distance is approximate.
+func (r *ReactionDiffusion) Lattice(cell, level float64) LatticeFunc {
+	if cell <= 0 || level <= 0 || level >= 1 {
+		panic("invalid mask parameters")
+	}
+	return func(p V3) float64 {
+		return (level - r.Sample(p, cell)) * cell
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,153 @@
+//-----------------------------------------------------------------------------
+/*
+
+Bounding Box Refinement
+
+The bounding box of an SDF is often loose. E.g. a rotated sphere has the
+bounding box of a rotated cube, and offsets and smooth unions are padded.
+Rendering a loose box wastes cells, and with a fixed number of cells on the
+longest axis it also lowers the resolution of the part.
+
+RefineBB tightens the box by sampling. The box is divided into a grid of
+cells and a cell is empty if the distance at its center is more than half the
+cell diagonal. The refined box covers the cells that aren't empty, and each
+iteration repeats this on the refined box with smaller cells.
+
+The refined box is conservative if the SDF is a lower bound on the distance.
+The octree renderers make the same assumption when they skip empty cubes.
+Refinement is opt-in, RenderSTLRefined and RenderDXFRefined render with a
+refined box.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+//-----------------------------------------------------------------------------
+
+// number of cells per axis for each refinement iteration
+const REFINE_CELLS = 16
+
+// number of refinement iterations used by the renderers
+const REFINE_ITERATIONS = 4
+
+//-----------------------------------------------------------------------------
+// 3D
+
+// RefineBB returns a tighter bounding box for an SDF3.
+func RefineBB(s SDF3, iterations int) Box3 {
+	bb := s.BoundingBox()
+	for i := 0; i < iterations; i++ {
+		h := bb.Size().DivScalar(REFINE_CELLS)
+		r := 0.5 * h.Length()
+		var nbb Box3
+		empty := true
+		for x := 0; x < REFINE_CELLS; x++ {
+			for y := 0; y < REFINE_CELLS; y++ {
+				for z := 0; z < REFINE_CELLS; z++ {
+					c := bb.Min.Add(h.Mul(V3{float64(x), float64(y), float64(z)}))
+					cell := Box3{c, c.Add(h)}
+					if s.Evaluate(cell.Center()) > r {
+						continue
+					}
+					if empty {
+						nbb, empty = cell, false
+					} else {
+						nbb = nbb.Extend(cell)
+					}
+				}
+			}
+		}
+		if empty || nbb == bb {
+			// nothing to render (leave the box alone) or no progress
+			break
+		}
+		bb = nbb
+	}
+	return bb
+}
+
+// an SDF3 with a refined bounding box
+type refined_sdf3 struct {
+	SDF3
+	bb Box3
+}
+
+func (s *refined_sdf3) BoundingBox() Box3 {
+	return s.bb
+}
+
+// pass on the optional interfaces
+
+func (s *refined_sdf3) Gradient(p V3, eps float64) V3 {
+	return Gradient3D(s.SDF3, p, eps)
+}
+
+// a refined SDF3 with an interval evaluation
+type refined_interval_sdf3 struct {
+	refined_sdf3
+}
+
+func (s *refined_interval_sdf3) EvaluateInterval(b Box3) Interval {
+	return s.SDF3.(SDF3Interval).EvaluateInterval(b)
+}
+
+// Return an SDF3 with a refined bounding box.
+// It has an interval evaluation only if the SDF3 has one.
+func refine_sdf3(s SDF3, iterations int) SDF3 {
+	r := refined_sdf3{s, RefineBB(s, iterations)}
+	if _, ok := s.(SDF3Interval); ok {
+		return &refined_interval_sdf3{r}
+	}
+	return &r
+}
+
+//-----------------------------------------------------------------------------
+// 2D
+
+// RefineBB2 returns a tighter bounding box for an SDF2.
+func RefineBB2(s SDF2, iterations int) Box2 {
+	bb := s.BoundingBox()
+	for i := 0; i < iterations; i++ {
+		h := bb.Size().DivScalar(REFINE_CELLS)
+		r := 0.5 * h.Length()
+		var nbb Box2
+		empty := true
+		for x := 0; x < REFINE_CELLS; x++ {
+			for y := 0; y < REFINE_CELLS; y++ {
+				c := bb.Min.Add(h.Mul(V2{float64(x), float64(y)}))
+				cell := Box2{c, c.Add(h)}
+				if s.Evaluate(cell.Center()) > r {
+					continue
+				}
+				if empty {
+					nbb, empty = cell, false
+				} else {
+					nbb = nbb.Extend(cell)
+				}
+			}
+		}
+		if empty || nbb == bb {
+			break
+		}
+		bb = nbb
+	}
+	return bb
+}
+
+// an SDF2 with a refined bounding box
+type refined_sdf2 struct {
+	SDF2
+	bb Box2
+}
+
+func (s *refined_sdf2) BoundingBox() Box2 {
+	return s.bb
+}
+
+// Return an SDF2 with a refined bounding box.
+func refine_sdf2(s SDF2, iterations int) SDF2 {
+	return &refined_sdf2{s, RefineBB2(s, iterations)}
+}
+
+//-----------------------------------------------------------------------------
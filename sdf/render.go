@@ -4,6 +4,7 @@
 Render an SDF
 
 SDF3 -> STL file
+SDF3 -> 3MF file
 SDF2 -> DXF file
 
 */
@@ -24,6 +25,21 @@ func RenderSTL(
 	mesh_cells int, //number of cells on the longest axis. e.g 200
 	path string, //path to filename
 ) {
+	RenderSTLWithMetadata(s, mesh_cells, path, nil)
+}
+
+// Render an SDF3 as an STL file (octree sampling) with metadata in the header.
+func RenderSTLWithMetadata(
+	s SDF3, //sdf3 to render
+	mesh_cells int, //number of cells on the longest axis. e.g 200
+	path string, //path to filename
+	meta *Metadata, //metadata for the STL header (nil for none)
+) {
+	render_stl(s, mesh_cells, path, meta, nil)
+}
+
+// Render an SDF3 as an STL file with distance samples shared across renders.
+func render_stl(s SDF3, mesh_cells int, path string, meta *Metadata, shared *samples3) {
 
 	// work out the sampling resolution to use
 	bb_size := s.BoundingBox().Size()
@@ -34,14 +50,14 @@ func RenderSTL(
 
 	// write the triangles to an STL file
 	var wg sync.WaitGroup
-	output, err := WriteSTL(&wg, path)
+	output, err := WriteSTLWithMetadata(&wg, path, meta)
 	if err != nil {
 		fmt.Printf("%s", err)
 		return
 	}
 
 	// run marching cubes to generate the triangle mesh
-	MarchingCubes_Octree(s, resolution, output)
+	marching_cubes_octree(s, resolution, output, shared)
 
 	// stop the STL writer reading on the channel
 	close(output)
@@ -49,6 +65,37 @@ func RenderSTL(
 	wg.Wait()
 }
 
+// Render an SDF3 as a 3MF file (octree sampling) with metadata in the model.
+func Render3MF(
+	s SDF3, //sdf3 to render
+	mesh_cells int, //number of cells on the longest axis. e.g 200
+	path string, //path to filename
+	meta *Metadata, //metadata for the model (nil for none)
+) {
+	resolution := s.BoundingBox().Size().MaxComponent() / float64(mesh_cells)
+	fmt.Printf("rendering %s (resolution %.2f)\n", path, resolution)
+	err := Save3MF(path, collect_triangles(s, resolution), meta)
+	if err != nil {
+		fmt.Printf("%s", err)
+	}
+}
+
+// Return the triangles of an SDF3 rendered with marching cubes (octree sampling).
+func collect_triangles(s SDF3, resolution float64) []*Triangle3 {
+	output := make(chan *Triangle3)
+	done := make(chan []*Triangle3)
+	go func() {
+		var triangles []*Triangle3
+		for t := range output {
+			triangles = append(triangles, t)
+		}
+		done <- triangles
+	}()
+	MarchingCubes_Octree(s, resolution, output)
+	close(output)
+	return <-done
+}
+
 // Render an SDF3 as an STL file.
 func RenderSTL_Slow(
 	s SDF3, //sdf3 to render
@@ -75,6 +122,16 @@ func RenderSTL_Slow(
 	}
 }
 
+// Render an SDF3 as an STL file (octree sampling) with a refined bounding box.
+// Use this for an SDF3 with a loose bounding box, see RefineBB.
+func RenderSTLRefined(
+	s SDF3, //sdf3 to render
+	mesh_cells int, //number of cells on the longest axis. e.g 200
+	path string, //path to filename
+) {
+	render_stl(refine_sdf3(s, REFINE_ITERATIONS), mesh_cells, path, nil, nil)
+}
+
 //-----------------------------------------------------------------------------
 
 // Render an SDF2 as a DXF file. (quadtree sampling)
@@ -83,6 +140,11 @@ func RenderDXF(
 	mesh_cells int, //number of cells on the longest axis. e.g 200
 	path string, //path to filename
 ) {
+	render_dxf(s, mesh_cells, path, nil)
+}
+
+// Render an SDF2 as a DXF file with distance samples shared across renders.
+func render_dxf(s SDF2, mesh_cells int, path string, shared *samples2) {
 
 	// work out the sampling resolution to use
 	bb_size := s.BoundingBox().Size()
@@ -100,7 +162,7 @@ func RenderDXF(
 	}
 
 	// run marching squares to generate the line segments
-	MarchingSquares_Quadtree(s, resolution, output)
+	marching_squares_quadtree(s, resolution, output, shared)
 
 	// stop the DXF writer reading on the channel
 	close(output)
@@ -134,4 +196,14 @@ func RenderDXF_Slow(
 	}
 }
 
+// Render an SDF2 as a DXF file (quadtree sampling) with a refined bounding box.
+// Use this for an SDF2 with a loose bounding box, see RefineBB2.
+func RenderDXFRefined(
+	s SDF2, //sdf2 to render
+	mesh_cells int, //number of cells on the longest axis. e.g 200
+	path string, //path to filename
+) {
+	render_dxf(refine_sdf2(s, REFINE_ITERATIONS), mesh_cells, path, nil)
+}
+
 //-----------------------------------------------------------------------------
@@ -0,0 +1,240 @@
+//-----------------------------------------------------------------------------
+/*
+
+Root Finding
+
+Robust 1D root finding and minimization for distance evaluation (E.g. the
+closest point on a spline). The methods need an interval that brackets the
+root (the function changes sign), so unlike bare Newton-Raphson they always
+converge.
+
+Bisection: slow but certain.
+Brent: inverse quadratic interpolation with bisection as the fall back.
+Newton polish: a few Newton-Raphson steps from the Brent result, kept inside
+the bracket, when the derivative is available.
+
+Minimize finds the minimum of f(x) on an interval. The interval is sampled to
+bracket the zeroes (-ve to +ve) of the derivative. Each local minimum is found
+and the smallest one (or an end point) is returned.
+
+*/
+//-----------------------------------------------------------------------------
+
+package roots
+
+import (
+	"errors"
+	"math"
+)
+
+//-----------------------------------------------------------------------------
+
+// ErrNotBracketed is returned when f(a) and f(b) have the same sign.
+var ErrNotBracketed = errors.New("root is not bracketed")
+
+const epsilon = 2.220446049250313e-16
+
+// maximum number of iterations
+const max_iterations = 200
+
+func sign(x float64) float64 {
+	if x < 0 {
+		return -1
+	}
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+
+//-----------------------------------------------------------------------------
+
+// Bisection finds a zero of f(x) in the interval [a, b] by bisection.
+// f(a) and f(b) must have opposite signs.
+func Bisection(f func(float64) float64, a, b, tolerance float64) (float64, error) {
+	fa := f(a)
+	fb := f(b)
+	if fa == 0 {
+		return a, nil
+	}
+	if fb == 0 {
+		return b, nil
+	}
+	if sign(fa) == sign(fb) {
+		return 0, ErrNotBracketed
+	}
+	for i := 0; i < max_iterations; i++ {
+		m := 0.5 * (a + b)
+		if math.Abs(b-a) <= 2*tolerance || m == a || m == b {
+			return m, nil
+		}
+		fm := f(m)
+		if fm == 0 {
+			return m, nil
+		}
+		if sign(fm) == sign(fa) {
+			a, fa = m, fm
+		} else {
+			b = m
+		}
+	}
+	return 0.5 * (a + b), nil
+}
+
+// Brent finds a zero of f(x) in the interval [a, b] using Brent's method.
+// f(a) and f(b) must have opposite signs.
+// See: https://en.wikipedia.org/wiki/Brent%27s_method
+func Brent(f func(float64) float64, a, b, tolerance float64) (float64, error) {
+	fa := f(a)
+	fb := f(b)
+	if fa == 0 {
+		return a, nil
+	}
+	if fb == 0 {
+		return b, nil
+	}
+	if sign(fa) == sign(fb) {
+		return 0, ErrNotBracketed
+	}
+	c, fc := a, fa
+	d := b - a
+	e := d
+	for i := 0; i < max_iterations; i++ {
+		if sign(fb) == sign(fc) {
+			c, fc = a, fa
+			d = b - a
+			e = d
+		}
+		if math.Abs(fc) < math.Abs(fb) {
+			a, b, c = b, c, b
+			fa, fb, fc = fb, fc, fb
+		}
+		tol := 2*epsilon*math.Abs(b) + 0.5*tolerance
+		m := 0.5 * (c - b)
+		if math.Abs(m) <= tol || fb == 0 {
+			break
+		}
+		if math.Abs(e) >= tol && math.Abs(fa) > math.Abs(fb) {
+			// attempt interpolation
+			var p, q float64
+			s := fb / fa
+			if a == c {
+				// linear interpolation
+				p = 2 * m * s
+				q = 1 - s
+			} else {
+				// inverse quadratic interpolation
+				q = fa / fc
+				r := fb / fc
+				p = s * (2*m*q*(q-r) - (b-a)*(r-1))
+				q = (q - 1) * (r - 1) * (s - 1)
+			}
+			if p > 0 {
+				q = -q
+			} else {
+				p = -p
+			}
+			if 2*p < math.Min(3*m*q-math.Abs(tol*q), math.Abs(e*q)) {
+				// accept the interpolation
+				e = d
+				d = p / q
+			} else {
+				// interpolation failed, use bisection
+				d = m
+				e = d
+			}
+		} else {
+			// bounds decreasing too slowly, use bisection
+			d = m
+			e = d
+		}
+		a, fa = b, fb
+		if math.Abs(d) > tol {
+			b += d
+		} else if m > 0 {
+			b += tol
+		} else {
+			b -= tol
+		}
+		fb = f(b)
+	}
+	return b, nil
+}
+
+// NewtonPolish refines a root x of f(x) with Newton-Raphson steps (df is the
+// derivative). The steps are kept inside [a, b] and stop when |f| stops
+// getting smaller, so the result is never worse than x.
+func NewtonPolish(f, df func(float64) float64, x, a, b float64, iterations int) float64 {
+	if a > b {
+		a, b = b, a
+	}
+	fx := f(x)
+	for i := 0; i < iterations && fx != 0; i++ {
+		d := df(x)
+		if d == 0 {
+			break
+		}
+		x1 := x - fx/d
+		if x1 < a || x1 > b {
+			break
+		}
+		f1 := f(x1)
+		if math.Abs(f1) >= math.Abs(fx) {
+			break
+		}
+		x, fx = x1, f1
+	}
+	return x
+}
+
+//-----------------------------------------------------------------------------
+
+// BracketMinima samples the derivative df(x) at n intervals on [a, b] and
+// returns the intervals with a -ve to +ve transition (a local minimum of f).
+func BracketMinima(df func(float64) float64, a, b float64, n int) [][2]float64 {
+	if n < 1 {
+		n = 1
+	}
+	var brackets [][2]float64
+	dx := (b - a) / float64(n)
+	x0 := a
+	y0 := df(x0)
+	for i := 1; i <= n; i++ {
+		x1 := a + float64(i)*dx
+		if i == n {
+			x1 = b
+		}
+		y1 := df(x1)
+		if y0 < 0 && y1 >= 0 {
+			brackets = append(brackets, [2]float64{x0, x1})
+		}
+		x0, y0 = x1, y1
+	}
+	return brackets
+}
+
+// Minimize returns the x value and minimum of f(x) on [a, b].
+// df is the derivative of f, ddf is the 2nd derivative (nil for no Newton polish).
+// n is the number of samples used to bracket the local minima.
+func Minimize(f, df, ddf func(float64) float64, a, b float64, n int, tolerance float64) (float64, float64) {
+	// consider the end points
+	xmin, fmin := a, f(a)
+	if fb := f(b); fb < fmin {
+		xmin, fmin = b, fb
+	}
+	for _, br := range BracketMinima(df, a, b, n) {
+		x, err := Brent(df, br[0], br[1], tolerance)
+		if err != nil {
+			continue
+		}
+		if ddf != nil {
+			x = NewtonPolish(df, ddf, x, br[0], br[1], 4)
+		}
+		if fx := f(x); fx < fmin {
+			xmin, fmin = x, fx
+		}
+	}
+	return xmin, fmin
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,83 @@
+//-----------------------------------------------------------------------------
+/*
+
+Root Finding Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package roots
+
+import (
+	"math"
+	"testing"
+)
+
+//-----------------------------------------------------------------------------
+
+const tolerance = 1e-12
+
+func Test_Brent(t *testing.T) {
+	f := func(x float64) float64 { return math.Cos(x) - x }
+	x0 := 0.7390851332151607
+	x, err := Brent(f, 0, 1, tolerance)
+	if err != nil || math.Abs(x-x0) > 1e-10 {
+		t.Error("FAIL")
+	}
+	x, err = Bisection(f, 0, 1, tolerance)
+	if err != nil || math.Abs(x-x0) > 1e-10 {
+		t.Error("FAIL")
+	}
+	// Newton-Raphson from x = 2 diverges for atan(x), Brent doesn't
+	x, err = Brent(math.Atan, -1, 3, tolerance)
+	if err != nil || math.Abs(x) > 1e-10 {
+		t.Error("FAIL")
+	}
+	if _, err := Brent(f, 1, 2, tolerance); err != ErrNotBracketed {
+		t.Error("FAIL")
+	}
+	if _, err := Bisection(f, 1, 2, tolerance); err != ErrNotBracketed {
+		t.Error("FAIL")
+	}
+}
+
+func Test_NewtonPolish(t *testing.T) {
+	f := func(x float64) float64 { return x*x - 2 }
+	df := func(x float64) float64 { return 2 * x }
+	x := NewtonPolish(f, df, 1.4, 1, 2, 5)
+	if math.Abs(x-math.Sqrt2) > 1e-12 {
+		t.Error("FAIL")
+	}
+	// the step leaves the bracket: keep the start value
+	x = NewtonPolish(math.Atan, func(x float64) float64 { return 1 / (1 + x*x) }, 2, 1, 3, 5)
+	if x != 2 {
+		t.Error("FAIL")
+	}
+}
+
+func Test_Minimize(t *testing.T) {
+	// two local minima near x = -1 (f = -0.5) and x = 1 (f = -1)
+	f := func(x float64) float64 { return x*x*x*x - 2*x*x - 0.25*(x-1) }
+	df := func(x float64) float64 { return 4*x*x*x - 4*x - 0.25 }
+	ddf := func(x float64) float64 { return 12*x*x - 4 }
+	br := BracketMinima(df, -2, 2, 16)
+	if len(br) != 2 {
+		t.Errorf("FAIL %v", br)
+	}
+	x, fx := Minimize(f, df, ddf, -2, 2, 16, tolerance)
+	if math.Abs(df(x)) > 1e-9 || x < 0.9 || fx != f(x) {
+		t.Errorf("FAIL %f %f", x, fx)
+	}
+	// no polish gives the same result
+	x1, _ := Minimize(f, df, nil, -2, 2, 16, tolerance)
+	if math.Abs(x-x1) > 1e-9 {
+		t.Error("FAIL")
+	}
+	// the minimum is at an end point
+	x, _ = Minimize(f, df, ddf, 1.5, 2, 16, tolerance)
+	if x != 1.5 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,130 @@
+//-----------------------------------------------------------------------------
+/*
+
+Bladed Rotors
+
+Fans, propellers and turbine blade rows. The blades are placed evenly around
+a hub and optionally joined at their tips by a shroud ring.
+
+The blade chord and twist vary with radius. They are given as knots
+(radius, value) for spline functions, so a few values give a smooth
+distribution along the blade. The blade section at each radius is a rounded
+slab (chord by thickness) at the blade angle, wrapped around the cylinder at
+that radius.
+
+The roots of the blades are filleted to the hub (and shroud) with a smooth union.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+
+type RotorParms struct {
+	Blades     int     // number of blades
+	Hub        float64 // hub radius
+	Shroud     float64 // blade tip radius (the inside of the shroud)
+	Height     float64 // height of the hub and shroud
+	Chord      []V2    // blade chord vs radius (radius, chord)
+	Twist      []V2    // blade angle from the plane of rotation vs radius (radius, radians)
+	Thickness  float64 // blade thickness as a fraction of the chord
+	Fillet     float64 // size of the fillet at the blade roots (0 for none)
+	ShroudWall float64 // shroud wall thickness (0 for no shroud)
+}
+
+//-----------------------------------------------------------------------------
+
+type BladeSDF3 struct {
+	chord     *SplineFunction // chord vs radius
+	twist     *SplineFunction // blade angle vs radius
+	thickness float64         // thickness as a fraction of the chord
+	r0, r1    float64         // radial extent of the blade
+	bb        Box3            // bounding box
+}
+
+// Blade3D returns a single blade of a rotor, centered on the x-axis.
+// The blade runs from inside the hub to the tip radius, or into the shroud.
+func Blade3D(k *RotorParms) SDF3 {
+	if k.Hub <= 0 || k.Shroud <= k.Hub {
+		panic("invalid blade radii")
+	}
+	if k.Thickness <= 0 || k.Thickness > 1 {
+		panic("invalid blade thickness")
+	}
+	s := BladeSDF3{}
+	s.chord = NewSplineFunction(k.Chord)
+	s.twist = NewSplineFunction(k.Twist)
+	s.thickness = k.Thickness
+	// run the blade into the hub and shroud so the fillets blend
+	s.r0 = 0.5 * k.Hub
+	s.r1 = k.Shroud
+	if k.ShroudWall > 0 {
+		s.r1 += 0.5 * k.ShroudWall
+	}
+	// work out the bounding box
+	const n = 32
+	h := 0.0
+	for i := 0; i <= n; i++ {
+		c := s.chord.Evaluate(Mix(s.r0, s.r1, float64(i)/n))
+		if c <= 0 {
+			panic("invalid blade chord")
+		}
+		h = Max(h, 0.5*c)
+	}
+	s.bb = Box3{V3{-s.r1, -s.r1, -h}, V3{s.r1, s.r1, h}}
+	return &s
+}
+
+// Evaluate returns the minimum distance to a blade.
+func (s *BladeSDF3) Evaluate(p V3) float64 {
+	r := math.Sqrt(p.X*p.X + p.Y*p.Y)
+	// the blade section at this radius
+	rs := Clamp(r, s.r0, s.r1)
+	c := s.chord.Evaluate(rs)
+	t := 0.5 * s.thickness * c
+	// unwrap the cylinder at this radius: u is around, v is up
+	u := rs * math.Atan2(p.Y, p.X)
+	q := Rotate(-s.twist.Evaluate(rs)).MulPosition(V2{u, p.Z})
+	a := V2{Max(Abs(q.X)-(0.5*c-t), 0), q.Y}.Length() - t
+	// radial extent of the blade
+	b := Max(s.r0-r, r-s.r1)
+	return Max(a, b)
+}
+
+// BoundingBox returns the bounding box of a blade.
+func (s *BladeSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+
+// Rotor3D returns a bladed rotor (fan, propeller, turbine blade row).
+// The rotor axis is the z-axis, the hub and shroud are centered on z = 0.
+func Rotor3D(k *RotorParms) SDF3 {
+	if k.Blades < 1 {
+		panic("invalid number of blades")
+	}
+	if k.Height <= 0 {
+		panic("invalid rotor height")
+	}
+	if k.Fillet < 0 || k.ShroudWall < 0 {
+		panic("invalid rotor parameters")
+	}
+	blades := RotateCopy3D(Blade3D(k), k.Blades)
+	parts := []SDF3{Cylinder3D(k.Height, k.Hub, 0)}
+	if k.ShroudWall > 0 {
+		outer := Cylinder3D(k.Height, k.Shroud+k.ShroudWall, 0)
+		inner := Cylinder3D(2*k.Height, k.Shroud, 0)
+		parts = append(parts, Difference3D(outer, inner))
+	}
+	if k.Fillet > 0 {
+		hub := Union3D(parts...)
+		return SmoothUnion3D(k.Fillet, hub, blades)
+	}
+	return Union3D(append(parts, blades)...)
+}
+
+//-----------------------------------------------------------------------------
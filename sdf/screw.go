@@ -11,9 +11,9 @@ the x-axis as the screw axis. Most thread profiles are symmetric about the y-axi
 but a few aren't (E.g. buttress threads) so in general we build the profile of
 an entire pitch period.
 
-This code doesn't deal with thread tolerancing. If you want threads to fit properly
+Screw3D doesn't deal with thread tolerancing. If you want threads to fit properly
 the radius of the thread will need to be tweaked (+/-) to give internal/external thread
-clearance.
+clearance. Thread3D (threads.go) does this for standard thread tolerance classes.
 
 */
 //-----------------------------------------------------------------------------
@@ -69,6 +69,23 @@ func (m ThreadDatabase) ISOAdd(
 	m[name] = &t
 }
 
+// PGAdd adds a PG (Panzergewinde) Thread Standard to the thread database.
+// PG threads are used for cable glands and electrical conduit.
+func (m ThreadDatabase) PGAdd(
+	name string, // thread name
+	diameter float64, // thread outer diameter
+	tpi float64, // threads per inch
+	hex_f2f float64, // hex nut flat to flat distance
+) {
+	t := ThreadParameters{}
+	t.Name = name
+	t.Radius = diameter / 2.0
+	t.Pitch = MM_PER_INCH / tpi
+	t.Hex_Flat2Flat = hex_f2f
+	t.Units = "mm"
+	m[name] = &t
+}
+
 func Init_ThreadLookup() ThreadDatabase {
 	m := make(ThreadDatabase)
 	// UTS Coarse
@@ -130,13 +147,27 @@ func Init_ThreadLookup() ThreadDatabase {
 	m.ISOAdd("M12x1.5", 12, 1.5, 19)
 	m.ISOAdd("M16x1.5", 16, 1.5, 24)
 	m.ISOAdd("M20x2", 20, 2, 30)
+	m.ISOAdd("M20x1.5", 20, 1.5, 30)
 	m.ISOAdd("M24x2", 24, 2, 36)
+	m.ISOAdd("M25x1.5", 25, 1.5, 36)
 	m.ISOAdd("M30x2", 30, 2, 46)
+	m.ISOAdd("M32x1.5", 32, 1.5, 50)
 	m.ISOAdd("M36x3", 36, 3, 55)
 	m.ISOAdd("M42x3", 42, 3, 65)
 	m.ISOAdd("M48x3", 48, 3, 75)
 	m.ISOAdd("M56x4", 56, 4, 85)
 	m.ISOAdd("M64x4", 64, 4, 95)
+	// PG (DIN 40430)
+	m.PGAdd("PG7", 12.5, 20, 15)
+	m.PGAdd("PG9", 15.2, 18, 19)
+	m.PGAdd("PG11", 18.6, 18, 22)
+	m.PGAdd("PG13.5", 20.4, 18, 24)
+	m.PGAdd("PG16", 22.5, 18, 27)
+	m.PGAdd("PG21", 28.3, 16, 33)
+	m.PGAdd("PG29", 37.0, 16, 42)
+	m.PGAdd("PG36", 47.0, 16, 53)
+	m.PGAdd("PG42", 54.0, 16, 60)
+	m.PGAdd("PG48", 59.3, 16, 65)
 	return m
 }
 
@@ -235,6 +266,35 @@ func ISOThread(radius, pitch float64, mode string) SDF2 {
 	return Polygon2D(iso.Vertices())
 }
 
+// Return the 2d profile for a Whitworth (BSW/BSP) thread.
+// https://en.wikipedia.org/wiki/British_Standard_Pipe
+// 55 degree flanks with rounded crests and roots.
+// The profile is the same for internal and external threads.
+// radius = radius of thread
+// pitch = thread to thread distance
+func WhitworthThread(radius, pitch float64) SDF2 {
+
+	theta := DtoR(27.5)
+	H := pitch / (2.0 * math.Tan(theta))
+	h := (2.0 / 3.0) * H
+	r := 0.137329 * pitch
+	// the sharp V extends H/6 past the crest and root
+	r_crest := radius + H/6.0
+	r_root := radius - h - H/6.0
+
+	wt := NewPolygon()
+	wt.Add(pitch, 0)
+	wt.Add(pitch, r_crest)
+	wt.Add(pitch/2.0, r_root).Smooth(r, 5)
+	wt.Add(0, r_crest).Smooth(r, 5)
+	wt.Add(-pitch/2.0, r_root).Smooth(r, 5)
+	wt.Add(-pitch, r_crest)
+	wt.Add(-pitch, 0)
+
+	//wt.Render("whitworth.dxf")
+	return Polygon2D(wt.Vertices())
+}
+
 // Return the 2d profile for an ANSI 45/7 buttress thread.
 // https://en.wikipedia.org/wiki/Buttress_thread
 // AMSE B1.9-1973
@@ -290,6 +350,52 @@ func PlasticButtressThread(radius, pitch float64) SDF2 {
 	return Polygon2D(tp.Vertices())
 }
 
+// Return the 2d profile for a tapping screw thread.
+// Tapping threads have a narrow crest and a wide flat root so the screw
+// forms (or cuts) its own thread in softer material.
+// radius = radius of thread
+// pitch = thread to thread distance
+// angle = included flank angle (radians)
+// depth = thread depth
+func tapping_thread(radius, pitch, angle, depth float64) SDF2 {
+
+	x_crest := 0.05 * pitch
+	x_root := x_crest + depth*math.Tan(0.5*angle)
+	r_root := radius - depth
+	if x_root >= 0.5*pitch || r_root <= 0 {
+		panic("thread is too deep for the pitch/radius")
+	}
+
+	tp := NewPolygon()
+	tp.Add(pitch, 0)
+	tp.Add(pitch, r_root)
+	tp.Add(x_root, r_root)
+	tp.Add(x_crest, radius)
+	tp.Add(-x_crest, radius)
+	tp.Add(-x_root, r_root)
+	tp.Add(-pitch, r_root)
+	tp.Add(-pitch, 0)
+
+	//tp.Render("tapping.dxf")
+	return Polygon2D(tp.Vertices())
+}
+
+// Return the 2d profile for a wood screw thread.
+// 60 degree flanks, the thread depth is 0.4 * pitch.
+// radius = radius of thread
+// pitch = thread to thread distance
+func WoodScrewThread(radius, pitch float64) SDF2 {
+	return tapping_thread(radius, pitch, DtoR(60.0), 0.4*pitch)
+}
+
+// Return the 2d profile for a thread forming screw for plastics (E.g. PT, Delta PT).
+// 30 degree flanks, the thread depth is 0.5 * pitch.
+// radius = radius of thread
+// pitch = thread to thread distance
+func PlasticScrewThread(radius, pitch float64) SDF2 {
+	return tapping_thread(radius, pitch, DtoR(30.0), 0.5*pitch)
+}
+
 //-----------------------------------------------------------------------------
 
 type ScrewSDF3 struct {
@@ -298,6 +404,7 @@ type ScrewSDF3 struct {
 	lead   float64 // distance per turn (starts * pitch)
 	length float64 // total length of screw
 	starts int     // number of thread starts
+	taper  float64 // change of radius per unit length
 	bb     Box3    // bounding box
 }
 
@@ -321,11 +428,29 @@ func Screw3D(
 	return &s
 }
 
+// Return a tapered screw SDF3 (E.g. pipe threads).
+// The thread radius gets smaller by taper for each unit of +z.
+// The thread profile gives the radius at z = 0 (the middle of the screw).
+func TaperScrew3D(
+	thread SDF2, // 2D thread profile
+	length float64, // length of screw
+	pitch float64, // thread to thread distance
+	taper float64, // change of radius per unit length
+	starts int, // number of thread starts (< 0 for left hand threads)
+) SDF3 {
+	s := Screw3D(thread, length, pitch, starts).(*ScrewSDF3)
+	s.taper = taper
+	r := s.bb.Max.Y + Abs(taper)*s.length
+	s.bb = Box3{V3{-r, -r, -s.length}, V3{r, r, s.length}}
+	return s
+}
+
 func (s *ScrewSDF3) Evaluate(p V3) float64 {
 	// map the 3d point back to the xy space of the profile
 	p0 := V2{}
 	// the distance from the 3d z-axis maps to the 2d y-axis
-	p0.Y = math.Sqrt(p.X*p.X + p.Y*p.Y)
+	// (moved out for tapered threads)
+	p0.Y = math.Sqrt(p.X*p.X+p.Y*p.Y) + s.taper*p.Z
 	// the x/y angle and the z-height map to the 2d x-axis
 	// ie: the position along thread pitch
 	theta := math.Atan2(p.Y, p.X)
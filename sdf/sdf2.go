@@ -82,6 +82,13 @@ func (s *CircleSDF2) Evaluate(p V2) float64 {
 	return p.Length() - s.radius
 }
 
+// EvaluateInterval returns the range of the distance over a box.
+func (s *CircleSDF2) EvaluateInterval(b Box2) Interval {
+	x := Interval{b.Min.X, b.Max.X}
+	y := Interval{b.Min.Y, b.Max.Y}
+	return x.Sqr().Add(y.Sqr()).Sqrt().AddScalar(-s.radius)
+}
+
 func (s *CircleSDF2) BoundingBox() Box2 {
 	return s.bb
 }
@@ -143,6 +150,13 @@ func (s *BoxSDF2) Evaluate(p V2) float64 {
 	return sdf_box2d(p, s.size) - s.round
 }
 
+// EvaluateInterval returns the range of the distance over a box.
+func (s *BoxSDF2) EvaluateInterval(b Box2) Interval {
+	x := Interval{b.Min.X, b.Max.X}.Abs().AddScalar(-s.size.X)
+	y := Interval{b.Min.Y, b.Max.Y}.Abs().AddScalar(-s.size.Y)
+	return interval_box(x, y).AddScalar(-s.round)
+}
+
 func (s *BoxSDF2) BoundingBox() Box2 {
 	return s.bb
 }
@@ -206,6 +220,15 @@ func (s *OffsetSDF2) BoundingBox() Box2 {
 	return s.bb
 }
 
+// Round2D rounds all the convex corners of an SDF2 by radius r.
+// The shape grows by r (see Round3D).
+func Round2D(sdf SDF2, r float64) SDF2 {
+	if r < 0 {
+		panic("invalid round radius")
+	}
+	return Offset2D(sdf, r)
+}
+
 //-----------------------------------------------------------------------------
 // Cut an SDF2 along a line
 
@@ -400,6 +423,40 @@ func (s *ScaleUniformSDF2) BoundingBox() Box2 {
 	return s.bb
 }
 
+//-----------------------------------------------------------------------------
+// Non-uniform scaling: scale by different factors along x and y.
+// The distance is a bound. See ScaleNonUniform3D.
+
+type ScaleNonUniformSDF2 struct {
+	sdf   SDF2
+	inv_k V2      // inverse scale factors
+	k_min float64 // smallest scale factor
+	bb    Box2
+}
+
+// ScaleNonUniform2D returns an SDF2 scaled by the factors in k.
+func ScaleNonUniform2D(sdf SDF2, k V2) SDF2 {
+	if k.X <= 0 || k.Y <= 0 {
+		panic("invalid scale factors")
+	}
+	return &ScaleNonUniformSDF2{
+		sdf:   sdf,
+		inv_k: V2{1 / k.X, 1 / k.Y},
+		k_min: k.MinComponent(),
+		bb:    Scale2d(k).MulBox(sdf.BoundingBox()),
+	}
+}
+
+// Evaluate returns the minimum distance to a non-uniformly scaled SDF2.
+func (s *ScaleNonUniformSDF2) Evaluate(p V2) float64 {
+	return s.sdf.Evaluate(p.Mul(s.inv_k)) * s.k_min
+}
+
+// BoundingBox returns the bounding box of a non-uniformly scaled SDF2.
+func (s *ScaleNonUniformSDF2) BoundingBox() Box2 {
+	return s.bb
+}
+
 //-----------------------------------------------------------------------------
 
 // Center the origin of an SDF2 on it's bounding box.
@@ -465,6 +522,85 @@ func (s *ArraySDF2) BoundingBox() Box2 {
 	return s.bb
 }
 
+//-----------------------------------------------------------------------------
+// Grid Arrays: rectangular or hexagonal grids with control of each instance.
+
+// CellFunc2 is called for each cell (i, j) of a grid array.
+// It returns false to skip the cell, and a transform for the instance
+// (applied before the instance is moved to the cell position).
+type CellFunc2 func(i, j int) (bool, M33)
+
+// Return the position of a grid cell. Hex grids offset the odd rows by half a step.
+func grid_position2(i, j int, step V2, hex bool) V2 {
+	x := float64(i) * step.X
+	if hex && j&1 == 1 {
+		x += 0.5 * step.X
+	}
+	return V2{x, float64(j) * step.Y}
+}
+
+// GridArray2D returns a union of instances of an SDF2 on a rectangular or
+// hexagonal grid. The first cell is at the origin. f controls each instance,
+// nil uses every cell with no transform.
+func GridArray2D(sdf SDF2, num V2i, step V2, hex bool, f CellFunc2) SDF2 {
+	var objects []SDF2
+	for j := 0; j < num[1]; j++ {
+		for i := 0; i < num[0]; i++ {
+			m := Identity2d()
+			if f != nil {
+				var ok bool
+				ok, m = f(i, j)
+				if !ok {
+					continue
+				}
+			}
+			m = Translate2d(grid_position2(i, j, step, hex)).Mul(m)
+			objects = append(objects, Transform2D(sdf, m))
+		}
+	}
+	return Union2D(objects...)
+}
+
+//-----------------------------------------------------------------------------
+// Domain Repetition: Repeat an SDF2 by folding the query point into one cell.
+// See Repeat3D.
+
+type RepeatSDF2 struct {
+	sdf  SDF2
+	num  V2i
+	step V2
+	bb   Box2
+}
+
+// Repeat2D returns an array of copies of an SDF2 by domain repetition.
+// The first copy is at the origin.
+func Repeat2D(sdf SDF2, step V2, num V2i) SDF2 {
+	if num[0] <= 0 || num[1] <= 0 {
+		return nil
+	}
+	if (num[0] > 1 && step.X <= 0) || (num[1] > 1 && step.Y <= 0) {
+		panic("invalid repeat step")
+	}
+	s := RepeatSDF2{}
+	s.sdf = sdf
+	s.num = num
+	s.step = step
+	bb0 := sdf.BoundingBox()
+	bb1 := bb0.Translate(step.Mul(num.SubScalar(1).ToV2()))
+	s.bb = bb0.Extend(bb1)
+	return &s
+}
+
+// Evaluate returns the minimum distance to a repeated SDF2.
+func (s *RepeatSDF2) Evaluate(p V2) float64 {
+	return s.sdf.Evaluate(V2{repeat_fold(p.X, s.step.X, s.num[0]), repeat_fold(p.Y, s.step.Y, s.num[1])})
+}
+
+// BoundingBox returns the bounding box of a repeated SDF2.
+func (s *RepeatSDF2) BoundingBox() Box2 {
+	return s.bb
+}
+
 //-----------------------------------------------------------------------------
 
 type RotateUnionSDF2 struct {
@@ -539,17 +675,17 @@ func RotateCopy2D(sdf SDF2, num int) SDF2 {
 	s := RotateCopySDF2{}
 	s.sdf = sdf
 	s.theta = TAU / float64(num)
-	// work out the bounding box
-	bb := sdf.BoundingBox()
-	rmax := 0.0
-	// find the bounding box vertex with the greatest distance from the origin
-	for _, v := range bb.Vertices() {
-		l := v.Length()
-		if l > rmax {
-			rmax = l
-		}
+	// work out the bounding box from the rotated copies
+	v := sdf.BoundingBox().Vertices()
+	bb_min := v[0]
+	bb_max := v[0]
+	step := Rotate2d(s.theta)
+	for i := 0; i < num; i++ {
+		bb_min = bb_min.Min(v.Min())
+		bb_max = bb_max.Max(v.Max())
+		v.MulVertices(step)
 	}
-	s.bb = Box2{V2{-rmax, -rmax}, V2{rmax, rmax}}
+	s.bb = Box2{bb_min, bb_max}
 	return &s
 }
 
@@ -567,6 +703,139 @@ func (s *RotateCopySDF2) BoundingBox() Box2 {
 
 //-----------------------------------------------------------------------------
 
+// PolarArray2D returns the union of num copies of an SDF2 rotated about the
+// origin. Unlike RotateCopy2D the copies can overlap the sectors.
+func PolarArray2D(sdf SDF2, num int) SDF2 {
+	if num <= 0 {
+		return nil
+	}
+	return RotateUnion2D(sdf, num, Rotate2d(TAU/float64(num)))
+}
+
+//-----------------------------------------------------------------------------
+// Elongate an SDF2: split it at the center lines and stretch it. See Elongate3D.
+
+type ElongateSDF2 struct {
+	sdf SDF2
+	h   V2 // half the elongation
+	bb  Box2
+}
+
+// Elongate2D returns an SDF2 stretched by lengths along the x and y axes.
+func Elongate2D(sdf SDF2, lengths V2) SDF2 {
+	if lengths.X < 0 || lengths.Y < 0 {
+		panic("invalid elongation")
+	}
+	s := ElongateSDF2{}
+	s.sdf = sdf
+	s.h = lengths.MulScalar(0.5)
+	bb := sdf.BoundingBox()
+	s.bb = Box2{bb.Min.Sub(s.h), bb.Max.Add(s.h)}
+	return &s
+}
+
+// Evaluate returns the minimum distance to an elongated SDF2.
+func (s *ElongateSDF2) Evaluate(p V2) float64 {
+	q := p.Sub(p.Max(s.h.Negate()).Min(s.h))
+	return s.sdf.Evaluate(q)
+}
+
+// BoundingBox returns the bounding box of an elongated SDF2.
+func (s *ElongateSDF2) BoundingBox() Box2 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+// Smooth an SDF2: low pass filter the distance field. See Smooth3D.
+
+type SmoothSDF2 struct {
+	sdf    SDF2
+	radius float64
+	bb     Box2
+}
+
+// Smooth2D returns an SDF2 with the distance field smoothed over a radius.
+func Smooth2D(sdf SDF2, radius float64) SDF2 {
+	if radius <= 0 {
+		panic("invalid smoothing radius")
+	}
+	s := SmoothSDF2{}
+	s.sdf = sdf
+	s.radius = radius
+	bb := sdf.BoundingBox()
+	s.bb = Box2{bb.Min.SubScalar(radius), bb.Max.AddScalar(radius)}
+	return &s
+}
+
+// Evaluate returns the minimum distance to a smoothed SDF2.
+func (s *SmoothSDF2) Evaluate(p V2) float64 {
+	w := [3]float64{1, 2, 1}
+	d := 0.0
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			q := p.Add(V2{float64(i - 1), float64(j - 1)}.MulScalar(s.radius))
+			d += w[i] * w[j] * s.sdf.Evaluate(q)
+		}
+	}
+	return d / 16.0
+}
+
+// BoundingBox returns the bounding box of a smoothed SDF2.
+func (s *SmoothSDF2) BoundingBox() Box2 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+// Mirror and Symmetry
+
+// Mirror2D returns an SDF2 mirrored across a line (a point on the line and the line normal).
+func Mirror2D(sdf SDF2, p, n V2) SDF2 {
+	return Transform2D(sdf, MirrorLine2d(p, n))
+}
+
+type SymmetrySDF2 struct {
+	sdf  SDF2
+	x, y bool
+	bb   Box2
+}
+
+// Symmetry2D returns an SDF2 that is symmetric about the selected axes
+// (x: the y-axis, y: the x-axis). Only the +ve side of the SDF2 is used.
+func Symmetry2D(sdf SDF2, x, y bool) SDF2 {
+	s := SymmetrySDF2{}
+	s.sdf = sdf
+	s.x, s.y = x, y
+	bb := sdf.BoundingBox()
+	if x {
+		m := Max(bb.Max.X, 0)
+		bb.Min.X, bb.Max.X = -m, m
+	}
+	if y {
+		m := Max(bb.Max.Y, 0)
+		bb.Min.Y, bb.Max.Y = -m, m
+	}
+	s.bb = bb
+	return &s
+}
+
+// Evaluate returns the minimum distance to a symmetric SDF2.
+func (s *SymmetrySDF2) Evaluate(p V2) float64 {
+	if s.x {
+		p.X = Abs(p.X)
+	}
+	if s.y {
+		p.Y = Abs(p.Y)
+	}
+	return s.sdf.Evaluate(p)
+}
+
+// BoundingBox returns the bounding box of a symmetric SDF2.
+func (s *SymmetrySDF2) BoundingBox() Box2 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+
 type SliceSDF2 struct {
 	sdf SDF3 // the sdf3 being sliced
 	a   V3   // 3d point for 2d origin
@@ -628,9 +897,10 @@ func (s *SliceSDF2) BoundingBox() Box2 {
 //-----------------------------------------------------------------------------
 
 type UnionSDF2 struct {
-	sdf []SDF2
-	min MinFunc
-	bb  Box2
+	sdf     []SDF2
+	min     MinFunc
+	blended bool // min is not Min
+	bb      Box2
 }
 
 // Union2D returns the union of multiple SDF2 objects.
@@ -714,6 +984,7 @@ func (s *UnionSDF2) Evaluate_Slow(p V2) float64 {
 // Set the minimum function to control blending.
 func (s *UnionSDF2) SetMin(min MinFunc) {
 	s.min = min
+	s.blended = true
 }
 
 // Return the bounding box.
@@ -725,10 +996,11 @@ func (s *UnionSDF2) BoundingBox() Box2 {
 
 // Difference of SDF2s
 type DifferenceSDF2 struct {
-	s0  SDF2
-	s1  SDF2
-	max MaxFunc
-	bb  Box2
+	s0      SDF2
+	s1      SDF2
+	max     MaxFunc
+	blended bool // max is not Max
+	bb      Box2
 }
 
 // Return the difference of two SDF2 objects, s0 - s1.
@@ -755,6 +1027,7 @@ func (s *DifferenceSDF2) Evaluate(p V2) float64 {
 // Set the maximum function to control blending.
 func (s *DifferenceSDF2) SetMax(max MaxFunc) {
 	s.max = max
+	s.blended = true
 }
 
 // Return the bounding box.
@@ -122,11 +122,102 @@ func (s *SorSDF3) Evaluate(p V3) float64 {
 	return Max(a, b)
 }
 
+// EvaluateInterval returns the range of the distance over a box.
+func (s *SorSDF3) EvaluateInterval(b Box3) Interval {
+	if s.theta != 0 {
+		return lipschitz_interval(s, b)
+	}
+	x, y, z := box_intervals(b)
+	r := x.Sqr().Add(y.Sqr()).Sqrt()
+	return EvaluateInterval2(s.sdf, Box2{V2{r.Lo, z.Lo}, V2{r.Hi, z.Hi}})
+}
+
 // Return the bounding box for a solid of revolution.
 func (s *SorSDF3) BoundingBox() Box3 {
 	return s.bb
 }
 
+//-----------------------------------------------------------------------------
+// Helix Extrude, SDF2 to SDF3
+// Sweep a profile along a helix (E.g. augers, springs, custom threads).
+// The profile is in the same plane as for Revolve3D (x = radius, y = z).
+// Note: The distance is measured in the plane of the profile, so it is
+// overestimated where the pitch is large relative to the radius.
+
+type HelixSDF3 struct {
+	sdf   SDF2
+	pitch float64 // rise per turn
+	theta float64 // total sweep angle
+	bb    Box3
+}
+
+// HelixExtrude3D returns an SDF3 for a profile swept along a helix.
+// The profile starts on the x-axis and turns counter-clockwise, rising by
+// pitch per turn. Use a -ve pitch for a left hand helix.
+func HelixExtrude3D(sdf SDF2, pitch, turns float64) SDF3 {
+	if pitch == 0 {
+		panic("invalid helix pitch")
+	}
+	if turns <= 0 {
+		panic("invalid number of turns")
+	}
+	s := HelixSDF3{}
+	s.sdf = sdf
+	s.pitch = pitch
+	s.theta = turns * TAU
+	// work out the bounding box
+	bb := sdf.BoundingBox()
+	if bb.Min.X < 0 {
+		panic("the helix profile must have x >= 0")
+	}
+	r := bb.Max.X
+	h := pitch * turns
+	s.bb = Box3{V3{-r, -r, bb.Min.Y + Min(h, 0)}, V3{r, r, bb.Max.Y + Max(h, 0)}}
+	return &s
+}
+
+// Return the distance to an end cap of the helix.
+// The end cap is the profile in the vertical plane at angle theta.
+func (s *HelixSDF3) cap(p V3, theta float64) float64 {
+	q := Rotate(-theta).MulPosition(V2{p.X, p.Y})
+	a := s.sdf.Evaluate(V2{q.X, p.Z - s.pitch*theta/TAU})
+	if a < 0 {
+		return Abs(q.Y)
+	}
+	return math.Sqrt(a*a + q.Y*q.Y)
+}
+
+// Evaluate returns the minimum distance to a helix extrusion.
+func (s *HelixSDF3) Evaluate(p V3) float64 {
+	r := math.Sqrt(p.X*p.X + p.Y*p.Y)
+	theta := math.Atan2(p.Y, p.X)
+	if theta < 0 {
+		theta += TAU
+	}
+	// Only the turns of the helix near p.Z need to be considered.
+	bb := s.sdf.BoundingBox()
+	kc := (p.Z-bb.Center().Y)/s.pitch - theta/TAU
+	n := math.Ceil(Abs(0.5*bb.Size().Y/s.pitch)) + 1
+	k0 := Max(math.Floor(kc-n), 0)
+	k1 := Min(math.Ceil(kc+n), math.Floor((s.theta-theta)/TAU))
+	d := math.MaxFloat64
+	for k := k0; k <= k1; k++ {
+		t := theta + k*TAU
+		d = Min(d, s.sdf.Evaluate(V2{r, p.Z - s.pitch*t/TAU}))
+	}
+	// the end caps
+	c := Min(s.cap(p, 0), s.cap(p, s.theta))
+	if d < 0 {
+		return Max(d, -c)
+	}
+	return Min(d, c)
+}
+
+// BoundingBox returns the bounding box for a helix extrusion.
+func (s *HelixSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
 //-----------------------------------------------------------------------------
 
 // Extrude, SDF2 to SDF3
@@ -134,6 +225,9 @@ type ExtrudeSDF3 struct {
 	sdf     SDF2
 	height  float64
 	extrude ExtrudeFunc
+	twist   float64 // twist extrusion (radians)
+	scale   V2      // scale extrusion (0 for none)
+	custom  bool    // extrude was set with SetExtrude
 	bb      Box3
 }
 
@@ -150,24 +244,28 @@ func Extrude3D(sdf SDF2, height float64) SDF3 {
 }
 
 // Twist Extrude - rotate by twist radians over the height of the extrusion
+// The profile rotates clockwise (viewed from above) moving up for +ve twist.
 func TwistExtrude3D(sdf SDF2, height, twist float64) SDF3 {
 	s := ExtrudeSDF3{}
 	s.sdf = sdf
 	s.height = height / 2
 	s.extrude = TwistExtrude(height, twist)
+	s.twist = twist
 	// work out the bounding box
 	bb := sdf.BoundingBox()
-	l := bb.Max.Length()
+	l := bb.Min.Abs().Max(bb.Max.Abs()).Length()
 	s.bb = Box3{V3{-l, -l, -s.height}, V3{l, l, s.height}}
 	return &s
 }
 
 // Scale Extrude - scale over the height of the extrusion
+// The bottom profile is unscaled, the top profile is scaled by scale.
 func ScaleExtrude3D(sdf SDF2, height float64, scale V2) SDF3 {
 	s := ExtrudeSDF3{}
 	s.sdf = sdf
 	s.height = height / 2
 	s.extrude = ScaleExtrude(height, scale)
+	s.scale = scale
 	// work out the bounding box
 	bb := sdf.BoundingBox()
 	bb = bb.Extend(Box2{bb.Min.Mul(scale), bb.Max.Mul(scale)})
@@ -181,10 +279,12 @@ func ScaleTwistExtrude3D(sdf SDF2, height, twist float64, scale V2) SDF3 {
 	s.sdf = sdf
 	s.height = height / 2
 	s.extrude = ScaleTwistExtrude(height, twist, scale)
+	s.twist = twist
+	s.scale = scale
 	// work out the bounding box
 	bb := sdf.BoundingBox()
 	bb = bb.Extend(Box2{bb.Min.Mul(scale), bb.Max.Mul(scale)})
-	l := bb.Max.Length()
+	l := bb.Min.Abs().Max(bb.Max.Abs()).Length()
 	s.bb = Box3{V3{-l, -l, -s.height}, V3{l, l, s.height}}
 	return &s
 }
@@ -198,9 +298,25 @@ func (s *ExtrudeSDF3) Evaluate(p V3) float64 {
 	return Max(a, b)
 }
 
+// Return true for a linear extrusion (no twist, scale or custom extrusion).
+func (s *ExtrudeSDF3) plain() bool {
+	return s.twist == 0 && s.scale == (V2{}) && !s.custom
+}
+
+// EvaluateInterval returns the range of the distance over a box.
+func (s *ExtrudeSDF3) EvaluateInterval(b Box3) Interval {
+	if !s.plain() {
+		return lipschitz_interval(s, b)
+	}
+	a := EvaluateInterval2(s.sdf, Box2{V2{b.Min.X, b.Min.Y}, V2{b.Max.X, b.Max.Y}})
+	_, _, z := box_intervals(b)
+	return a.Max(z.Abs().AddScalar(-s.height))
+}
+
 // Set the evaluation function to control extrusion.
 func (s *ExtrudeSDF3) SetExtrude(extrude ExtrudeFunc) {
 	s.extrude = extrude
+	s.custom = true
 }
 
 func (s *ExtrudeSDF3) BoundingBox() Box3 {
@@ -266,6 +382,13 @@ func (s *ExtrudeRoundedSDF3) Evaluate(p V3) float64 {
 	return d - s.round
 }
 
+// EvaluateInterval returns the range of the distance over a box.
+func (s *ExtrudeRoundedSDF3) EvaluateInterval(b Box3) Interval {
+	a := EvaluateInterval2(s.sdf, Box2{V2{b.Min.X, b.Min.Y}, V2{b.Max.X, b.Max.Y}})
+	_, _, z := box_intervals(b)
+	return interval_box(a, z.Abs().AddScalar(-s.height)).AddScalar(-s.round)
+}
+
 func (s *ExtrudeRoundedSDF3) BoundingBox() Box3 {
 	return s.bb
 }
@@ -335,6 +458,124 @@ func (s *LoftSDF3) BoundingBox() Box3 {
 	return s.bb
 }
 
+//-----------------------------------------------------------------------------
+// Multiple Section Loft
+// Blend between a stack of 2D cross sections as we move from bottom to top.
+
+type MultiLoftSDF3 struct {
+	sdf    []SDF2    // cross sections
+	height []float64 // z height of each cross section
+	smooth bool      // use a spline blend
+	bb     Box3
+}
+
+// MultiLoft3D returns an SDF3 that blends between a stack of 2D cross sections.
+// The sections are at increasing z heights. If smooth is true the blend is a
+// monotone cubic spline (no kinks at the sections, no overshoot), otherwise
+// the blend is linear.
+func MultiLoft3D(sections []SDF2, heights []float64, smooth bool) SDF3 {
+	n := len(sections)
+	if n < 2 {
+		panic("need at least 2 sections")
+	}
+	if len(heights) != n {
+		panic("need a height for each section")
+	}
+	for i := 1; i < n; i++ {
+		if heights[i] <= heights[i-1] {
+			panic("section heights must be increasing")
+		}
+	}
+	s := MultiLoftSDF3{}
+	s.sdf = sections
+	s.height = heights
+	s.smooth = smooth
+	// work out the bounding box
+	bb := sections[0].BoundingBox()
+	for _, x := range sections[1:] {
+		bb = bb.Extend(x.BoundingBox())
+	}
+	s.bb = Box3{V3{bb.Min.X, bb.Min.Y, heights[0]}, V3{bb.Max.X, bb.Max.Y, heights[n-1]}}
+	return &s
+}
+
+// Return the slope of the section distances (wrt z) at the i-th section.
+func (s *MultiLoftSDF3) slope(i int, p V2) float64 {
+	i0 := Max(float64(i-1), 0)
+	i1 := Min(float64(i+1), float64(len(s.sdf)-1))
+	j0, j1 := int(i0), int(i1)
+	return (s.sdf[j1].Evaluate(p) - s.sdf[j0].Evaluate(p)) / (s.height[j1] - s.height[j0])
+}
+
+// Return the blended section distance at height z.
+func (s *MultiLoftSDF3) blend(p V2, z float64) float64 {
+	n := len(s.height)
+	z = Clamp(z, s.height[0], s.height[n-1])
+	// find the sections above and below z
+	i := 0
+	for i < n-2 && z > s.height[i+1] {
+		i++
+	}
+	h := s.height[i+1] - s.height[i]
+	k := (z - s.height[i]) / h
+	a0 := s.sdf[i].Evaluate(p)
+	a1 := s.sdf[i+1].Evaluate(p)
+	if !s.smooth {
+		return Mix(a0, a1, k)
+	}
+	// monotone cubic hermite (Fritsch-Carlson)
+	m0 := s.slope(i, p)
+	m1 := s.slope(i+1, p)
+	delta := (a1 - a0) / h
+	if delta == 0 {
+		m0, m1 = 0, 0
+	} else {
+		alpha := m0 / delta
+		beta := m1 / delta
+		if alpha < 0 {
+			m0, alpha = 0, 0
+		}
+		if beta < 0 {
+			m1, beta = 0, 0
+		}
+		if r := alpha*alpha + beta*beta; r > 9 {
+			tau := 3 / math.Sqrt(r)
+			m0 = tau * alpha * delta
+			m1 = tau * beta * delta
+		}
+	}
+	k2 := k * k
+	k3 := k2 * k
+	return (2*k3-3*k2+1)*a0 + (k3-2*k2+k)*h*m0 + (-2*k3+3*k2)*a1 + (k3-k2)*h*m1
+}
+
+// Evaluate returns the minimum distance to the multiple section loft.
+func (s *MultiLoftSDF3) Evaluate(p V3) float64 {
+	a := s.blend(V2{p.X, p.Y}, p.Z)
+	b := Max(s.height[0]-p.Z, p.Z-s.height[len(s.height)-1])
+	if b > 0 {
+		// outside the object Z extent
+		if a < 0 {
+			// inside the boundary
+			return b
+		}
+		// outside the boundary
+		return math.Sqrt((a * a) + (b * b))
+	}
+	// within the object Z extent
+	if a < 0 {
+		// inside the boundary
+		return Max(a, b)
+	}
+	// outside the boundary
+	return a
+}
+
+// BoundingBox returns the bounding box of the multiple section loft.
+func (s *MultiLoftSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
 //-----------------------------------------------------------------------------
 // Box (exact distance field)
 
@@ -360,11 +601,76 @@ func (s *BoxSDF3) Evaluate(p V3) float64 {
 	return sdf_box3d(p, s.size) - s.round
 }
 
+// EvaluateInterval returns the range of the distance to a box over a box.
+func (s *BoxSDF3) EvaluateInterval(b Box3) Interval {
+	x, y, z := box_intervals(b)
+	d := interval_box(x.Abs().AddScalar(-s.size.X), y.Abs().AddScalar(-s.size.Y), z.Abs().AddScalar(-s.size.Z))
+	return d.AddScalar(-s.round)
+}
+
+// Gradient returns the gradient of the distance to a box.
+func (s *BoxSDF3) Gradient(p V3, eps float64) V3 {
+	k := V3{sign_nz(p.X), sign_nz(p.Y), sign_nz(p.Z)}
+	return gradient_box(p.Abs().Sub(s.size), k)
+}
+
 // Return the bounding box for a box.
 func (s *BoxSDF3) BoundingBox() Box3 {
 	return s.bb
 }
 
+//-----------------------------------------------------------------------------
+// Box Frame (exact distance field)
+// The 12 edges of a box as square bars.
+
+type BoxFrameSDF3 struct {
+	size  V3      // half size (less rounding)
+	e     float64 // half bar thickness (less rounding)
+	round float64
+	bb    Box3
+}
+
+// BoxFrame3D returns the frame of a box (rounded edges with round > 0).
+// The bars have a square section of the given thickness, they are inside the box.
+func BoxFrame3D(size V3, thickness, round float64) SDF3 {
+	size = size.MulScalar(0.5)
+	if thickness <= 0 || thickness > size.MinComponent() || round < 0 || round > 0.5*thickness {
+		panic("invalid box frame")
+	}
+	s := BoxFrameSDF3{}
+	s.size = size.SubScalar(round)
+	s.e = 0.5*thickness - round
+	s.round = round
+	s.bb = Box3{size.Negate(), size}
+	return &s
+}
+
+// BoundingWire3D returns a box frame around the bounding box of an SDF3.
+func BoundingWire3D(sdf SDF3, thickness float64) SDF3 {
+	bb := sdf.BoundingBox()
+	s := BoxFrame3D(bb.Size().AddScalar(2.0*thickness), thickness, 0)
+	return Transform3D(s, Translate3d(bb.Center()))
+}
+
+// Return the minimum distance to a box frame.
+// See: https://iquilezles.org/articles/distfunctions/
+func (s *BoxFrameSDF3) Evaluate(p V3) float64 {
+	p = p.Abs().Sub(s.size)
+	q := p.AddScalar(s.e).Abs().SubScalar(s.e)
+	bar := func(a V3) float64 {
+		return a.Max(V3{0, 0, 0}).Length() + Min(a.MaxComponent(), 0)
+	}
+	d := bar(V3{p.X, q.Y, q.Z})
+	d = Min(d, bar(V3{q.X, p.Y, q.Z}))
+	d = Min(d, bar(V3{q.X, q.Y, p.Z}))
+	return d - s.round
+}
+
+// Return the bounding box for a box frame.
+func (s *BoxFrameSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
 //-----------------------------------------------------------------------------
 // Sphere (exact distance field)
 
@@ -388,11 +694,58 @@ func (s *SphereSDF3) Evaluate(p V3) float64 {
 	return p.Length() - s.radius
 }
 
+// EvaluateInterval returns the range of the distance to a sphere over a box.
+func (s *SphereSDF3) EvaluateInterval(b Box3) Interval {
+	x, y, z := box_intervals(b)
+	return x.Sqr().Add(y.Sqr()).Add(z.Sqr()).Sqrt().AddScalar(-s.radius)
+}
+
+// Gradient returns the gradient of the distance to a sphere.
+func (s *SphereSDF3) Gradient(p V3, eps float64) V3 {
+	if p.Length() == 0 {
+		return V3{0, 0, 1}
+	}
+	return p.Normalize()
+}
+
 // Return the bounding box for a sphere.
 func (s *SphereSDF3) BoundingBox() Box3 {
 	return s.bb
 }
 
+//-----------------------------------------------------------------------------
+// Ellipsoid (bounded distance field)
+
+type EllipsoidSDF3 struct {
+	radius V3
+	min    float64 // minimum radius
+	bb     Box3
+}
+
+// Ellipsoid3D returns an SDF3 for an ellipsoid with x, y and z radii.
+func Ellipsoid3D(radius V3) SDF3 {
+	s := EllipsoidSDF3{}
+	s.radius = radius
+	s.min = Min(radius.X, Min(radius.Y, radius.Z))
+	if s.min <= 0 {
+		panic("invalid ellipsoid radius")
+	}
+	s.bb = Box3{radius.Negate(), radius}
+	return &s
+}
+
+// Evaluate returns the minimum distance to an ellipsoid.
+// The distance is a lower bound (it is exact for a sphere).
+func (s *EllipsoidSDF3) Evaluate(p V3) float64 {
+	k := V3{p.X / s.radius.X, p.Y / s.radius.Y, p.Z / s.radius.Z}.Length()
+	return (k - 1) * s.min
+}
+
+// BoundingBox returns the bounding box for an ellipsoid.
+func (s *EllipsoidSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
 //-----------------------------------------------------------------------------
 // Cylinder (exact distance field)
 
@@ -426,6 +779,29 @@ func (s *CylinderSDF3) Evaluate(p V3) float64 {
 	return d - s.round
 }
 
+// EvaluateInterval returns the range of the distance to a cylinder over a box.
+func (s *CylinderSDF3) EvaluateInterval(b Box3) Interval {
+	x, y, z := box_intervals(b)
+	r := x.Sqr().Add(y.Sqr()).Sqrt()
+	d := interval_box(r.AddScalar(-s.radius), z.Abs().AddScalar(-s.height))
+	return d.AddScalar(-s.round)
+}
+
+// Gradient returns the gradient of the distance to a cylinder.
+func (s *CylinderSDF3) Gradient(p V3, eps float64) V3 {
+	r := V2{p.X, p.Y}
+	rho := r.Length()
+	if rho == 0 {
+		r = V2{1, 0}
+	} else {
+		r = r.DivScalar(rho)
+	}
+	// the gradient in the (radius, z) plane
+	d := V3{rho - s.radius, Abs(p.Z) - s.height, -math.MaxFloat64}
+	g := gradient_box(d, V3{1, sign_nz(p.Z), 0})
+	return V3{g.X * r.X, g.X * r.Y, g.Y}
+}
+
 // Return the bounding box for a cylinder.
 func (s *CylinderSDF3) BoundingBox() Box3 {
 	return s.bb
@@ -568,6 +944,16 @@ func (s *TransformSDF3) Evaluate(p V3) float64 {
 	return s.sdf.Evaluate(s.inverse.MulPosition(p))
 }
 
+// EvaluateInterval returns the range of the distance over a box.
+func (s *TransformSDF3) EvaluateInterval(b Box3) Interval {
+	return EvaluateInterval(s.sdf, s.inverse.MulBox(b))
+}
+
+// Gradient returns the gradient of the distance.
+func (s *TransformSDF3) Gradient(p V3, eps float64) V3 {
+	return s.inverse.mul_transpose_direction(Gradient3D(s.sdf, s.inverse.MulPosition(p), eps))
+}
+
 func (s *TransformSDF3) BoundingBox() Box3 {
 	return s.bb
 }
@@ -598,17 +984,73 @@ func (s *ScaleUniformSDF3) Evaluate(p V3) float64 {
 	return s.sdf.Evaluate(q) * s.k
 }
 
+// EvaluateInterval returns the range of the distance over a box.
+func (s *ScaleUniformSDF3) EvaluateInterval(b Box3) Interval {
+	b = Scale3d(V3{s.inv_k, s.inv_k, s.inv_k}).MulBox(b)
+	return EvaluateInterval(s.sdf, b).MulScalar(s.k)
+}
+
+// Gradient returns the gradient of the distance.
+func (s *ScaleUniformSDF3) Gradient(p V3, eps float64) V3 {
+	return Gradient3D(s.sdf, p.MulScalar(s.inv_k), eps*Abs(s.inv_k))
+}
+
 func (s *ScaleUniformSDF3) BoundingBox() Box3 {
 	return s.bb
 }
 
+//-----------------------------------------------------------------------------
+// Non-uniform scaling: scale by different factors along x, y and z.
+// The scaled distance is divided by the largest inverse scale factor (it is
+// multiplied by the smallest scale factor). This is a bound: it is never
+// larger than the true distance so ray marching and rendering are safe.
+// It is exact for a uniform scale, and along the axis with the smallest scale.
+// The surface (distance = 0) is always in the right place.
+
+type ScaleNonUniformSDF3 struct {
+	sdf   SDF3
+	inv_k V3      // inverse scale factors
+	k_min float64 // smallest scale factor
+	bb    Box3
+}
+
+// ScaleNonUniform3D returns an SDF3 scaled by the factors in k.
+func ScaleNonUniform3D(sdf SDF3, k V3) SDF3 {
+	if k.X <= 0 || k.Y <= 0 || k.Z <= 0 {
+		panic("invalid scale factors")
+	}
+	return &ScaleNonUniformSDF3{
+		sdf:   sdf,
+		inv_k: V3{1 / k.X, 1 / k.Y, 1 / k.Z},
+		k_min: k.MinComponent(),
+		bb:    Scale3d(k).MulBox(sdf.BoundingBox()),
+	}
+}
+
+// Evaluate returns the minimum distance to a non-uniformly scaled SDF3.
+func (s *ScaleNonUniformSDF3) Evaluate(p V3) float64 {
+	return s.sdf.Evaluate(p.Mul(s.inv_k)) * s.k_min
+}
+
+// EvaluateInterval returns the range of the distance over a box.
+func (s *ScaleNonUniformSDF3) EvaluateInterval(b Box3) Interval {
+	b = Box3{b.Min.Mul(s.inv_k), b.Max.Mul(s.inv_k)}
+	return EvaluateInterval(s.sdf, b).MulScalar(s.k_min)
+}
+
+// BoundingBox returns the bounding box of a non-uniformly scaled SDF3.
+func (s *ScaleNonUniformSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
 //-----------------------------------------------------------------------------
 // Union of SDF3s
 
 type UnionSDF3 struct {
-	sdf []SDF3
-	min MinFunc
-	bb  Box3
+	sdf     []SDF3
+	min     MinFunc
+	blended bool // min is not Min
+	bb      Box3
 }
 
 // Union3D returns the union of multiple SDF3 objects.
@@ -657,6 +1099,33 @@ func (s *UnionSDF3) Evaluate(p V3) float64 {
 // Set the minimum function to control blending.
 func (s *UnionSDF3) SetMin(min MinFunc) {
 	s.min = min
+	s.blended = true
+}
+
+// EvaluateInterval returns the range of the distance over a box.
+func (s *UnionSDF3) EvaluateInterval(b Box3) Interval {
+	if s.blended {
+		return lipschitz_interval(s, b)
+	}
+	d := EvaluateInterval(s.sdf[0], b)
+	for _, x := range s.sdf[1:] {
+		d = d.Min(EvaluateInterval(x, b))
+	}
+	return d
+}
+
+// Gradient returns the gradient of the distance.
+func (s *UnionSDF3) Gradient(p V3, eps float64) V3 {
+	if s.blended {
+		return numeric_gradient(s, p, eps)
+	}
+	i, dmin := 0, s.sdf[0].Evaluate(p)
+	for j, x := range s.sdf[1:] {
+		if d := x.Evaluate(p); d < dmin {
+			i, dmin = j+1, d
+		}
+	}
+	return Gradient3D(s.sdf[i], p, eps)
 }
 
 // Return the bounding box.
@@ -668,10 +1137,11 @@ func (s *UnionSDF3) BoundingBox() Box3 {
 
 // Difference of SDF3s
 type DifferenceSDF3 struct {
-	s0  SDF3
-	s1  SDF3
-	max MaxFunc
-	bb  Box3
+	s0      SDF3
+	s1      SDF3
+	max     MaxFunc
+	blended bool // max is not Max
+	bb      Box3
 }
 
 // Return the difference of two SDF3 objects, s0 - s1.
@@ -698,6 +1168,26 @@ func (s *DifferenceSDF3) Evaluate(p V3) float64 {
 // Set the maximum function to control blending.
 func (s *DifferenceSDF3) SetMax(max MaxFunc) {
 	s.max = max
+	s.blended = true
+}
+
+// EvaluateInterval returns the range of the distance over a box.
+func (s *DifferenceSDF3) EvaluateInterval(b Box3) Interval {
+	if s.blended {
+		return lipschitz_interval(s, b)
+	}
+	return EvaluateInterval(s.s0, b).Max(EvaluateInterval(s.s1, b).Neg())
+}
+
+// Gradient returns the gradient of the distance.
+func (s *DifferenceSDF3) Gradient(p V3, eps float64) V3 {
+	if s.blended {
+		return numeric_gradient(s, p, eps)
+	}
+	if s.s0.Evaluate(p) >= -s.s1.Evaluate(p) {
+		return Gradient3D(s.s0, p, eps)
+	}
+	return Gradient3D(s.s1, p, eps).Neg()
 }
 
 // Return the bounding box.
@@ -709,10 +1199,11 @@ func (s *DifferenceSDF3) BoundingBox() Box3 {
 
 // Intersection of SDF3s
 type IntersectionSDF3 struct {
-	s0  SDF3
-	s1  SDF3
-	max MaxFunc
-	bb  Box3
+	s0      SDF3
+	s1      SDF3
+	max     MaxFunc
+	blended bool // max is not Max
+	bb      Box3
 }
 
 // Return the intersection of two SDF3 objects, s0 with s1.
@@ -737,6 +1228,26 @@ func (s *IntersectionSDF3) Evaluate(p V3) float64 {
 // Set the maximum function to control blending.
 func (s *IntersectionSDF3) SetMax(max MaxFunc) {
 	s.max = max
+	s.blended = true
+}
+
+// EvaluateInterval returns the range of the distance over a box.
+func (s *IntersectionSDF3) EvaluateInterval(b Box3) Interval {
+	if s.blended {
+		return lipschitz_interval(s, b)
+	}
+	return EvaluateInterval(s.s0, b).Max(EvaluateInterval(s.s1, b))
+}
+
+// Gradient returns the gradient of the distance.
+func (s *IntersectionSDF3) Gradient(p V3, eps float64) V3 {
+	if s.blended {
+		return numeric_gradient(s, p, eps)
+	}
+	if s.s0.Evaluate(p) >= s.s1.Evaluate(p) {
+		return Gradient3D(s.s0, p, eps)
+	}
+	return Gradient3D(s.s1, p, eps)
 }
 
 // Return the bounding box.
@@ -744,6 +1255,428 @@ func (s *IntersectionSDF3) BoundingBox() Box3 {
 	return s.bb
 }
 
+//-----------------------------------------------------------------------------
+// Smooth Booleans: Blend the joins with a polynomial smooth min/max.
+// k (the first argument) is the size of the blend. For other blends use SetMin/SetMax.
+
+// SmoothUnion3D returns the union of SDF3 objects with blended joins.
+func SmoothUnion3D(k float64, sdf ...SDF3) SDF3 {
+	s := Union3D(sdf...)
+	if u, ok := s.(*UnionSDF3); ok {
+		u.SetMin(PolyMin(k))
+		// the blend can add up to k/4 to the surface
+		u.bb = Box3{u.bb.Min.SubScalar(0.25 * k), u.bb.Max.AddScalar(0.25 * k)}
+	}
+	return s
+}
+
+// SmoothDifference3D returns the difference of two SDF3 objects (s0 - s1) with blended joins.
+func SmoothDifference3D(k float64, s0, s1 SDF3) SDF3 {
+	s := Difference3D(s0, s1)
+	if d, ok := s.(*DifferenceSDF3); ok {
+		d.SetMax(PolyMax(k))
+	}
+	return s
+}
+
+// SmoothIntersect3D returns the intersection of two SDF3 objects with blended joins.
+func SmoothIntersect3D(k float64, s0, s1 SDF3) SDF3 {
+	s := Intersect3D(s0, s1)
+	if i, ok := s.(*IntersectionSDF3); ok {
+		i.SetMax(PolyMax(k))
+	}
+	return s
+}
+
+//-----------------------------------------------------------------------------
+// Chamfered Booleans: Add a flat chamfer at the joins. Chamfers print better
+// than fillets on overhangs. The chamfer face is at angle theta (E.g. PI/4)
+// to the surface of the first object, k is the width of the chamfer along that
+// surface.
+
+// ChamferUnion3D returns the union of SDF3 objects with chamfered joins.
+func ChamferUnion3D(k, theta float64, sdf ...SDF3) SDF3 {
+	s := Union3D(sdf...)
+	if u, ok := s.(*UnionSDF3); ok {
+		u.SetMin(ChamferAngleMin(k, theta))
+		// the chamfer can add up to k to the surface
+		u.bb = Box3{u.bb.Min.SubScalar(k), u.bb.Max.AddScalar(k)}
+	}
+	return s
+}
+
+// ChamferDifference3D returns the difference of two SDF3 objects (s0 - s1) with a chamfered join.
+func ChamferDifference3D(k, theta float64, s0, s1 SDF3) SDF3 {
+	s := Difference3D(s0, s1)
+	if d, ok := s.(*DifferenceSDF3); ok {
+		d.SetMax(ChamferAngleMax(k, theta))
+	}
+	return s
+}
+
+//-----------------------------------------------------------------------------
+// Variable Fillets: A union blended with a fillet size that varies with position.
+
+// FilletFunc returns the fillet size at a point.
+type FilletFunc func(p V3) float64
+
+// LinearFillet returns a fillet size that varies linearly from k0 at p0 to k1 at p1.
+// The size is constant beyond the end points.
+func LinearFillet(p0, p1 V3, k0, k1 float64) FilletFunc {
+	v := p1.Sub(p0)
+	l2 := v.Dot(v)
+	if l2 == 0 {
+		panic("p0 == p1")
+	}
+	return func(p V3) float64 {
+		t := Clamp(p.Sub(p0).Dot(v)/l2, 0, 1)
+		return Mix(k0, k1, t)
+	}
+}
+
+type FilletUnionSDF3 struct {
+	s0, s1 SDF3
+	fillet FilletFunc
+	bb     Box3
+}
+
+// FilletedUnion3D returns the union of two SDF3 objects with a variable size fillet.
+// The bounding box allows for the largest fillet size at the corners and center
+// of the union bounding box (E.g. a LinearFillet).
+func FilletedUnion3D(s0, s1 SDF3, fillet FilletFunc) SDF3 {
+	if s0 == nil {
+		return s1
+	}
+	if s1 == nil {
+		return s0
+	}
+	s := FilletUnionSDF3{}
+	s.s0 = s0
+	s.s1 = s1
+	s.fillet = fillet
+	bb := s0.BoundingBox().Extend(s1.BoundingBox())
+	k := fillet(bb.Center())
+	for _, v := range bb.Vertices() {
+		k = Max(k, fillet(v))
+	}
+	// the blend can add up to k/4 to the surface
+	s.bb = Box3{bb.Min.SubScalar(0.25 * k), bb.Max.AddScalar(0.25 * k)}
+	return &s
+}
+
+// Evaluate returns the minimum distance to the filleted union.
+func (s *FilletUnionSDF3) Evaluate(p V3) float64 {
+	a := s.s0.Evaluate(p)
+	b := s.s1.Evaluate(p)
+	k := s.fillet(p)
+	if k <= 0 {
+		return Min(a, b)
+	}
+	return Poly(a, b, k)
+}
+
+// BoundingBox returns the bounding box of the filleted union.
+func (s *FilletUnionSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+
+type OffsetSDF3 struct {
+	sdf    SDF3
+	offset float64
+	bb     Box3
+}
+
+// Offset an SDF3 - add a constant to the distance function
+// offset > 0, enlarges and adds rounding to convex corners of the SDF
+// offset < 0, skeletonizes the SDF
+func Offset3D(sdf SDF3, offset float64) SDF3 {
+	s := OffsetSDF3{}
+	s.sdf = sdf
+	s.offset = offset
+	// work out the bounding box
+	bb := sdf.BoundingBox()
+	s.bb = NewBox3(bb.Center(), bb.Size().AddScalar(2*offset))
+	return &s
+}
+
+func (s *OffsetSDF3) Evaluate(p V3) float64 {
+	return s.sdf.Evaluate(p) - s.offset
+}
+
+// EvaluateInterval returns the range of the distance over a box.
+func (s *OffsetSDF3) EvaluateInterval(b Box3) Interval {
+	return EvaluateInterval(s.sdf, b).AddScalar(-s.offset)
+}
+
+// Gradient returns the gradient of the distance.
+func (s *OffsetSDF3) Gradient(p V3, eps float64) V3 {
+	return Gradient3D(s.sdf, p, eps)
+}
+
+func (s *OffsetSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+// Round3D rounds all the convex edges and corners of an SDF3 by radius r.
+// The solid grows by r, shrink the parts it is made from by r to keep the
+// overall size. The rounding is exact where the distance outside the SDF3 is
+// exact (E.g. primitives, unions), intersections and differences only bound
+// the distance so their edges get a smaller radius.
+func Round3D(sdf SDF3, r float64) SDF3 {
+	if r < 0 {
+		panic("invalid round radius")
+	}
+	return Offset3D(sdf, r)
+}
+
+//-----------------------------------------------------------------------------
+// Elongate an SDF3: split it at the center planes and stretch it.
+// E.g. sphere -> capsule, cylinder -> slot. The SDF3 should be centered on
+// the origin. The distance is exact outside and a bound inside.
+
+type ElongateSDF3 struct {
+	sdf SDF3
+	h   V3 // half the elongation
+	bb  Box3
+}
+
+// Elongate3D returns an SDF3 stretched by lengths along the x, y and z axes.
+func Elongate3D(sdf SDF3, lengths V3) SDF3 {
+	if lengths.X < 0 || lengths.Y < 0 || lengths.Z < 0 {
+		panic("invalid elongation")
+	}
+	s := ElongateSDF3{}
+	s.sdf = sdf
+	s.h = lengths.MulScalar(0.5)
+	bb := sdf.BoundingBox()
+	s.bb = Box3{bb.Min.Sub(s.h), bb.Max.Add(s.h)}
+	return &s
+}
+
+// Evaluate returns the minimum distance to an elongated SDF3.
+func (s *ElongateSDF3) Evaluate(p V3) float64 {
+	q := p.Sub(p.Max(s.h.Negate()).Min(s.h))
+	return s.sdf.Evaluate(q)
+}
+
+// BoundingBox returns the bounding box of an elongated SDF3.
+func (s *ElongateSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+// Shell an SDF3
+
+type ShellSDF3 struct {
+	sdf   SDF3
+	delta float64 // half shell thickness
+	bb    Box3
+}
+
+// Shell3D returns an SDF3 that shells the surface of an existing SDF3.
+// The shell is centered on the surface. Use Cut3D or DrainHoles3D to open the shell.
+func Shell3D(sdf SDF3, thickness float64) SDF3 {
+	if thickness <= 0 {
+		panic("invalid shell thickness")
+	}
+	s := ShellSDF3{}
+	s.sdf = sdf
+	s.delta = 0.5 * thickness
+	bb := sdf.BoundingBox()
+	s.bb = Box3{bb.Min.SubScalar(s.delta), bb.Max.AddScalar(s.delta)}
+	return &s
+}
+
+// Evaluate returns the minimum distance to a shelled SDF3.
+func (s *ShellSDF3) Evaluate(p V3) float64 {
+	return Abs(s.sdf.Evaluate(p)) - s.delta
+}
+
+// EvaluateInterval returns the range of the distance over a box.
+func (s *ShellSDF3) EvaluateInterval(b Box3) Interval {
+	return EvaluateInterval(s.sdf, b).Abs().AddScalar(-s.delta)
+}
+
+// Gradient returns the gradient of the distance.
+func (s *ShellSDF3) Gradient(p V3, eps float64) V3 {
+	return Gradient3D(s.sdf, p, eps).MulScalar(sign_nz(s.sdf.Evaluate(p)))
+}
+
+// BoundingBox returns the bounding box of a shelled SDF3.
+func (s *ShellSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+// DrainHoles3D cuts drain holes (E.g. for resin prints) through a shell of a solid.
+// The holes are centered on points on the surface of the solid and follow the
+// surface normal through the wall.
+func DrainHoles3D(shell, solid SDF3, radius float64, points []V3) SDF3 {
+	if radius <= 0 {
+		panic("invalid drain hole radius")
+	}
+	holes := make([]SDF3, len(points))
+	for i, p := range points {
+		d := shell.Evaluate(p)
+		if d >= 0 {
+			panic("drain hole is not on the shell")
+		}
+		n := Normal3D(solid, p, 1e-3*radius)
+		if n.Length() == 0 {
+			panic("no surface normal for the drain hole")
+		}
+		// extend past both faces of the wall to give a clean cut
+		hole := Cylinder3D(2.0*(radius-d), radius, 0)
+		holes[i] = Transform3D(hole, split_frame(p, n))
+	}
+	return Difference3D(shell, Union3D(holes...))
+}
+
+//-----------------------------------------------------------------------------
+// Displace an SDF3
+
+// DisplaceFunc returns the displacement of the surface at a point.
+type DisplaceFunc func(p V3) float64
+
+type DisplaceSDF3 struct {
+	sdf SDF3
+	f   DisplaceFunc
+	bb  Box3
+}
+
+// Displace3D returns an SDF3 with the surface displaced by a function.
+// +ve displacement moves the surface outwards. The distance field is not exact,
+// keep the displacement gradient small (< 1) so the surface is not overshot.
+// Use SetAmplitude to enlarge the bounding box for +ve displacements.
+func Displace3D(sdf SDF3, f DisplaceFunc) SDF3 {
+	s := DisplaceSDF3{}
+	s.sdf = sdf
+	s.f = f
+	s.bb = sdf.BoundingBox()
+	return &s
+}
+
+// SetAmplitude sets the maximum displacement and enlarges the bounding box.
+func (s *DisplaceSDF3) SetAmplitude(amplitude float64) {
+	bb := s.sdf.BoundingBox()
+	a := Abs(amplitude)
+	s.bb = Box3{bb.Min.SubScalar(a), bb.Max.AddScalar(a)}
+}
+
+// Evaluate returns the minimum distance to a displaced SDF3.
+func (s *DisplaceSDF3) Evaluate(p V3) float64 {
+	return s.sdf.Evaluate(p) - s.f(p)
+}
+
+// BoundingBox returns the bounding box of a displaced SDF3.
+func (s *DisplaceSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+// Smooth an SDF3: low pass filter the distance field.
+// The distance is a weighted average of samples on a 3x3x3 grid (binomial
+// weights) around the point. The average of distance fields is still a
+// bound on the distance. Use it to soften stair-steps from sampled SDFs
+// (E.g. voxels, heightmaps). Sharp edges are rounded by about the radius.
+
+type SmoothSDF3 struct {
+	sdf    SDF3
+	radius float64
+	bb     Box3
+}
+
+// Smooth3D returns an SDF3 with the distance field smoothed over a radius.
+func Smooth3D(sdf SDF3, radius float64) SDF3 {
+	if radius <= 0 {
+		panic("invalid smoothing radius")
+	}
+	s := SmoothSDF3{}
+	s.sdf = sdf
+	s.radius = radius
+	bb := sdf.BoundingBox()
+	s.bb = Box3{bb.Min.SubScalar(radius), bb.Max.AddScalar(radius)}
+	return &s
+}
+
+// Evaluate returns the minimum distance to a smoothed SDF3.
+func (s *SmoothSDF3) Evaluate(p V3) float64 {
+	w := [3]float64{1, 2, 1}
+	d := 0.0
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				q := p.Add(V3{float64(i - 1), float64(j - 1), float64(k - 1)}.MulScalar(s.radius))
+				d += w[i] * w[j] * w[k] * s.sdf.Evaluate(q)
+			}
+		}
+	}
+	return d / 64.0
+}
+
+// BoundingBox returns the bounding box of a smoothed SDF3.
+func (s *SmoothSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+// Mirror and Symmetry
+
+// Mirror3D returns an SDF3 mirrored across a plane (a point on the plane and the plane normal).
+func Mirror3D(sdf SDF3, p, n V3) SDF3 {
+	return Transform3D(sdf, MirrorPlane3d(p, n))
+}
+
+type SymmetrySDF3 struct {
+	sdf     SDF3
+	x, y, z bool
+	bb      Box3
+}
+
+// Symmetry3D returns an SDF3 that is symmetric about the selected axis planes
+// (x: the YZ plane, etc). Only the +ve side of the SDF3 is used, it is
+// reflected to the -ve side. Model half (or a quarter, eighth) of the part.
+func Symmetry3D(sdf SDF3, x, y, z bool) SDF3 {
+	s := SymmetrySDF3{}
+	s.sdf = sdf
+	s.x, s.y, s.z = x, y, z
+	bb := sdf.BoundingBox()
+	if x {
+		m := Max(bb.Max.X, 0)
+		bb.Min.X, bb.Max.X = -m, m
+	}
+	if y {
+		m := Max(bb.Max.Y, 0)
+		bb.Min.Y, bb.Max.Y = -m, m
+	}
+	if z {
+		m := Max(bb.Max.Z, 0)
+		bb.Min.Z, bb.Max.Z = -m, m
+	}
+	s.bb = bb
+	return &s
+}
+
+// Evaluate returns the minimum distance to a symmetric SDF3.
+func (s *SymmetrySDF3) Evaluate(p V3) float64 {
+	if s.x {
+		p.X = Abs(p.X)
+	}
+	if s.y {
+		p.Y = Abs(p.Y)
+	}
+	if s.z {
+		p.Z = Abs(p.Z)
+	}
+	return s.sdf.Evaluate(p)
+}
+
+// BoundingBox returns the bounding box of a symmetric SDF3.
+func (s *SymmetrySDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
 //-----------------------------------------------------------------------------
 // Cut an SDF3 along a plane
 
@@ -826,6 +1759,96 @@ func (s *ArraySDF3) BoundingBox() Box3 {
 	return s.bb
 }
 
+//-----------------------------------------------------------------------------
+// Grid Arrays: rectangular or hexagonal grids with control of each instance.
+
+// CellFunc3 is called for each cell (i, j, k) of a grid array.
+// It returns false to skip the cell, and a transform for the instance
+// (applied before the instance is moved to the cell position).
+type CellFunc3 func(i, j, k int) (bool, M44)
+
+// GridArray3D returns a union of instances of an SDF3 on a rectangular or
+// hexagonal grid. Hex grids offset the odd rows (in y) of each layer by half
+// a step in x. The first cell is at the origin. f controls each instance,
+// nil uses every cell with no transform.
+func GridArray3D(sdf SDF3, num V3i, step V3, hex bool, f CellFunc3) SDF3 {
+	var objects []SDF3
+	for k := 0; k < num[2]; k++ {
+		for j := 0; j < num[1]; j++ {
+			for i := 0; i < num[0]; i++ {
+				m := Identity3d()
+				if f != nil {
+					var ok bool
+					ok, m = f(i, j, k)
+					if !ok {
+						continue
+					}
+				}
+				p := grid_position2(i, j, V2{step.X, step.Y}, hex)
+				m = Translate3d(V3{p.X, p.Y, float64(k) * step.Z}).Mul(m)
+				objects = append(objects, Transform3D(sdf, m))
+			}
+		}
+	}
+	return Union3D(objects...)
+}
+
+//-----------------------------------------------------------------------------
+// Domain Repetition: Repeat an SDF3 by folding the query point into one cell.
+// The evaluation cost doesn't depend on the number of copies. The SDF3 should
+// fit inside its cell (within half a step of the origin) or the distance to
+// neighbouring copies will be wrong.
+
+type RepeatSDF3 struct {
+	sdf  SDF3
+	num  V3i
+	step V3
+	bb   Box3
+}
+
+// Return the position of x folded into the nearest of num cells.
+func repeat_fold(x, step float64, num int) float64 {
+	if num == 1 {
+		return x
+	}
+	i := Clamp(math.Round(x/step), 0, float64(num-1))
+	return x - i*step
+}
+
+// Repeat3D returns an array of copies of an SDF3 by domain repetition.
+// The first copy is at the origin.
+func Repeat3D(sdf SDF3, step V3, num V3i) SDF3 {
+	if num[0] <= 0 || num[1] <= 0 || num[2] <= 0 {
+		return nil
+	}
+	if (num[0] > 1 && step.X <= 0) || (num[1] > 1 && step.Y <= 0) || (num[2] > 1 && step.Z <= 0) {
+		panic("invalid repeat step")
+	}
+	s := RepeatSDF3{}
+	s.sdf = sdf
+	s.num = num
+	s.step = step
+	bb0 := sdf.BoundingBox()
+	bb1 := bb0.Translate(step.Mul(num.SubScalar(1).ToV3()))
+	s.bb = bb0.Extend(bb1)
+	return &s
+}
+
+// Evaluate returns the minimum distance to a repeated SDF3.
+func (s *RepeatSDF3) Evaluate(p V3) float64 {
+	q := V3{
+		repeat_fold(p.X, s.step.X, s.num[0]),
+		repeat_fold(p.Y, s.step.Y, s.num[1]),
+		repeat_fold(p.Z, s.step.Z, s.num[2]),
+	}
+	return s.sdf.Evaluate(q)
+}
+
+// BoundingBox returns the bounding box of a repeated SDF3.
+func (s *RepeatSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
 //-----------------------------------------------------------------------------
 
 type RotateUnionSDF3 struct {
@@ -901,20 +1924,17 @@ func RotateCopy3D(
 	s := RotateCopySDF3{}
 	s.sdf = sdf
 	s.theta = TAU / float64(num)
-	// work out the bounding box
-	bb := sdf.BoundingBox()
-	zmax := bb.Max.Z
-	zmin := bb.Min.Z
-	rmax := 0.0
-	// find the bounding box vertex with the greatest distance from the z-axis
-	// TODO - revisit - should go by real vertices
-	for _, v := range bb.Vertices() {
-		l := V2{v.X, v.Y}.Length()
-		if l > rmax {
-			rmax = l
-		}
+	// work out the bounding box from the rotated copies
+	v := sdf.BoundingBox().Vertices()
+	bb_min := v[0]
+	bb_max := v[0]
+	step := RotateZ(s.theta)
+	for i := 0; i < num; i++ {
+		bb_min = bb_min.Min(v.Min())
+		bb_max = bb_max.Max(v.Max())
+		v.MulVertices(step)
 	}
-	s.bb = Box3{V3{-rmax, -rmax, zmin}, V3{rmax, rmax, zmax}}
+	s.bb = Box3{bb_min, bb_max}
 	return &s
 }
 
@@ -933,6 +1953,18 @@ func (s *RotateCopySDF3) BoundingBox() Box3 {
 
 //-----------------------------------------------------------------------------
 
+// PolarArray3D returns the union of num copies of an SDF3 rotated about an
+// axis through the origin. Unlike RotateCopy3D the copies can overlap the
+// sectors, E.g. fan blades, bolt circles.
+func PolarArray3D(sdf SDF3, num int, axis V3) SDF3 {
+	if num <= 0 {
+		return nil
+	}
+	return RotateUnion3D(sdf, num, Rotate3d(axis, TAU/float64(num)))
+}
+
+//-----------------------------------------------------------------------------
+
 // Intersect a chamfered cylinder with an SDF3
 func Chamfered_Cylinder(s SDF3, kb, kt float64) SDF3 {
 	// get the length and radius from the bounding box
@@ -5,8 +5,22 @@
 package sdf
 
 import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
 	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -317,6 +331,38 @@ func Test_Polygon3(t *testing.T) {
 
 //-----------------------------------------------------------------------------
 
+func Test_PolygonBezier(t *testing.T) {
+	// quadratic curve: x = 2t, y = 4t(1-t)
+	p := NewPolygon()
+	p.Add(0, 0)
+	p.AddBezier(V2{1, 2}, V2{2, 0})
+	p.Add(2, -1)
+	v := p.Vertices()
+	if len(v) < 5 {
+		t.Error("FAIL")
+	}
+	if !v[0].Equals(V2{0, 0}, TOLERANCE) || !v[len(v)-2].Equals(V2{2, 0}, TOLERANCE) {
+		t.Error("FAIL")
+	}
+	for _, x := range v[:len(v)-1] {
+		k := x.X / 2
+		if Abs(x.Y-4*k*(1-k)) > TOLERANCE {
+			t.Logf("%v is not on the curve\n", x)
+			t.Error("FAIL")
+		}
+	}
+	// relative endpoint and control points
+	p = NewPolygon()
+	p.Add(1, 1)
+	p.AddBezier(V2{1, 2}, V2{2, 0}).Rel()
+	v = p.Vertices()
+	if !v[len(v)-1].Equals(V2{3, 1}, TOLERANCE) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
 func Test_ArraySDF2(t *testing.T) {
 	r := 0.5
 	s := Circle2D(r)
@@ -418,7 +464,10 @@ func Test_TriDiagonal(t *testing.T) {
 
 	d := []float64{0, 1, 2, 3, 4}
 	x0 := []float64{-1.0 / 12.0, 1.0 / 6.0, 5.0 / 12.0, 1.0 / 6.0, 23.0 / 12.0}
-	x := TriDiagonal(m, d)
+	x, err := TriDiagonal(m, d)
+	if err != nil {
+		t.Fatal(err)
+	}
 	for i := 0; i < n; i++ {
 		if Abs(x[i]-x0[i]) > TOLERANCE {
 			t.Error("FAIL")
@@ -427,7 +476,10 @@ func Test_TriDiagonal(t *testing.T) {
 
 	d = []float64{10, 20, 30, 40, 50}
 	x0 = []float64{15.0 / 4.0, 5.0 / 2.0, 25.0 / 4.0, 5.0 / 2.0, 95.0 / 4.0}
-	x = TriDiagonal(m, d)
+	x, err = TriDiagonal(m, d)
+	if err != nil {
+		t.Fatal(err)
+	}
 	for i := 0; i < n; i++ {
 		if Abs(x[i]-x0[i]) > TOLERANCE {
 			t.Error("FAIL")
@@ -441,7 +493,10 @@ func Test_TriDiagonal(t *testing.T) {
 	m[4] = V3{12, 13, 0}
 	d = []float64{-10, -20, -30, 40, 50}
 	x0 = []float64{60.0 / 49.0, -275.0 / 49.0, -12.0 / 49.0, 33.0 / 49.0, 158.0 / 49.0}
-	x = TriDiagonal(m, d)
+	x, err = TriDiagonal(m, d)
+	if err != nil {
+		t.Fatal(err)
+	}
 	for i := 0; i < n; i++ {
 		if Abs(x[i]-x0[i]) > TOLERANCE {
 			t.Error("FAIL")
@@ -450,6 +505,28 @@ func Test_TriDiagonal(t *testing.T) {
 
 }
 
+func Test_TriDiagonalCyclic(t *testing.T) {
+	n := 6
+	m := make([]V3, n)
+	for i := range m {
+		m[i] = V3{1, 4, 1}
+	}
+	m[0].X = 2
+	m[n-1].Z = 3
+	d := []float64{1, -2, 3, 4, -5, 6}
+	x, err := TriDiagonalCyclic(m, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// check the residual
+	for i := 0; i < n; i++ {
+		r := m[i].X*x[(i+n-1)%n] + m[i].Y*x[i] + m[i].Z*x[(i+1)%n]
+		if Abs(r-d[i]) > TOLERANCE {
+			t.Error("FAIL")
+		}
+	}
+}
+
 //-----------------------------------------------------------------------------
 
 func Test_CubicSpline(t *testing.T) {
@@ -506,6 +583,162 @@ func Test_CubicSpline(t *testing.T) {
 	}
 }
 
+// Return the minimum distance to a spline by brute force sampling.
+func spline_distance(s *CubicSplineSDF2, p V2) float64 {
+	n := 20000
+	dmin := math.MaxFloat64
+	for i := 0; i <= n; i++ {
+		t := float64(len(s.spline)) * float64(i) / float64(n)
+		dmin = Min(dmin, s.F0(t).Sub(p).Length())
+	}
+	return dmin
+}
+
+func Test_CubicSplineSDF2(t *testing.T) {
+	// open spline
+	knot := []V2{
+		{-1.5, -1.2},
+		{-0.2, 0},
+		{1, 0.5},
+		{5, 1},
+		{10, 2.2},
+		{12, 3.2},
+		{-16, -1.2},
+		{-18, -3.2},
+	}
+	s := CubicSpline2D(knot).(*CubicSplineSDF2)
+	b := NewBox2(s.BoundingBox().Center(), s.BoundingBox().Size().MulScalar(1.5))
+	for i := 0; i < 200; i++ {
+		p := b.Random()
+		d0 := s.Evaluate(p)
+		d1 := spline_distance(s, p)
+		if d0 < 0 || d0 > d1+TOLERANCE || d1-d0 > 0.01 {
+			t.Logf("p %v expected %f actual %f\n", p, d1, d0)
+			t.Error("FAIL")
+		}
+	}
+	// closed spline
+	knot = []V2{
+		{2, 0},
+		{0, 2},
+		{-2, 0},
+		{0, -2},
+		{2, 0},
+	}
+	s = CubicSpline2D(knot).(*CubicSplineSDF2)
+	if s.Evaluate(V2{0, 0}) >= 0 {
+		t.Error("FAIL")
+	}
+	if s.Evaluate(V2{3, 3}) <= 0 {
+		t.Error("FAIL")
+	}
+	for i := 0; i < 200; i++ {
+		p := b.Random()
+		d0 := Abs(s.Evaluate(p))
+		d1 := spline_distance(s, p)
+		if d0 > d1+TOLERANCE || d1-d0 > 0.01 {
+			t.Logf("p %v expected %f actual %f\n", p, d1, d0)
+			t.Error("FAIL")
+		}
+	}
+}
+
+func Test_ClosedCubicSpline(t *testing.T) {
+	knot := []V2{
+		{3, 0},
+		{1, 1},
+		{0, 2},
+		{-1, 0.5},
+		{-2, -1},
+		{0, -1.5},
+	}
+	s := ClosedCubicSpline2D(knot).(*CubicSplineSDF2)
+	n := len(s.spline)
+	if n != len(knot) {
+		t.Error("FAIL")
+	}
+	// check continuity of the derivatives (including the closure)
+	for i := 0; i < n; i++ {
+		cs := s.spline[i]
+		cs_next := s.spline[(i+1)%n]
+		if !cs.f0(1).Equals(cs_next.f0(0), TOLERANCE) {
+			t.Error("FAIL")
+		}
+		if !cs.f1(1).Equals(cs_next.f1(0), TOLERANCE) {
+			t.Error("FAIL")
+		}
+		if !cs.f2(1).Equals(cs_next.f2(0), TOLERANCE) {
+			t.Error("FAIL")
+		}
+	}
+	// check inside/outside
+	if s.Evaluate(V2{0, 0}) >= 0 {
+		t.Error("FAIL")
+	}
+	if s.Evaluate(V2{4, 4}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_CardinalSpline(t *testing.T) {
+	knot := []V2{{0, 0}, {1, 2}, {3, 2}, {4, 0}, {2, -1}}
+	tension := []float64{0, 0, 0.5, 1, 0}
+	s := CardinalSpline2D(knot, tension).(*CubicSplineSDF2)
+	for i := range knot {
+		// the spline passes through the knots
+		if !s.F0(float64(i)).Equals(knot[i], TOLERANCE) {
+			t.Error("FAIL")
+		}
+	}
+	for i := 1; i < len(knot)-1; i++ {
+		// the 1st derivative is continuous and set by the tension
+		d := knot[i+1].Sub(knot[i-1]).MulScalar(0.5 * (1 - tension[i]))
+		if !s.spline[i-1].f1(1).Equals(d, TOLERANCE) || !s.spline[i].f1(0).Equals(d, TOLERANCE) {
+			t.Error("FAIL")
+		}
+	}
+	// closed Catmull-Rom spline
+	knot = []V2{{2, 0}, {0, 2}, {-2, 0}, {0, -2}, {2, 0}}
+	s = CatmullRomSpline2D(knot).(*CubicSplineSDF2)
+	if len(s.spline) != 4 {
+		t.Error("FAIL")
+	}
+	if s.Evaluate(V2{0, 0}) >= 0 || s.Evaluate(V2{3, 3}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_NURBS(t *testing.T) {
+	// exact circle: 9 control points, degree 2
+	r := 2.0
+	w := SQRT_HALF
+	ctrl := []V2{{r, 0}, {r, r}, {0, r}, {-r, r}, {-r, 0}, {-r, -r}, {0, -r}, {r, -r}, {r, 0}}
+	weight := []float64{1, w, 1, w, 1, w, 1, w, 1}
+	knot := []float64{0, 0, 0, 0.25, 0.25, 0.5, 0.5, 0.75, 0.75, 1, 1, 1}
+	s := NURBS2D(2, ctrl, weight, knot)
+	c := Circle2D(r)
+	for i := 0; i < 1000; i++ {
+		b := NewBox2(V2{0, 0}, V2{3 * r, 3 * r})
+		p := b.Random()
+		if Abs(s.Evaluate(p)-c.Evaluate(p)) > TOLERANCE {
+			t.Logf("p %v expected %f actual %f\n", p, c.Evaluate(p), s.Evaluate(p))
+			t.Error("FAIL")
+			break
+		}
+	}
+	// default weights and knots give a clamped b-spline through the end points
+	ctrl = []V2{{0, 0}, {1, 2}, {3, 2}, {4, 0}}
+	n := NewNURBS(3, ctrl, nil, nil)
+	t0, t1 := n.Domain()
+	if !n.Evaluate(t0).Equals(ctrl[0], TOLERANCE) || !n.Evaluate(t1).Equals(ctrl[3], TOLERANCE) {
+		t.Error("FAIL")
+	}
+}
+
 //-----------------------------------------------------------------------------
 
 func Test_Quadratic(t *testing.T) {
@@ -722,3 +955,3638 @@ func Test_Box2_Distances(t *testing.T) {
 }
 
 //-----------------------------------------------------------------------------
+
+func Test_MirrorPart(t *testing.T) {
+	body := Transform3D(Box3D(V3{10, 4, 4}, 0), Translate3d(V3{2, 0, 0}))
+	screw := Screw3D(ISOThread(1, 0.5, "external"), 6, 0.5, 1)
+	p := NewPart()
+	p.Add(body)
+	p.AddHanded(screw, Translate3d(V3{5, 0, 4}))
+	// the mirrored body with an unmirrored (right hand) screw
+	s0 := MirrorPart(p, MirrorYZ()).SDF3()
+	s1 := Union3D(Transform3D(body, MirrorYZ()), Transform3D(screw, Translate3d(V3{-5, 0, 4})))
+	for i := 0; i < 1000; i++ {
+		b := NewBox3(V3{0, 0, 0}, V3{20, 10, 20})
+		q := b.Random()
+		if Abs(s0.Evaluate(q)-s1.Evaluate(q)) > TOLERANCE {
+			t.Error("FAIL")
+			break
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Assembly(t *testing.T) {
+	// a lever on a hinge at (1,0,0) with a slider on the end of the lever
+	hinge := &Joint{REVOLUTE, V3{1, 0, 0}, V3{0, 0, 1}, 0, PI / 2}
+	slide := &Joint{PRISMATIC, V3{}, V3{1, 0, 0}, 0, 1}
+	a := NewAssembly(Sphere3D(0.5))
+	lever := a.AddLink(Transform3D(Sphere3D(0.1), Translate3d(V3{2, 0, 0})), hinge, nil)
+	a.AddLink(Transform3D(Sphere3D(0.1), Translate3d(V3{3, 0, 0})), slide, lever)
+	frames := a.Sweep(3)
+	if len(frames) != 3 || frames[2][0] != PI/2 || frames[1][1] != 0.5 {
+		t.Error("FAIL")
+	}
+	parts := a.Pose(frames[2])
+	// lever end rotated 90 degrees about the hinge
+	if Abs(parts[1].Evaluate(V3{1, 1, 0})+0.1) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	// slider moved 1 along x, then rotated with the lever
+	if Abs(parts[2].Evaluate(V3{1, 3, 0})+0.1) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	// joint values are clamped to the limits
+	if !hinge.Transform(PI).MulPosition(V3{2, 0, 0}).Equals(V3{1, 1, 0}, TOLERANCE) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_SweptVolume(t *testing.T) {
+	motion := func(t float64) M44 {
+		return Translate3d(V3{10 * t, 0, 0})
+	}
+	s := SweptVolume3D(Sphere3D(1), motion, 10)
+	if Abs(s.Evaluate(V3{5, 0, 0})+1) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	if Abs(s.Evaluate(V3{12, 0, 0})-1) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	if !s.BoundingBox().Equals(Box3{V3{-1, -1, -1}, V3{11, 1, 1}}, TOLERANCE) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_FirstCollision(t *testing.T) {
+	// a sphere moving along x towards a wall at x = 5
+	wall := Transform3D(Box3D(V3{2, 10, 10}, 0), Translate3d(V3{6, 0, 0}))
+	motion := func(t float64) M44 {
+		return Translate3d(V3{10 * t, 0, 0})
+	}
+	c := FirstCollision(Sphere3D(1), wall, motion, 10, 0.01)
+	if c == nil || Abs(c.T-0.4) > 0.01 {
+		t.Error("FAIL")
+	}
+	if c != nil && (c.P.X < 5 || c.P.X > 5.1) {
+		t.Error("FAIL")
+	}
+	// moving away from the wall
+	motion = func(t float64) M44 {
+		return Translate3d(V3{-10 * t, 0, 0})
+	}
+	if FirstCollision(Sphere3D(1), wall, motion, 10, 0.01) != nil {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_SmoothBooleans(t *testing.T) {
+	s0 := Sphere3D(1)
+	s1 := Transform3D(Sphere3D(1), Translate3d(V3{1.5, 0, 0}))
+	u0 := Union3D(s0, s1)
+	u1 := SmoothUnion3D(0.5, s0, s1)
+	// away from the join the union is unchanged
+	p := V3{-2, 0, 0}
+	if Abs(u0.Evaluate(p)-u1.Evaluate(p)) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	// at the join material is added
+	p = V3{0.75, 1, 0}
+	if u1.Evaluate(p) >= u0.Evaluate(p) {
+		t.Error("FAIL")
+	}
+	// at the join of a difference/intersection material is removed
+	d0 := Difference3D(s0, s1)
+	d1 := SmoothDifference3D(0.5, s0, s1)
+	if d1.Evaluate(V3{0.5, 0.5, 0}) <= d0.Evaluate(V3{0.5, 0.5, 0}) {
+		t.Error("FAIL")
+	}
+	i0 := Intersect3D(s0, s1)
+	i1 := SmoothIntersect3D(0.5, s0, s1)
+	if i1.Evaluate(V3{0.75, 0.6, 0}) <= i0.Evaluate(V3{0.75, 0.6, 0}) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_FilletedUnion(t *testing.T) {
+	// a boss on a panel with a larger fillet at the base
+	panel := Box3D(V3{20, 20, 2}, 0)
+	boss := Transform3D(Cylinder3D(10, 3, 0), Translate3d(V3{0, 0, 5}))
+	fillet := LinearFillet(V3{0, 0, 1}, V3{0, 0, 10}, 2, 0)
+	s := FilletedUnion3D(panel, boss, fillet)
+	u := Union3D(panel, boss)
+	// material is added in the corner at the base of the boss
+	p := V3{3.3, 0, 1.3}
+	if s.Evaluate(p) >= u.Evaluate(p) {
+		t.Error("FAIL")
+	}
+	// no fillet at the top of the boss
+	p = V3{3.3, 0, 10}
+	if Abs(s.Evaluate(p)-u.Evaluate(p)) > TOLERANCE {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_ChamferBooleans(t *testing.T) {
+	// a wall (x < 0) on a floor (z < 0)
+	floor := Box3D(V3{20, 20, 10}, 0)
+	floor = Transform3D(floor, Translate3d(V3{5, 0, -5}))
+	wall := Box3D(V3{10, 20, 20}, 0)
+	wall = Transform3D(wall, Translate3d(V3{-5, 0, 5}))
+	// 45 degree chamfer, 2 wide
+	s := ChamferUnion3D(2, PI/4, floor, wall)
+	for _, v := range []struct {
+		p V3
+		d float64
+	}{
+		{V3{1, 0, 1}, 0},              // on the chamfer face
+		{V3{2, 0, 2}, SQRT_HALF * 2},  // outside the chamfer
+		{V3{0.5, 0, 0.5}, -SQRT_HALF}, // inside the chamfer
+		{V3{5, 0, 1}, 1},              // above the floor
+		{V3{1, 0, 5}, 1},              // beside the wall
+	} {
+		if Abs(s.Evaluate(v.p)-v.d) > TOLERANCE {
+			t.Logf("p %v expected %f actual %f\n", v.p, v.d, s.Evaluate(v.p))
+			t.Error("FAIL")
+		}
+	}
+	// 60 degree chamfer, 1 wide: the face runs from (1, 0, 0) to (0, 0, tan(60))
+	s = ChamferUnion3D(1, DtoR(60), floor, wall)
+	p := V3{0.5, 0, 0.5 * math.Tan(DtoR(60))}
+	if Abs(s.Evaluate(p)) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	// a chamfer at each join of a union
+	wall2 := Transform3D(Box3D(V3{10, 20, 20}, 0), Translate3d(V3{15, 0, 5}))
+	s = ChamferUnion3D(2, PI/4, floor, wall, wall2)
+	if Abs(s.Evaluate(V3{1, 0, 1})) > TOLERANCE || Abs(s.Evaluate(V3{9, 0, 1})) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	// chamfered difference removes material at the join
+	d0 := Difference3D(floor, wall)
+	d1 := ChamferDifference3D(1, PI/4, floor, wall)
+	p = V3{-0.2, 0, 0.2}
+	if d1.Evaluate(p) <= d0.Evaluate(p) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_BeltLength(t *testing.T) {
+	// open belt: equal pulleys
+	p := []BeltPulley{
+		{V2{0, 0}, 10, false},
+		{V2{100, 0}, 10, false},
+	}
+	l, err := BeltLength(p)
+	if err != nil || Abs(l-(200+TAU*10)) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	// unequal pulleys
+	p[1].Radius = 20
+	l, _ = BeltLength(p)
+	theta := math.Asin(10.0 / 100.0)
+	x := 2*100*math.Cos(theta) + (PI+2*theta)*20 + (PI-2*theta)*10
+	if Abs(l-x) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	// idler on the back of the belt, on a slot pushing it in to the belt
+	p = []BeltPulley{
+		{V2{0, 0}, 10, false},
+		{V2{100, 0}, 10, false},
+		{V2{50, 15}, 5, true},
+	}
+	c, err := IdlerPosition(p, 2, V2{50, 15}, V2{50, 0}, 265)
+	if err != nil {
+		t.Error("FAIL")
+	}
+	p[2].Center = c
+	l, _ = BeltLength(p)
+	if Abs(l-265) > TOLERANCE || c.Y >= 15 || c.Y <= 0 {
+		t.Error("FAIL")
+	}
+	if BeltTeeth(240, 2) != 120 || BeltTeeth(241, 2) != 121 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Offset3D(t *testing.T) {
+	s := Box3D(V3{2, 4, 6}, 0)
+	s0 := Offset3D(s, 0.5)
+	s1 := Box3D(V3{3, 5, 7}, 0.5)
+	if !s0.BoundingBox().Equals(s1.BoundingBox(), TOLERANCE) {
+		t.Error("FAIL")
+	}
+	for i := 0; i < 1000; i++ {
+		b := NewBox3(V3{0, 0, 0}, V3{10, 10, 10})
+		p := b.Random()
+		if Abs(s0.Evaluate(p)-s1.Evaluate(p)) > TOLERANCE {
+			t.Error("FAIL")
+			break
+		}
+	}
+	// erode
+	s0 = Offset3D(Sphere3D(2), -0.5)
+	if Abs(s0.Evaluate(V3{1.5, 0, 0})) > TOLERANCE {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Shell3D(t *testing.T) {
+	s := Shell3D(Sphere3D(10), 2)
+	for _, v := range []struct {
+		p V3
+		d float64
+	}{
+		{V3{0, 0, 0}, 9},
+		{V3{10, 0, 0}, -1},
+		{V3{0, 9.5, 0}, -0.5},
+		{V3{0, 0, 12}, 1},
+	} {
+		if Abs(s.Evaluate(v.p)-v.d) > TOLERANCE {
+			t.Error("FAIL")
+		}
+	}
+	if !s.BoundingBox().Equals(Box3{V3{-11, -11, -11}, V3{11, 11, 11}}, TOLERANCE) {
+		t.Error("FAIL")
+	}
+	// drain holes at the bottom and on the side
+	solid := Sphere3D(10)
+	s = DrainHoles3D(s, solid, 1.5, []V3{{0, 0, -10}, {10, 0, 0}})
+	for _, p := range []V3{{0, 0, -11.5}, {0, 0, -10}, {0, 0, -8.5}, {1.4, 0, -10}, {10, 0, 0}, {10, 0, 1.4}, {9, 0, 0}, {11, 0, 0}} {
+		if s.Evaluate(p) <= 0 {
+			t.Errorf("FAIL %v", p)
+		}
+	}
+	// the wall around the holes and elsewhere
+	for _, p := range []V3{{1.7, 0, -9.9}, {0, 10, 0}, {10, 0, 1.7}, {-10, 0, 0}} {
+		p = p.Normalize().MulScalar(10)
+		if s.Evaluate(p) >= 0 {
+			t.Errorf("FAIL %v", p)
+		}
+	}
+	// not on the shell
+	defer func() {
+		if recover() == nil {
+			t.Error("FAIL")
+		}
+	}()
+	DrainHoles3D(s, solid, 1.5, []V3{{0, 0, 0}})
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_SoftJaws(t *testing.T) {
+	part := Transform3D(Sphere3D(5), Translate3d(V3{10, 0, 0}))
+	k := &SoftJawParms{Grip: V3{0, 1, 0}, Width: 30, Margin: 3, Clearance: 0.1}
+	jaw0, jaw1 := SoftJaws3D(part, k)
+	// the cavity is empty
+	if jaw0.Evaluate(V3{10, -1, 0}) <= 0 || jaw1.Evaluate(V3{10, 1, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	// jaw material beside the part
+	if jaw0.Evaluate(V3{10, -6.5, -1}) >= 0 || jaw1.Evaluate(V3{10, 6.5, -1}) >= 0 {
+		t.Error("FAIL")
+	}
+	// each jaw is on its own side of the grip
+	if jaw0.Evaluate(V3{10, 6.5, -1}) <= 0 || jaw1.Evaluate(V3{10, -6.5, -1}) <= 0 {
+		t.Error("FAIL")
+	}
+	// the jaws are at least the minimum width
+	if jaw0.Evaluate(V3{-4, -6.5, -1}) >= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_DrillGuide(t *testing.T) {
+	k := &DrillGuideParms{
+		Holes:        []DrillHole{{V2{10, 10}, 3}, {V2{40, 20}, 5}},
+		Thickness:    8,
+		Margin:       5,
+		BushingWall:  2,
+		BushingDepth: 4,
+		Registration: 3,
+	}
+	s := DrillGuide3D(k)
+	for _, v := range []struct {
+		p      V3
+		inside bool
+	}{
+		{V3{10, 10, 1}, false},   // drill hole
+		{V3{11.8, 10, 1}, true},  // below the bushing
+		{V3{11.8, 10, 7}, false}, // bushing pocket
+		{V3{20, 20, 4}, true},    // plate
+		{V3{-2, 20, -2}, true},   // x registration lip
+		{V3{20, -2, -2}, true},   // y registration lip
+		{V3{20, 20, -2}, false},  // workpiece
+	} {
+		if (s.Evaluate(v.p) < 0) != v.inside {
+			t.Logf("p %v\n", v.p)
+			t.Error("FAIL")
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Stand(t *testing.T) {
+	// a phone shaped object standing on its end
+	phone := Box3D(V3{70, 10, 140}, 5)
+	k := &StandParms{Wall: 4, Base: 3, Depth: 20, Clearance: 0.5, Tilt: DtoR(-15)}
+	s, m := Stand3D(phone, k)
+	// the object sits above the base
+	bb := m.MulBox(phone.BoundingBox())
+	if Abs(bb.Min.Z-k.Base) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	// the cradle is empty, the base and walls are not
+	if s.Evaluate(m.MulPosition(V3{0, 0, -60})) <= 0 {
+		t.Error("FAIL")
+	}
+	if s.Evaluate(V3{0, 0, 1}) >= 0 || s.Evaluate(V3{38, 0, 10}) >= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_MultiLoft(t *testing.T) {
+	sections := []SDF2{Circle2D(2), Circle2D(4), Circle2D(4), Circle2D(1)}
+	heights := []float64{0, 5, 10, 20}
+	for _, smooth := range []bool{false, true} {
+		s := MultiLoft3D(sections, heights, smooth)
+		// at the sections the profile matches
+		for i, h := range heights {
+			r := []float64{2, 4, 4, 1}[i]
+			if Abs(s.Evaluate(V3{r, 0, h})) > TOLERANCE {
+				t.Error("FAIL")
+			}
+		}
+		// no overshoot between equal sections
+		if Abs(s.Evaluate(V3{4, 0, 7.5})) > TOLERANCE {
+			t.Error("FAIL")
+		}
+		// between sections the radius is between the section radii
+		if s.Evaluate(V3{2, 0, 2.5}) >= 0 || s.Evaluate(V3{4, 0, 2.5}) <= 0 {
+			t.Error("FAIL")
+		}
+		if !s.BoundingBox().Equals(Box3{V3{-4, -4, 0}, V3{4, 4, 20}}, TOLERANCE) {
+			t.Error("FAIL")
+		}
+	}
+	// linear blend
+	s := MultiLoft3D(sections, heights, false)
+	if Abs(s.Evaluate(V3{3, 0, 2.5})) > TOLERANCE {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_FingerNotches(t *testing.T) {
+	// a wall along an L-shaped edge
+	wall := Box3D(V3{100, 2, 20}, 0)
+	wall = Transform3D(wall, Translate3d(V3{50, 0, -10}))
+	k := NotchParms{
+		Path:  []V2{{0, 0}, {50, 0}, {100, 0}},
+		Width: 20,
+		Depth: 10,
+		Reach: 5,
+		Count: 2,
+	}
+	s := FingerNotches3D(wall, &k)
+	// notch centers at 25 and 75
+	for _, x := range []float64{25, 75} {
+		if s.Evaluate(V3{x, 0, -9}) <= 0 || s.Evaluate(V3{x, 0, -11}) >= 0 {
+			t.Error("FAIL")
+		}
+	}
+	if s.Evaluate(V3{50, 0, -1}) >= 0 {
+		t.Error("FAIL")
+	}
+	// notches follow the path direction
+	k.Path = []V2{{0, 0}, {0, 100}}
+	k.Count = 1
+	wall = Transform3D(wall, RotateZ(DtoR(90)))
+	s = FingerNotches3D(wall, &k)
+	if s.Evaluate(V3{0, 41, -1}) <= 0 || s.Evaluate(V3{0, 39, -1}) >= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Escapement(t *testing.T) {
+	w := EscapeWheelParms{Teeth: 30, Radius: 30, Depth: 4, Lean: DtoR(10)}
+	wheel := EscapeWheel2D(&w)
+	for i := 0; i < w.Teeth; i++ {
+		// tooth tips
+		p := PolarToXY(w.Radius, TAU*float64(i)/float64(w.Teeth))
+		if Abs(wheel.Evaluate(p)) > TOLERANCE {
+			t.Error("FAIL")
+		}
+	}
+	if wheel.Evaluate(V2{27, 0}) >= 0 || wheel.Evaluate(PolarToXY(29, DtoR(3))) <= 0 {
+		t.Error("FAIL")
+	}
+	k := AnchorParms{Wheel: &w, Span: 7.5, Width: 2, Lift: DtoR(4), Arm: 3, Hub: 4}
+	anchor := Anchor2D(&k)
+	pivot := AnchorPivot(&k)
+	if !pivot.Equals(V2{0, 30 * math.Sqrt2}, TOLERANCE) {
+		t.Error("FAIL")
+	}
+	// the pallets are on the tangents from the pivot to the wheel
+	c := PolarToXY(w.Radius+1, DtoR(45))
+	if anchor.Evaluate(pivot) >= 0 || anchor.Evaluate(c) >= 0 || anchor.Evaluate(V2{-c.X, c.Y}) >= 0 {
+		t.Error("FAIL")
+	}
+}
+
+func Test_ClockTrain(t *testing.T) {
+	// 30 tooth escape wheel, 1 second beat
+	r := EscapeWheelRate(30, 1)
+	if r != 60 {
+		t.Error("FAIL")
+	}
+	train, err := ClockTrain(r, 2, [2]int{8, 8}, [2]int{30, 80})
+	if err != nil {
+		t.Error(err)
+	}
+	if Abs(TrainRatio(train)-r) > TOLERANCE || train[0].Wheel+train[1].Wheel != 124 {
+		t.Error("FAIL")
+	}
+	// motion work
+	train, err = ClockTrain(12, 2, [2]int{6, 12}, [2]int{20, 60})
+	if err != nil {
+		t.Error(err)
+	}
+	if Abs(TrainRatio(train)-12) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	// not possible
+	_, err = ClockTrain(7, 1, [2]int{8, 8}, [2]int{30, 50})
+	if err == nil {
+		t.Error("FAIL")
+	}
+	if (GearPair{36, 12}).CenterDistance(1) != 24 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_TwistScaleExtrude(t *testing.T) {
+	bar := Box2D(V2{10, 2}, 0)
+	// the bar rotates clockwise by 90 degrees from bottom to top
+	s := TwistExtrude3D(bar, 10, PI/2)
+	for _, z := range []float64{-5, 0, 5} {
+		p0 := PolarToXY(4, -z*PI/20)
+		p1 := PolarToXY(4, -z*PI/20+PI/2)
+		if s.Evaluate(V3{p0.X, p0.Y, 0.99 * z}) >= 0 || s.Evaluate(V3{p1.X, p1.Y, 0.99 * z}) <= 0 {
+			t.Error("FAIL")
+		}
+	}
+	// the bounding box covers profiles away from the origin
+	s = TwistExtrude3D(Transform2D(bar, Translate2d(V2{-10, 0})), 10, PI)
+	bb := s.BoundingBox()
+	if bb.Max.X < 15 || bb.Min.X > -15 {
+		t.Error("FAIL")
+	}
+	// the top is scaled by 0.5
+	s = ScaleExtrude3D(bar, 10, V2{0.5, 0.5})
+	if s.Evaluate(V3{4.9, 0, -4.9}) >= 0 || s.Evaluate(V3{2.4, 0, 4.9}) >= 0 {
+		t.Error("FAIL")
+	}
+	if s.Evaluate(V3{2.7, 0, 4.9}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_HelixExtrude(t *testing.T) {
+	// a spring: a circular wire on a 10 mm radius, 2.5 turns
+	wire := Transform2D(Circle2D(1), Translate2d(V2{10, 0}))
+	s := HelixExtrude3D(wire, 5, 2.5)
+	// the wire center at various angles
+	for _, a := range []float64{1, PI, 4, 2 * TAU} {
+		p := PolarToXY(10, a)
+		if Abs(s.Evaluate(V3{p.X, p.Y, 5 * a / TAU})+1) > TOLERANCE {
+			t.Error("FAIL")
+		}
+	}
+	// between the coils
+	if Abs(s.Evaluate(V3{10, 0, 2.5})-1.5) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	// on and beyond the end caps
+	if Abs(s.Evaluate(V3{10, 0, 0})) > TOLERANCE || Abs(s.Evaluate(V3{-10, 0, 12.5})) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	if Abs(s.Evaluate(V3{10, -2, 0})-2) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	if Abs(s.Evaluate(V3{-10, -2, 12.5})-2) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	bb := s.BoundingBox()
+	if !bb.Equals(Box3{V3{-11, -11, -1}, V3{11, 11, 13.5}}, TOLERANCE) {
+		t.Error("FAIL")
+	}
+	// left hand
+	s = HelixExtrude3D(wire, -5, 1)
+	p := PolarToXY(10, PI/2)
+	if Abs(s.Evaluate(V3{p.X, p.Y, -1.25})+1) > TOLERANCE {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_SplineFunction(t *testing.T) {
+	// passes through the knots
+	knot := []V2{{0, 1}, {1, 3}, {2, 2}, {4, 5}}
+	f := NewSplineFunction(knot)
+	for _, k := range knot {
+		if Abs(f.Evaluate(k.X)-k.Y) > TOLERANCE {
+			t.Error("FAIL")
+		}
+	}
+	// clamped outside the knots
+	if f.Evaluate(-1) != 1 || f.Evaluate(5) != 5 {
+		t.Error("FAIL")
+	}
+	// straight lines are reproduced
+	f = NewSplineFunction([]V2{{0, 0}, {1, 2}, {3, 6}})
+	if Abs(f.Evaluate(2)-4) > TOLERANCE || Abs(f.Evaluate(0.5)-1) > TOLERANCE {
+		t.Error("FAIL")
+	}
+}
+
+func Test_Rotor(t *testing.T) {
+	k := RotorParms{
+		Blades:     5,
+		Hub:        10,
+		Shroud:     40,
+		Height:     10,
+		Chord:      []V2{{10, 12}, {25, 16}, {40, 10}},
+		Twist:      []V2{{10, DtoR(60)}, {40, DtoR(20)}},
+		Thickness:  0.1,
+		ShroudWall: 2,
+	}
+	s := Rotor3D(&k)
+	// blades, hub and shroud
+	for i := 0; i < k.Blades; i++ {
+		p := PolarToXY(25, TAU*float64(i)/float64(k.Blades))
+		if s.Evaluate(V3{p.X, p.Y, 0}) >= 0 {
+			t.Error("FAIL")
+		}
+	}
+	if s.Evaluate(V3{5, 0, 0}) >= 0 || s.Evaluate(V3{0, 41, 0}) >= 0 {
+		t.Error("FAIL")
+	}
+	// between the blades
+	p := PolarToXY(25, PI/5)
+	if s.Evaluate(V3{p.X, p.Y, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	// the blade is twisted: the blade angle is 20 degrees at the tip
+	q := Rotate(DtoR(20)).MulPosition(V2{4, 0})
+	p = PolarToXY(39.5, q.X/39.5)
+	if s.Evaluate(V3{p.X, p.Y, q.Y}) >= 0 {
+		t.Error("FAIL")
+	}
+	// the root fillet fills the corner between the hub and the blade
+	p = PolarToXY(10.5, 2/10.5)
+	p0 := V3{p.X, p.Y, 0}
+	if s.Evaluate(p0) <= 0 {
+		t.Error("FAIL")
+	}
+	k.Fillet = 4
+	s = Rotor3D(&k)
+	if s.Evaluate(p0) >= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_ParseThread(t *testing.T) {
+	test := []struct {
+		name     string
+		radius   float64
+		pitch    float64
+		internal bool
+		offset   float64 // sign of the offset
+	}{
+		{"M6x1-6H", 3, 1, true, 1},
+		{"M6-6g", 3, 1, false, -1},
+		{"M8x1-6h", 4, 1, false, -1},
+		{"M10", 5, 1.5, false, 0},
+		{"M7x0.75", 3.5, 0.75, false, 0},
+		{"1/4-20 UNC-2A", 0.125, 1.0 / 20.0, false, -1},
+		{"1/4-28UNF-3B", 0.125, 1.0 / 28.0, true, 1},
+		{"0.5-13 UNC", 0.25, 1.0 / 13.0, false, 0},
+	}
+	for _, v := range test {
+		spec, err := ParseThread(v.name)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if spec.Thread.Radius != v.radius || Abs(spec.Thread.Pitch-v.pitch) > TOLERANCE {
+			t.Errorf("%s: bad thread %v", v.name, spec.Thread)
+		}
+		if spec.Internal != v.internal || Sign(spec.Offset) != v.offset {
+			t.Errorf("%s: bad class %v", v.name, spec)
+		}
+	}
+	// g has a larger deviation than h
+	g, _ := ParseThread("M6-6g")
+	h, _ := ParseThread("M6-6h")
+	if g.Offset >= h.Offset || g.Offset > -0.03 || g.Offset < -0.05 {
+		t.Error("FAIL")
+	}
+	// ISO 261 coarse pitches, fine only sizes need a pitch
+	if spec, err := ParseThread("M14"); err != nil || spec.Thread.Pitch != 2 {
+		t.Error("FAIL")
+	}
+	if spec, err := ParseThread("M25x1.5"); err != nil || spec.Thread.Pitch != 1.5 {
+		t.Error("FAIL")
+	}
+	for _, name := range []string{"M6x1-6X", "X6", "M3-6q", "1/4-20 UNC-4A", "M-6H", "M25", "M32-6g"} {
+		if _, err := ParseThread(name); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func Test_Thread3D(t *testing.T) {
+	spec, _ := ParseThread("M10-6g")
+	k := ThreadParms{Length: 20, LeadIn: 1, Runout: 3}
+	s := Thread3D(spec, &k)
+	// the core of the thread
+	if s.Evaluate(V3{2, 0, 10}) >= 0 || s.Evaluate(V3{2, 0, 21}) <= 0 || s.Evaluate(V3{2, 0, -1}) <= 0 {
+		t.Error("FAIL")
+	}
+	// the lead-in chamfer removes the crest at the end
+	if s.Evaluate(V3{4.9, 0, 19.9}) <= 0 {
+		t.Error("FAIL")
+	}
+	// the runout fills the thread at z = 0
+	for _, a := range []float64{0, 1, 2, 3} {
+		p := PolarToXY(4.9, a)
+		if s.Evaluate(V3{p.X, p.Y, 0.01}) >= 0 {
+			t.Error("FAIL")
+		}
+	}
+	// a tapped hole
+	spec, _ = ParseThread("M10-6H")
+	s = Thread3D(spec, &k)
+	if s.Evaluate(V3{2, 0, 10}) >= 0 || s.Evaluate(V3{2, 0, 21}) >= 0 || s.Evaluate(V3{2, 0, -0.1}) <= 0 {
+		t.Error("FAIL")
+	}
+	// countersunk entry
+	if s.Evaluate(V3{5.5, 0, 19.9}) >= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_MarbleRun(t *testing.T) {
+	k := TrackParms{Ball: 16, Clearance: 1, Wall: 2, Rail: 4}
+	r := k.groove()
+	// the groove is open at the joints and the floor is below it
+	check := func(name string, s SDF3, j TrackJoint) {
+		if s.Evaluate(j.Position) <= 0 {
+			t.Errorf("%s: groove not open at %v", name, j.Position)
+		}
+		floor := j.Position.Sub(V3{0, 0, r + 0.5*k.Wall})
+		if s.Evaluate(floor) >= 0 {
+			t.Errorf("%s: no floor at %v", name, floor)
+		}
+	}
+	segs := map[string]*TrackSegment{
+		"straight": StraightTrack3D(&k, 100, 5),
+		"left":     CurvedTrack3D(&k, 50, PI/2),
+		"right":    CurvedTrack3D(&k, 50, -PI/2),
+		"helix":    HelixTrack3D(&k, 50, 40, 1.25),
+	}
+	for name, seg := range segs {
+		check(name, seg.SDF, TrackJoint{V3{1, 0, 0}, 0})
+		for _, j := range seg.Out {
+			// step back from the output joint along the track
+			v := PolarToXY(1, j.Heading)
+			check(name, seg.SDF, TrackJoint{j.Position.Sub(V3{v.X, v.Y, 0}), j.Heading})
+		}
+	}
+	if !segs["left"].Out[0].Position.Equals(V3{50, 50, 0}, TOLERANCE) {
+		t.Error("FAIL")
+	}
+	if !segs["right"].Out[0].Position.Equals(V3{50, -50, 0}, TOLERANCE) {
+		t.Error("FAIL")
+	}
+	if !segs["helix"].Out[0].Position.Equals(V3{50, 50, -50}, TOLERANCE) {
+		t.Error("FAIL")
+	}
+	// attach a curve to the end of the straight
+	c := segs["left"].Attach(segs["straight"].Out[0])
+	if !c.Out[0].Position.Equals(V3{150, 50, -5}, TOLERANCE) || Abs(c.Out[0].Heading-PI/2) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	check("attached", c.SDF, TrackJoint{c.In.Position.Add(V3{1, 0, 0}), 0})
+	// funnel
+	f := FunnelTrack3D(&k, 60, 30)
+	if f.SDF.Evaluate(f.Out[0].Position) <= 0 || f.SDF.Evaluate(V3{0, -1, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	// switch
+	sw, flipper := SwitchTrack3D(&k, 120, DtoR(20))
+	if len(sw.Out) != 2 {
+		t.Error("FAIL")
+	}
+	for _, j := range sw.Out {
+		v := PolarToXY(1, j.Heading)
+		check("switch", sw.SDF, TrackJoint{j.Position.Sub(V3{v.X, v.Y, 0}), j.Heading})
+	}
+	if flipper.Evaluate(V3{60, 0, 0}) >= 0 || sw.SDF.Evaluate(V3{60, 0, -r - 1}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Fasteners(t *testing.T) {
+	for _, style := range []string{"hex", "socket", "countersunk"} {
+		s := Bolt3D(&BoltParms{Thread: "M6-6g", Style: style, Tolerance: 0.1, TotalLength: 20, ShankLength: 5})
+		// shank, thread and head
+		if s.Evaluate(V3{1, 0, 2}) >= 0 || s.Evaluate(V3{1, 0, 15}) >= 0 || s.Evaluate(V3{4.5, 0, -2}) >= 0 {
+			t.Errorf("%s: FAIL", style)
+		}
+		// past the end and beside the shank
+		if s.Evaluate(V3{1, 0, 21}) <= 0 || s.Evaluate(V3{3.5, 0, 2}) <= 0 {
+			t.Errorf("%s: FAIL", style)
+		}
+	}
+	// the socket is open
+	s := Bolt3D(&BoltParms{Thread: "M6", Style: "socket", TotalLength: 10})
+	if s.Evaluate(V3{1, 0, -5.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	// a nut: solid body with a threaded hole
+	s = Nut3D(&NutParms{Thread: "M6-6H", Style: "hex", Tolerance: 0.1})
+	if s.Evaluate(V3{4, 0, 2}) >= 0 || s.Evaluate(V3{2, 0, 2}) <= 0 || s.Evaluate(V3{4, 0, 6}) <= 0 {
+		t.Error("FAIL")
+	}
+	// a washer
+	s = PlainWasher3D(&WasherParms{Thread: "M6", Clearance: 0.1})
+	if s.Evaluate(V3{4.5, 0, 0.5}) >= 0 || s.Evaluate(V3{3, 0, 0.5}) <= 0 || s.Evaluate(V3{7, 0, 0.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	// standoffs: threaded through, stud on top and female below, studs at both ends
+	k := ThreadedStandoffParms{Thread: "M3", Ends: "female-female", Body: "hex", Length: 10, Tolerance: 0.1}
+	s = ThreadedStandoff3D(&k)
+	if s.Evaluate(V3{2.5, 0, 5}) >= 0 || s.Evaluate(V3{3.2, 0, 5}) >= 0 || s.Evaluate(V3{0.3, 0, 5}) <= 0 || s.Evaluate(V3{0.5, 0, 11}) <= 0 {
+		t.Error("FAIL")
+	}
+	k.Ends, k.Body = "male-female", "round"
+	s = ThreadedStandoff3D(&k)
+	if s.Evaluate(V3{0.5, 0, 13}) >= 0 || s.Evaluate(V3{0.3, 0, 1}) <= 0 || s.Evaluate(V3{0.3, 0, 9}) >= 0 || s.Evaluate(V3{3.2, 0, 5}) <= 0 {
+		t.Error("FAIL")
+	}
+	k.Ends = "male-male"
+	s = ThreadedStandoff3D(&k)
+	if s.Evaluate(V3{0.5, 0, -3}) >= 0 || s.Evaluate(V3{0.5, 0, 13}) >= 0 || s.Evaluate(V3{0.3, 0, 5}) >= 0 || s.Evaluate(V3{0.5, 0, 17}) <= 0 {
+		t.Error("FAIL")
+	}
+	// a spacer
+	s = Spacer3D(&SpacerParms{Thread: "M3", Body: "round", Length: 5})
+	if s.Evaluate(V3{1, 0, 2.5}) <= 0 || s.Evaluate(V3{2, 0, 2.5}) >= 0 || s.Evaluate(V3{2, 0, 5.5}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_PipePlugs(t *testing.T) {
+	// the NPT thread tapers: the crest is larger at the pipe end
+	crest := func(s SDF3, r, z float64) float64 {
+		d := math.Inf(1)
+		for i := 0; i < 64; i++ {
+			p := PolarToXY(r, TAU*float64(i)/64)
+			d = Min(d, s.Evaluate(V3{p.X, p.Y, z}))
+		}
+		return d
+	}
+	npt := PipeThreadLookup("npt_1/2")
+	s := PipeThread3D(npt, false, npt.Length, 0)
+	if crest(s, 0.415, 0.05) >= 0 || crest(s, 0.415, 0.5) <= 0 {
+		t.Error("FAIL")
+	}
+	s = PipePlug3D(&PipePlugParms{Thread: "npt_1/2", Style: "square"})
+	if s.Evaluate(V3{0.3, 0, 0.3}) >= 0 || s.Evaluate(V3{0.3, 0, 0.6}) <= 0 || s.Evaluate(V3{0.45, 0, -0.1}) >= 0 {
+		t.Error("FAIL")
+	}
+	// a BSP plug with an o-ring groove under the head
+	oring := &ORingParms{Section: 1.5, Squeeze: 0.25}
+	s = PipePlug3D(&PipePlugParms{Thread: "bsp_1/2", Style: "hex", Tolerance: 0.1, ORing: oring})
+	if s.Evaluate(V3{5, 0, 5}) >= 0 || s.Evaluate(V3{11.6, 0, -0.5}) <= 0 || s.Evaluate(V3{11.6, 0, -2}) >= 0 {
+		t.Error("FAIL")
+	}
+	// a BSP cap with an o-ring groove in the floor
+	s = PipeCap3D(&PipeCapParms{Thread: "bsp_1/2", Style: "round", Wall: 3, Tolerance: 0.1, ORing: oring})
+	if s.Evaluate(V3{8.3, 0, 2.5}) <= 0 || s.Evaluate(V3{8.3, 0, 1}) >= 0 || s.Evaluate(V3{12, 0, 8}) >= 0 {
+		t.Error("FAIL")
+	}
+	if s.Evaluate(V3{2, 0, 8}) <= 0 || s.Evaluate(V3{2, 0, 1}) >= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_PipeThreads(t *testing.T) {
+	// BSPT has the G diameter at the gauge plane
+	g := PipeThreadLookup("bsp_1/2")
+	r := PipeThreadLookup("bspt_1/2")
+	if !EqualFloat64(r.Radius+8.2/32.0, g.Radius, EPSILON) || r.Pitch != g.Pitch || r.Taper == 0 {
+		t.Error("FAIL")
+	}
+	// garden hose thread
+	ght := PipeThreadLookup("ght_3/4")
+	if ght.Taper != 0 || !EqualFloat64(ght.Pitch, 1.0/11.5, EPSILON) {
+		t.Error("FAIL")
+	}
+	s := PipeCap3D(&PipeCapParms{Thread: "ght_3/4", Style: "knurl", Wall: 0.1})
+	if s.Evaluate(V3{0.3, 0, 0.3}) <= 0 || s.Evaluate(V3{0.6, 0, 0.3}) >= 0 || s.Evaluate(V3{0.3, 0, 0.05}) >= 0 {
+		t.Error("FAIL")
+	}
+	s = PipePlug3D(&PipePlugParms{Thread: "bspt_1/4", Style: "socket"})
+	if s.Evaluate(V3{4, 0, 5}) >= 0 || s.Evaluate(V3{4, 0, 10}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_GearGenerator(t *testing.T) {
+	k := GearParms{
+		Teeth:         20,
+		Module:        2,
+		PressureAngle: DtoR(20),
+		Width:         10,
+		Bore:          5,
+		Keyway:        V2{2, 1},
+		Hub:           V2{8, 5},
+	}
+	// spur gear: tooth on the x-axis, bore and keyway
+	s2 := SpurGear2D(&k)
+	if s2.Evaluate(V2{21, 0}) >= 0 || s2.Evaluate(PolarToXY(21, DtoR(9))) <= 0 {
+		t.Error("FAIL")
+	}
+	if s2.Evaluate(V2{5.5, 0}) <= 0 || s2.Evaluate(V2{0, 5.5}) >= 0 {
+		t.Error("FAIL")
+	}
+	s3 := Gear3D(&k)
+	if s3.Evaluate(V3{21, 0, 0}) >= 0 || s3.Evaluate(V3{6, 3, 7}) >= 0 || s3.Evaluate(V3{0, 3, 7}) <= 0 {
+		t.Error("FAIL")
+	}
+	// right hand helical gear: the tooth turns counter-clockwise moving up
+	k.Helix = DtoR(30)
+	s3 = Gear3D(&k)
+	a := 0.5 * k.Width * math.Tan(k.Helix) / k.PitchRadius()
+	p := PolarToXY(21, a)
+	if s3.Evaluate(V3{p.X, p.Y, 4.9}) >= 0 || s3.Evaluate(V3{21, 0, 4.9}) <= 0 {
+		t.Error("FAIL")
+	}
+	// ring gear: tooth space on the x-axis
+	k = GearParms{Teeth: 60, Module: 1, PressureAngle: DtoR(20), Width: 5, Rim: 3}
+	r2 := RingGear2D(&k)
+	if r2.Evaluate(V2{30, 0}) <= 0 || r2.Evaluate(PolarToXY(30, DtoR(3))) >= 0 {
+		t.Error("FAIL")
+	}
+	if r2.Evaluate(V2{28, 0}) <= 0 || r2.Evaluate(V2{32.5, 0}) >= 0 || r2.Evaluate(V2{35, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	if RingGear3D(&k).Evaluate(V3{32.5, 0, 2}) >= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_BevelWormGears(t *testing.T) {
+	k := BevelGearParms{Teeth: [2]int{20, 30}, Module: 2, PressureAngle: DtoR(20), ShaftAngle: DtoR(90), Face: 8, Backlash: 0.1}
+	d0, d1 := k.PitchAngles()
+	if !EqualFloat64(d0+d1, k.ShaftAngle, EPSILON) || !EqualFloat64(math.Tan(d0), 20.0/30.0, EPSILON) {
+		t.Error("FAIL")
+	}
+	g0, g1 := BevelGears3D(&k)
+	// a tooth of the second gear meets a tooth space of the first gear
+	a := k.ConeDistance()
+	u := V3{-math.Sin(d0), 0, -math.Cos(d0)}
+	p := u.MulScalar(a - 1)
+	if g0.Evaluate(p) <= 0 || g1.Evaluate(p) >= 0 {
+		t.Error("FAIL")
+	}
+	// the teeth don't overlap along the line of contact
+	for i := 0; i <= 8; i++ {
+		for j := -5; j <= 5; j++ {
+			p := u.MulScalar(a - k.Face*float64(i)/8)
+			p.Y = 0.5 * float64(j)
+			if Max(g0.Evaluate(p), g1.Evaluate(p)) < 0 {
+				t.Fatal("FAIL")
+			}
+		}
+	}
+	// worm gears: no overlap when meshed, overlap when the worm is moved along its axis
+	w := WormGearParms{Module: 2, Starts: 2, Teeth: 31, Diameter: 20, PressureAngle: DtoR(20), Clearance: 0.5, Length: 30, Width: 12}
+	worm, wheel := WormGears3D(&w)
+	overlap := func(worm SDF3) bool {
+		for x := -8.0; x <= 8; x += 0.5 {
+			for z := -5.0; z <= 5; z += 0.5 {
+				for y := 27.0; y <= 33; y += 0.5 {
+					p := V3{x, y, z}
+					if Max(worm.Evaluate(p), wheel.Evaluate(p)) < -0.1 {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	}
+	if overlap(worm) || !overlap(Transform3D(worm, Translate3d(V3{1, 0, 0}))) {
+		t.Error("FAIL")
+	}
+	// the throat trims the wheel tips
+	wheel = WormWheel3D(&w)
+	if wheel.Evaluate(V3{32.5, 0, 0}) >= 0 || wheel.Evaluate(V3{33.5, 0, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Fittings(t *testing.T) {
+	s := PushFitStem3D(&PushFitParms{Size: "6mm", Tolerance: 0.02})
+	if s.Evaluate(V3{2.5, 0, 5}) >= 0 || s.Evaluate(V3{1.5, 0, 5}) <= 0 || s.Evaluate(V3{2.9, 0, 16.45}) <= 0 {
+		t.Error("FAIL")
+	}
+	// the luer slip tapers seat with the male tip 5.33mm into the socket
+	m := MaleLuer3D(&LuerParms{})
+	f := FemaleLuer3D(&LuerParms{})
+	overlap := func(mouth float64) bool {
+		ff := Transform3D(f, Translate3d(V3{0, 0, mouth + luer_female_l + 1}).Mul(RotateX(PI)))
+		for x := 0.0; x <= 3; x += 0.05 {
+			for z := 0.0; z <= 8; z += 0.1 {
+				p := V3{x, 0, z}
+				if Max(m.Evaluate(p), ff.Evaluate(p)) < -0.005 {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if !overlap(1.0) || overlap(2.2) {
+		t.Error("FAIL")
+	}
+	// luer lock: lugs on the female hub, a threaded collar on the male
+	m = MaleLuer3D(&LuerParms{Lock: true, Tolerance: 0.05})
+	f = FemaleLuer3D(&LuerParms{Lock: true, Tolerance: 0.05})
+	lug, groove, crest := false, false, false
+	for i := 0; i < 32; i++ {
+		p := PolarToXY(3.6, TAU*float64(i)/32)
+		lug = lug || f.Evaluate(V3{p.X, p.Y, 7.5}) < 0
+		groove = groove || m.Evaluate(V3{p.X, p.Y, 3}) > 0
+		crest = crest || m.Evaluate(V3{p.X, p.Y, 3}) < 0
+	}
+	if !lug || !groove || !crest || m.Evaluate(V3{4.5, 0, 0.5}) >= 0 || m.Evaluate(V3{5.5, 0, 3}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_LabParts(t *testing.T) {
+	k := PeristalticParms{
+		TubeID:    1.6,
+		TubeOD:    4.8,
+		Occlusion: 0.15,
+		Rollers:   3,
+		Bearing:   "623",
+		Radius:    15,
+		Shaft:     5,
+		Flat:      0.5,
+		SetScrew:  "M3",
+		Wall:      2,
+		Clearance: 0.2,
+	}
+	if !EqualFloat64(k.HousingRadius(), 15+5+3.2*0.85, EPSILON) {
+		t.Error("FAIL")
+	}
+	s := PeristalticRotor3D(&k)
+	// roller pocket between the plates, axle hole through the plates
+	if s.Evaluate(V3{18, 0, 0}) <= 0 || s.Evaluate(V3{18, 0, 3.5}) >= 0 || s.Evaluate(V3{15, 0, 3.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	// set screw and shaft flat between the first two rollers
+	p0 := PolarToXY(6, DtoR(60))
+	p1 := PolarToXY(6, DtoR(180))
+	if s.Evaluate(V3{p0.X, p0.Y, 0.5}) <= 0 || s.Evaluate(V3{p1.X, p1.Y, 0.5}) >= 0 {
+		t.Error("FAIL")
+	}
+	p0 = PolarToXY(2.45, DtoR(60))
+	p1 = PolarToXY(2.45, DtoR(240))
+	if s.Evaluate(V3{p0.X, p0.Y, 3}) >= 0 || s.Evaluate(V3{p1.X, p1.Y, 3}) <= 0 {
+		t.Error("FAIL")
+	}
+	// syringe clamp
+	base, cap := SyringeClamp3D(&SyringeParms{Size: "10ml", Screw: "M3", Wall: 3, Clearance: 0.2})
+	h := 0.5*15.9 + 0.2 + 3
+	ys := h + 1.5
+	if base.Evaluate(V3{0, 0, h - 1}) <= 0 || base.Evaluate(V3{0, 0, 1}) >= 0 || base.Evaluate(V3{-5.5, ys, h - 1}) <= 0 {
+		t.Error("FAIL")
+	}
+	if cap.Evaluate(V3{-5.5, ys, h + 1}) <= 0 || cap.Evaluate(V3{0, 10, h + 1}) >= 0 || cap.Evaluate(V3{0, 0, h + 1}) <= 0 {
+		t.Error("FAIL")
+	}
+	// flange slot
+	if base.Evaluate(V3{7.8, 14, h - 1}) <= 0 || base.Evaluate(V3{7.8, 14, 1}) >= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Pulley(t *testing.T) {
+	k := PulleyParms{
+		Profile:   "GT2",
+		Teeth:     20,
+		Width:     7,
+		Flange:    V2{1, 1},
+		Boss:      V2{8, 6},
+		Bore:      5,
+		SetScrew:  "M3",
+		Clearance: 0.05,
+	}
+	if !EqualFloat64(k.PitchRadius(), 20.0/PI, EPSILON) {
+		t.Error("FAIL")
+	}
+	// groove on the x-axis, tooth between the grooves
+	r := k.PitchRadius() - 0.254
+	s2 := Pulley2D(&k)
+	p := PolarToXY(r-0.3, DtoR(9))
+	if s2.Evaluate(V2{r - 0.3, 0}) <= 0 || s2.Evaluate(p) >= 0 || s2.Evaluate(V2{r - 1, 0}) >= 0 {
+		t.Error("FAIL")
+	}
+	s3 := Pulley3D(&k)
+	// flanges, bore, boss and set screw
+	if s3.Evaluate(V3{r + 0.5, 0, -0.5}) >= 0 || s3.Evaluate(V3{r + 0.5, 0, 3}) <= 0 || s3.Evaluate(V3{r + 0.5, 0, 7.5}) >= 0 {
+		t.Error("FAIL")
+	}
+	if s3.Evaluate(V3{1, 0, 3}) <= 0 || s3.Evaluate(V3{0, 6, -4}) >= 0 || s3.Evaluate(V3{6, 0, -3.5}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_FeetBumpers(t *testing.T) {
+	s := FootPocket3D(6, 1)
+	if s.Evaluate(V3{3, 0, -0.5}) >= 0 || s.Evaluate(V3{3, 0, -1.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	for _, style := range []string{"dome", "cone", "pad"} {
+		s = Bumper3D(&BumperParms{Style: style, Radius: 8, Height: 4, Peg: V2{2, 4}})
+		if s.Evaluate(V3{0, 0, 3.5}) >= 0 || s.Evaluate(V3{0, 0, 4.5}) <= 0 || s.Evaluate(V3{7, 0, 0.1}) >= 0 {
+			t.Errorf("%s: FAIL", style)
+		}
+		// peg with a barb
+		if s.Evaluate(V3{1.5, 0, -2}) >= 0 || s.Evaluate(V3{2.3, 0, -3.5}) >= 0 || s.Evaluate(V3{2.3, 0, -1}) <= 0 {
+			t.Errorf("%s: FAIL", style)
+		}
+	}
+	foot, hole := ScrewFoot3D(&ScrewFootParms{Style: "pad", Radius: 10, Height: 6, Screw: "M3", Wall: 2, Clearance: 0.2})
+	// screw hole, nut pocket open at the bottom of the foot, solid around the nut
+	if foot.Evaluate(V3{1, 0, 2}) <= 0 || foot.Evaluate(V3{2.5, 0, 5.5}) <= 0 || foot.Evaluate(V3{2.5, 0, 2}) >= 0 || foot.Evaluate(V3{6, 0, 5.5}) >= 0 {
+		t.Error("FAIL")
+	}
+	if hole.Evaluate(V3{1, 0, -1}) >= 0 || hole.Evaluate(V3{2, 0, -1}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Heightmap(t *testing.T) {
+	// a ramp in x
+	s := Heightmap3D(func(x, y float64) float64 { return x + 5 }, V2{10, 10}, 1)
+	if s.Evaluate(V3{0, 0, 5.5}) >= 0 || s.Evaluate(V3{0, 0, 6.5}) <= 0 || s.Evaluate(V3{0, 0, -0.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	if s.Evaluate(V3{4, 0, 9.5}) >= 0 || s.Evaluate(V3{-4, 0, 2.5}) <= 0 || s.Evaluate(V3{6, 0, 1}) <= 0 {
+		t.Error("FAIL")
+	}
+	// the distance must not overshoot the sloped surface
+	if s.Evaluate(V3{0, 0, 8}) > 2/math.Sqrt2 {
+		t.Error("FAIL")
+	}
+	bb := s.BoundingBox()
+	if bb.Min.Z != 0 || bb.Max.Z < 11 || bb.Max.X != 5 {
+		t.Error("FAIL")
+	}
+	// a 2x1 image: black on the left, white on the right
+	img := image.NewGray(image.Rect(0, 0, 2, 1))
+	img.SetGray(1, 0, color.Gray{255})
+	f := ImageHeight(img, V2{20, 10}, 2, false)
+	if !EqualFloat64(f(-10, 0), 0, EPSILON) || !EqualFloat64(f(10, 0), 2, EPSILON) || !EqualFloat64(f(0, 3), 1, EPSILON) {
+		t.Error("FAIL")
+	}
+	f = ImageHeight(img, V2{20, 10}, 2, true)
+	if !EqualFloat64(f(-10, 0), 2, EPSILON) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_VentCap(t *testing.T) {
+	s := VentCap3D(&VentCapParms{Finish: "28-400", Wall: 2, Tolerance: 0.2, Mesh: V2{2, 1}, Chamber: 5})
+	// skirt, bottle neck and thread lead-in
+	if s.Evaluate(V3{15, 0, 5}) >= 0 || s.Evaluate(V3{12, 0, 5}) <= 0 || s.Evaluate(V3{13.5, 0, 0.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	// mesh holes and bars, solid rim around the mesh
+	if s.Evaluate(V3{0, 0, 10.45}) <= 0 || s.Evaluate(V3{1.5, 0, 10.45}) >= 0 || s.Evaluate(V3{12, 0, 10.45}) >= 0 {
+		t.Error("FAIL")
+	}
+	// desiccant chamber
+	if s.Evaluate(V3{0, 0, 14}) <= 0 || s.Evaluate(V3{15, 0, 14}) >= 0 || s.Evaluate(V3{0, 0, 17}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Noise(t *testing.T) {
+	n := NewNoise(1)
+	// zero at the lattice points, bounded between them
+	if n.Perlin(V3{3, -2, 7}) != 0 {
+		t.Error("FAIL")
+	}
+	max := 0.0
+	for i := 0; i < 1000; i++ {
+		p := V3{0.37 * float64(i), 0.11 * float64(i), 0.23 * float64(i)}
+		v := n.Fractal(p, 4)
+		if v != NewNoise(1).Fractal(p, 4) {
+			t.Error("FAIL")
+		}
+		max = Max(max, Abs(v))
+	}
+	if max > 1 || max < 0.1 {
+		t.Errorf("FAIL %f", max)
+	}
+	// displace a sphere
+	s := Displace3D(Sphere3D(10), NoiseDisplacement(2, 5, 0.5, 3))
+	if s.Evaluate(V3{9, 0, 0}) >= 0 || s.Evaluate(V3{11, 0, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	s.(*DisplaceSDF3).SetAmplitude(0.5)
+	if s.BoundingBox().Max.X != 10.5 {
+		t.Error("FAIL")
+	}
+	s = Displace3D(Sphere3D(10), func(p V3) float64 { return 1 })
+	if !EqualFloat64(s.Evaluate(V3{12, 0, 0}), 1, EPSILON) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Metaballs(t *testing.T) {
+	// two points blend into a bridge between them
+	s := Metaballs3D(0.5, MetaPoint(V3{-3, 0, 0}, 6, 1), MetaPoint(V3{3, 0, 0}, 6, 1))
+	if s.Evaluate(V3{0, 0, 0}) >= 0 || s.Evaluate(V3{3, 0, 0}) >= 0 || s.Evaluate(V3{3, 4, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	// a single point is a sphere, the distance is a lower bound
+	s = Metaballs3D(0.5, MetaPoint(V3{0, 0, 0}, 6, 1))
+	r := 6 * math.Sqrt(1-math.Cbrt(0.5))
+	if s.Evaluate(V3{r - 0.1, 0, 0}) >= 0 || s.Evaluate(V3{r + 0.1, 0, 0}) <= 0 || s.Evaluate(V3{r + 1, 0, 0}) > 1 {
+		t.Error("FAIL")
+	}
+	// a line and a -ve point
+	s = Metaballs3D(0.5, MetaLine(V3{0, 0, 0}, V3{10, 0, 0}, 2, 1), MetaPoint(V3{10, 0, 0}, 2, -1))
+	if s.Evaluate(V3{5, 0.5, 0}) >= 0 || s.Evaluate(V3{5, 1.5, 0}) <= 0 || s.Evaluate(V3{10, 0, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	// a plane is limited to the bounding box of the other sources
+	s = Metaballs3D(0.5, MetaPoint(V3{0, 0, 2}, 4, 1), MetaPlane(V3{0, 0, 0}, V3{0, 0, 1}, 2, 1))
+	if s.Evaluate(V3{0, 0, 0}) >= 0 || s.Evaluate(V3{3, 3, 0}) >= 0 || s.Evaluate(V3{5, 5, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	// a tapered line
+	s = Metaballs3D(0.5, MetaTaperedLine(V3{0, 0, 0}, V3{20, 0, 0}, 2, 6, 1))
+	if s.Evaluate(V3{0, 0.5, 0}) >= 0 || s.Evaluate(V3{0, 1.5, 0}) <= 0 || s.Evaluate(V3{20, 2, 0}) >= 0 || s.BoundingBox().Max.Y != 6 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_SortingTray(t *testing.T) {
+	k := TrayParms{
+		Size:      V2{100, 60},
+		Height:    30,
+		Grid:      V2i{2, 2},
+		Wall:      2,
+		Floor:     3,
+		Taper:     0.5,
+		Chamfer:   0.5,
+		Stack:     2,
+		Clearance: 0.2,
+	}
+	s := SortingTray3D(&k)
+	// the +x+y compartment funnels to its outside corner
+	if s.Evaluate(V3{40, 25, 4}) <= 0 || s.Evaluate(V3{5, 3, 4}) >= 0 || s.Evaluate(V3{5, 3, 29}) <= 0 {
+		t.Error("FAIL")
+	}
+	// floor and divider walls
+	if s.Evaluate(V3{40, 25, 2}) >= 0 || s.Evaluate(V3{0, 0, 20}) >= 0 || s.Evaluate(V3{-40, -25, 4}) <= 0 {
+		t.Error("FAIL")
+	}
+	// stacking lip and bottom step
+	if s.Evaluate(V3{49.5, 0, 31}) >= 0 || s.Evaluate(V3{47, 0, 31}) <= 0 || s.Evaluate(V3{49.5, 0, 1}) <= 0 || s.Evaluate(V3{48, 0, 1}) >= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Infill(t *testing.T) {
+	box := Box3D(V3{20, 20, 20}, 0)
+	// struts, voids, skin
+	s := Infill3D(box, CubicLattice(5, 1), 1)
+	if s.Evaluate(V3{0, 0, 0}) >= 0 || s.Evaluate(V3{2.5, 2.5, 2.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	if s.Evaluate(V3{9.5, 2.5, 2.5}) >= 0 || s.Evaluate(V3{10.5, 2.5, 2.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	s = Infill3D(box, HoneycombLattice(6, 1), 1)
+	if s.Evaluate(V3{0, 0, 2.5}) <= 0 || s.Evaluate(V3{3, 0, 2.5}) >= 0 || s.Evaluate(V3{3, 5.2, 2.5}) <= 0 || s.Evaluate(V3{0, 5.2, 2.5}) >= 0 {
+		t.Error("FAIL")
+	}
+	s = Infill3D(box, GyroidLattice(10, 1), 1)
+	if s.Evaluate(V3{0, 0, 0}) >= 0 || s.Evaluate(V3{0, 2.5, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	s = Infill3D(box, SchwarzPLattice(10, 1), 1)
+	if s.Evaluate(V3{0, 0, 0}) <= 0 || s.Evaluate(V3{2.5, 2.5, 2.5}) >= 0 {
+		t.Error("FAIL")
+	}
+	s = Infill3D(box, DiamondLattice(10, 1), 1)
+	if s.Evaluate(V3{0, 0, 0}) >= 0 || s.Evaluate(V3{2.5, 0, -2.5}) >= 0 || s.Evaluate(V3{1.25, 1.25, 1.25}) <= 0 {
+		t.Error("FAIL")
+	}
+	// lidinoid: periodic, with sheets and voids along the x-axis
+	l := LidinoidLattice(10, 0.5)
+	inside, outside := false, false
+	for x := 0.0; x < 10; x += 0.1 {
+		d := l(V3{x, 1, 2})
+		if !EqualFloat64(d, l(V3{x + 10, 1, 2}), TOLERANCE) || !EqualFloat64(d, l(V3{x, 11, -8}), TOLERANCE) {
+			t.Error("FAIL")
+		}
+		inside = inside || d < 0
+		outside = outside || d > 0
+	}
+	if !inside || !outside {
+		t.Error("FAIL")
+	}
+	// trimmed to a box
+	s = Lattice3D(GyroidLattice(10, 1), V3{30, 20, 10})
+	if s.Evaluate(V3{0, 0, 0}) >= 0 || s.Evaluate(V3{0, 2.5, 0}) <= 0 || s.Evaluate(V3{20, 0, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_CoonsPatch(t *testing.T) {
+	// a flat square
+	c0 := BezierCurve3(V3{0, 0, 0}, V3{10, 0, 0})
+	c1 := BezierCurve3(V3{0, 10, 0}, V3{10, 10, 0})
+	d0 := BezierCurve3(V3{0, 0, 0}, V3{0, 10, 0})
+	d1 := BezierCurve3(V3{10, 0, 0}, V3{10, 10, 0})
+	s := CoonsPatch3D(NewCoonsPatch(c0, c1, d0, d1), 2)
+	if s.Evaluate(V3{5, 5, 0.9}) >= 0 || s.Evaluate(V3{5, 5, 1.1}) <= 0 || s.Evaluate(V3{5, 5, -0.5}) >= 0 {
+		t.Error("FAIL")
+	}
+	if !EqualFloat64(s.Evaluate(V3{3, 7, 3}), 2, 1e-6) || !EqualFloat64(s.Evaluate(V3{11.5, 5, 0}), 0.5, 1e-6) {
+		t.Error("FAIL")
+	}
+	// a ridge: curved along u, straight along v
+	c0 = BezierCurve3(V3{0, 0, 0}, V3{5, 0, 5}, V3{10, 0, 0})
+	c1 = BezierCurve3(V3{0, 10, 0}, V3{5, 10, 5}, V3{10, 10, 0})
+	p := NewCoonsPatch(c0, c1, d0, d1)
+	if !p.Evaluate(0.5, 0.3).Equals(V3{5, 3, 2.5}, 1e-9) {
+		t.Error("FAIL")
+	}
+	s = CoonsPatch3D(p, 2)
+	if s.Evaluate(V3{5, 5, 2.5}) >= 0 || s.Evaluate(V3{5, 5, 0}) <= 0 || !EqualFloat64(s.Evaluate(V3{5, 5, 5}), 1.5, 1e-4) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_PolarArray(t *testing.T) {
+	c := Transform2D(Circle2D(1), Translate2d(V2{10, 0}))
+	s2 := PolarArray2D(c, 4)
+	if s2.Evaluate(V2{0, 10}) >= 0 || s2.Evaluate(V2{7, 7}) <= 0 {
+		t.Error("FAIL")
+	}
+	bb2 := Box2{V2{-11, -11}, V2{11, 11}}
+	if !s2.BoundingBox().Equals(bb2, 1e-9) || !RotateCopy2D(c, 4).BoundingBox().Equals(bb2, 1e-9) {
+		t.Error("FAIL")
+	}
+	// rotate about the y-axis
+	s := PolarArray3D(Transform3D(Sphere3D(1), Translate3d(V3{10, 0, 0})), 4, V3{0, 1, 0})
+	if s.Evaluate(V3{0, 0, 10}) >= 0 || s.Evaluate(V3{-10, 0, 0}) >= 0 || s.Evaluate(V3{0, 10, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	bb := Box3{V3{-11, -1, -11}, V3{11, 1, 11}}
+	if !s.BoundingBox().Equals(bb, 1e-9) {
+		t.Error("FAIL")
+	}
+	s = RotateCopy3D(Transform3D(Sphere3D(1), Translate3d(V3{10, 0, 0})), 4)
+	if !s.BoundingBox().Equals(Box3{V3{-11, -11, -1}, V3{11, 11, 1}}, 1e-9) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_GridArray(t *testing.T) {
+	// hex grid, skip one cell and move another
+	f := func(i, j int) (bool, M33) {
+		if i == 1 && j == 0 {
+			return false, Identity2d()
+		}
+		if i == 2 && j == 1 {
+			return true, Translate2d(V2{0, 3})
+		}
+		return true, Identity2d()
+	}
+	s2 := GridArray2D(Circle2D(2), V2i{3, 2}, V2{10, 8.66}, true, f)
+	if s2.Evaluate(V2{0, 0}) >= 0 || s2.Evaluate(V2{10, 0}) <= 0 || s2.Evaluate(V2{20, 0}) >= 0 {
+		t.Error("FAIL")
+	}
+	if s2.Evaluate(V2{5, 8.66}) >= 0 || s2.Evaluate(V2{0, 8.66}) <= 0 || s2.Evaluate(V2{25, 11.66}) >= 0 || s2.Evaluate(V2{25, 8.66}) <= 0 {
+		t.Error("FAIL")
+	}
+	// rectangular 3d grid, skip the top layer
+	s := GridArray3D(Sphere3D(1), V3i{2, 2, 2}, V3{5, 5, 5}, false, func(i, j, k int) (bool, M44) {
+		return k == 0, Identity3d()
+	})
+	if s.Evaluate(V3{5, 5, 0}) >= 0 || s.Evaluate(V3{0, 0, 5}) <= 0 {
+		t.Error("FAIL")
+	}
+	s = GridArray3D(Sphere3D(1), V3i{2, 2, 2}, V3{5, 5, 5}, false, nil)
+	if s.Evaluate(V3{5, 5, 5}) >= 0 || !s.BoundingBox().Equals(Box3{V3{-1, -1, -1}, V3{6, 6, 6}}, 1e-9) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Skeleton(t *testing.T) {
+	k := NewSkeleton()
+	n0 := k.AddNode(V3{0, 0, 0}, 2)
+	n1 := k.AddNode(V3{0, 0, 10}, 1)
+	n2 := k.AddNode(V3{5, 0, 15}, 0.5)
+	k.AddNode(V3{20, 0, 0}, 1)
+	k.AddEdge(n0, n1)
+	k.AddEdge(n1, n2)
+	s := Skeleton3D(k, 0)
+	if !EqualFloat64(s.Evaluate(V3{0, 0, -3}), 1, 1e-9) || s.Evaluate(V3{20, 0, 0.5}) >= 0 {
+		t.Error("FAIL")
+	}
+	// the tapered cone between the nodes
+	if s.Evaluate(V3{1.4, 0, 5}) >= 0 || s.Evaluate(V3{1.7, 0, 5}) <= 0 {
+		t.Error("FAIL")
+	}
+	// blending grows the joins, not the ends
+	b := Skeleton3D(k, 0.5)
+	if !EqualFloat64(b.Evaluate(V3{0, 0, -3}), 1, 1e-6) || b.Evaluate(V3{-1.2, 0, 10.5}) >= s.Evaluate(V3{-1.2, 0, 10.5})-0.1 {
+		t.Error("FAIL")
+	}
+	if b.BoundingBox().Max.X <= 21 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Repeat(t *testing.T) {
+	// domain repetition matches the union of copies
+	s0 := Repeat3D(Sphere3D(1), V3{4, 3, 5}, V3i{10, 3, 1})
+	s1 := Array3D(Sphere3D(1), V3i{10, 3, 1}, V3{4, 3, 5})
+	for _, p := range []V3{{18.2, 0.3, 0}, {50, 0, 0}, {-3, -2, 1}, {7, 4.5, 0.5}, {30, 20, -4}} {
+		if !EqualFloat64(s0.Evaluate(p), s1.Evaluate(p), 1e-9) {
+			t.Errorf("FAIL %v", p)
+		}
+	}
+	if !s0.BoundingBox().Equals(s1.BoundingBox(), 1e-9) {
+		t.Error("FAIL")
+	}
+	s2 := Repeat2D(Circle2D(1), V2{4, 3}, V2i{10, 3})
+	s3 := Array2D(Circle2D(1), V2i{10, 3}, V2{4, 3})
+	for _, p := range []V2{{18.2, 0.3}, {50, 0}, {-3, -2}, {7, 4.5}} {
+		if !EqualFloat64(s2.Evaluate(p), s3.Evaluate(p), 1e-9) {
+			t.Errorf("FAIL %v", p)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_ReactionDiffusion(t *testing.T) {
+	k := ReactionDiffusionParms{
+		Size:  V3i{32, 32, 1},
+		Du:    0.16,
+		Dv:    0.08,
+		Feed:  0.055,
+		Kill:  0.062,
+		Steps: 500,
+		Spots: 8,
+		Seed:  1,
+	}
+	r0 := NewReactionDiffusion(&k)
+	r1 := NewReactionDiffusion(&k)
+	v_min, v_max := 1.0, 0.0
+	for i := 0; i < 100; i++ {
+		p := V3{0.37 * float64(i), 0.71 * float64(i), 0}
+		v := r0.Sample(p, 1)
+		if v != r1.Sample(p, 1) || v < 0 || v > 1 {
+			t.Error("FAIL")
+		}
+		v_min = Min(v_min, v)
+		v_max = Max(v_max, v)
+		// the pattern tiles
+		if !EqualFloat64(v, r0.Sample(p.Add(V3{32, -64, 7}), 1), 1e-9) {
+			t.Error("FAIL")
+		}
+	}
+	if v_max-v_min < 0.5 {
+		t.Error("FAIL")
+	}
+	// displacement and perforation
+	d := r0.Displacement(2, 0.5)
+	if !EqualFloat64(d(V3{3, 5, 0}), 0.5*r0.Sample(V3{1.5, 2.5, 0}, 1), 1e-9) {
+		t.Error("FAIL")
+	}
+	l := r0.Lattice(1, 0.5)
+	if (l(V3{3, 5, 0}) < 0) != (r0.Sample(V3{3, 5, 0}, 1) > 0.5) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_LSystem(t *testing.T) {
+	l := LSystem{Axiom: "X", Rules: map[rune]string{'X': "F[+X][-X]"}}
+	if l.Expand(0) != "X" || l.Expand(2) != "F[+F[+X][-X]][-F[+X][-X]]" {
+		t.Error("FAIL")
+	}
+	k := TurtleParms{Step: 10, Angle: DtoR(30), Radius: 2, RadiusTaper: 0.5, StepTaper: 1}
+	sk := k.Skeleton("F[+F][-!F]")
+	if len(sk.nodes) != 4 || len(sk.edges) != 3 {
+		t.Error("FAIL")
+	}
+	// turn left is towards +y, the tapered branch has a smaller radius
+	if !sk.nodes[2].Equals(V3{0, 5, 10 + 5*math.Sqrt(3)}, 1e-9) || !sk.nodes[3].Equals(V3{0, -5, 10 + 5*math.Sqrt(3)}, 1e-9) || sk.radii[3] != 1 {
+		t.Error("FAIL")
+	}
+	s := Skeleton3D(sk, 0.5)
+	if s.Evaluate(V3{0, 0, 5}) >= 0 || s.Evaluate(V3{0, 5, 18.66}) >= 0 || s.Evaluate(V3{0, 0, 20}) <= 0 {
+		t.Error("FAIL")
+	}
+	// pitch, roll and move without drawing
+	sk = k.Skeleton("&F/f!F")
+	if len(sk.nodes) != 4 || len(sk.edges) != 2 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Mirror(t *testing.T) {
+	if !MirrorPlane3d(V3{0, 3, 4}, V3{2, 0, 0}).Equals(MirrorYZ(), 1e-12) {
+		t.Error("FAIL")
+	}
+	ball := Transform3D(Sphere3D(1), Translate3d(V3{5, 0, 0}))
+	s := Mirror3D(ball, V3{10, 0, 0}, V3{1, 0, 0})
+	if s.Evaluate(V3{15, 0, 0}) >= 0 || s.Evaluate(V3{5, 0, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	s = Mirror3D(ball, V3{0, 0, 0}, V3{1, -1, 0})
+	if !EqualFloat64(s.Evaluate(V3{0, 5, 0}), -1, 1e-9) {
+		t.Error("FAIL")
+	}
+	disk := Transform2D(Circle2D(1), Translate2d(V2{5, 1}))
+	s2 := Mirror2D(disk, V2{0, 3}, V2{0, 1})
+	if !EqualFloat64(s2.Evaluate(V2{5, 5}), -1, 1e-9) {
+		t.Error("FAIL")
+	}
+	// model one octant
+	cube := Transform3D(Box3D(V3{2, 2, 2}, 0), Translate3d(V3{5, 5, 5}))
+	s = Symmetry3D(cube, true, true, true)
+	if s.Evaluate(V3{-5, -5, -5}) >= 0 || s.Evaluate(V3{5, -5, 5}) >= 0 || s.Evaluate(V3{0, 0, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	if !s.BoundingBox().Equals(Box3{V3{-6, -6, -6}, V3{6, 6, 6}}, 1e-9) {
+		t.Error("FAIL")
+	}
+	s2 = Symmetry2D(disk, true, false)
+	if s2.Evaluate(V2{-5, 1}) >= 0 || s2.Evaluate(V2{-5, -1}) <= 0 || !s2.BoundingBox().Equals(Box2{V2{-6, 0}, V2{6, 2}}, 1e-9) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Smooth(t *testing.T) {
+	// a flat face is unchanged, a corner is rounded
+	box := Box3D(V3{10, 10, 10}, 0)
+	s := Smooth3D(box, 0.5)
+	if !EqualFloat64(s.Evaluate(V3{0, 0, 6}), 1, 1e-9) || !EqualFloat64(s.Evaluate(V3{0, 0, 4}), -1, 1e-9) {
+		t.Error("FAIL")
+	}
+	if s.Evaluate(V3{4.95, 4.95, 4.95}) <= box.Evaluate(V3{4.95, 4.95, 4.95}) {
+		t.Error("FAIL")
+	}
+	// stair-steps are softened
+	steps := Union2D(Box2D(V2{10, 2}, 0), Transform2D(Box2D(V2{10, 2}, 0), Translate2d(V2{0.5, 0.5})))
+	s2 := Smooth2D(steps, 0.5)
+	if Abs(s2.Evaluate(V2{5.25, 0.75})) >= Abs(steps.Evaluate(V2{5.25, 0.75})) {
+		t.Error("FAIL")
+	}
+	if !s2.BoundingBox().Equals(Box2{V2{-5.5, -1.5}, V2{6, 2}}, 1e-9) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Elongate(t *testing.T) {
+	// sphere -> capsule
+	s := Elongate3D(Sphere3D(2), V3{0, 0, 10})
+	c := Cylinder3D(14, 2, 2)
+	for _, p := range []V3{{0, 0, 0}, {1, 0, 6}, {3, 1, 2}, {0, 0, 9}, {-1, 2, -8}} {
+		if !EqualFloat64(s.Evaluate(p), c.Evaluate(p), 1e-9) {
+			t.Errorf("FAIL %v", p)
+		}
+	}
+	if !s.BoundingBox().Equals(Box3{V3{-2, -2, -7}, V3{2, 2, 7}}, 1e-9) {
+		t.Error("FAIL")
+	}
+	// circle -> stadium (slot)
+	s2 := Elongate2D(Circle2D(1), V2{6, 0})
+	if !EqualFloat64(s2.Evaluate(V2{2, 3}), 2, 1e-9) || !EqualFloat64(s2.Evaluate(V2{5, 0}), 1, 1e-9) || !EqualFloat64(s2.Evaluate(V2{0, 0}), -1, 1e-9) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_BoxFrame(t *testing.T) {
+	s := BoxFrame3D(V3{20, 10, 10}, 2, 0)
+	// on a bar, the open face, the center
+	if !EqualFloat64(s.Evaluate(V3{0, 4.5, 4.5}), -0.5, 1e-9) || !EqualFloat64(s.Evaluate(V3{0, 0, 5}), 3, 1e-9) || !EqualFloat64(s.Evaluate(V3{0, 0, 0}), 3*math.Sqrt2, 1e-9) {
+		t.Error("FAIL")
+	}
+	if !EqualFloat64(s.Evaluate(V3{10, 5, 7}), 2, 1e-9) || !EqualFloat64(s.Evaluate(V3{0, 5, 2}), 1, 1e-9) {
+		t.Error("FAIL")
+	}
+	// rounded bars
+	r := BoxFrame3D(V3{20, 10, 10}, 2, 0.5)
+	if !EqualFloat64(r.Evaluate(V3{0, 0, 4}), 3, 1e-9) || r.Evaluate(V3{0, 4.95, 4.95}) <= s.Evaluate(V3{0, 4.95, 4.95}) {
+		t.Error("FAIL")
+	}
+	w := BoundingWire3D(Sphere3D(5), 1)
+	if !w.BoundingBox().Equals(Box3{V3{-6, -6, -6}, V3{6, 6, 6}}, 1e-9) || w.Evaluate(V3{5.5, 5.5, 0}) >= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_ScaleNonUniform(t *testing.T) {
+	// sphere -> ellipsoid
+	s := ScaleNonUniform3D(Sphere3D(1), V3{4, 2, 1})
+	e := Ellipsoid3D(V3{4, 2, 1})
+	if !s.BoundingBox().Equals(e.BoundingBox(), 1e-9) {
+		t.Error("FAIL")
+	}
+	// exact along the smallest axis, a bound elsewhere, same inside/outside
+	if !EqualFloat64(s.Evaluate(V3{0, 0, 3}), 2, 1e-9) || s.Evaluate(V3{6, 0, 0}) > 2 || s.Evaluate(V3{6, 0, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	for _, p := range []V3{{3.9, 0, 0}, {0, 1.9, 0}, {2, 1, 0.5}, {3, 1.5, 0.2}} {
+		if (s.Evaluate(p) < 0) != (e.Evaluate(p) < 0) {
+			t.Errorf("FAIL %v", p)
+		}
+	}
+	// uniform scale is exact
+	u := ScaleNonUniform3D(Sphere3D(1), V3{3, 3, 3})
+	if !EqualFloat64(u.Evaluate(V3{0, 5, 0}), 2, 1e-9) {
+		t.Error("FAIL")
+	}
+	s2 := ScaleNonUniform2D(Circle2D(1), V2{3, 1})
+	if !EqualFloat64(s2.Evaluate(V2{0, 2}), 1, 1e-9) || s2.Evaluate(V2{2.9, 0}) >= 0 || s2.Evaluate(V2{3.1, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Bend(t *testing.T) {
+	l := 10 * PI
+	plate := Box3D(V3{l, 4, 2}, 0)
+	s := Bend3D(plate, 20)
+	// the bottom face stays at z = -1 in the middle, the ends are on the bend
+	a := 0.25 * PI
+	end := V3{20 * math.Sin(a), 0, 20 - 20*math.Cos(a)}
+	if !EqualFloat64(s.Evaluate(V3{0, 0, -1}), 0, 1e-9) || !EqualFloat64(s.Evaluate(end), 0, 1e-9) {
+		t.Error("FAIL")
+	}
+	mid := V3{20 * math.Sin(0.5*a), 0, 20 - 20*math.Cos(0.5*a)}
+	if s.Evaluate(mid) >= 0 || s.Evaluate(V3{0, 0, 1.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	bb := Box3{V3{-21 * math.Sin(a), -2, -1}, V3{21 * math.Sin(a), 2, 20 - 19*math.Cos(a)}}
+	if !s.BoundingBox().Equals(bb, 1e-9) {
+		t.Error("FAIL")
+	}
+	// bend down
+	d := Bend3D(plate, -20)
+	if !EqualFloat64(d.Evaluate(V3{end.X, 0, -end.Z}), 0, 1e-9) || !d.BoundingBox().Equals(MirrorXY().MulBox(bb), 1e-9) {
+		t.Error("FAIL")
+	}
+}
+
+func Test_Taper(t *testing.T) {
+	s := Taper3D(Cylinder3D(10, 2, 0), 0.5)
+	if !EqualFloat64(s.Evaluate(V3{1.5, 0, 0}), 0, 1e-9) {
+		t.Error("FAIL")
+	}
+	if s.Evaluate(V3{1.5, 0, 4.9}) <= 0 || s.Evaluate(V3{1.5, 0, -4.9}) >= 0 {
+		t.Error("FAIL")
+	}
+	if !s.BoundingBox().Equals(Box3{V3{-2, -2, -5}, V3{2, 2, 5}}, 1e-9) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_TappingScrew(t *testing.T) {
+	k := TappingScrewParms{Diameter: 4, Pitch: 1.8, Length: 20, Tip: 4, Thread: "wood", Head: "pan"}
+	s := TappingScrew3D(&k)
+	if s.Evaluate(V3{0.5, 0, 8}) >= 0 || s.Evaluate(V3{0, 0, 20.1}) <= 0 || s.Evaluate(V3{0, 0, -1}) >= 0 {
+		t.Error("FAIL")
+	}
+	// the thread on the tip lines up with the thread on the body
+	l := k.Length - k.Tip
+	body := Screw3D(WoodScrewThread(2, 1.8), l+4*1.8, 1.8, 1)
+	body = Transform3D(body, Translate3d(V3{0, 0, 0.5 * l}))
+	for i := 0; i < 36; i++ {
+		a := DtoR(float64(i * 10))
+		p := V3{1.65 * math.Cos(a), 1.65 * math.Sin(a), l + 0.05}
+		d := body.Evaluate(p)
+		if Abs(d) > 0.05 && (d < 0) != (s.Evaluate(p) < 0) {
+			t.Errorf("FAIL %v", p)
+		}
+	}
+	// thread too coarse for the diameter
+	defer func() {
+		if recover() == nil {
+			t.Error("FAIL")
+		}
+	}()
+	k.Pitch = 2.5
+	TappingScrew3D(&k)
+}
+
+func Test_ScrewBoss(t *testing.T) {
+	b := ScrewBossLookup(3, "ABS")
+	if !EqualFloat64(b.Pilot, 2.4, 1e-9) || !EqualFloat64(b.Outside, 6, 1e-9) || !EqualFloat64(b.Depth, 6, 1e-9) {
+		t.Error("FAIL")
+	}
+	s := ScrewBoss3D(&ScrewBossParms{Diameter: 3, Material: "ABS", Height: 10})
+	if s.Evaluate(V3{1.5, 0, 5}) >= 0 || s.Evaluate(V3{1, 0, 9}) <= 0 || s.Evaluate(V3{1, 0, 1}) >= 0 {
+		t.Error("FAIL")
+	}
+	// entry counterbore
+	if s.Evaluate(V3{1.55, 0, 9.9}) <= 0 || s.Evaluate(V3{1.55, 0, 8}) >= 0 {
+		t.Error("FAIL")
+	}
+	if !s.BoundingBox().Equals(Box3{V3{-3, -3, 0}, V3{3, 3, 10}}, 1e-9) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Metadata(t *testing.T) {
+	k := BoltParms{Thread: "M6x1-6g", Style: "hex", TotalLength: 20}
+	m, err := NewMetadata(&k, "v1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Config[0] != '{' || len(m.Hash()) != 16 {
+		t.Error("FAIL")
+	}
+	mesh := []*Triangle3{{V: [3]V3{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}}}
+	path := t.TempDir() + "/meta.stl"
+	if err := SaveSTLWithMetadata(path, mesh, m); err != nil {
+		t.Fatal(err)
+	}
+	h, err := ReadSTLHeader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := m.Summary()
+	if len(h) != 80 || h[:len(summary)] != summary || h[len(summary)+1] != '{' {
+		t.Errorf("FAIL %q", h)
+	}
+	// no metadata
+	if err := SaveSTL(path, mesh); err != nil {
+		t.Fatal(err)
+	}
+	if h, _ := ReadSTLHeader(path); h != "" {
+		t.Error("FAIL")
+	}
+	// binary STL: 80 byte header + 4 byte count, 50 bytes per triangle
+	if n := binary.Size(STLHeader{}); n != 84 {
+		t.Errorf("FAIL %d", n)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 84+50 {
+		t.Error("FAIL")
+	}
+	// 3mf metadata, two objects with a shared vertex
+	mesh = append(mesh, &Triangle3{V: [3]V3{{1, 0, 0}, {1, 1, 0}, {0, 1, 0}}})
+	path = filepath.Join(t.TempDir(), "meta.3mf")
+	if err := Save3MFObjects(path, []*Object3MF{{"a<b", mesh}, {"c", mesh[:1]}}, m); err != nil {
+		t.Fatal(err)
+	}
+	md, err := Read3MFMetadata(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md["sdfx:config"] != m.Config || md["Description"] != summary || md["Application"] != "sdfx v1.0" || md["CreationDate"] == "" {
+		t.Errorf("FAIL %v", md)
+	}
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := z.Open(TMF_MODEL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, _ := io.ReadAll(f)
+	z.Close()
+	if strings.Count(string(model), "<vertex ") != 4+3 || strings.Count(string(model), "<triangle ") != 3 || !strings.Contains(string(model), `name="a&lt;b"`) || strings.Count(string(model), "<item ") != 2 {
+		t.Errorf("FAIL %s", model)
+	}
+	// no metadata
+	if err := Save3MF(path, mesh, nil); err != nil {
+		t.Fatal(err)
+	}
+	if md, err := Read3MFMetadata(path); err != nil || len(md) != 0 {
+		t.Error("FAIL")
+	}
+	// svg comment, "--" isn't allowed in the comment
+	m.Config = `{"a":"x--y---z"}`
+	path = filepath.Join(t.TempDir(), "layer_%d.svg")
+	if _, err := SliceStack(Sphere3D(5), &SliceParms{LayerHeight: 10, Resolution: 0.5, Format: "svg", Path: path, Metadata: m}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(fmt.Sprintf(path, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(b), "\n")
+	if lines[1] != "<!-- "+m.Summary() || lines[2] != `{"a":"x- -y- - -z"} -->` || !strings.HasPrefix(lines[3], "<svg") {
+		t.Errorf("FAIL %q", lines[:3])
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Shear(t *testing.T) {
+	s := Shear2D(Box2D(V2{2, 2}, 0), 1)
+	if !EqualFloat64(s.Evaluate(V2{2, 1}), 0, 1e-9) || s.Evaluate(V2{0, 0}) >= 0 || s.Evaluate(V2{-1.5, 0.9}) <= 0 {
+		t.Error("FAIL")
+	}
+	if !s.BoundingBox().Equals(Box2{V2{-2, -1}, V2{2, 1}}, 1e-9) {
+		t.Error("FAIL")
+	}
+	// conservative: the true distance from (3,0) is sqrt(2)
+	d := s.Evaluate(V2{3, 0})
+	if d <= 0 || d > math.Sqrt(2) {
+		t.Error("FAIL")
+	}
+	s3 := Shear3D(Box3D(V3{2, 2, 2}, 0), V2{1, 0})
+	if !EqualFloat64(s3.Evaluate(V3{2, 0, 1}), 0, 1e-9) || s3.Evaluate(V3{1.5, 0, 0.9}) >= 0 || s3.Evaluate(V3{-1.5, 0, 0.9}) <= 0 {
+		t.Error("FAIL")
+	}
+	if !s3.BoundingBox().Equals(Box3{V3{-2, -1, -1}, V3{2, 1, 1}}, 1e-9) {
+		t.Error("FAIL")
+	}
+	// no shear is exact
+	s0 := Shear3D(Sphere3D(1), V2{0, 0})
+	if !EqualFloat64(s0.Evaluate(V3{0, 0, 3}), 2, 1e-9) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Round(t *testing.T) {
+	// a rounded box the same size as Box3D with rounding
+	s := Round3D(Box3D(V3{8, 8, 8}, 0), 1)
+	b := Box3D(V3{10, 10, 10}, 1)
+	if !s.BoundingBox().Equals(b.BoundingBox(), 1e-9) {
+		t.Error("FAIL")
+	}
+	for _, p := range []V3{{6, 6, 6}, {5, 0, 0}, {4.9, 4.9, 0}, {0, 0, 0}, {7, 2, -3}} {
+		if !EqualFloat64(s.Evaluate(p), b.Evaluate(p), 1e-9) {
+			t.Errorf("FAIL %v", p)
+		}
+	}
+	s2 := Round2D(Box2D(V2{8, 8}, 0), 1)
+	b2 := Box2D(V2{10, 10}, 1)
+	if !EqualFloat64(s2.Evaluate(V2{6, 6}), b2.Evaluate(V2{6, 6}), 1e-9) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_CheckDistance(t *testing.T) {
+	sphere := Sphere3D(5)
+	box := Box3D(V3{8, 8, 8}, 1)
+	exact := []struct {
+		name string
+		s    SDF3
+	}{
+		{"sphere", sphere},
+		{"box", box},
+		{"elongate", Elongate3D(sphere, V3{4, 0, 2})},
+	}
+	for _, x := range exact {
+		c := CheckDistance3D(x.name, x.s, 40, 100)
+		t.Log(c)
+		if c.MaxError > 2*c.Resolution {
+			t.Errorf("FAIL %s", c)
+		}
+	}
+	bounds := []struct {
+		name string
+		s    SDF3
+	}{
+		{"union", Union3D(sphere, Transform3D(box, Translate3d(V3{6, 0, 0})))},
+		{"difference", Difference3D(box, sphere)},
+		{"intersect", Intersect3D(box, Transform3D(sphere, Translate3d(V3{3, 0, 0})))},
+		{"shear", Shear3D(box, V2{1, 0})},
+		{"taper", Taper3D(box, 0.5)},
+	}
+	for _, x := range bounds {
+		c := CheckDistance3D(x.name, x.s, 40, 100)
+		t.Log(c)
+		if c.MaxOver > c.Resolution {
+			t.Errorf("FAIL %s", c)
+		}
+	}
+	c := CheckDistance2D("circle", Circle2D(3), 100, 100)
+	if c.MaxError > 2*c.Resolution {
+		t.Errorf("FAIL %s", c)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_SplitWithJoints(t *testing.T) {
+	s := Box3D(V3{60, 40, 20}, 0)
+	k := SplitParms{Normal: V3{0, 0, 1}, Style: "pin", Size: 4, Length: 8, Clearance: 0.2, Count: 4}
+	m := split_frame(k.Point, k.Normal)
+	if !m.MulPosition(V3{1, 2, 3}).Equals(V3{1, 2, 3}, 1e-9) {
+		t.Error("FAIL")
+	}
+	positions := split_positions(s, m, &k)
+	if len(positions) != 4 {
+		t.Errorf("FAIL %v", positions)
+	}
+	top, bottom := SplitWithJoints3D(s, &k)
+	for _, p := range positions {
+		// the pin sticks out of the top part into the socket in the bottom part
+		x := V3{p.X, p.Y, -2}
+		if top.Evaluate(x) >= 0 || bottom.Evaluate(x) <= 0 {
+			t.Errorf("FAIL %v", p)
+		}
+		// clearance around the pin
+		x = V3{p.X + 2.1, p.Y, -2}
+		if top.Evaluate(x) <= 0 || bottom.Evaluate(x) <= 0 {
+			t.Errorf("FAIL %v", p)
+		}
+	}
+	if top.Evaluate(V3{0, 0, -5}) <= 0 || bottom.Evaluate(V3{0, 0, 5}) <= 0 {
+		t.Error("FAIL")
+	}
+
+	k.Style = "dovetail"
+	k.Length = 4
+	k.Count = 2
+	positions = split_positions(s, m, &k)
+	if len(positions) != 2 {
+		t.Errorf("FAIL %v", positions)
+	}
+	top, bottom = SplitWithJoints3D(s, &k)
+	for _, p := range positions {
+		// the rail runs along x, it is wider at the bottom
+		for _, x := range []V3{{p.X, p.Y, -2}, {25, p.Y + 2.8, -3.8}} {
+			if top.Evaluate(x) >= 0 || bottom.Evaluate(x) <= 0 {
+				t.Errorf("FAIL %v", x)
+			}
+		}
+		// the rail stays inside the solid
+		if top.Evaluate(V3{31, p.Y, -2}) <= 0 {
+			t.Error("FAIL")
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_SliceStack(t *testing.T) {
+	ring := Difference3D(Cylinder3D(2, 5, 0), Cylinder3D(3, 2, 0))
+	dir := t.TempDir()
+	for _, f := range []string{"png", "svg"} {
+		k := SliceParms{LayerHeight: 0.5, Resolution: 0.1, Format: f, Path: dir + "/layer_%02d." + f}
+		n, err := SliceStack(ring, &k)
+		if err != nil || n != 4 {
+			t.Errorf("FAIL %d %v", n, err)
+		}
+	}
+	layer := &layer_sdf2{sdf: ring, z: 0.25, bb: Box2{V2{-5, -5}, V2{5, 5}}}
+	img := slice_image(layer, 0.1, false)
+	if img.Bounds().Dx() != 100 || img.Bounds().Dy() != 100 {
+		t.Error("FAIL")
+	}
+	// hole, ring, outside
+	if img.GrayAt(50, 50).Y != 0 || img.GrayAt(50, 10).Y != 255 || img.GrayAt(1, 1).Y != 0 {
+		t.Error("FAIL")
+	}
+	// anti-aliased edge: the pixel center is on the outer edge
+	layer.bb = Box2{V2{-5.05, -5.05}, V2{5.05, 5.05}}
+	if v := slice_image(layer, 0.1, false).GrayAt(0, 50).Y; v < 100 || v > 155 {
+		t.Errorf("FAIL %d", v)
+	}
+	// two closed contours
+	paths := join_lines(MarchingSquares(layer, Box2{V2{-6, -6}, V2{6, 6}}, 0.1), 1e-7)
+	if len(paths) != 2 {
+		t.Errorf("FAIL %d", len(paths))
+	}
+	for _, p := range paths {
+		if !p[0].Equals(p[len(p)-1], 1e-6) {
+			t.Error("FAIL")
+		}
+	}
+	if _, err := SliceStack(ring, &SliceParms{LayerHeight: 0.5, Resolution: 0.1, Format: "bmp"}); err == nil {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// Return the signed area of a closed path (+ve for counter clockwise).
+func path_area(p []V2) float64 {
+	a := 0.0
+	for i := 0; i < len(p)-1; i++ {
+		a += p[i].Cross(p[i+1])
+	}
+	return 0.5 * a
+}
+
+func Test_Mill(t *testing.T) {
+	s := Box2D(V2{20, 10}, 0)
+	k := &MillParms{
+		Mode:       "outside",
+		Tool:       2,
+		Depth:      3,
+		StepDown:   1,
+		StepOver:   0.5,
+		Resolution: 0.1,
+		SafeZ:      5,
+		Feed:       300,
+		Plunge:     100,
+		Climb:      true,
+	}
+	paths, err := MillPaths(s, k)
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("FAIL %d %v", len(paths), err)
+	}
+	// the tool center is a tool radius from the profile
+	for _, v := range paths[0] {
+		if Abs(s.Evaluate(v)-1) > 0.01 {
+			t.Errorf("FAIL %v %f", v, s.Evaluate(v))
+		}
+	}
+	// climb milling an outside contour is clockwise
+	if path_area(paths[0]) > 0 {
+		t.Error("FAIL")
+	}
+	k.Climb = false
+	paths, _ = MillPaths(s, k)
+	if path_area(paths[0]) < 0 {
+		t.Error("FAIL")
+	}
+	// inside
+	k.Mode = "inside"
+	paths, _ = MillPaths(s, k)
+	if len(paths) != 1 || Abs(s.Evaluate(paths[0][0])+1) > 0.01 {
+		t.Error("FAIL")
+	}
+	// pocket: 5 half width, 1 tool radius, 1 step over
+	k.Mode = "pocket"
+	paths, _ = MillPaths(s, k)
+	if len(paths) != 4 {
+		t.Fatalf("FAIL %d", len(paths))
+	}
+	if Abs(s.Evaluate(paths[3][0])+1) > 0.01 || Abs(s.Evaluate(paths[0][0])+4) > 0.01 {
+		t.Error("FAIL")
+	}
+	// a hole smaller than the tool isn't cut
+	if paths, _ := MillPaths(Circle2D(0.8), k); len(paths) != 0 {
+		t.Error("FAIL")
+	}
+	// g-code: 3 passes of 4 paths
+	var b strings.Builder
+	if err := WriteGCode(&b, s, k); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(b.String(), "G1 Z-"); n != 12 {
+		t.Errorf("FAIL %d", n)
+	}
+	if !strings.Contains(b.String(), "G1 Z-3.0000") || !strings.HasSuffix(b.String(), "M2\n") {
+		t.Error("FAIL")
+	}
+	k.Mode = "drill"
+	if err := WriteGCode(&b, s, k); err == nil {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// Return the minimum distance squared to a curve by sampling.
+func curve_sample_distance2(c Curve2, p V2, n int) float64 {
+	t0, t1 := c.domain()
+	dmin := math.MaxFloat64
+	for i := 0; i <= n; i++ {
+		t := t0 + (t1-t0)*float64(i)/float64(n)
+		dmin = Min(dmin, c.f0(t).Sub(p).Length2())
+	}
+	return dmin
+}
+
+func Test_Curve(t *testing.T) {
+	// polynomial bounds
+	c := []float64{1, -3, 0.5, 2, -1}
+	f := func(t float64) float64 { return c[0] + t*(c[1]+t*(c[2]+t*(c[3]+t*c[4]))) }
+	for _, r := range [][2]float64{{0, 1}, {-1, 2}, {0.3, 0.4}} {
+		lo, hi := poly_bound(c, r[0], r[1])
+		for i := 0; i <= 100; i++ {
+			x := f(r[0] + (r[1]-r[0])*float64(i)/100)
+			if x < lo-1e-9 || x > hi+1e-9 {
+				t.Errorf("FAIL %v %f %f %f", r, x, lo, hi)
+			}
+		}
+	}
+	// the closest point matches a dense sampling of the curve
+	s := CubicSpline2D([]V2{{0, 0}, {1, 2}, {3, -1}, {4, 1}}).(*CubicSplineSDF2)
+	b := NewBezierSpline([]V2{{0, 0}, {4, 6}, {-2, 6}, {2, 0}})
+	arc := NewArc2(V2{1, 1}, 2, 3, 0.5)
+	curves := []Curve2{&s.spline[0], &s.spline[1], b, arc}
+	for i := 0; i < 200; i++ {
+		p := V2{-2 + 0.03*float64(i), 4 - 0.027*float64(i)}
+		for _, cv := range curves {
+			d, tmin := CurveMinDistance2(cv, p)
+			ds := curve_sample_distance2(cv, p, 20000)
+			if d > ds+1e-6 || Abs(cv.f0(tmin).Sub(p).Length2()-d) > 1e-9 {
+				t.Errorf("FAIL %v %f %f", p, d, ds)
+			}
+		}
+	}
+	// the arc distance
+	if d, a := arc.MinDistance2(V2{1, 4}); Abs(d-1) > 1e-9 || Abs(a-0.5*PI) > 1e-6 {
+		t.Errorf("FAIL %f %f", d, a)
+	}
+	if d, _ := arc.MinDistance2(V2{1, -4}); Abs(d-arc.f0(3).Sub(V2{1, -4}).Length2()) > 1e-9 {
+		t.Errorf("FAIL %f", d)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// An SDF3 that counts evaluations.
+type count_sdf3 struct {
+	SDF3
+	n int
+}
+
+func (s *count_sdf3) Evaluate(p V3) float64 {
+	s.n++
+	return s.SDF3.Evaluate(p)
+}
+
+// Return the number of triangles in an octree render.
+func render_count(rc *RenderContext, s SDF3, resolution float64) int {
+	output := make(chan *Triangle3)
+	done := make(chan int)
+	go func() {
+		n := 0
+		for range output {
+			n++
+		}
+		done <- n
+	}()
+	if rc == nil {
+		MarchingCubes_Octree(s, resolution, output)
+	} else {
+		rc.MarchingCubes_Octree(s, resolution, output)
+	}
+	close(output)
+	return <-done
+}
+
+func Test_RenderContext(t *testing.T) {
+	s := &count_sdf3{SDF3: Sphere3D(5)}
+	r := s.BoundingBox().Size().MaxComponent() / 16
+	// reference: no context
+	n0 := render_count(nil, s, r)
+	e0 := s.n
+	rc := NewRenderContext()
+	s.n = 0
+	if n := render_count(rc, s, r); n != n0 || s.n != e0 {
+		t.Errorf("FAIL %d %d", n, s.n)
+	}
+	// the same render again: no evaluations
+	s.n = 0
+	if n := render_count(rc, s, r); n != n0 || s.n != 0 {
+		t.Errorf("FAIL %d %d", n, s.n)
+	}
+	// a finer render reuses the coarse samples
+	s.n = 0
+	n1 := render_count(rc, s, r/2)
+	e1 := s.n
+	s.n = 0
+	if n := render_count(nil, s, r/2); n != n1 || s.n <= e1 {
+		t.Errorf("FAIL %d %d %d", n, s.n, e1)
+	}
+	// node data
+	builds := 0
+	build := func() interface{} { builds++; return builds }
+	if rc.Lookup(s, "x", build) != 1 || rc.Lookup(s, "x", build) != 1 || rc.Lookup(s, "y", build) != 2 {
+		t.Error("FAIL")
+	}
+	rc.Forget(s)
+	s.n = 0
+	if rc.Lookup(s, "x", build) != 3 || render_count(rc, s, r) != n0 || s.n != e0 {
+		t.Error("FAIL")
+	}
+	// a build function can lookup other data
+	nested := func() interface{} { return rc.Lookup(s, "x", build).(int) + 10 }
+	if rc.Lookup(s, "z", nested) != 13 || rc.Lookup(s, "z", nested) != 13 {
+		t.Error("FAIL")
+	}
+	// concurrent lookups return the same data
+	var wg sync.WaitGroup
+	vals := make([]interface{}, 8)
+	for i := range vals {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			vals[i] = rc.Lookup(s, "w", func() interface{} { return new(int) })
+		}(i)
+	}
+	wg.Wait()
+	for _, v := range vals {
+		if v != vals[0] {
+			t.Error("FAIL")
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Mass(t *testing.T) {
+	// sphere
+	r := 5.0
+	v := 4.0 / 3.0 * PI * r * r * r
+	a := 4 * PI * r * r
+	i := 0.4 * v * r * r
+	s := Sphere3D(r)
+	for _, m := range []*MassProperties{MassGrid3D(s, 60), MassMonteCarlo3D(s, 100000)} {
+		if Abs(m.Volume-v) > 0.01*v || Abs(m.Area-a) > 0.02*a {
+			t.Errorf("FAIL %s", m)
+		}
+		if m.Centroid.Length() > 0.05 || !m.Inertia.Equals(V3{i, i, i}, 0.02*i) {
+			t.Errorf("FAIL %s", m)
+		}
+		// the error estimates are sane
+		if m.VolumeError <= 0 || m.VolumeError > 0.02*v || m.AreaError <= 0 || m.AreaError > 0.05*a {
+			t.Errorf("FAIL %s", m)
+		}
+	}
+	// offset box
+	b := Transform3D(Box3D(V3{10, 6, 4}, 0), Translate3d(V3{1, 2, 3}))
+	m := MassGrid3D(b, 60)
+	if Abs(m.Volume-240) > 0.5 || Abs(m.Area-248) > 5 || !m.Centroid.Equals(V3{1, 2, 3}, 1e-6) {
+		t.Errorf("FAIL %s", m)
+	}
+	// Ixx = m(b^2 + c^2)/12
+	if !m.Inertia.Equals(V3{1040, 2320, 2720}, 5) || !m.Product.Equals(V3{0, 0, 0}, 1e-6) {
+		t.Errorf("FAIL %s", m)
+	}
+	if Abs(Volume(b)-240) > 0.5 || Abs(Area(b)-248) > 5 || !Centroid(b).Equals(V3{1, 2, 3}, 1e-6) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Mesh(t *testing.T) {
+	m := RenderMesh(Sphere3D(5), 20)
+	nt := len(m.Index)
+	// a closed mesh: V - E + F = 2, E = 3F/2
+	if nt == 0 || len(m.Vertex)-nt/2 != 2 || len(m.Normal) != len(m.Vertex) {
+		t.Fatalf("FAIL %d %d", len(m.Vertex), nt)
+	}
+	// the normals point outwards
+	for i, v := range m.Vertex {
+		if m.Normal[i].Dot(v.Normalize()) < 0.9 {
+			t.Errorf("FAIL %v %v", v, m.Normal[i])
+		}
+	}
+	// transform
+	m1 := m.Transform(Translate3d(V3{20, 0, 0}))
+	if !m1.BoundingBox().Center().Equals(m.BoundingBox().Center().Add(V3{20, 0, 0}), 1e-9) {
+		t.Error("FAIL")
+	}
+	// a mirror keeps the normals pointing outwards
+	m2 := m.Transform(MirrorXY())
+	for i, v := range m2.Vertex {
+		if m2.Normal[i].Dot(v.Normalize()) < 0.9 {
+			t.Error("FAIL")
+			break
+		}
+	}
+	// merge and save as one file
+	mm := m.Merge(m1)
+	if len(mm.Index) != 2*nt || len(mm.Vertex) != 2*len(m.Vertex) {
+		t.Error("FAIL")
+	}
+	path := t.TempDir() + "/plate.stl"
+	if err := mm.SaveSTL(path); err != nil {
+		t.Fatal(err)
+	}
+	ml, err := LoadSTL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ml.Index) != len(mm.Index) || len(ml.Vertex) != len(mm.Vertex) {
+		t.Errorf("FAIL %d %d", len(ml.Index), len(ml.Vertex))
+	}
+	if !ml.BoundingBox().Min.Equals(mm.BoundingBox().Min, 1e-5) || !ml.BoundingBox().Max.Equals(mm.BoundingBox().Max, 1e-5) {
+		t.Error("FAIL")
+	}
+	// a count that doesn't fit the file
+	b, _ := os.ReadFile(path)
+	binary.LittleEndian.PutUint32(b[80:], 1<<30)
+	os.WriteFile(path, b, 0644)
+	if _, err := LoadSTL(path); err == nil || !strings.Contains(err.Error(), "short STL file") {
+		t.Error("FAIL")
+	}
+	// booleans re-mesh the solids
+	s0 := Sphere3D(5)
+	s1 := Transform3D(Sphere3D(5), Translate3d(V3{5, 0, 0}))
+	m1 = RenderMesh(s1, 20)
+	cell := 0.25
+	for _, x := range []struct {
+		m *Mesh
+		s SDF3
+	}{
+		{m.Union(cell, m1), Union3D(s0, s1)},
+		{m.Difference(cell, m1), Difference3D(s0, s1)},
+		{m.Intersect(cell, m1), Intersect3D(s0, s1)},
+	} {
+		// a closed mesh on the surface of the boolean
+		if len(x.m.Index) == 0 || len(x.m.Vertex)-len(x.m.Index)/2 != 2 {
+			t.Errorf("FAIL %d %d", len(x.m.Vertex), len(x.m.Index))
+		}
+		for _, v := range x.m.Vertex {
+			if Abs(x.s.Evaluate(v)) > cell {
+				t.Errorf("FAIL %v %f", v, x.s.Evaluate(v))
+				break
+			}
+		}
+	}
+	// the merged meshes have vertices inside the union
+	inside := 0
+	for _, v := range m.Merge(m1).Vertex {
+		if Union3D(s0, s1).Evaluate(v) < -cell {
+			inside++
+		}
+	}
+	if inside == 0 {
+		t.Error("FAIL")
+	}
+	if u := m.Union(cell, m1); !u.BoundingBox().Min.Equals(V3{-5, -5, -5}, cell) || !u.BoundingBox().Max.Equals(V3{10, 5, 5}, cell) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Printability(t *testing.T) {
+	// a base with a sealed cavity, a thin fin and a plate on a post
+	base := Transform3D(Box3D(V3{20, 20, 4}, 0), Translate3d(V3{0, 0, 2}))
+	base = Difference3D(base, Transform3D(Sphere3D(1.2), Translate3d(V3{-5, 0, 2})))
+	fin := Transform3D(Box3D(V3{0.8, 10, 6}, 0), Translate3d(V3{5, 0, 7}))
+	post := Transform3D(Box3D(V3{2, 2, 7}, 0), Translate3d(V3{-5, 5, 7.5}))
+	plate := Transform3D(Box3D(V3{8, 8, 2}, 0), Translate3d(V3{-5, 5, 12}))
+	s := Union3D(base, fin, post, plate)
+	k := &PrintParms{MinWall: 1.5, MaxOverhang: 45, Resolution: 0.25}
+	count := make(map[string]int)
+	for _, i := range Printability(s, k) {
+		count[i.Kind]++
+		switch i.Kind {
+		case "wall":
+			if Abs(i.Value-0.8) > 0.25 || Abs(i.Position.X-5) > 0.25 {
+				t.Errorf("FAIL %s", i)
+			}
+		case "overhang":
+			// the plate underside or the cavity ceiling
+			plate := Abs(i.Position.Z-11) < 0.25
+			cavity := Abs(i.Position.Sub(V3{-5, 0, 2}).Length()-1.2) < 0.25
+			if Abs(i.Value-90) > 1 || !(plate || cavity) {
+				t.Errorf("FAIL %s", i)
+			}
+		case "trapped":
+			if Abs(i.Value-4.0/3.0*PI*1.2*1.2*1.2) > 1 || !i.Position.Equals(V3{-5, 0, 2}, 0.1) {
+				t.Errorf("FAIL %s", i)
+			}
+		}
+	}
+	if count["wall"] != 1 || count["overhang"] != 2 || count["trapped"] != 1 {
+		t.Errorf("FAIL %v", count)
+	}
+	// a simple box is fine
+	if issues := Printability(Box3D(V3{10, 10, 10}, 0), k); len(issues) != 0 {
+		t.Errorf("FAIL %v", issues)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Interference(t *testing.T) {
+	a := Sphere3D(5)
+	// overlap by 1 on the x-axis
+	b := Transform3D(Sphere3D(3), Translate3d(V3{7, 0, 0}))
+	i := Interference3D(a, b, 0.5)
+	if Abs(i.Penetration-1) > 1e-4 || i.Clearance != 0 || !i.Position.Equals(V3{4.5, 0, 0}, 1e-3) || len(i.Overlaps) != 1 {
+		t.Errorf("FAIL %s", i)
+	}
+	// a 0.5 gap on the y-axis
+	b = Transform3D(Sphere3D(3), Translate3d(V3{0, 8.5, 0}))
+	i = Interference3D(a, b, 0.5)
+	if Abs(i.Clearance-0.5) > 1e-4 || i.Penetration != 0 || !i.Position.Equals(V3{0, 5.25, 0}, 1e-3) || len(i.Overlaps) != 0 {
+		t.Errorf("FAIL %s", i)
+	}
+	// an oversize button in a hole and a peg in the body: two overlaps
+	body := Difference3D(Box3D(V3{20, 10, 10}, 0), Cylinder3D(12, 2, 0))
+	button := Union3D(
+		Transform3D(Cylinder3D(10, 2.5, 0), Translate3d(V3{0, 0, 1})),
+		Transform3D(Cylinder3D(6, 1, 0), Translate3d(V3{6, 0, 0})),
+	)
+	i = Interference3D(body, button, 0.25)
+	if len(i.Overlaps) != 2 || Abs(i.Penetration-2) > 1e-3 || Abs(i.Position.X-6) > 1e-3 {
+		t.Errorf("FAIL %s", i)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Xform(t *testing.T) {
+	s := Box3D(V3{1, 2, 3}, 0)
+	// the builder matches the nested matrices
+	m := Translate3d(V3{1, 2, 3}).Mul(RotateX(DtoR(20))).Mul(RotateZ(DtoR(-30)))
+	x := T(1, 2, 3).RxDeg(20).RzDeg(-30)
+	if !x.Matrix().Equals(m, 1e-12) {
+		t.Error("FAIL")
+	}
+	// scaling is distance preserving
+	a := x.Scale(2).Apply(s)
+	b := Transform3D(ScaleUniform3D(s, 2), m)
+	for i := 0; i < 100; i++ {
+		p := V3{0.1 * float64(i), 3 - 0.05*float64(i), -2 + 0.07*float64(i)}
+		if Abs(a.Evaluate(p)-b.Evaluate(p)) > 1e-9 {
+			t.Errorf("FAIL %v", p)
+		}
+	}
+	// a partial chain can be reused
+	base := T(0, 0, 10)
+	if !base.RzDeg(90).Matrix().Equals(Translate3d(V3{0, 0, 10}).Mul(RotateZ(DtoR(90))), 1e-12) ||
+		base.Matrix() != Translate3d(V3{0, 0, 10}) {
+		t.Error("FAIL")
+	}
+	// 2d
+	s2 := Box2D(V2{1, 2}, 0)
+	a2 := T2(1, 2).RDeg(45).Scale(3).Apply(s2)
+	b2 := Transform2D(ScaleUniform2D(s2, 3), Translate2d(V2{1, 2}).Mul(Rotate2d(DtoR(45))))
+	for i := 0; i < 100; i++ {
+		p := V2{0.1 * float64(i), 3 - 0.05*float64(i)}
+		if Abs(a2.Evaluate(p)-b2.Evaluate(p)) > 1e-9 {
+			t.Errorf("FAIL %v", p)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Units(t *testing.T) {
+	if Abs(Deg(180).Radians()-PI) > TOLERANCE || Abs(Rad(PI/2).Degrees()-90) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	if Abs(Inch(1).MM()-25.4) > TOLERANCE || Abs(MM(127).Inches()-5) > TOLERANCE || CM(2) != MM(20) {
+		t.Error("FAIL")
+	}
+	if V3L(MM(6), Inch(0.25), CM(1)) != (V3{6, 6.35, 10}) || V2L(Inch(2), 3) != (V2{50.8, 3}) {
+		t.Error("FAIL")
+	}
+	if Deg(90).String() != "90 deg" || MM(6).String() != "6 mm" {
+		t.Error("FAIL")
+	}
+	// the builders take typed units
+	m := Translate3d(V3{6, 6.35, 0}).Mul(RotateX(DtoR(20))).Mul(Rotate3d(V3{1, 1, 0}, PI/3))
+	x := T(MM(6), Inch(0.25), 0).Rx(Deg(20)).Rotate(V3{1, 1, 0}, Rad(PI/3))
+	if !x.Matrix().Equals(m, 1e-12) {
+		t.Error("FAIL")
+	}
+	m2 := Translate2d(V2{25.4, 0}).Mul(Rotate2d(DtoR(-30)))
+	if !T2(Inch(1), 0).R(Deg(-30)).Matrix().Equals(m2, 1e-12) {
+		t.Error("FAIL")
+	}
+	// typed matrix constructors
+	if !TranslateLength3d(MM(6), Inch(0.25), 0).Mul(RotateAngle3d(V3{1, 0, 0}, Deg(20))).Equals(Translate3d(V3{6, 6.35, 0}).Mul(RotateX(DtoR(20))), 1e-12) {
+		t.Error("FAIL")
+	}
+	if !TranslateLength2d(Inch(1), 0).Mul(RotateAngle2d(Deg(-30))).Equals(m2, 1e-12) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_RefineBB(t *testing.T) {
+	// a rotated sphere has the bounding box of a rotated cube
+	s := Transform3D(Sphere3D(5), RotateZ(DtoR(45)).Mul(RotateX(DtoR(45))))
+	bb := RefineBB(s, 4)
+	exact := Box3{V3{-5, -5, -5}, V3{5, 5, 5}}
+	// within about a cell (size/16) of the surface
+	if s.BoundingBox().Equals(exact, 1) || !bb.Equals(exact, 0.5) {
+		t.Errorf("FAIL %v", bb)
+	}
+	// the refined box is conservative
+	if bb.Min.MaxComponent() > -5 || bb.Max.MinComponent() < 5 {
+		t.Errorf("FAIL %v", bb)
+	}
+	// no iterations
+	if RefineBB(s, 0) != s.BoundingBox() {
+		t.Error("FAIL")
+	}
+	// 2d
+	s2 := Transform2D(Circle2D(3), Translate2d(V2{1, 2}).Mul(Rotate2d(DtoR(45))))
+	bb2 := RefineBB2(s2, 4)
+	exact2 := Box2{V2{-2, -1}, V2{4, 5}}
+	if !bb2.Equals(exact2, 0.25) || bb2.Min.X > -2 || bb2.Min.Y > -1 || bb2.Max.X < 4 || bb2.Max.Y < 5 {
+		t.Errorf("FAIL %v", bb2)
+	}
+	// refinement is opt-in, the refined render has a finer resolution
+	path := filepath.Join(t.TempDir(), "refine.stl")
+	RenderSTL(s, 20, path)
+	m0, err := LoadSTL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	RenderSTLRefined(s, 20, path)
+	m1, err := LoadSTL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m1.Triangles()) <= len(m0.Triangles()) || !m1.BoundingBox().Equals(exact, 0.2) {
+		t.Errorf("FAIL %d %d", len(m0.Triangles()), len(m1.Triangles()))
+	}
+	// the refined SDF3 only has an interval evaluation if the SDF3 has one
+	if _, ok := refine_sdf3(s, 1).(SDF3Interval); !ok {
+		t.Error("FAIL")
+	}
+	if _, ok := refine_sdf3(Displace3D(s, func(p V3) float64 { return 0 }), 1).(SDF3Interval); ok {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Decor(t *testing.T) {
+	// dome: a hemisphere, and less and more than a hemisphere
+	for _, h := range []float64{2, 5, 8} {
+		s := Dome3D(5, h)
+		r := (25 + h*h) / (2 * h)
+		v := PI * h * h * (3*r - h) / 3
+		if m := MassGrid3D(s, 60); Abs(m.Volume-v) > 0.01*v {
+			t.Errorf("FAIL %f %s", h, m)
+		}
+		if Abs(s.Evaluate(V3{0, 0, h + 1})-1) > TOLERANCE || Abs(s.Evaluate(V3{0, 0, -1})-1) > TOLERANCE {
+			t.Errorf("FAIL %f", h)
+		}
+		// the base edge
+		if Abs(s.Evaluate(V3{6, 0, -1})-math.Sqrt2) > TOLERANCE {
+			t.Errorf("FAIL %f", h)
+		}
+	}
+	if s := Dome3D(5, 5); Abs(s.Evaluate(V3{3, 4, 7})-(math.Sqrt(74)-5)) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	// torus knot: the tube is on the curve
+	k := TorusKnot3D(2, 3, 10, 4, 1.5)
+	for i := 0; i < 10; i++ {
+		a := 0.37 * float64(i)
+		rho := 10 + 4*math.Cos(3*a)
+		p := V3{rho * math.Cos(2*a), rho * math.Sin(2*a), 4 * math.Sin(3*a)}
+		if Abs(k.Evaluate(p)+1.5) > 1e-6 {
+			t.Errorf("FAIL %v %f", p, k.Evaluate(p))
+		}
+	}
+	// the volume is the tube area times the curve length
+	l := 0.0
+	for i := 0; i < 10000; i++ {
+		a0, a1 := TAU*float64(i)/10000, TAU*float64(i+1)/10000
+		c := func(a float64) V3 {
+			rho := 10 + 4*math.Cos(3*a)
+			return V3{rho * math.Cos(2*a), rho * math.Sin(2*a), 4 * math.Sin(3*a)}
+		}
+		l += c(a1).Sub(c(a0)).Length()
+	}
+	v := PI * 1.5 * 1.5 * l
+	if m := MassGrid3D(k, 40); Abs(m.Volume-v) > 0.03*v {
+		t.Errorf("FAIL %f %s", v, m)
+	}
+	// mobius band: the section makes a half turn
+	b := MobiusBand3D(10, 4, 1)
+	if b.Evaluate(V3{11.9, 0, 0}) >= 0 || b.Evaluate(V3{10, 0, 1.9}) <= 0 ||
+		b.Evaluate(V3{-10, 0, 1.9}) >= 0 || b.Evaluate(V3{-11.9, 0, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	v = TAU * 10 * 4 * 1
+	if m := MassGrid3D(b, 80); Abs(m.Volume-v) > 0.02*v {
+		t.Errorf("FAIL %f %s", v, m)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// an SDF3 without interval evaluation
+type plain_sdf3 struct {
+	SDF3
+}
+
+func Test_Interval(t *testing.T) {
+	b := Transform3D(Box3D(V3{6, 4, 2}, 0.5), RotateZ(DtoR(30)).Mul(Translate3d(V3{1, 0, 0})))
+	c := ScaleNonUniform3D(Cylinder3D(6, 1, 0.2), V3{1, 2, 1})
+	u := Union3D(b, Sphere3D(2.5), Offset3D(Ellipsoid3D(V3{1, 2, 3}), 0.1))
+	s := Shell3D(Difference3D(u, c), 0.4)
+	s = Intersect3D(s, ScaleUniform3D(Box3D(V3{4, 4, 4}, 0), 2))
+	// the interval holds all the values in the box
+	bb := s.BoundingBox()
+	for i := 0; i < 200; i++ {
+		p := bb.Random()
+		size := V3{0.1, 0.2, 0.3}.MulScalar(float64(i % 20))
+		box := NewBox3(p, size)
+		d := EvaluateInterval(s, box)
+		if d.Lo > d.Hi {
+			t.Errorf("FAIL %v", d)
+		}
+		for j := 0; j < 20; j++ {
+			if x := s.Evaluate(box.Random()); d.Excludes(x) && Abs(x-Clamp(x, d.Lo, d.Hi)) > 1e-9 {
+				t.Errorf("FAIL %v %f", d, x)
+			}
+		}
+	}
+	// exact ranges
+	x := EvaluateInterval(Sphere3D(1), Box3{V3{2, -1, -1}, V3{3, 1, 1}})
+	if Abs(x.Lo-1) > TOLERANCE || Abs(x.Hi-(math.Sqrt(11)-1)) > TOLERANCE {
+		t.Errorf("FAIL %v", x)
+	}
+	x = EvaluateInterval(Box3D(V3{2, 2, 2}, 0), Box3{V3{-0.5, -0.5, -0.5}, V3{0.5, 0.5, 0.5}})
+	if x.Lo != -1 || x.Hi != -0.5 || !x.Excludes(0) {
+		t.Errorf("FAIL %v", x)
+	}
+	// blended unions fall back to the center distance
+	su := SmoothUnion3D(0.5, Sphere3D(1), Box3D(V3{1, 1, 1}, 0))
+	box := Box3{V3{3, 3, 3}, V3{4, 4, 4}}
+	if EvaluateInterval(su, box) != EvaluateInterval(&plain_sdf3{su}, box) {
+		t.Error("FAIL")
+	}
+	// only real interval evaluations are used by the renderer
+	if !has_interval(s) || has_interval(su) || has_interval(Transform3D(&plain_sdf3{s}, Translate3d(V3{1, 0, 0}))) {
+		t.Error("FAIL")
+	}
+	if !has_interval(Union3D(&plain_sdf3{s}, Sphere3D(1))) || has_interval(Offset3D(&plain_sdf3{s}, 1)) {
+		t.Error("FAIL")
+	}
+	// the center distance bound clips the interval
+	box = Box3{V3{-0.5, -0.5, 2}, V3{0.5, 0.5, 3}}
+	x = evaluate_interval_center(su.(SDF3Interval), box, su.Evaluate(box.Center()))
+	if x != EvaluateInterval(su, box) {
+		t.Errorf("FAIL %v", x)
+	}
+	x = evaluate_interval_center(b.(SDF3Interval), box, b.Evaluate(box.Center()))
+	if y := EvaluateInterval(b, box); x.Lo < y.Lo || x.Hi > y.Hi || x.Hi-x.Lo > box.Size().Length() {
+		t.Errorf("FAIL %v %v", x, y)
+	}
+	// the octree renders the same triangles, and more for the split cubes
+	r := s.BoundingBox().Size().MaxComponent() / 50
+	if n0, n1 := render_count(nil, s, r), render_count(nil, &plain_sdf3{s}, r); n0 < n1 || n1 == 0 {
+		t.Errorf("FAIL %d %d", n0, n1)
+	}
+	// a plate thinner than the resolution is found by splitting the cubes
+	p := Transform3D(Box3D(V3{10, 10, 0.3}, 0), Translate3d(V3{0, 0, 0.4}))
+	p = Union3D(p, Sphere3D(2), Transform3D(Sphere3D(1), Translate3d(V3{0, 0, -3.3})))
+	if n0, n1 := render_count(nil, p, 1), render_count(nil, &plain_sdf3{p}, 1); n0 < n1+1000 {
+		t.Errorf("FAIL %d %d", n0, n1)
+	}
+	// extrusions and solids of revolution
+	c2 := Difference2D(Box2D(V2{4, 3}, 0.5), Circle2D(1))
+	for _, e := range []SDF3{
+		Extrude3D(c2, 2),
+		Extrude3D(Circle2D(3), 0.1),
+		ExtrudeRounded3D(Box2D(V2{4, 3}, 0), 2, 0.5),
+		Revolve3D(Transform2D(Circle2D(1), Translate2d(V2{3, 0}))),
+		Revolve3D(Box2D(V2{2, 4}, 0.2)),
+	} {
+		if !has_interval(e) {
+			t.Errorf("FAIL %T", e)
+		}
+		bb := e.BoundingBox()
+		for i := 0; i < 100; i++ {
+			box := NewBox3(bb.Random(), V3{0.1, 0.2, 0.3}.MulScalar(float64(i%10)))
+			d := EvaluateInterval(e, box)
+			for j := 0; j < 20; j++ {
+				if x := e.Evaluate(box.Random()); d.Excludes(x) && Abs(x-Clamp(x, d.Lo, d.Hi)) > 1e-9 {
+					t.Errorf("FAIL %T %v %f", e, d, x)
+				}
+			}
+		}
+	}
+	if has_interval(TwistExtrude3D(c2, 2, PI)) || has_interval(RevolveTheta3D(c2, PI)) {
+		t.Error("FAIL")
+	}
+	x = EvaluateInterval(Extrude3D(Circle2D(1), 2), Box3{V3{2, -0.5, -0.5}, V3{3, 0.5, 0.5}})
+	if Abs(x.Lo-1) > TOLERANCE || !x.Excludes(0) {
+		t.Errorf("FAIL %v", x)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Gradient(t *testing.T) {
+	b := Transform3D(Box3D(V3{6, 4, 2}, 0.5), RotateZ(DtoR(30)).Mul(Translate3d(V3{1, 0, 0})))
+	c := Transform3D(Cylinder3D(6, 1, 0.2), RotateX(DtoR(60)))
+	u := Union3D(b, ScaleUniform3D(Sphere3D(1.25), 2), Offset3D(Ellipsoid3D(V3{1, 2, 3}), 0.1))
+	s := Shell3D(Difference3D(u, c), 0.4)
+	s = Intersect3D(s, Box3D(V3{8, 8, 8}, 0))
+	if _, ok := s.(SDF3Gradient); !ok {
+		t.Fatal("FAIL")
+	}
+	// the analytic gradient matches central differences (except at the creases)
+	bb := s.BoundingBox()
+	bad := 0
+	for i := 0; i < 1000; i++ {
+		p := bb.Random()
+		if !Gradient3D(s, p, 1e-6).Equals(Gradient3D(&plain_sdf3{s}, p, 1e-6), 1e-4) {
+			bad++
+		}
+	}
+	if bad > 20 {
+		t.Errorf("FAIL %d", bad)
+	}
+	// normals
+	if !Normal3D(Sphere3D(2), V3{3, 4, 0}, 1e-6).Equals(V3{0.6, 0.8, 0}, TOLERANCE) ||
+		!Normal3D(&plain_sdf3{Sphere3D(2)}, V3{0, 0, 5}, 1e-6).Equals(V3{0, 0, 1}, 1e-6) {
+		t.Error("FAIL")
+	}
+	// surface projection
+	for i := 0; i < 100; i++ {
+		p := bb.Random()
+		q, err := ProjectSurface(b, p, 1e-9)
+		if err != nil || Abs(b.Evaluate(q)) > 1e-9 {
+			t.Errorf("FAIL %v %v", p, err)
+		}
+	}
+	if _, err := ProjectSurface(Sphere3D(1), V3{}, 1e-9); err != nil {
+		t.Error("FAIL")
+	}
+	// smooth mesh normals
+	m := RenderMesh(Sphere3D(5), 20)
+	for i, v := range m.Vertex {
+		if !m.Normal[i].Equals(v.Normalize(), 1e-12) {
+			t.Errorf("FAIL %v %v", v, m.Normal[i])
+			break
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Cache(t *testing.T) {
+	s := Transform3D(Cylinder3D(6, 2, 0.5), RotateX(DtoR(30)).Mul(Translate3d(V3{1, 2, 3})))
+	c := Cache3D(s, 0.1)
+	if c.BoundingBox() != s.BoundingBox() {
+		t.Error("FAIL")
+	}
+	// close to the SDF near the surface (away from the interior creases)
+	bb := s.BoundingBox()
+	for i := 0; i < 1000; i++ {
+		p := bb.Random()
+		if Abs(s.Evaluate(p)) < 0.3 && Abs(c.Evaluate(p)-s.Evaluate(p)) > 0.01 {
+			t.Errorf("FAIL %v %f %f", p, c.Evaluate(p), s.Evaluate(p))
+		}
+	}
+	// the grid samples are exact
+	cs := c.(*CacheSDF3)
+	p := cs.grid.Min.Add(V3{3, 4, 5}.MulScalar(0.1))
+	if Abs(c.Evaluate(p)-s.Evaluate(p)) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	// a lower bound outside the grid
+	for i := 1; i < 100; i++ {
+		p := bb.Center().Add(V3{float64(i%7) - 3, float64(i%5) - 2, float64(i%3) - 1}.MulScalar(10))
+		if p.Max(cs.grid.Min).Min(cs.grid.Max) == p {
+			continue
+		}
+		if c.Evaluate(p) > s.Evaluate(p) || c.Evaluate(p) <= 0 {
+			t.Errorf("FAIL %v %f %f", p, c.Evaluate(p), s.Evaluate(p))
+		}
+	}
+	// a union of copies renders like the original
+	a0 := Union3D(s, Transform3D(s, Translate3d(V3{5, 0, 0})))
+	a1 := Union3D(c, Transform3D(c, Translate3d(V3{5, 0, 0})))
+	if v0, v1 := Volume(a0), Volume(a1); Abs(v0-v1) > 0.01*v0 {
+		t.Errorf("FAIL %f %f", v0, v1)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_CableManagement(t *testing.T) {
+	// wall on the +x face, features up the z-axis
+	m := WallAnchor(V3{10, 0, 5}, V3{1, 0, 0}, V3{0, 0, 1})
+	if !m.MulPosition(V3{0, 0, 1}).Equals(V3{11, 0, 5}, TOLERANCE) || !m.MulPosition(V3{1, 2, 0}).Equals(V3{10, 1, 7}, TOLERANCE) {
+		t.Error("FAIL")
+	}
+	k := ZipTieParms{Tie: "3.6", Span: 6, Wall: 2, Clearance: 0.2}
+	slots := Transform3D(ZipTieSlots3D(&k), m)
+	if slots.Evaluate(V3{9, 3.85, 5}) >= 0 || slots.Evaluate(V3{9, -3.85, 5}) >= 0 || slots.Evaluate(V3{9, 0, 5}) <= 0 || slots.Evaluate(V3{9, 3.85, 7.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	// tunnel under the bridge
+	mount := ZipTieMount3D(&k)
+	if mount.Evaluate(V3{0, 0, 1}) <= 0 || mount.Evaluate(V3{0, 0, 3}) >= 0 || mount.Evaluate(V3{0, 3, 1}) >= 0 || mount.Evaluate(V3{5, 0, 1}) <= 0 {
+		t.Error("FAIL")
+	}
+	base, clamp := StrainRelief3D(&StrainReliefParms{Cable: 6, Length: 10, Screw: "M3", Grip: 1, Clearance: 0.2})
+	// cable groove, pilot holes and the gap between the halves
+	xs := 7.7
+	if base.Evaluate(V3{0, 0, 1}) >= 0 || base.Evaluate(V3{0, 0, 5}) <= 0 || base.Evaluate(V3{5, 0, 5}) >= 0 {
+		t.Error("FAIL")
+	}
+	if base.Evaluate(V3{xs, 0, 3}) <= 0 || base.Evaluate(V3{xs + 1.5, 0, 3}) >= 0 || base.Evaluate(V3{10, 0, 6}) <= 0 {
+		t.Error("FAIL")
+	}
+	if clamp.Evaluate(V3{0, 0, 11}) >= 0 || clamp.Evaluate(V3{0, 0, 8}) <= 0 || clamp.Evaluate(V3{10, 0, 6}) <= 0 {
+		t.Error("FAIL")
+	}
+	if clamp.Evaluate(V3{xs + 1.5, 0, 9}) <= 0 || clamp.Evaluate(V3{xs + 2.5, 0, 9}) >= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Magnets(t *testing.T) {
+	// disc with a retention lip
+	s := MagnetPocket3D(&MagnetParms{Magnet: "6x3", Clearance: 0.1, Glue: 0.2, Lip: V2{0.4, 0.6}})
+	if s.Evaluate(V3{3.1, 0, -2}) >= 0 || s.Evaluate(V3{3.1, 0, -0.3}) <= 0 || s.Evaluate(V3{2.5, 0, -0.3}) >= 0 || s.Evaluate(V3{0, 0, -3.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	// cube without a lip
+	s = MagnetPocket3D(&MagnetParms{Magnet: "5x5x5", Clearance: 0.1})
+	if s.Evaluate(V3{2.55, 2.55, -4.9}) >= 0 || s.Evaluate(V3{2.55, 2.55, -5.1}) <= 0 || s.Evaluate(V3{2.7, 0, -2}) <= 0 {
+		t.Error("FAIL")
+	}
+	housing, holes := PogoPin3D(&PogoPinParms{Pins: 3, Pitch: 2.54, Barrel: 1.5, Length: 4, Flange: V2{2, 0.5}, Wall: 1, Clearance: 0.1})
+	if housing.Evaluate(V3{4.5, 0, -2}) >= 0 || housing.Evaluate(V3{0, 2.5, -2}) <= 0 || housing.Evaluate(V3{0, 0, 0.1}) <= 0 {
+		t.Error("FAIL")
+	}
+	// barrel through the face, flange recess at the back
+	if holes.Evaluate(V3{2.54, 0, 0}) >= 0 || holes.Evaluate(V3{3.54, 0, -4.3}) >= 0 || holes.Evaluate(V3{3.54, 0, -2}) <= 0 || holes.Evaluate(V3{1.27, 0, -2}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Voxels(t *testing.T) {
+	s := Sphere3D(5)
+	v := Voxelize3D(s, 0.5)
+	if n, total := v.Blocks(); n == 0 || n >= total {
+		t.Error("FAIL")
+	}
+	// close to the surface
+	for i := 0; i < 1000; i++ {
+		p := V3{random_range(-6, 6), random_range(-6, 6), random_range(-6, 6)}
+		d := s.Evaluate(p)
+		if Abs(d) < 1 && Abs(v.Evaluate(p)-d) > 0.05 {
+			t.Errorf("%v: %f != %f", p, v.Evaluate(p), d)
+			break
+		}
+	}
+	// clamped to the band inside, a lower bound outside
+	if v.Evaluate(V3{0, 0, 0}) != -1.5 || v.Evaluate(V3{20, 0, 0}) <= 1.5 || v.Evaluate(V3{20, 0, 0}) > 15 {
+		t.Error("FAIL")
+	}
+	w := Voxelize3D(Transform3D(s, Translate3d(V3{4, 0, 0})), 0.5)
+	u := VoxelUnion3D(v, w)
+	if u.Evaluate(V3{8, 0, 0}) >= 0 || u.Evaluate(V3{-4, 0, 0}) >= 0 || u.Evaluate(V3{0, 6, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	d := VoxelDifference3D(v, w)
+	if d.Evaluate(V3{-3, 0, 0}) >= 0 || d.Evaluate(V3{3, 0, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	x := VoxelIntersect3D(v, w)
+	if x.Evaluate(V3{2, 0, 0}) >= 0 || x.Evaluate(V3{-3, 0, 0}) <= 0 || x.Evaluate(V3{8, 0, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// a backend that evaluates on the CPU and counts the points
+type test_backend struct {
+	n    int
+	fail bool
+}
+
+func (b *test_backend) Name() string {
+	return "test"
+}
+
+func (b *test_backend) Compile(s SDF3) (BatchFunc, error) {
+	if b.fail {
+		return nil, errors.New("unsupported")
+	}
+	return func(p []V3, out []float64) {
+		for i := range p {
+			out[i] = s.Evaluate(p[i])
+		}
+		b.n += len(p)
+	}, nil
+}
+
+func Test_Backend(t *testing.T) {
+	s := Box3D(V3{2, 3, 4}, 0.5)
+	bb := s.BoundingBox().ScaleAboutCenter(1.1)
+	n := len(MarchingCubes(s, bb, 0.25))
+	b := test_backend{}
+	SetBackend(&b)
+	defer SetBackend(nil)
+	if len(MarchingCubes(s, bb, 0.25)) != n || b.n == 0 {
+		t.Error("FAIL")
+	}
+	// fall back to the CPU
+	b = test_backend{fail: true}
+	if len(MarchingCubes(s, bb, 0.25)) != n || b.n != 0 {
+		t.Error("FAIL")
+	}
+	// the octree renderer evaluates batches with the backend
+	n = render_count(nil, s, 0.2)
+	b = test_backend{}
+	if render_count(nil, s, 0.2) != n || b.n == 0 {
+		t.Error("FAIL")
+	}
+	// the cpu backend gives the same distances as Evaluate
+	u := Union3D(Transform3D(s, RotateZ(DtoR(30)).Mul(Translate3d(V3{1, 0, 0}))), Sphere3D(2.5), Offset3D(Cylinder3D(6, 1, 0.2), 0.1))
+	c := SmoothUnion3D(0.5, ScaleNonUniform3D(Cylinder3D(6, 1, 0.2), V3{1, 2, 1}), Sphere3D(1))
+	x := Shell3D(Difference3D(u, c), 0.4)
+	x = Intersect3D(x, ScaleUniform3D(Box3D(V3{4, 4, 4}, 0), 2))
+	f, err := CPUBackend{}.Compile(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := make([]V3, 1000)
+	xbb := x.BoundingBox().ScaleAboutCenter(1.2)
+	for i := range p {
+		p[i] = xbb.Random()
+	}
+	d := make([]float64, len(p))
+	f(p, d)
+	for i := range p {
+		if d[i] != x.Evaluate(p[i]) {
+			t.Errorf("FAIL %v %f %f", p[i], d[i], x.Evaluate(p[i]))
+		}
+	}
+	// the scratch buffers are reused for a smaller batch
+	d1 := make([]float64, 100)
+	f(p[:100], d1)
+	for i := range d1 {
+		if d1[i] != d[i] {
+			t.Error("FAIL")
+		}
+	}
+	// unsupported nodes
+	if _, err := (CPUBackend{}).Compile(Union3D(x, Ellipsoid3D(V3{1, 2, 3}))); err == nil {
+		t.Error("FAIL")
+	}
+	// opencl kernel source
+	y := Intersect3D(Shell3D(Difference3D(u, Sphere3D(1)), 0.4), ScaleUniform3D(Box3D(V3{4, 4, 4}, 0), 2))
+	src, err := OpenCLSource(y)
+	if err != nil || !strings.Contains(src, "__kernel void sdf(") || strings.Count(src, "sdf_box3d(v") != 2 || !strings.Contains(src, "fabs(v") {
+		t.Error("FAIL")
+	}
+	if _, err := OpenCLSource(x); err == nil {
+		// blended union
+		t.Error("FAIL")
+	}
+	if _, err := OpenCLSource(Ellipsoid3D(V3{1, 2, 3})); err == nil {
+		t.Error("FAIL")
+	}
+	// the octree renders the same triangles
+	r := x.BoundingBox().Size().MaxComponent() / 50
+	n = render_count(nil, x, r)
+	SetBackend(CPUBackend{})
+	if n == 0 || render_count(nil, x, r) != n {
+		t.Error("FAIL")
+	}
+	// with samples shared across renders
+	rc := NewRenderContext()
+	if render_count(rc, x, r) != n || render_count(rc, x, r) != n {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Holders(t *testing.T) {
+	s := BitHolder3D(&BitHolderParms{Bit: HEX_BIT, Grid: V2i{3, 2}, Spacing: 10, Depth: 10, Angle: DtoR(15), Floor: 2, Clearance: 0.1})
+	// along the tilted pocket axis, past the bottom of the pocket, between pockets
+	if s.Evaluate(V3{0, -6.035, 3.93}) <= 0 || s.Evaluate(V3{3, -6.035, 3.93}) <= 0 || s.Evaluate(V3{0, -6.553, 1.998}) >= 0 || s.Evaluate(V3{5, -3.7, 8}) >= 0 {
+		t.Error("FAIL")
+	}
+	s = ScrewdriverRack3D(&ScrewdriverRackParms{
+		Count: 4, Spacing: 20, Shaft: 6, Slot: 4, Angle: DtoR(10), Depth: 40, Thickness: 6,
+		Back: V2{30, 5}, Screw: "M4", Clearance: 0.3,
+	})
+	// holes, slots and the shelf between them
+	if s.Evaluate(V3{10, 22.5, -3}) <= 0 || s.Evaluate(V3{-30, 35, -3}) <= 0 || s.Evaluate(V3{0, 22.5, -3}) >= 0 || s.Evaluate(V3{15, 35, -3}) >= 0 {
+		t.Error("FAIL")
+	}
+	// back plate and mounting holes
+	if s.Evaluate(V3{0, 2.5, -20}) >= 0 || s.Evaluate(V3{0, 2.5, -31}) <= 0 || s.Evaluate(V3{44, 2.5, -18}) <= 0 || s.Evaluate(V3{44, 2.5, -10}) >= 0 {
+		t.Error("FAIL")
+	}
+	s = WrenchRail3D(&WrenchRailParms{
+		Count: 3, Spacing: 25, Peg: V2{6, 30}, Angle: DtoR(10), Lip: 1.5,
+		Rail: V2{20, 6}, Screw: "M4", Clearance: 0.3,
+	})
+	// tilted pegs with a ball end
+	if s.Evaluate(V3{25, 19.7, 3.47}) >= 0 || s.Evaluate(V3{0, 35.45, 10.25}) >= 0 || s.Evaluate(V3{0, 35.45, 11.25}) <= 0 || s.Evaluate(V3{12.5, 19.7, 3.47}) <= 0 {
+		t.Error("FAIL")
+	}
+	if s.Evaluate(V3{0, -0.5, 0}) <= 0 || s.Evaluate(V3{41.5, 3, 0}) <= 0 || s.Evaluate(V3{41.5, 3, 5}) >= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_MediaHolders(t *testing.T) {
+	s := CardSlot3D(&CardSlotParms{Card: "SD", Clearance: 0.1, Grip: 0.3, Chamfer: 0.5, Notch: 5})
+	// narrower at the bottom, lead-in at the top
+	if s.Evaluate(V3{0, 11, -10}) >= 0 || s.Evaluate(V3{1.1, 11, -15.5}) <= 0 || s.Evaluate(V3{1.1, 11, -1}) >= 0 || s.Evaluate(V3{1.4, 5, -0.1}) >= 0 {
+		t.Error("FAIL")
+	}
+	// card width, finger notch
+	if s.Evaluate(V3{0, 12.3, -5}) <= 0 || s.Evaluate(V3{0, 0, -16.5}) <= 0 || s.Evaluate(V3{3, 0, -2}) >= 0 || s.Evaluate(V3{3, 8, -2}) <= 0 {
+		t.Error("FAIL")
+	}
+	s = CardSlot3D(&CardSlotParms{Card: "microSD", Depth: 8, Clearance: 0.1})
+	if s.Evaluate(V3{0, 5, -7}) >= 0 || s.Evaluate(V3{0.7, 5, -7}) <= 0 || s.Evaluate(V3{0, 5, -8.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	s = CoinCellPocket3D(&CoinCellParms{Cell: "CR2032", Clearance: 0.2, Finger: 8})
+	if s.Evaluate(V3{9, 0, -1}) >= 0 || s.Evaluate(V3{0, 0, -3.5}) <= 0 || s.Evaluate(V3{13, 0, -1}) >= 0 || s.Evaluate(V3{0, 11, -1}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Serialize(t *testing.T) {
+	profile := Transform2D(Polygon2D([]V2{{0, 0}, {4, 0}, {0, 3}}), Rotate2d(DtoR(30)))
+	profile = Difference2D(Union2D(profile, Box2D(V2{2, 5}, 0.5)), Circle2D(0.5))
+	s := Difference3D(
+		Union3D(Box3D(V3{6, 5, 4}, 0.5), Transform3D(Sphere3D(2), Translate3d(V3{3, 0, 2}))),
+		Intersect3D(Cylinder3D(10, 1, 0.2), ScaleUniform3D(Sphere3D(2), 2)),
+	)
+	s = Union3D(s, Shell3D(Offset3D(Extrude3D(profile, 3), 0.2), 0.4), TwistExtrude3D(profile, 2, 1))
+	data, err := MarshalSDF3(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s1, err := UnmarshalSDF3(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		p := V3{random_range(-6, 6), random_range(-6, 6), random_range(-6, 6)}
+		if !EqualFloat64(s.Evaluate(p), s1.Evaluate(p), TOLERANCE) {
+			t.Errorf("%v: %f != %f", p, s.Evaluate(p), s1.Evaluate(p))
+			break
+		}
+	}
+	if data1, _ := MarshalSDF3(s1); string(data1) != string(data) {
+		t.Error("FAIL")
+	}
+	// errors
+	u := Union3D(Sphere3D(1), Box3D(V3{1, 1, 1}, 0))
+	u.(*UnionSDF3).SetMin(PolyMin(0.1))
+	if _, err := MarshalSDF3(u); err == nil {
+		t.Error("FAIL")
+	}
+	for _, x := range []string{
+		`{"type": "cone"}`,
+		`{"type": "sphere", "parms": {"size": 1}}`,
+		`{"type": "shell", "parms": {"thickness": -1}, "children": [{"type": "sphere", "parms": {"radius": 1}}]}`,
+		`{"type": "union"}`,
+	} {
+		if _, err := UnmarshalSDF3([]byte(x)); err == nil {
+			t.Errorf("%s: expected an error", x)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Sign(t *testing.T) {
+	k := &SignParms{
+		Size:      V2{100, 40},
+		Thickness: 6,
+		Round:     3,
+		Border:    V2{3, 1.5},
+		Text:      Box2D(V2{60, 10}, 0),
+		Margin:    2,
+		Relief:    1.5,
+		Embed:     1,
+		Mount:     "keyholes",
+		Screw:     V2{8, 4},
+		Inset:     10,
+	}
+	plate, text := Sign3D(k)
+	// plate, border, text pocket
+	if plate.Evaluate(V3{0, 15, 3}) >= 0 || plate.Evaluate(V3{48.5, 0, 6.5}) >= 0 || plate.Evaluate(V3{40, 0, 6.5}) <= 0 || plate.Evaluate(V3{0, 0, 5.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	// text body, embedded in the plate
+	if text.Evaluate(V3{0, 0, 5.5}) >= 0 || text.Evaluate(V3{0, 0, 7}) >= 0 || text.Evaluate(V3{0, 0, 7.8}) <= 0 || text.Evaluate(V3{0, 15, 7}) <= 0 {
+		t.Error("FAIL")
+	}
+	// keyhole: entry, shaft slot, lip, head channel
+	if plate.Evaluate(V3{40, -8, 0.5}) <= 0 || plate.Evaluate(V3{40, -4, 0.5}) <= 0 || plate.Evaluate(V3{43, -4, 0.5}) >= 0 || plate.Evaluate(V3{43, -4, 2}) <= 0 || plate.Evaluate(V3{40, -4, 3}) >= 0 {
+		t.Error("FAIL")
+	}
+	// through holes, text scaled down to fit
+	k.Mount = "holes"
+	k.Text = Box2D(V2{200, 10}, 0)
+	plate, text = Sign3D(k)
+	if plate.Evaluate(V3{40, 0, 3}) <= 0 || plate.Evaluate(V3{40, -4, 0.5}) >= 0 {
+		t.Error("FAIL")
+	}
+	if text.Evaluate(V3{44, 0, 7}) >= 0 || text.Evaluate(V3{46, 0, 7}) <= 0 || text.Evaluate(V3{0, 2, 7}) >= 0 || text.Evaluate(V3{0, 2.5, 7}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Mounting(t *testing.T) {
+	s := KeyholeSlot3D(&KeyholeParms{Screw: V2{8, 4}, Length: 10, Lip: 1.5, Depth: 4, Clearance: 0.2})
+	// entry, shaft slot, lip, head channel, rounded top
+	if s.Evaluate(V3{0, 0, 0.5}) >= 0 || s.Evaluate(V3{0, 5, 0.5}) >= 0 || s.Evaluate(V3{3, 5, 0.5}) <= 0 || s.Evaluate(V3{3, 5, 2.5}) >= 0 {
+		t.Error("FAIL")
+	}
+	if s.Evaluate(V3{0, 5, 4.5}) <= 0 || s.Evaluate(V3{0, 14, 3}) >= 0 || s.Evaluate(V3{0, 14.5, 3}) <= 0 {
+		t.Error("FAIL")
+	}
+	s = SawtoothHanger3D(&SawtoothParms{Width: 30, Height: 6, Depth: 3, Teeth: 5, Tooth: 2})
+	// notch, tooth, pocket depth
+	if s.Evaluate(V3{0, 7.5, 1}) >= 0 || s.Evaluate(V3{3, 7.5, 1}) <= 0 || s.Evaluate(V3{0, 3, -1}) >= 0 || s.Evaluate(V3{0, 3, 3.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	s = DrywallHole3D(&DrywallHoleParms{Screw: "#8", Thickness: 5, Clearance: 0.1, Countersink: true})
+	if s.Evaluate(V3{0, 0, 2.5}) >= 0 || s.Evaluate(V3{3, 0, 2.5}) <= 0 || s.Evaluate(V3{3, 0, 4.8}) >= 0 || s.Evaluate(V3{3, 0, 3.2}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Preview(t *testing.T) {
+	builds := 0
+	p := NewPreview(func(v map[string]float64) SDF3 {
+		builds++
+		if v["r"] > 5 {
+			panic("radius is too big")
+		}
+		return Sphere3D(v["r"])
+	})
+	p.Parameter("r", 1, 10, 2)
+	s, err := p.model(map[string]float64{"r": 2})
+	if err != nil || s.Evaluate(V3{2, 0, 0}) != 0 {
+		t.Error("FAIL")
+	}
+	// cached, then a build error
+	p.model(map[string]float64{"r": 2})
+	if _, err := p.model(map[string]float64{"r": 6}); err == nil || builds != 2 {
+		t.Error("FAIL")
+	}
+	// an image, or the error message in the response
+	p.SetPixels(32)
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/image?r=3&_az=10", nil))
+	if img, err := png.Decode(w.Body); err != nil || w.Code != http.StatusOK || img.Bounds().Dx() != 32 {
+		t.Errorf("FAIL %d %v", w.Code, err)
+	}
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/image?r=6", nil))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "radius is too big") {
+		t.Errorf("FAIL %d %q", w.Code, w.Body.String())
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_ColorModel(t *testing.T) {
+	m := NewColorModel()
+	m.Add("base", Box3D(V3{20, 20, 4}, 0))
+	m.Add("text", Transform3D(Box3D(V3{6, 6, 2}, 0), Translate3d(V3{0, 0, 2})))
+	m.Add("base", Transform3D(Sphere3D(2), Translate3d(V3{15, 0, 0})))
+	if len(m.Colors()) != 2 || m.Colors()[1] != "text" {
+		t.Error("FAIL")
+	}
+	b := m.Separate(0.2)
+	// the text wins over the base
+	if b[0].Evaluate(V3{0, 0, 1.5}) <= 0 || b[0].Evaluate(V3{5, 0, 1.5}) >= 0 || b[0].Evaluate(V3{16, 0, 0}) >= 0 {
+		t.Error("FAIL")
+	}
+	// the text overlaps the base, but not outside the model
+	if b[1].Evaluate(V3{0, 0, 1.5}) >= 0 || b[1].Evaluate(V3{3.1, 0, 1.5}) >= 0 || b[1].Evaluate(V3{3.3, 0, 1.5}) <= 0 || b[1].Evaluate(V3{3.1, 0, 2.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	// a later color next to the text: the text doesn't grow into it
+	m.Add("logo", Transform3D(Box3D(V3{4, 6, 2}, 0), Translate3d(V3{5, 0, 2})))
+	b = m.Separate(0.2)
+	if b[1].Evaluate(V3{3.1, 0, 1.5}) <= 0 || b[2].Evaluate(V3{3.1, 0, 1.5}) >= 0 || b[2].Evaluate(V3{2.9, 0, 1.5}) >= 0 || b[1].Evaluate(V3{-3.1, 0, 1.5}) >= 0 {
+		t.Error("FAIL")
+	}
+	// one 3mf file with an object for each color
+	path := filepath.Join(t.TempDir(), "sign.3mf")
+	m.Render3MF(40, path, 0.2, nil)
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+	f, err := z.Open(TMF_MODEL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, _ := io.ReadAll(f)
+	for _, c := range []string{"base", "text", "logo"} {
+		if !strings.Contains(string(model), `name="`+c+`"`) {
+			t.Errorf("FAIL %s", c)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_RenderImage(t *testing.T) {
+	s := Sphere3D(2)
+	for _, fov := range []float64{0, DtoR(40)} {
+		c := ViewCamera(s.BoundingBox(), DtoR(30), DtoR(20), fov, V2i{48, 32})
+		img := RenderImage(s, c, nil)
+		// lit at the center (headlight), background at the corners
+		if img.RGBAAt(24, 16).R < 200 || img.RGBAAt(0, 0).R != 31 || img.RGBAAt(47, 31).R != 31 {
+			t.Error("FAIL")
+		}
+		// the bounding box fits the view height
+		if img.RGBAAt(24, 4).R != 31 || img.RGBAAt(24, 10).R == 31 {
+			t.Error("FAIL")
+		}
+	}
+	// the light from +x, ambient occlusion darkens the inside corner
+	l := DefaultLighting
+	l.Light = V3{1, 0, 0}
+	c := &Camera{Eye: V3{0, 0, 10}, Target: V3{0, 0, 0}, Up: V3{0, 1, 0}, Size: 8, Pixels: V2i{32, 32}}
+	img := RenderImage(s, c, &l)
+	if img.RGBAAt(22, 16).R <= img.RGBAAt(9, 16).R {
+		t.Error("FAIL")
+	}
+	l.AO = 2
+	s = Union3D(Box3D(V3{8, 8, 1}, 0), Transform3D(Box3D(V3{1, 8, 4}, 0), Translate3d(V3{0, 0, 2})))
+	l.Light = V3{0, 0, 0}
+	img = RenderImage(s, c, &l)
+	if img.RGBAAt(12, 16).R >= img.RGBAAt(2, 16).R {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_TopSurfaces(t *testing.T) {
+	plate := Box3D(V3{20, 20, 4}, 0)
+	boss := Transform3D(Box3D(V3{6, 6, 4}, 0), Translate3d(V3{0, 0, 4}))
+	s := Union3D(plate, boss, Transform3D(Sphere3D(2), Translate3d(V3{-6, -6, 2})))
+	k := TopSurfaceParms{MaxAngle: 5, MinArea: 10, Resolution: 0.5}
+	faces := TopSurfaces(s, &k)
+	if len(faces) != 2 {
+		t.Fatalf("FAIL: %d faces", len(faces))
+	}
+	// order by height
+	if faces[0].Z > faces[1].Z {
+		faces[0], faces[1] = faces[1], faces[0]
+	}
+	f0, f1 := faces[0], faces[1]
+	if Abs(f0.Z-2) > 0.1 || Abs(f1.Z-6) > 0.1 || f0.Angle > 5 || f1.Angle > 5 {
+		t.Error("FAIL")
+	}
+	// the edges are lost to about the resolution
+	if f0.Area < 300 || f0.Area > 364 || f1.Area < 25 || f1.Area > 36 {
+		t.Errorf("FAIL: areas %f %f", f0.Area, f1.Area)
+	}
+	// the plate face goes around the boss and the sphere
+	if f0.Region.Evaluate(V2{8, 0}) >= 0 || f0.Region.Evaluate(V2{0, 0}) <= 0 || f0.Region.Evaluate(V2{-6, -6}) <= 0 || f0.Region.Evaluate(V2{12, 0}) <= 0 {
+		t.Error("FAIL")
+	}
+	if f1.Region.Evaluate(V2{0, 0}) >= 0 || f1.Region.Evaluate(V2{5, 0}) <= 0 || f1.Region.Evaluate(V2{30, 30}) <= 0 {
+		t.Error("FAIL")
+	}
+	// drop the small face
+	k.MinArea = 50
+	if len(TopSurfaces(s, &k)) != 1 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Animate(t *testing.T) {
+	// count the pixels that aren't background
+	lit := func(img *image.RGBA) int {
+		n := 0
+		for i := 0; i < len(img.Pix); i += 4 {
+			if img.Pix[i] != 31 {
+				n++
+			}
+		}
+		return n
+	}
+	k := AnimationParms{Frames: 4, Delay: 10, Elevation: DtoR(30), Pixels: V2i{24, 24}}
+	frames := Animate(func(t float64) SDF3 { return Sphere3D(1 + t) }, &k)
+	// the camera is fixed, the sphere grows
+	if len(frames) != 4 {
+		t.Fatal("FAIL")
+	}
+	for i := 1; i < len(frames); i++ {
+		if lit(frames[i]) <= lit(frames[i-1]) {
+			t.Error("FAIL")
+		}
+	}
+	// a quarter turn of a long box
+	k = AnimationParms{Frames: 2, Delay: 10, Turns: 0.25, Pixels: V2i{24, 24}}
+	s := Box3D(V3{8, 1, 1}, 0)
+	frames = Turntable(s, &k)
+	if lit(frames[0]) >= lit(frames[1]) {
+		t.Error("FAIL")
+	}
+	path := filepath.Join(t.TempDir(), "test.gif")
+	if err := SaveGIF(path, frames, k.Delay); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	g, err := gif.DecodeAll(f)
+	if err != nil || len(g.Image) != 2 || g.Delay[1] != 10 || g.Image[0].Bounds().Dx() != 24 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Spline_Sign(t *testing.T) {
+	knot := []V2{{10, 0}, {0, 10}, {-10, 0}, {0, -10}}
+	for _, s := range []SDF2{ClosedCubicSpline2D(knot), CatmullRomSpline2D(append(knot, knot[0]))} {
+		cs := s.(*CubicSplineSDF2)
+		// between two polygonization samples, the chord is well inside the curve
+		for _, t0 := range []float64{0.5 / SPLINE_SAMPLES, 1.5 + 0.5/SPLINE_SAMPLES} {
+			c := cs.F0(t0)
+			tangent := cs.F1(t0).Normalize()
+			// knots are counter clockwise, inside is to the left
+			n := V2{-tangent.Y, tangent.X}
+			if d := s.Evaluate(c.Add(n.MulScalar(0.005))); d >= 0 || Abs(d+0.005) > 1e-4 {
+				t.Errorf("FAIL: inside %f", d)
+			}
+			if d := s.Evaluate(c.Sub(n.MulScalar(0.005))); d <= 0 || Abs(d-0.005) > 1e-4 {
+				t.Errorf("FAIL: outside %f", d)
+			}
+		}
+		// on a knot's horizontal line, and far away
+		if s.Evaluate(V2{0, 0}) >= 0 || s.Evaluate(V2{-15, 0}) <= 0 || s.Evaluate(V2{5, 10}) <= 0 || s.Evaluate(V2{0, 9.5}) >= 0 {
+			t.Error("FAIL")
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_TongueAndGroove(t *testing.T) {
+	outline := Box2D(V2{60, 40}, 0)
+	k := SealParms{Wall: 6, Width: 1.5, Height: 3, Clearance: 0.2}
+	tongue, groove := TongueAndGroove3D(outline, &k)
+	if k.Ridges != 0 {
+		t.Error("FAIL")
+	}
+	// the rings are centered on the wall (x = 27)
+	if tongue.Evaluate(V3{27, 0, -1.5}) >= 0 || tongue.Evaluate(V3{27.8, 0, -1.5}) <= 0 || tongue.Evaluate(V3{27, 0, -3.05}) <= 0 {
+		t.Error("FAIL")
+	}
+	if groove.Evaluate(V3{27.9, 0, -1.5}) >= 0 || groove.Evaluate(V3{28, 0, -1.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	if groove.Evaluate(V3{27, 0, -3.15}) >= 0 || groove.Evaluate(V3{27, 0, -3.3}) <= 0 {
+		t.Error("FAIL")
+	}
+	// the clearance between the tongue and the groove walls and floor
+	for _, p := range []V3{{27.95, 0, -1.5}, {26.05, 0, -1.5}, {0, 17.95, -1.5}, {27, 0, -3.2}} {
+		if groove.Evaluate(p) > TOLERANCE*10 || Abs(tongue.Evaluate(p)-k.Clearance) > TOLERANCE*10 {
+			t.Errorf("FAIL %v", p)
+		}
+	}
+	bb := tongue.BoundingBox()
+	if !bb.Min.Equals(V3{-27.75, -17.75, -3}, TOLERANCE) || !bb.Max.Equals(V3{27.75, 17.75, 0}, TOLERANCE) {
+		t.Errorf("FAIL %v", bb)
+	}
+	bb = groove.BoundingBox()
+	if !bb.Min.Equals(V3{-27.95, -17.95, -3.2}, TOLERANCE) || !bb.Max.Equals(V3{27.95, 17.95, 3.2}, TOLERANCE) {
+		t.Errorf("FAIL %v", bb)
+	}
+	// labyrinth seal, rings at x = 24.1 and x = 27.9
+	k = SealParms{Wall: 8, Width: 1.5, Height: 3, Clearance: 0.2, Ridges: 2}
+	tongue, groove = TongueAndGroove3D(outline, &k)
+	if tongue.Evaluate(V3{24.1, 0, -1.5}) >= 0 || tongue.Evaluate(V3{27.9, 0, -1.5}) >= 0 || tongue.Evaluate(V3{26, 0, -1.5}) <= 0 {
+		t.Error("FAIL")
+	}
+	if groove.Evaluate(V3{26, 0, -1.5}) <= 0 || Abs(tongue.Evaluate(V3{28.85, 0, -1.5})-k.Clearance) > TOLERANCE*10 {
+		t.Error("FAIL")
+	}
+	// too many ridges for the wall
+	defer func() {
+		if recover() == nil {
+			t.Error("FAIL")
+		}
+	}()
+	k.Ridges = 3
+	TongueAndGroove3D(outline, &k)
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_CableGland(t *testing.T) {
+	th := ThreadLookup("PG9")
+	if Abs(th.Radius-7.6) > TOLERANCE || Abs(th.Pitch-25.4/18) > TOLERANCE || th.Hex_Flat2Flat != 19 || th.Units != "mm" {
+		t.Errorf("FAIL %v", th)
+	}
+	k := CableGlandParms{Thread: "PG9", Wall: 3, Boss: 4, Clearance: 0.2, NutClearance: 0.5}
+	boss, hole := CableGland3D(&k)
+	// boss radius: 19 mm locknut (across corners) + clearance, z = -7 to -3
+	r := 19/(2*math.Cos(DtoR(30))) + 0.5
+	if Abs(boss.Evaluate(V3{r, 0, -5})) > TOLERANCE || Abs(boss.Evaluate(V3{0, r, -5})) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	if boss.Evaluate(V3{10, 0, -2.9}) <= 0 || boss.Evaluate(V3{10, 0, -3.1}) >= 0 || boss.Evaluate(V3{10, 0, -7.1}) <= 0 {
+		t.Error("FAIL")
+	}
+	bb := boss.BoundingBox()
+	if !bb.Min.Equals(V3{-r, -r, -7}, TOLERANCE) || !bb.Max.Equals(V3{r, r, -3}, TOLERANCE) {
+		t.Errorf("FAIL %v", bb)
+	}
+	// the bore is inside the minor radius of the thread, clear of the major radius
+	for z := -7.0; z <= 0; z += 0.25 {
+		if hole.Evaluate(V3{6.9, 0, z}) >= 0 || hole.Evaluate(V3{0, -6.9, z}) >= 0 || hole.Evaluate(V3{8.1, 0, z}) <= 0 {
+			t.Errorf("FAIL %f", z)
+		}
+	}
+	// the hole goes through both faces (one pitch past each face)
+	if hole.Evaluate(V3{3, 0, 1}) >= 0 || hole.Evaluate(V3{3, 0, -8.2}) >= 0 || hole.Evaluate(V3{3, 0, -8.7}) <= 0 {
+		t.Error("FAIL")
+	}
+	// locknut size from the gland table (24 mm, not the 30 mm nut of the thread)
+	boss, _ = CableGland3D(&CableGlandParms{Thread: "M20x1.5", Wall: 3, Boss: 4})
+	r = 24 / (2 * math.Cos(DtoR(30)))
+	if Abs(boss.Evaluate(V3{r, 0, -5})) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	// unknown and non-metric threads
+	for _, name := range []string{"PG10", "unc_1/4"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("FAIL %s", name)
+				}
+			}()
+			CableGland3D(&CableGlandParms{Thread: name, Wall: 3, Boss: 4})
+		}()
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Louver(t *testing.T) {
+	k := LouverParms{Length: 40, Depth: 3, Gap: 2, Pitch: 5, Angle: DtoR(30), Slots: 4}
+	s := Louver3D(&k)
+	// count the slots across the middle of the wall
+	n, inside := 0, false
+	var centers []float64
+	y0 := 0.0
+	for y := -15.0; y <= 15.0; y += 0.01 {
+		in := s.Evaluate(V3{0, y, -1.5}) < 0
+		if in && !inside {
+			n++
+			y0 = y
+		}
+		if !in && inside {
+			// the slot is the gap wide
+			if Abs(y-y0-k.Gap) > 0.02 {
+				t.Errorf("FAIL %f", y-y0)
+			}
+			centers = append(centers, 0.5*(y+y0))
+		}
+		inside = in
+	}
+	if n != k.Slots || len(centers) != k.Slots {
+		t.Fatalf("FAIL %d", n)
+	}
+	// the pitch
+	for i, y := range centers {
+		if Abs(y-(float64(i)-1.5)*k.Pitch) > 0.02 {
+			t.Errorf("FAIL %f", y)
+		}
+	}
+	// each slot is open through the wall, along a line sloping down towards the outside
+	dy := math.Tan(k.Angle)
+	for _, y := range centers {
+		for z := -3.0; z <= 0; z += 0.1 {
+			c := y - (z+1.5)*dy
+			if s.Evaluate(V3{0, c, z}) >= 0 || s.Evaluate(V3{19.9, c + 0.9, z}) >= 0 || s.Evaluate(V3{-19.9, c - 0.9, z}) >= 0 {
+				t.Errorf("FAIL %f %f", y, z)
+			}
+			if s.Evaluate(V3{0, c + 1.1, z}) <= 0 || s.Evaluate(V3{0, c - 1.1, z}) <= 0 || s.Evaluate(V3{20.1, c, z}) <= 0 {
+				t.Errorf("FAIL %f %f", y, z)
+			}
+		}
+		// not open along the wall normal
+		if s.Evaluate(V3{0, y + 0.9, 0}) <= 0 || s.Evaluate(V3{0, y - 0.9, -3}) <= 0 {
+			t.Errorf("FAIL %f", y)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_LabelRecess(t *testing.T) {
+	k := LabelParms{Size: V2{40, 20}, Radius: 3, Depth: 1, Chamfer: 0.5}
+	s := LabelRecess3D(&k)
+	// recess depth
+	if s.Evaluate(V3{0, 0, -0.95}) >= 0 || s.Evaluate(V3{0, 0, -1.05}) <= 0 || s.Evaluate(V3{15, 5, -1.05}) <= 0 {
+		t.Error("FAIL")
+	}
+	// straight walls below the chamfer, rounded corners
+	if s.Evaluate(V3{19.9, 0, -0.7}) >= 0 || s.Evaluate(V3{20.1, 0, -0.7}) <= 0 || s.Evaluate(V3{0, 10.1, -0.7}) <= 0 || s.Evaluate(V3{19.5, 9.5, -0.7}) <= 0 {
+		t.Error("FAIL")
+	}
+	// 45 degree chamfer: the edge is out by the chamfer size at the face
+	for _, z := range []float64{-0.4, -0.25, 0} {
+		x := 20 + z + k.Chamfer
+		if s.Evaluate(V3{x - 0.05, 0, z}) >= 0 || s.Evaluate(V3{x + 0.05, 0, z}) <= 0 {
+			t.Errorf("FAIL %f", z)
+		}
+		if s.Evaluate(V3{0, x - 10 - 0.05, z}) >= 0 || s.Evaluate(V3{0, x - 10 + 0.05, z}) <= 0 {
+			t.Errorf("FAIL %f", z)
+		}
+	}
+	// no chamfer
+	k.Chamfer = 0
+	s = LabelRecess3D(&k)
+	if s.Evaluate(V3{19.9, 0, 0}) >= 0 || s.Evaluate(V3{20.1, 0, 0}) <= 0 || s.Evaluate(V3{20.1, 0, -0.1}) <= 0 {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_ExpMax(t *testing.T) {
+	max := ExpMax(32)
+	min := ExpMin(32)
+	for _, x := range [][3]float64{
+		{0, 0, math.Ln2 / 32},
+		{1, -1, 1},
+		{-1, 1, 1},
+		{100, 100, 100 + math.Ln2/32},
+		{1000, 999, 1000},
+		{-1000, -1000, -1000 + math.Ln2/32},
+	} {
+		if d := max(x[0], x[1]); Abs(d-x[2]) > TOLERANCE || math.IsNaN(d) {
+			t.Errorf("FAIL %v %f", x, d)
+		}
+		// ExpMin is the mirror image
+		if d := min(-x[0], -x[1]); Abs(d+x[2]) > TOLERANCE || math.IsNaN(d) {
+			t.Errorf("FAIL %v %f", x, d)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Trace(t *testing.T) {
+	n := 0
+	s := Trace2D(Circle2D(1), func(p V2, d float64) {
+		n++
+	})
+	d := s.Evaluate(V2{2, 0})
+	if n != 1 || Abs(d-1) > TOLERANCE {
+		t.Error("FAIL")
+	}
+	if !s.BoundingBox().Equals(Box2{V2{-1, -1}, V2{1, 1}}, TOLERANCE) {
+		t.Error("FAIL")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,510 @@
+//-----------------------------------------------------------------------------
+/*
+
+SDF Serialization
+
+Save the CSG tree of an SDF as JSON, and rebuild the SDF from the JSON. The
+tree is a node per primitive/operation with its parameters and children:
+
+{"type": "difference", "children": [
+  {"type": "box", "parms": {"size": [10, 10, 10], "round": 1}},
+  {"type": "sphere", "parms": {"radius": 6}}
+]}
+
+Nodes are rebuilt with their constructors, so the JSON holds the constructor
+parameters. The supported nodes are the basic primitives, transforms,
+booleans, offsets, shells and extrusions of 2D primitives. Nodes with a Go
+function for their behavior (E.g. blended booleans, custom extrusions) can't
+be serialized and return an error, as do the other SDF types.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//-----------------------------------------------------------------------------
+
+type sdf_node struct {
+	Type     string                 `json:"type"`
+	Parms    map[string]interface{} `json:"parms,omitempty"`
+	Children []*sdf_node            `json:"children,omitempty"`
+}
+
+// MarshalSDF3 returns the JSON encoding of an SDF3.
+func MarshalSDF3(s SDF3) ([]byte, error) {
+	n, err := encode_sdf3(s)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(n, "", "  ")
+}
+
+// UnmarshalSDF3 returns the SDF3 for a JSON encoding.
+func UnmarshalSDF3(data []byte) (s SDF3, err error) {
+	defer recover_parms(&err)
+	var n sdf_node
+	if err := json.Unmarshal(data, &n); err != nil {
+		return nil, err
+	}
+	return decode_sdf3(&n)
+}
+
+// MarshalSDF2 returns the JSON encoding of an SDF2.
+func MarshalSDF2(s SDF2) ([]byte, error) {
+	n, err := encode_sdf2(s)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(n, "", "  ")
+}
+
+// UnmarshalSDF2 returns the SDF2 for a JSON encoding.
+func UnmarshalSDF2(data []byte) (s SDF2, err error) {
+	defer recover_parms(&err)
+	var n sdf_node
+	if err := json.Unmarshal(data, &n); err != nil {
+		return nil, err
+	}
+	return decode_sdf2(&n)
+}
+
+//-----------------------------------------------------------------------------
+// Parameters
+
+// The constructors panic with bad parameters, return an error instead.
+func recover_parms(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("%v", r)
+	}
+}
+
+// Return a new node.
+func new_node(t string, children ...*sdf_node) *sdf_node {
+	return &sdf_node{Type: t, Parms: make(map[string]interface{}), Children: children}
+}
+
+// Return a scalar parameter.
+func (n *sdf_node) num(name string) (float64, error) {
+	x, ok := n.Parms[name].(float64)
+	if !ok {
+		return 0, fmt.Errorf("%s: bad parameter \"%s\"", n.Type, name)
+	}
+	return x, nil
+}
+
+// Return a vector parameter with k values.
+func (n *sdf_node) vec(name string, k int) ([]float64, error) {
+	a, ok := n.Parms[name].([]interface{})
+	if !ok || (k > 0 && len(a) != k) {
+		return nil, fmt.Errorf("%s: bad parameter \"%s\"", n.Type, name)
+	}
+	x := make([]float64, len(a))
+	for i := range a {
+		if x[i], ok = a[i].(float64); !ok {
+			return nil, fmt.Errorf("%s: bad parameter \"%s\"", n.Type, name)
+		}
+	}
+	return x, nil
+}
+
+// Check the number of children.
+func (n *sdf_node) check_children(k int) error {
+	if (k > 0 && len(n.Children) != k) || len(n.Children) == 0 {
+		return fmt.Errorf("%s: bad number of children", n.Type)
+	}
+	return nil
+}
+
+// Decode the parameters of a node. The names are scalars, or vectors of
+// length k with a "name:k" suffix.
+func (n *sdf_node) decode(names ...string) ([][]float64, error) {
+	x := make([][]float64, len(names))
+	for i, name := range names {
+		if j := strings.Index(name, ":"); j >= 0 {
+			k, _ := strconv.Atoi(name[j+1:])
+			v, err := n.vec(name[:j], k)
+			if err != nil {
+				return nil, err
+			}
+			x[i] = v
+			continue
+		}
+		v, err := n.num(name)
+		if err != nil {
+			return nil, err
+		}
+		x[i] = []float64{v}
+	}
+	return x, nil
+}
+
+//-----------------------------------------------------------------------------
+// 3D
+
+// Return the node for an SDF3.
+func encode_sdf3(s SDF3) (*sdf_node, error) {
+	switch s := s.(type) {
+	case *SphereSDF3:
+		n := new_node("sphere")
+		n.Parms["radius"] = s.radius
+		return n, nil
+	case *BoxSDF3:
+		n := new_node("box")
+		size := s.size.AddScalar(s.round).MulScalar(2)
+		n.Parms["size"] = []float64{size.X, size.Y, size.Z}
+		n.Parms["round"] = s.round
+		return n, nil
+	case *CylinderSDF3:
+		n := new_node("cylinder")
+		n.Parms["height"] = 2 * (s.height + s.round)
+		n.Parms["radius"] = s.radius + s.round
+		n.Parms["round"] = s.round
+		return n, nil
+	case *TransformSDF3:
+		c, err := encode_sdf3(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		n := new_node("transform", c)
+		m := s.matrix
+		n.Parms["matrix"] = []float64{
+			m.x00, m.x01, m.x02, m.x03,
+			m.x10, m.x11, m.x12, m.x13,
+			m.x20, m.x21, m.x22, m.x23,
+			m.x30, m.x31, m.x32, m.x33,
+		}
+		return n, nil
+	case *ScaleUniformSDF3:
+		c, err := encode_sdf3(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		n := new_node("scale", c)
+		n.Parms["k"] = s.k
+		return n, nil
+	case *UnionSDF3:
+		if s.blended {
+			return nil, fmt.Errorf("can't serialize a blended union")
+		}
+		return encode_children3("union", s.sdf...)
+	case *DifferenceSDF3:
+		if s.blended {
+			return nil, fmt.Errorf("can't serialize a blended difference")
+		}
+		return encode_children3("difference", s.s0, s.s1)
+	case *IntersectionSDF3:
+		if s.blended {
+			return nil, fmt.Errorf("can't serialize a blended intersection")
+		}
+		return encode_children3("intersection", s.s0, s.s1)
+	case *OffsetSDF3:
+		n, err := encode_children3("offset", s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		n.Parms["offset"] = s.offset
+		return n, nil
+	case *ShellSDF3:
+		n, err := encode_children3("shell", s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		n.Parms["thickness"] = 2 * s.delta
+		return n, nil
+	case *ExtrudeSDF3:
+		if s.custom {
+			return nil, fmt.Errorf("can't serialize a custom extrusion")
+		}
+		c, err := encode_sdf2(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		n := new_node("extrude", c)
+		n.Parms["height"] = 2 * s.height
+		n.Parms["twist"] = s.twist
+		n.Parms["scale"] = []float64{s.scale.X, s.scale.Y}
+		return n, nil
+	}
+	return nil, fmt.Errorf("can't serialize %T", s)
+}
+
+// Return a node with SDF3 children.
+func encode_children3(t string, sdf ...SDF3) (*sdf_node, error) {
+	n := new_node(t)
+	for _, x := range sdf {
+		c, err := encode_sdf3(x)
+		if err != nil {
+			return nil, err
+		}
+		n.Children = append(n.Children, c)
+	}
+	return n, nil
+}
+
+// Return the SDF3 children of a node.
+func decode_children3(n *sdf_node, k int) ([]SDF3, error) {
+	if err := n.check_children(k); err != nil {
+		return nil, err
+	}
+	s := make([]SDF3, len(n.Children))
+	for i, c := range n.Children {
+		var err error
+		if s[i], err = decode_sdf3(c); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Return the SDF3 for a node.
+func decode_sdf3(n *sdf_node) (SDF3, error) {
+	switch n.Type {
+	case "sphere":
+		x, err := n.decode("radius")
+		if err != nil {
+			return nil, err
+		}
+		return Sphere3D(x[0][0]), nil
+	case "box":
+		x, err := n.decode("size:3", "round")
+		if err != nil {
+			return nil, err
+		}
+		return Box3D(V3{x[0][0], x[0][1], x[0][2]}, x[1][0]), nil
+	case "cylinder":
+		x, err := n.decode("height", "radius", "round")
+		if err != nil {
+			return nil, err
+		}
+		return Cylinder3D(x[0][0], x[1][0], x[2][0]), nil
+	case "transform":
+		x, err := n.decode("matrix:16")
+		if err != nil {
+			return nil, err
+		}
+		c, err := decode_children3(n, 1)
+		if err != nil {
+			return nil, err
+		}
+		m := x[0]
+		return Transform3D(c[0], M44{
+			m[0], m[1], m[2], m[3],
+			m[4], m[5], m[6], m[7],
+			m[8], m[9], m[10], m[11],
+			m[12], m[13], m[14], m[15],
+		}), nil
+	case "scale":
+		x, err := n.decode("k")
+		if err != nil {
+			return nil, err
+		}
+		c, err := decode_children3(n, 1)
+		if err != nil {
+			return nil, err
+		}
+		return ScaleUniform3D(c[0], x[0][0]), nil
+	case "union":
+		c, err := decode_children3(n, 0)
+		if err != nil {
+			return nil, err
+		}
+		return Union3D(c...), nil
+	case "difference", "intersection":
+		c, err := decode_children3(n, 2)
+		if err != nil {
+			return nil, err
+		}
+		if n.Type == "difference" {
+			return Difference3D(c[0], c[1]), nil
+		}
+		return Intersect3D(c[0], c[1]), nil
+	case "offset":
+		x, err := n.decode("offset")
+		if err != nil {
+			return nil, err
+		}
+		c, err := decode_children3(n, 1)
+		if err != nil {
+			return nil, err
+		}
+		return Offset3D(c[0], x[0][0]), nil
+	case "shell":
+		x, err := n.decode("thickness")
+		if err != nil {
+			return nil, err
+		}
+		c, err := decode_children3(n, 1)
+		if err != nil {
+			return nil, err
+		}
+		return Shell3D(c[0], x[0][0]), nil
+	case "extrude":
+		x, err := n.decode("height", "twist", "scale:2")
+		if err != nil {
+			return nil, err
+		}
+		if err := n.check_children(1); err != nil {
+			return nil, err
+		}
+		c, err := decode_sdf2(n.Children[0])
+		if err != nil {
+			return nil, err
+		}
+		h, twist, scale := x[0][0], x[1][0], V2{x[2][0], x[2][1]}
+		if scale == (V2{}) {
+			if twist == 0 {
+				return Extrude3D(c, h), nil
+			}
+			return TwistExtrude3D(c, h, twist), nil
+		}
+		if twist == 0 {
+			return ScaleExtrude3D(c, h, scale), nil
+		}
+		return ScaleTwistExtrude3D(c, h, twist, scale), nil
+	}
+	return nil, fmt.Errorf("unknown SDF3 type \"%s\"", n.Type)
+}
+
+//-----------------------------------------------------------------------------
+// 2D
+
+// Return the node for an SDF2.
+func encode_sdf2(s SDF2) (*sdf_node, error) {
+	switch s := s.(type) {
+	case *CircleSDF2:
+		n := new_node("circle")
+		n.Parms["radius"] = s.radius
+		return n, nil
+	case *BoxSDF2:
+		n := new_node("box")
+		size := s.size.AddScalar(s.round).MulScalar(2)
+		n.Parms["size"] = []float64{size.X, size.Y}
+		n.Parms["round"] = s.round
+		return n, nil
+	case *PolySDF2:
+		n := new_node("polygon")
+		v := make([]float64, 0, 2*len(s.vertex))
+		for _, p := range s.vertex {
+			v = append(v, p.X, p.Y)
+		}
+		n.Parms["vertex"] = v
+		return n, nil
+	case *TransformSDF2:
+		c, err := encode_sdf2(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		n := new_node("transform", c)
+		m := s.m_inv.Inverse()
+		n.Parms["matrix"] = []float64{
+			m.x00, m.x01, m.x02,
+			m.x10, m.x11, m.x12,
+			m.x20, m.x21, m.x22,
+		}
+		return n, nil
+	case *UnionSDF2:
+		if s.blended {
+			return nil, fmt.Errorf("can't serialize a blended union")
+		}
+		return encode_children2("union", s.sdf...)
+	case *DifferenceSDF2:
+		if s.blended {
+			return nil, fmt.Errorf("can't serialize a blended difference")
+		}
+		return encode_children2("difference", s.s0, s.s1)
+	}
+	return nil, fmt.Errorf("can't serialize %T", s)
+}
+
+// Return a node with SDF2 children.
+func encode_children2(t string, sdf ...SDF2) (*sdf_node, error) {
+	n := new_node(t)
+	for _, x := range sdf {
+		c, err := encode_sdf2(x)
+		if err != nil {
+			return nil, err
+		}
+		n.Children = append(n.Children, c)
+	}
+	return n, nil
+}
+
+// Return the SDF2 children of a node.
+func decode_children2(n *sdf_node, k int) ([]SDF2, error) {
+	if err := n.check_children(k); err != nil {
+		return nil, err
+	}
+	s := make([]SDF2, len(n.Children))
+	for i, c := range n.Children {
+		var err error
+		if s[i], err = decode_sdf2(c); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Return the SDF2 for a node.
+func decode_sdf2(n *sdf_node) (SDF2, error) {
+	switch n.Type {
+	case "circle":
+		x, err := n.decode("radius")
+		if err != nil {
+			return nil, err
+		}
+		return Circle2D(x[0][0]), nil
+	case "box":
+		x, err := n.decode("size:2", "round")
+		if err != nil {
+			return nil, err
+		}
+		return Box2D(V2{x[0][0], x[0][1]}, x[1][0]), nil
+	case "polygon":
+		v, err := n.vec("vertex", 0)
+		if err != nil || len(v) < 6 || len(v)%2 != 0 {
+			return nil, fmt.Errorf("polygon: bad parameter \"vertex\"")
+		}
+		p := make([]V2, len(v)/2)
+		for i := range p {
+			p[i] = V2{v[2*i], v[2*i+1]}
+		}
+		return Polygon2D(p), nil
+	case "transform":
+		x, err := n.decode("matrix:9")
+		if err != nil {
+			return nil, err
+		}
+		c, err := decode_children2(n, 1)
+		if err != nil {
+			return nil, err
+		}
+		m := x[0]
+		return Transform2D(c[0], M33{
+			m[0], m[1], m[2],
+			m[3], m[4], m[5],
+			m[6], m[7], m[8],
+		}), nil
+	case "union":
+		c, err := decode_children2(n, 0)
+		if err != nil {
+			return nil, err
+		}
+		return Union2D(c...), nil
+	case "difference":
+		c, err := decode_children2(n, 2)
+		if err != nil {
+			return nil, err
+		}
+		return Difference2D(c[0], c[1]), nil
+	}
+	return nil, fmt.Errorf("unknown SDF2 type \"%s\"", n.Type)
+}
+
+//-----------------------------------------------------------------------------
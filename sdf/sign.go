@@ -0,0 +1,116 @@
+//-----------------------------------------------------------------------------
+/*
+
+Signs and Nameplates
+
+A base plate with a raised border and raised text. The text is a separate
+body so a sign can be printed in two colors: render the plate and the text
+to separate STL files (see ColorModel), they share the same coordinates. The text is embedded
+a little way into the plate (the plate has a matching pocket) to register
+and hold the two bodies together.
+
+The text is any SDF2 (E.g. from TextSDF2), it's centered on the plate and
+scaled down if it doesn't fit inside the border.
+
+The sign is mounted with screw holes through the plate or with keyhole slots
+in the back. Keyholes need a plate thick enough for the screw head.
+
+The back of the plate is on the z = 0 plane and the sign is centered on the
+origin.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+//-----------------------------------------------------------------------------
+
+type SignParms struct {
+	Size      V2      // plate size
+	Thickness float64 // plate thickness
+	Round     float64 // corner radius
+	Border    V2      // border width and height above the plate (0 for none)
+	Text      SDF2    // text (or any 2D shape)
+	Margin    float64 // space between the border and the text
+	Relief    float64 // height of the text above the plate
+	Embed     float64 // depth of the text into the plate
+	Mount     string  // mounting: "none", "holes", "keyholes"
+	Screw     V2      // screw head and shaft diameter (for the mounting)
+	Inset     float64 // distance from the end of the plate to the mounting center
+}
+
+// Sign3D returns the plate and the text of a sign.
+func Sign3D(k *SignParms) (SDF3, SDF3) {
+	if k.Size.X <= 0 || k.Size.Y <= 0 || k.Thickness <= 0 {
+		panic("invalid sign size")
+	}
+	if k.Round < 0 || 2.0*k.Round >= Min(k.Size.X, k.Size.Y) {
+		panic("invalid corner radius")
+	}
+	if k.Border.X < 0 || k.Border.Y < 0 || k.Margin < 0 {
+		panic("invalid border")
+	}
+	if k.Text == nil || k.Relief <= 0 || k.Embed < 0 || k.Embed >= k.Thickness {
+		panic("invalid text")
+	}
+
+	// plate
+	outline := Box2D(k.Size, k.Round)
+	plate := Extrude3D(outline, k.Thickness)
+	plate = Transform3D(plate, Translate3d(V3{0, 0, 0.5 * k.Thickness}))
+	if k.Border.X > 0 && k.Border.Y > 0 {
+		border := Difference2D(outline, Offset2D(outline, -k.Border.X))
+		b := Extrude3D(border, k.Border.Y)
+		b = Transform3D(b, Translate3d(V3{0, 0, k.Thickness + 0.5*k.Border.Y}))
+		plate = Union3D(plate, b)
+	}
+
+	// fit the text inside the border
+	area := k.Size.SubScalar(2.0 * (k.Border.X + k.Margin))
+	if area.X <= 0 || area.Y <= 0 {
+		panic("no room for the text")
+	}
+	size := k.Text.BoundingBox().Size()
+	scale := Min(1, Min(area.X/size.X, area.Y/size.Y))
+	text2d := CenterAndScale2D(k.Text, scale)
+	h := k.Relief + k.Embed
+	text := Extrude3D(text2d, h)
+	text = Transform3D(text, Translate3d(V3{0, 0, k.Thickness - k.Embed + 0.5*h}))
+	if k.Embed > 0 {
+		plate = Difference3D(plate, text)
+	}
+
+	// mounting
+	x := 0.5*k.Size.X - k.Inset
+	switch k.Mount {
+	case "", "none":
+	case "holes":
+		if k.Screw.Y <= 0 || x <= 0 {
+			panic("invalid mounting holes")
+		}
+		hole := Cylinder3D(4.0*k.Thickness, 0.5*k.Screw.Y, 0)
+		plate = Difference3D(plate, Union3D(
+			Transform3D(hole, Translate3d(V3{-x, 0, 0})),
+			Transform3D(hole, Translate3d(V3{x, 0, 0})),
+		))
+	case "keyholes":
+		if k.Screw.Y <= 0 || k.Screw.X <= k.Screw.Y || x <= 0 {
+			panic("invalid keyholes")
+		}
+		// half the plate under the text pocket, the lip is 40% of that
+		depth := 0.5 * (k.Thickness - k.Embed)
+		lip := 0.4 * depth
+		kh := KeyholeSlot3D(&KeyholeParms{Screw: k.Screw, Lip: lip, Depth: depth})
+		// the slots end at the middle of the plate
+		y := -k.Screw.X
+		plate = Difference3D(plate, Union3D(
+			Transform3D(kh, Translate3d(V3{-x, y, 0})),
+			Transform3D(kh, Translate3d(V3{x, y, 0})),
+		))
+	default:
+		panic("invalid mounting")
+	}
+	return plate, text
+}
+
+//-----------------------------------------------------------------------------
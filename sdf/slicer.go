@@ -0,0 +1,238 @@
+//-----------------------------------------------------------------------------
+/*
+
+Slice Stack Export
+
+Slice an SDF3 with z planes at a given layer height and write each slice as
+a file. E.g. masks for SLA/DLP resin printers, or the layers of a laminated
+construction. There is no mesh, the SDF3 is evaluated directly.
+
+png: A gray scale image per layer. The pixels are anti-aliased using the
+distance at the pixel center (white is solid). Every layer has the same size,
+the image covers the XY bounding box of the SDF3.
+
+svg: The slice contours (marching squares) as a filled path per layer. The
+path uses the even-odd fill rule so holes are cut out. The units are mm
+(1 SDF unit = 1 mm).
+
+The layers are sampled at the middle of each layer.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+)
+
+//-----------------------------------------------------------------------------
+
+type SliceParms struct {
+	LayerHeight float64   // layer height
+	Resolution  float64   // pixel size (png), contour step (svg)
+	Format      string    // file format: "png", "svg"
+	Path        string    // file path with a layer number verb (E.g. "layer_%04d.png")
+	Invert      bool      // invert the image (png: black is solid)
+	Metadata    *Metadata // model metadata (svg: a comment, nil for none)
+}
+
+// An SDF2 for a z slice of an SDF3.
+type layer_sdf2 struct {
+	sdf SDF3
+	z   float64
+	bb  Box2
+}
+
+func (s *layer_sdf2) Evaluate(p V2) float64 {
+	return s.sdf.Evaluate(V3{p.X, p.Y, s.z})
+}
+
+func (s *layer_sdf2) BoundingBox() Box2 {
+	return s.bb
+}
+
+// SliceStack writes the z slices of an SDF3 to files and returns the number of layers.
+func SliceStack(s SDF3, k *SliceParms) (int, error) {
+	if k.LayerHeight <= 0 || k.Resolution <= 0 {
+		return 0, fmt.Errorf("invalid layer height/resolution")
+	}
+	var write func(layer *layer_sdf2, path string) error
+	switch k.Format {
+	case "png":
+		write = func(layer *layer_sdf2, path string) error {
+			return save_slice_png(layer, k.Resolution, k.Invert, path)
+		}
+	case "svg":
+		write = func(layer *layer_sdf2, path string) error {
+			return save_slice_svg(layer, k.Resolution, k.Metadata, path)
+		}
+	default:
+		return 0, fmt.Errorf("unknown slice format \"%s\"", k.Format)
+	}
+	bb := s.BoundingBox()
+	n := int(math.Ceil((bb.Max.Z - bb.Min.Z) / k.LayerHeight))
+	layer := layer_sdf2{sdf: s, bb: Box2{V2{bb.Min.X, bb.Min.Y}, V2{bb.Max.X, bb.Max.Y}}}
+	for i := 0; i < n; i++ {
+		layer.z = bb.Min.Z + (float64(i)+0.5)*k.LayerHeight
+		if err := write(&layer, fmt.Sprintf(k.Path, i)); err != nil {
+			return i, err
+		}
+	}
+	return n, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// Return the anti-aliased gray scale image of a slice.
+func slice_image(s SDF2, resolution float64, invert bool) *image.Gray {
+	bb := s.BoundingBox()
+	size := bb.Size()
+	nx := int(math.Ceil(size.X / resolution))
+	ny := int(math.Ceil(size.Y / resolution))
+	img := image.NewGray(image.Rect(0, 0, nx, ny))
+	for j := 0; j < ny; j++ {
+		// the first row is at the top (+y)
+		y := bb.Max.Y - (float64(j)+0.5)*resolution
+		for i := 0; i < nx; i++ {
+			x := bb.Min.X + (float64(i)+0.5)*resolution
+			// the pixel coverage from the distance to the edge
+			k := Clamp(0.5-s.Evaluate(V2{x, y})/resolution, 0, 1)
+			if invert {
+				k = 1 - k
+			}
+			img.SetGray(i, j, color.Gray{uint8(math.Round(255 * k))})
+		}
+	}
+	return img
+}
+
+// Write a slice as a png file.
+func save_slice_png(s SDF2, resolution float64, invert bool, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return png.Encode(f, slice_image(s, resolution, invert))
+}
+
+//-----------------------------------------------------------------------------
+
+// Join line segments into polylines (closed polylines repeat the first point).
+func join_lines(lines []*Line2_PP, tolerance float64) [][]V2 {
+	key := func(p V2) [2]int64 {
+		return [2]int64{int64(math.Round(p.X / tolerance)), int64(math.Round(p.Y / tolerance))}
+	}
+	// Remove zero length and duplicate segments.
+	// These happen when the contour passes through a sample point.
+	seen := make(map[[2][2]int64]bool)
+	var unique []*Line2_PP
+	for _, l := range lines {
+		k0, k1 := key(l[0]), key(l[1])
+		if k0 == k1 {
+			continue
+		}
+		if k1[0] < k0[0] || (k1[0] == k0[0] && k1[1] < k0[1]) {
+			k0, k1 = k1, k0
+		}
+		if seen[[2][2]int64{k0, k1}] {
+			continue
+		}
+		seen[[2][2]int64{k0, k1}] = true
+		unique = append(unique, l)
+	}
+	lines = unique
+	// segments at each end point
+	ends := make(map[[2]int64][]int)
+	for i, l := range lines {
+		ends[key(l[0])] = append(ends[key(l[0])], i)
+		ends[key(l[1])] = append(ends[key(l[1])], i)
+	}
+	used := make([]bool, len(lines))
+	// return an unused segment at a point
+	next := func(p V2) int {
+		for _, i := range ends[key(p)] {
+			if !used[i] {
+				return i
+			}
+		}
+		return -1
+	}
+	var paths [][]V2
+	for i, l := range lines {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		path := []V2{l[0], l[1]}
+		// extend forwards, then backwards
+		for dir := 0; dir < 2; dir++ {
+			for {
+				p := path[len(path)-1]
+				j := next(p)
+				if j < 0 {
+					break
+				}
+				used[j] = true
+				if key(lines[j][0]) == key(p) {
+					path = append(path, lines[j][1])
+				} else {
+					path = append(path, lines[j][0])
+				}
+			}
+			// reverse the path
+			for a, b := 0, len(path)-1; a < b; a, b = a+1, b-1 {
+				path[a], path[b] = path[b], path[a]
+			}
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// Write a slice as an svg file.
+func save_slice_svg(s SDF2, resolution float64, meta *Metadata, path string) error {
+	bb := s.BoundingBox()
+	// enlarge the box so the contours are closed
+	bb = NewBox2(bb.Center(), bb.Size().AddScalar(2*resolution))
+	paths := join_lines(MarchingSquares(s, bb, resolution), 1e-6*resolution)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	size := bb.Size()
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprint(w, meta.svg_comment())
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%gmm\" height=\"%gmm\" viewBox=\"0 0 %g %g\">\n",
+		size.X, size.Y, size.X, size.Y)
+	if len(paths) != 0 {
+		fmt.Fprintf(w, "<path fill=\"black\" fill-rule=\"evenodd\" d=\"")
+		for _, p := range paths {
+			for i, v := range p {
+				// svg y is down
+				x, y := v.X-bb.Min.X, bb.Max.Y-v.Y
+				if i == 0 {
+					fmt.Fprintf(w, "M%.4f,%.4f", x, y)
+				} else {
+					fmt.Fprintf(w, "L%.4f,%.4f", x, y)
+				}
+			}
+			fmt.Fprintf(w, "Z")
+		}
+		fmt.Fprintf(w, "\"/>\n")
+	}
+	fmt.Fprintf(w, "</svg>\n")
+	return w.Flush()
+}
+
+//-----------------------------------------------------------------------------
@@ -8,8 +8,17 @@ y(t) = a + bt + ct^2 + dt^3 for t in [0,1]
 
 1st and 2nd derivatives are continuous across intervals.
 2nd derivatives == 0 at the endpoints (natural splines).
+Closed splines are periodic, the derivatives are continuous at all knots.
 See: http://mathworld.wolfram.com/CubicSpline.html
 
+Cardinal splines (and Catmull-Rom splines) are local, the 1st derivative at
+each knot is set from the neighbouring knots and a per-knot tension value.
+Only the 1st derivative is continuous across intervals.
+See: https://en.wikipedia.org/wiki/Cubic_Hermite_spline
+
+Spline functions interpolate y = f(x) through knots with increasing x. They
+are natural cubic splines in x, useful for smooth parameter distributions.
+
 */
 //-----------------------------------------------------------------------------
 
@@ -18,42 +27,45 @@ package sdf
 import (
 	"fmt"
 	"math"
+	"sort"
+	"sync"
+
+	"github.com/deadsy/sdfx/sdf/linalg"
+	"github.com/deadsy/sdfx/sdf/roots"
 )
 
 //-----------------------------------------------------------------------------
 
+// Return the diagonals of a tridiagonal matrix given as rows.
+func tridiagonal_split(m []V3) ([]float64, []float64, []float64) {
+	a := make([]float64, len(m))
+	b := make([]float64, len(m))
+	c := make([]float64, len(m))
+	for i, r := range m {
+		a[i], b[i], c[i] = r.X, r.Y, r.Z
+	}
+	return a, b, c
+}
+
 // Solve the tridiagonal matrix equation m.x = d, return x
-// See: https://en.wikipedia.org/wiki/Tridiagonal_matrix_algorithm
-func TriDiagonal(m []V3, d []float64) []float64 {
-	// Sanity checks
-	n := len(m)
-	if len(d) != n {
-		panic("bad sizes rows(m) != rows(d)")
-	}
-	if m[0].X != 0 || m[n-1].Z != 0 {
-		panic("bad values for tridiagonal matrix")
-	}
-	if m[0].Y == 0 {
-		panic("m[0].Y == 0")
-	}
-	cp := make([]float64, n) // c-prime
-	x := make([]float64, n)  // d-prime -> x solution
-	// elimination
-	cp[0] = m[0].Z / m[0].Y
-	x[0] = d[0] / m[0].Y
-	for i := 1; i < n; i++ {
-		denom := m[i].Y - m[i].X*cp[i-1]
-		if denom == 0 {
-			panic("denom == 0")
-		}
-		cp[i] = m[i].Z / denom
-		x[i] = (d[i] - m[i].X*x[i-1]) / denom
+// See: linalg.TriDiagonal
+func TriDiagonal(m []V3, d []float64) ([]float64, error) {
+	if len(m) != len(d) {
+		return nil, fmt.Errorf("bad sizes rows(m) != rows(d)")
 	}
-	// back substitution
-	for i := n - 2; i >= 0; i-- {
-		x[i] -= cp[i] * x[i+1]
+	a, b, c := tridiagonal_split(m)
+	return linalg.TriDiagonal(a, b, c, d)
+}
+
+// Solve the cyclic tridiagonal matrix equation m.x = d, return x
+// m[0].X and m[n-1].Z are the corner elements of the matrix.
+// See: linalg.TriDiagonalCyclic
+func TriDiagonalCyclic(m []V3, d []float64) ([]float64, error) {
+	if len(m) != len(d) {
+		return nil, fmt.Errorf("bad sizes rows(m) != rows(d)")
 	}
-	return x
+	a, b, c := tridiagonal_split(m)
+	return linalg.TriDiagonalCyclic(a, b, c, d)
 }
 
 //-----------------------------------------------------------------------------
@@ -103,6 +115,7 @@ type CubicSpline struct {
 	idx    int             // index within spline set
 	p0, p1 V2              // end points of cubic spline
 	px, py CubicPolynomial // cubic polynomial
+	bb     Box2            // bounding box
 }
 
 // Return the function value for a given t value.
@@ -134,32 +147,39 @@ func (s *CubicSpline) BoundingBox() Box2 {
 	return Box2{p.Min(), p.Max()}
 }
 
-const NR_TOLERANCE = 0.0001
-const NR_MAXITERS = 10
+// Return the parameter range of the spline.
+func (s *CubicSpline) domain() (float64, float64) {
+	return 0, 1
+}
 
-func (s *CubicSpline) NR_Iterate(t float64, p V2) float64 {
-	// We are minimising the distance squared function.
-	// We are looking for the zeroes of the first derivative of this function.
-	// dx = x0 - p.X
-	// dy = y0 - p.Y
-	// d0 = dx*dx + dy*dy // distance * distance
-	// d1 = 2*(dx*x1 + dy*y1)
-	// d2 = 2*(dx*x2 + x1*x1 + dy*y2 + y1*y1)
-	// tnew = t - d1 / d2
-	f0 := s.f0(t)
-	f1 := s.f1(t)
-	f2 := s.f2(t)
-	dx := f0.X - p.X
-	dy := f0.Y - p.Y
-	return t - (dx*f1.X+dy*f1.Y)/(dx*f2.X+f1.X*f1.X+dy*f2.Y+f1.Y*f1.Y)
+// Return the bounding box of the spline on [t0, t1].
+func (s *CubicSpline) bound(t0, t1 float64) Box2 {
+	x0, x1 := poly_bound([]float64{s.px.a, s.px.b, s.px.c, s.px.d}, t0, t1)
+	y0, y1 := poly_bound([]float64{s.py.a, s.py.b, s.py.c, s.py.d}, t0, t1)
+	return Box2{V2{x0, y0}, V2{x1, y1}}
+}
+
+//-----------------------------------------------------------------------------
+// Closest point on a cubic spline.
+// The distance squared function is a quintic in t, so there can be multiple
+// local minima. See CurveMinDistance2.
+
+// number of sub-intervals used to polygonize a spline
+const SPLINE_SAMPLES = 16
+
+// MinDistance2 returns the minimum distance squared between a point
+// and the spline, and the t value at which it occurs.
+func (s *CubicSpline) MinDistance2(p V2) (float64, float64) {
+	return CurveMinDistance2(s, p)
 }
 
 //-----------------------------------------------------------------------------
 
 type CubicSplineSDF2 struct {
-	spline   []CubicSpline // cubic splines
-	maxiters int           // max newton-raphson iterations
-	bb       Box2          // bounding box
+	spline []CubicSpline // cubic splines
+	closed bool          // is the spline a closed curve?
+	bb     Box2          // bounding box
+	vs     sync.Pool     // per spline bounding box distances (for Evaluate)
 }
 
 // Return the spline and t value for a given t value.
@@ -192,37 +212,14 @@ func (s *CubicSplineSDF2) F2(t float64) V2 {
 	return cs.f2(t)
 }
 
-// Return the distance squared between a point and a point on the splines curve.
-func (s *CubicSplineSDF2) D0(t float64, p V2) float64 {
-	f0 := s.F0(t)
-	dx := f0.X - p.X
-	dy := f0.Y - p.Y
-	return dx*dx + dy*dy
-}
-
-func (s *CubicSplineSDF2) D1(t float64, p V2) float64 {
-	f0 := s.F0(t)
-	f1 := s.F1(t)
-	dx := f0.X - p.X
-	dy := f0.Y - p.Y
-	return 2 * (dx*f1.X + dy*f1.Y)
-}
-
-func (s *CubicSplineSDF2) D2(t float64, p V2) float64 {
-	f0 := s.F0(t)
-	f1 := s.F1(t)
-	f2 := s.F2(t)
-	dx := f0.X - p.X
-	dy := f0.Y - p.Y
-	return 2 * (dx*f2.X + f1.X*f1.X + dy*f2.Y + f1.Y*f1.Y)
-}
-
+// CubicSpline2D returns an SDF2 for a natural cubic spline through the knots.
+// If the first and last knots are the same the spline is a closed curve
+// and the distance is -ve inside the curve.
 func CubicSpline2D(knot []V2) SDF2 {
 	if len(knot) < 2 {
 		panic("cubic splines need at least 2 knots")
 	}
 	s := CubicSplineSDF2{}
-	s.maxiters = NR_MAXITERS
 
 	// Build and solve the tridiagonal matrices
 	n := len(knot)
@@ -243,8 +240,14 @@ func CubicSpline2D(knot []V2) SDF2 {
 	dx[n-1] = 3 * (knot[n-1].X - knot[n-2].X)
 	dy[n-1] = 3 * (knot[n-1].Y - knot[n-2].Y)
 	// solve to give the first derivatives at the knot points
-	xx := TriDiagonal(m, dx)
-	xy := TriDiagonal(m, dy)
+	xx, err := TriDiagonal(m, dx)
+	if err != nil {
+		panic(err)
+	}
+	xy, err := TriDiagonal(m, dy)
+	if err != nil {
+		panic(err)
+	}
 
 	// The solution data are the first derivatives.
 	// Reformat as the cubic polynomial coefficients.
@@ -257,66 +260,229 @@ func CubicSpline2D(knot []V2) SDF2 {
 		s.spline[i].py.Set(knot[i].Y, knot[i+1].Y, xy[i], xy[i+1])
 	}
 
-	// work out the bounding box
-	s.bb = s.spline[0].BoundingBox()
-	for i := 1; i < n-1; i++ {
-		s.bb = s.bb.Extend(s.spline[i].BoundingBox())
+	s.setup(n > 3 && knot[0].Equals(knot[n-1], TOLERANCE))
+	return &s
+}
+
+// ClosedCubicSpline2D returns an SDF2 for a periodic cubic spline through the knots.
+// The spline is a closed curve with continuous 1st and 2nd derivatives at all
+// knots. The distance is -ve inside the curve.
+func ClosedCubicSpline2D(knot []V2) SDF2 {
+	// the closing knot is implicit
+	n := len(knot)
+	if n > 1 && knot[0].Equals(knot[n-1], TOLERANCE) {
+		knot = knot[:n-1]
+		n--
+	}
+	if n < 3 {
+		panic("closed cubic splines need at least 3 knots")
+	}
+	s := CubicSplineSDF2{}
+
+	// Build and solve the cyclic tridiagonal matrices
+	m := make([]V3, n)
+	dx := make([]float64, n)
+	dy := make([]float64, n)
+	for i := 0; i < n; i++ {
+		prev := knot[(i+n-1)%n]
+		next := knot[(i+1)%n]
+		m[i] = V3{1, 4, 1}
+		dx[i] = 3 * (next.X - prev.X)
+		dy[i] = 3 * (next.Y - prev.Y)
 	}
+	// solve to give the first derivatives at the knot points
+	xx, err := TriDiagonalCyclic(m, dx)
+	if err != nil {
+		panic(err)
+	}
+	xy, err := TriDiagonalCyclic(m, dy)
+	if err != nil {
+		panic(err)
+	}
+
+	// The solution data are the first derivatives.
+	// Reformat as the cubic polynomial coefficients.
+	s.spline = make([]CubicSpline, n)
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		s.spline[i].idx = i
+		s.spline[i].p0 = knot[i]
+		s.spline[i].p1 = knot[j]
+		s.spline[i].px.Set(knot[i].X, knot[j].X, xx[i], xx[j])
+		s.spline[i].py.Set(knot[i].Y, knot[j].Y, xy[i], xy[j])
+	}
+
+	s.setup(true)
 	return &s
 }
 
-func (s *CubicSplineSDF2) Evaluate(p V2) float64 {
+// CardinalSpline2D returns an SDF2 for a cardinal spline through the knots.
+// Each knot has a tension value: 0 is a Catmull-Rom spline, 1 gives a zero
+// tangent (a sharp knot), values in between tighten the curve at the knot.
+// A nil tension slice gives a Catmull-Rom spline.
+// If the first and last knots are the same the spline is a closed curve
+// and the distance is -ve inside the curve.
+func CardinalSpline2D(knot []V2, tension []float64) SDF2 {
+	n := len(knot)
+	if n < 2 {
+		panic("cardinal splines need at least 2 knots")
+	}
+	if tension == nil {
+		tension = make([]float64, n)
+	}
+	if len(tension) != n {
+		panic("cardinal splines need a tension value for each knot")
+	}
+	closed := n > 3 && knot[0].Equals(knot[n-1], TOLERANCE)
+	if closed {
+		// the closing knot is implicit
+		knot = knot[:n-1]
+		tension = tension[:n-1]
+		n--
+	}
+	s := CubicSplineSDF2{}
 
-	// initial estimate
-	n := 9 // len(s.spline)
-	cs, t := s.Find(float64(n) / 2)
-
-	var i int
-	for i = 0; i < s.maxiters; i++ {
-
-		t_old := t
-		t = cs.NR_Iterate(t, p)
-		fmt.Printf("%d t_old %f t %f\n", cs.idx, t_old, t)
-
-		if t < 0 {
-			// previous spline
-			if cs.idx == 0 {
-				// no previous splines
-				t = 0
-				break
-			}
-			// find the previous spline
-			cs, t = s.Find(float64(cs.idx) + t)
-		} else if t > 1 {
-			// next spline
-			if cs.idx == n-1 {
-				// on the last spline
-				t = 1
-				break
-			}
-			// find the next spline
-			cs, t = s.Find(float64(cs.idx) + t)
+	// work out the first derivatives at the knot points
+	d := make([]V2, n)
+	for i := 0; i < n; i++ {
+		var prev, next V2
+		k := 0.5
+		if closed {
+			prev = knot[(i+n-1)%n]
+			next = knot[(i+1)%n]
+		} else if i == 0 {
+			// one sided difference at the end points
+			prev, next, k = knot[0], knot[1], 1
+		} else if i == n-1 {
+			prev, next, k = knot[n-2], knot[n-1], 1
 		} else {
-			// on the same spline
-			if Abs(t-t_old) < NR_TOLERANCE*Abs(t) {
-				// The t estimate is within tolerance
-				break
-			}
+			prev, next = knot[i-1], knot[i+1]
 		}
+		d[i] = next.Sub(prev).MulScalar(k * (1 - tension[i]))
+	}
+
+	// the derivatives and end points give the cubic polynomial coefficients
+	m := n - 1
+	if closed {
+		m = n
+	}
+	s.spline = make([]CubicSpline, m)
+	for i := 0; i < m; i++ {
+		j := (i + 1) % n
+		s.spline[i].idx = i
+		s.spline[i].p0 = knot[i]
+		s.spline[i].p1 = knot[j]
+		s.spline[i].px.Set(knot[i].X, knot[j].X, d[i].X, d[j].X)
+		s.spline[i].py.Set(knot[i].Y, knot[j].Y, d[i].Y, d[j].Y)
 	}
-	t += float64(cs.idx)
-	dmin := math.Sqrt(s.D0(t, p))
 
-	//if i == s.maxiters {
-	//	// deliberately cause rendering problems
-	//	dmin = 0
-	//}
+	s.setup(closed)
+	return &s
+}
+
+// CatmullRomSpline2D returns an SDF2 for a Catmull-Rom spline through the knots.
+func CatmullRomSpline2D(knot []V2) SDF2 {
+	return CardinalSpline2D(knot, nil)
+}
+
+// Work out the per-spline bounding boxes.
+func (s *CubicSplineSDF2) setup(closed bool) {
+	for i := range s.spline {
+		s.spline[i].bb = s.spline[i].BoundingBox()
+	}
+	// work out the overall bounding box
+	s.bb = s.spline[0].bb
+	for i := 1; i < len(s.spline); i++ {
+		s.bb = s.bb.Extend(s.spline[i].bb)
+	}
+	// closed curves have an inside and an outside
+	s.closed = closed
+	// Evaluate is called concurrently, each call gets a buffer from the pool
+	n := len(s.spline)
+	s.vs.New = func() interface{} {
+		vs := make([]V2, n)
+		return &vs
+	}
+}
+
+// Return the winding number contribution of the spline for a horizontal ray
+// from p towards +x. Crossings are counted on [y0, y1) so a crossing at a
+// knot is counted once.
+func (s *CubicSpline) winding(p V2) int {
+	if p.Y < s.bb.Min.Y || p.Y > s.bb.Max.Y || p.X > s.bb.Max.X {
+		return 0
+	}
+	// split the spline into pieces that are monotonic in y
+	t := []float64{0}
+	for _, x := range s.py.f1_zeroes() {
+		if x > 0 && x < 1 {
+			t = append(t, x)
+		}
+	}
+	sort.Float64s(t)
+	t = append(t, 1)
+	f := func(x float64) float64 { return s.py.f0(x) - p.Y }
+	w := 0
+	for i := 0; i < len(t)-1; i++ {
+		y0, y1 := s.py.f0(t[i]), s.py.f0(t[i+1])
+		var k int
+		if y0 <= p.Y && p.Y < y1 {
+			k = 1
+		} else if y1 <= p.Y && p.Y < y0 {
+			k = -1
+		} else {
+			continue
+		}
+		x, err := roots.Brent(f, t[i], t[i+1], EPSILON)
+		if err != nil {
+			continue
+		}
+		if s.px.f0(x) > p.X {
+			w += k
+		}
+	}
+	return w
+}
 
-	fmt.Printf("p %v F0 %v t %f\n", p, s.F0(t), t)
+// Return true if the point is inside a closed spline (non-zero winding number).
+func (s *CubicSplineSDF2) inside(p V2) bool {
+	w := 0
+	for i := range s.spline {
+		w += s.spline[i].winding(p)
+	}
+	return w != 0
+}
 
-	return dmin
+// Evaluate returns the minimum distance to the cubic spline.
+func (s *CubicSplineSDF2) Evaluate(p V2) float64 {
+	// work out the min/max distance to each spline bounding box
+	buf := s.vs.Get().(*[]V2)
+	defer s.vs.Put(buf)
+	vs := *buf
+	dmax := math.MaxFloat64
+	for i := range s.spline {
+		vs[i] = s.spline[i].bb.MinMaxDist2(p)
+		// the closest spline is no further away than this
+		dmax = Min(dmax, vs[i].Y)
+	}
+	dmin := math.MaxFloat64
+	for i := range s.spline {
+		// skip splines that can't be the closest
+		if vs[i].X > dmax || vs[i].X > dmin {
+			continue
+		}
+		d, _ := s.spline[i].MinDistance2(p)
+		dmin = Min(dmin, d)
+	}
+	d := math.Sqrt(dmin)
+	if s.closed && s.inside(p) {
+		// p is inside the closed curve
+		return -d
+	}
+	return d
 }
 
+// BoundingBox returns the bounding box of the cubic spline.
 func (s *CubicSplineSDF2) BoundingBox() Box2 {
 	return s.bb
 }
@@ -342,3 +508,67 @@ func (s *CubicSplineSDF2) PolySpline2D(n int) SDF2 {
 }
 
 //-----------------------------------------------------------------------------
+// Spline Functions
+
+type SplineFunction struct {
+	x, y []float64 // knot values
+	m    []float64 // 2nd derivatives at the knots
+}
+
+// NewSplineFunction returns a natural cubic spline function y = f(x) through
+// the knots. The knot x values must be increasing.
+func NewSplineFunction(knot []V2) *SplineFunction {
+	n := len(knot)
+	if n < 2 {
+		panic("spline functions need at least 2 knots")
+	}
+	s := SplineFunction{}
+	s.x = make([]float64, n)
+	s.y = make([]float64, n)
+	for i, k := range knot {
+		if i > 0 && k.X <= knot[i-1].X {
+			panic("spline function knots must have increasing x values")
+		}
+		s.x[i] = k.X
+		s.y[i] = k.Y
+	}
+	// solve for the 2nd derivatives (0 at the end points)
+	m := make([]V3, n)
+	d := make([]float64, n)
+	m[0] = V3{0, 1, 0}
+	m[n-1] = V3{0, 1, 0}
+	for i := 1; i < n-1; i++ {
+		h0 := s.x[i] - s.x[i-1]
+		h1 := s.x[i+1] - s.x[i]
+		m[i] = V3{h0, 2 * (h0 + h1), h1}
+		d[i] = 6 * ((s.y[i+1]-s.y[i])/h1 - (s.y[i]-s.y[i-1])/h0)
+	}
+	x, err := TriDiagonal(m, d)
+	if err != nil {
+		panic(err)
+	}
+	s.m = x
+	return &s
+}
+
+// Evaluate returns the spline function value at x.
+// Outside the knot range the end values are returned.
+func (s *SplineFunction) Evaluate(x float64) float64 {
+	n := len(s.x)
+	if x <= s.x[0] {
+		return s.y[0]
+	}
+	if x >= s.x[n-1] {
+		return s.y[n-1]
+	}
+	i := 0
+	for x > s.x[i+1] {
+		i++
+	}
+	h := s.x[i+1] - s.x[i]
+	a := (s.x[i+1] - x) / h
+	b := (x - s.x[i]) / h
+	return a*s.y[i] + b*s.y[i+1] + ((a*a*a-a)*s.m[i]+(b*b*b-b)*s.m[i+1])*h*h/6
+}
+
+//-----------------------------------------------------------------------------
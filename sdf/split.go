@@ -0,0 +1,190 @@
+//-----------------------------------------------------------------------------
+/*
+
+Split with Joints
+
+Cut a solid into two parts along a plane and add alignment features so the
+parts register when they are glued back together.
+
+pin: Round pins on one part, matching sockets in the other.
+dovetail: Dovetail rails on one part, matching slots in the other. The rails
+run across the whole cut section, the parts slide together along the rail.
+
+The joints are placed automatically. Candidate positions on a grid over the
+cut section are kept if the joint has a wall of material around it (at least
+half the joint size) in both parts. The joints are picked from the candidates
+to spread them out as much as possible, starting with the deepest position.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+
+type SplitParms struct {
+	Point     V3      // point on the cut plane
+	Normal    V3      // normal of the cut plane (points to the first part)
+	Style     string  // joint style: "pin", "dovetail"
+	Size      float64 // pin diameter, dovetail width (at the cut plane)
+	Length    float64 // pin length (half in each part), dovetail depth
+	Clearance float64 // clearance between the joint and the socket/slot
+	Count     int     // maximum number of joints
+}
+
+// Return the local to world matrix for the cut plane.
+// local x, y are in the plane, local z is the plane normal.
+func split_frame(p, n V3) M44 {
+	n = n.Normalize()
+	u := V3{0, 1, 0}.Cross(n)
+	if u.Length() < 0.1 {
+		u = V3{1, 0, 0}.Cross(n)
+	}
+	u = u.Normalize()
+	v := n.Cross(u)
+	return M44{
+		u.X, v.X, n.X, p.X,
+		u.Y, v.Y, n.Y, p.Y,
+		u.Z, v.Z, n.Z, p.Z,
+		0, 0, 0, 1,
+	}
+}
+
+// Return the joint positions on the cut plane (local xy).
+func split_positions(s SDF3, m M44, k *SplitParms) []V2 {
+	// the cut section is within the local bounding box
+	bb := m.Inverse().MulBox(s.BoundingBox())
+	r := 0.5 * k.Size
+	wall := 2.0 * r
+	depth := 0.5 * k.Length
+	if k.Style == "dovetail" {
+		depth = k.Length
+	}
+	n := 16
+	type candidate struct {
+		p V2
+		d float64
+	}
+	var candidates []candidate
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			x := Mix(bb.Min.X, bb.Max.X, (float64(i)+0.5)/float64(n))
+			y := Mix(bb.Min.Y, bb.Max.Y, (float64(j)+0.5)/float64(n))
+			// the joint needs material around it on both sides of the plane
+			ok := true
+			for t := -1.0; t <= 1.0; t += 0.25 {
+				if s.Evaluate(m.MulPosition(V3{x, y, t * depth})) > -wall {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				candidates = append(candidates, candidate{V2{x, y}, s.Evaluate(m.MulPosition(V3{x, y, 0}))})
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		panic("no room for the joints")
+	}
+	// distance between joints: dovetails run along local x
+	dist := func(a, b V2) float64 {
+		if k.Style == "dovetail" {
+			return Abs(a.Y - b.Y)
+		}
+		return a.Sub(b).Length()
+	}
+	// start with the deepest position
+	best := 0
+	for i, c := range candidates {
+		if c.d < candidates[best].d {
+			best = i
+		}
+	}
+	positions := []V2{candidates[best].p}
+	for len(positions) < k.Count {
+		best, best_d := -1, 0.0
+		for i, c := range candidates {
+			d := math.Inf(1)
+			for _, p := range positions {
+				d = Min(d, dist(c.p, p))
+			}
+			if d > best_d {
+				best, best_d = i, d
+			}
+		}
+		// the joints must not overlap
+		if best < 0 || best_d < 2.0*(k.Size+k.Clearance) {
+			break
+		}
+		positions = append(positions, candidates[best].p)
+	}
+	return positions
+}
+
+// SplitWithJoints3D cuts an SDF3 along a plane and returns the two parts
+// with matching alignment joints. The first part is on the same side as the
+// normal and has the pins/rails, the second part has the sockets/slots.
+func SplitWithJoints3D(s SDF3, k *SplitParms) (SDF3, SDF3) {
+	if k.Size <= 0 || k.Length <= 0 || k.Clearance < 0 || k.Count < 1 {
+		panic("invalid joint parameters")
+	}
+	m := split_frame(k.Point, k.Normal)
+	positions := split_positions(s, m, k)
+	r := 0.5 * k.Size
+	c := k.Clearance
+
+	var joints, sockets []SDF3
+	switch k.Style {
+	case "pin":
+		// the pins overlap the first part by r
+		l := 0.5*k.Length + r
+		pin := Cylinder3D(l, r, 0.1*k.Size)
+		pin = Transform3D(pin, Translate3d(V3{0, 0, r - 0.5*l}))
+		l = 0.5*k.Length + c + r
+		socket := Cylinder3D(l, r+c, 0)
+		socket = Transform3D(socket, Translate3d(V3{0, 0, r - 0.5*l}))
+		for _, p := range positions {
+			t := m.Mul(Translate3d(V3{p.X, p.Y, 0}))
+			joints = append(joints, Transform3D(pin, t))
+			sockets = append(sockets, Transform3D(socket, t))
+		}
+	case "dovetail":
+		// rail cross section in the local yz plane, running along local x
+		d := k.Length
+		w := r + d*math.Tan(DtoR(15))
+		rail := NewPolygon()
+		rail.Add(-r, r)
+		rail.Add(-r, 0)
+		rail.Add(-w, -d)
+		rail.Add(w, -d)
+		rail.Add(r, 0)
+		rail.Add(r, r)
+		rail_2d := Polygon2D(rail.Vertices())
+		slot_2d := Offset2D(rail_2d, c)
+		// long enough to cross the section
+		l := 2.0 * s.BoundingBox().Size().Length()
+		// local (x, y, z) -> (y, z, x)
+		yz := M44{
+			0, 0, 1, 0,
+			1, 0, 0, 0,
+			0, 1, 0, 0,
+			0, 0, 0, 1,
+		}
+		for _, p := range positions {
+			t := m.Mul(Translate3d(V3{p.X, p.Y, 0})).Mul(yz)
+			// the rail stays inside the solid
+			joints = append(joints, Intersect3D(Transform3D(Extrude3D(rail_2d, l), t), s))
+			sockets = append(sockets, Transform3D(Extrude3D(slot_2d, l), t))
+		}
+	default:
+		panic("unknown joint style")
+	}
+
+	a := Union3D(append([]SDF3{Cut3D(s, k.Point, k.Normal)}, joints...)...)
+	b := Difference3D(Cut3D(s, k.Point, k.Normal.Negate()), Union3D(sockets...))
+	return a, b
+}
+
+//-----------------------------------------------------------------------------
@@ -12,6 +12,7 @@ import (
 	"bufio"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
 	"sync"
 )
@@ -19,7 +20,7 @@ import (
 //-----------------------------------------------------------------------------
 
 type STLHeader struct {
-	_     [80]uint8 // Header
+	Text  [80]uint8 // Header
 	Count uint32    // Number of triangles
 }
 
@@ -32,14 +33,24 @@ type STLTriangle struct {
 
 // SaveSTL writes a triangle mesh to an STL file.
 func SaveSTL(path string, mesh []*Triangle3) error {
+	return SaveSTLWithMetadata(path, mesh, nil)
+}
+
+// SaveSTLWithMetadata writes a triangle mesh to an STL file with the metadata in the header.
+func SaveSTLWithMetadata(path string, mesh []*Triangle3, meta *Metadata) error {
 	file, err := os.Create(path)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
+	return EncodeSTL(file, mesh, meta)
+}
 
-	buf := bufio.NewWriter(file)
+// EncodeSTL writes a triangle mesh in binary STL format with the metadata in the header.
+func EncodeSTL(w io.Writer, mesh []*Triangle3, meta *Metadata) error {
+	buf := bufio.NewWriter(w)
 	header := STLHeader{}
+	header.Text = meta.stl_header()
 	header.Count = uint32(len(mesh))
 	if err := binary.Write(buf, binary.LittleEndian, &header); err != nil {
 		return err
@@ -72,6 +83,11 @@ func SaveSTL(path string, mesh []*Triangle3) error {
 
 // WriteSTL writes a stream of triangles to an STL file.
 func WriteSTL(wg *sync.WaitGroup, path string) (chan<- *Triangle3, error) {
+	return WriteSTLWithMetadata(wg, path, nil)
+}
+
+// WriteSTLWithMetadata writes a stream of triangles to an STL file with the metadata in the header.
+func WriteSTLWithMetadata(wg *sync.WaitGroup, path string, meta *Metadata) (chan<- *Triangle3, error) {
 
 	f, err := os.Create(path)
 	if err != nil {
@@ -82,8 +98,9 @@ func WriteSTL(wg *sync.WaitGroup, path string) (chan<- *Triangle3, error) {
 	// The default buffer size doesn't appear to limit performance.
 	buf := bufio.NewWriter(f)
 
-	// write an empty header
+	// write the header (the count is filled in later)
 	hdr := STLHeader{}
+	hdr.Text = meta.stl_header()
 	if err := binary.Write(buf, binary.LittleEndian, &hdr); err != nil {
 		return nil, err
 	}
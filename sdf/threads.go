@@ -0,0 +1,280 @@
+//-----------------------------------------------------------------------------
+/*
+
+Threads
+
+Generate internal and external ISO metric and Unified (UNC/UNF) threads from
+standard designations. E.g. "M6x1-6H", "M8-6g", "1/4-20 UNC-2A"
+
+The tolerance class sets the radial offset of the thread from the basic
+profile. The offset is the middle of the pitch diameter tolerance zone:
+
+ISO 965-1: The tolerance position letter gives the fundamental deviation
+(H/h = 0, G/g = 15 + 11P um, f = 30 + 11P um, e = 50 + 11P um) and the grade
+number scales the pitch diameter tolerance.
+
+ASME B1.1: Classes 1A/2A have an allowance, 3A and the B (internal) classes
+don't. The pitch diameter tolerance depends on the class.
+
+Threads start at z = 0 and run up to z = length. The free end of the thread
+(at z = length) has a lead-in chamfer. The other end has a runout where the
+thread depth fades out (E.g. under the head of a bolt, or at the bottom of a
+blind hole).
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//-----------------------------------------------------------------------------
+
+type ThreadSpec struct {
+	Thread   *ThreadParameters // basic thread
+	Internal bool              // internal thread (nut, tapped hole)
+	Class    string            // tolerance class (E.g. "6H", "6g", "2A")
+	Offset   float64           // radial offset from the basic profile (+ve is larger)
+}
+
+var iso_thread_re = regexp.MustCompile(`^M(\d+(?:\.\d+)?)(?:x(\d+(?:\.\d+)?))?(?:-(\w+))?$`)
+var uts_thread_re = regexp.MustCompile(`^(\d+/\d+|\d*\.\d+|\d+)-(\d+)\s*(UNC|UNF|UNEF)(?:-(\d[AB]))?$`)
+
+// ISO 261 coarse pitches (diameter -> pitch, mm)
+var iso_coarse = map[float64]float64{
+	1: 0.25, 1.1: 0.25, 1.2: 0.25, 1.4: 0.3, 1.6: 0.35, 1.8: 0.35,
+	2: 0.4, 2.2: 0.45, 2.5: 0.45, 3: 0.5, 3.5: 0.6, 4: 0.7, 4.5: 0.75,
+	5: 0.8, 6: 1, 7: 1, 8: 1.25, 10: 1.5, 12: 1.75, 14: 2, 16: 2,
+	18: 2.5, 20: 2.5, 22: 2.5, 24: 3, 27: 3, 30: 3.5, 33: 3.5, 36: 4,
+	39: 4, 42: 4.5, 45: 4.5, 48: 5, 52: 5, 56: 5.5, 60: 5.5, 64: 6,
+}
+
+// Return the coarse pitch for an ISO thread diameter.
+// Some sizes (E.g. M25, M32) only have fine pitches.
+func iso_coarse_pitch(d float64) (float64, error) {
+	pitch, ok := iso_coarse[d]
+	if !ok {
+		return 0, fmt.Errorf("no coarse pitch for M%g", d)
+	}
+	return pitch, nil
+}
+
+// Return the thread parameters, using the thread database when possible.
+func thread_parameters(name string, diameter, pitch float64, units string) *ThreadParameters {
+	if t, ok := thread_db[name]; ok {
+		return t
+	}
+	return &ThreadParameters{name, diameter / 2.0, pitch, -1, units}
+}
+
+// Return the ISO 965-1 radial offset for a tolerance class (mm).
+func iso_offset(class string, d, pitch float64) (float64, error) {
+	if len(class) < 2 {
+		return 0, fmt.Errorf("bad tolerance class \"%s\"", class)
+	}
+	// use the pitch diameter tolerance (E.g. 5g6g -> 5g)
+	grade := map[byte]float64{'3': 0.5, '4': 0.63, '5': 0.8, '6': 1, '7': 1.25, '8': 1.6, '9': 2}
+	k, ok := grade[class[0]]
+	if !ok {
+		return 0, fmt.Errorf("bad tolerance grade \"%s\"", class)
+	}
+	// pitch diameter tolerance for grade 6 external threads (um)
+	td2 := k * 90.0 * math.Pow(pitch, 0.4) * math.Pow(d, 0.1)
+	// fundamental deviation (um)
+	var ei float64
+	switch class[1] {
+	case 'H', 'h':
+		ei = 0
+	case 'G', 'g':
+		ei = 15 + 11*pitch
+	case 'f':
+		ei = 30 + 11*pitch
+	case 'e':
+		ei = 50 + 11*pitch
+	default:
+		return 0, fmt.Errorf("bad tolerance position \"%s\"", class)
+	}
+	if class[1] >= 'A' && class[1] <= 'Z' {
+		// internal: the zone is above the basic size
+		return 0.5 * (ei + 0.5*1.32*td2) / 1000.0, nil
+	}
+	// external: the zone is below the basic size
+	return -0.5 * (ei + 0.5*td2) / 1000.0, nil
+}
+
+// Return the ASME B1.1 radial offset for a tolerance class (inch).
+func uts_offset(class string, d, pitch float64) (float64, error) {
+	// pitch diameter tolerance for class 2A
+	le := 9.0 * pitch
+	td2 := 0.0015*math.Cbrt(d) + 0.0015*math.Sqrt(le) + 0.015*math.Pow(pitch, 2.0/3.0)
+	allowance := 0.3 * td2
+	switch class {
+	case "1A":
+		return -0.5 * (allowance + 0.5*1.5*td2), nil
+	case "2A":
+		return -0.5 * (allowance + 0.5*td2), nil
+	case "3A":
+		return -0.5 * (0.5 * 0.75 * td2), nil
+	case "1B":
+		return 0.5 * (0.5 * 1.3 * 1.5 * td2), nil
+	case "2B":
+		return 0.5 * (0.5 * 1.3 * td2), nil
+	case "3B":
+		return 0.5 * (0.5 * 1.3 * 0.75 * td2), nil
+	}
+	return 0, fmt.Errorf("bad tolerance class \"%s\"", class)
+}
+
+// ParseThread returns the thread specification for a thread designation.
+// ISO metric: "M6x1-6H", "M6-6g" (coarse pitch), "M6x0.75" (no class)
+// Unified: "1/4-20 UNC-2A", "0.25-28 UNF-2B"
+// Without a class the thread is external with the basic profile.
+func ParseThread(designation string) (*ThreadSpec, error) {
+	s := strings.TrimSpace(designation)
+	if m := iso_thread_re.FindStringSubmatch(s); m != nil {
+		d, _ := strconv.ParseFloat(m[1], 64)
+		var pitch float64
+		var err error
+		if m[2] == "" {
+			pitch, err = iso_coarse_pitch(d)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			pitch, _ = strconv.ParseFloat(m[2], 64)
+		}
+		if d <= 0 || pitch <= 0 {
+			return nil, fmt.Errorf("bad thread designation \"%s\"", designation)
+		}
+		spec := ThreadSpec{}
+		spec.Thread = thread_parameters(fmt.Sprintf("M%gx%g", d, pitch), d, pitch, "mm")
+		spec.Class = m[3]
+		if spec.Class != "" {
+			spec.Offset, err = iso_offset(spec.Class, d, pitch)
+			if err != nil {
+				return nil, err
+			}
+			spec.Internal = spec.Class[1] >= 'A' && spec.Class[1] <= 'Z'
+		}
+		return &spec, nil
+	}
+	if m := uts_thread_re.FindStringSubmatch(s); m != nil {
+		var d float64
+		if i := strings.Index(m[1], "/"); i >= 0 {
+			n, _ := strconv.ParseFloat(m[1][:i], 64)
+			q, _ := strconv.ParseFloat(m[1][i+1:], 64)
+			if q == 0 {
+				return nil, fmt.Errorf("bad thread designation \"%s\"", designation)
+			}
+			d = n / q
+		} else {
+			d, _ = strconv.ParseFloat(m[1], 64)
+		}
+		tpi, _ := strconv.ParseFloat(m[2], 64)
+		if d <= 0 || tpi <= 0 {
+			return nil, fmt.Errorf("bad thread designation \"%s\"", designation)
+		}
+		pitch := 1.0 / tpi
+		spec := ThreadSpec{}
+		name := fmt.Sprintf("%s_%s", strings.ToLower(m[3]), m[1])
+		spec.Thread = thread_parameters(name, d, pitch, "inch")
+		if spec.Thread.Pitch != pitch {
+			// the name is in the database with a different pitch
+			spec.Thread = &ThreadParameters{name, d / 2.0, pitch, -1, "inch"}
+		}
+		spec.Class = m[4]
+		if spec.Class != "" {
+			var err error
+			spec.Offset, err = uts_offset(spec.Class, d, pitch)
+			if err != nil {
+				return nil, err
+			}
+			spec.Internal = spec.Class[1] == 'B'
+		}
+		return &spec, nil
+	}
+	return nil, fmt.Errorf("bad thread designation \"%s\"", designation)
+}
+
+//-----------------------------------------------------------------------------
+
+type ThreadParms struct {
+	Length float64 // length of the thread
+	LeadIn float64 // size of the lead-in chamfer at z = length (0 for none)
+	Runout float64 // length of the thread runout at z = 0 (0 for none)
+}
+
+// Thread3D returns an SDF3 for a thread with tolerance offsets, lead-in and runout.
+// External threads are the threaded rod. Internal threads are a cutter for
+// the threaded hole, it extends above z = length to give a clean cut.
+func Thread3D(spec *ThreadSpec, k *ThreadParms) SDF3 {
+	if k.Length <= 0 {
+		panic("invalid thread length")
+	}
+	if k.LeadIn < 0 || k.Runout < 0 || k.LeadIn+k.Runout > k.Length {
+		panic("invalid thread lead-in/runout")
+	}
+	t := spec.Thread
+	r := t.Radius + spec.Offset
+	p := t.Pitch
+	// basic thread depth (external)
+	h := 0.625 * p / (2.0 * math.Tan(DtoR(30)))
+	r_minor := r - h
+
+	if !spec.Internal {
+		screw := Screw3D(ISOThread(r, p, "external"), k.Length, p, 1)
+		screw = Transform3D(screw, Translate3d(V3{0, 0, 0.5 * k.Length}))
+		// trim to length with the lead-in chamfer
+		envelope := NewPolygon()
+		envelope.Add(0, 0)
+		envelope.Add(r+p, 0)
+		// 45 degree chamfer
+		z := Max(k.Length-k.LeadIn-p, 0)
+		envelope.Add(r+p, z)
+		envelope.Add(r+p-(k.Length-z), k.Length)
+		envelope.Add(0, k.Length)
+		s := Intersect3D(screw, Revolve3D(Polygon2D(envelope.Vertices())))
+		if k.Runout > 0 {
+			// fill the thread to the major radius
+			fill := NewPolygon()
+			fill.Add(0, 0)
+			fill.Add(r, 0)
+			fill.Add(r_minor, k.Runout)
+			fill.Add(0, k.Runout)
+			s = Union3D(s, Revolve3D(Polygon2D(fill.Vertices())))
+		}
+		return s
+	}
+
+	// internal thread: extend past both ends, the envelope trims it
+	l := k.Length + 4.0*p
+	hole := Screw3D(ISOThread(r, p, "internal"), l, p, 1)
+	hole = Transform3D(hole, Translate3d(V3{0, 0, 0.5*k.Length + p}))
+	envelope := NewPolygon()
+	envelope.Add(0, 0)
+	if k.Runout > 0 {
+		// the thread depth fades out at the bottom
+		envelope.Add(r_minor, 0)
+		envelope.Add(r+p, k.Runout*(r+p-r_minor)/(r-r_minor))
+	} else {
+		envelope.Add(r+p, 0)
+	}
+	envelope.Add(r+p, k.Length+2.0*p)
+	envelope.Add(0, k.Length+2.0*p)
+	s := Intersect3D(hole, Revolve3D(Polygon2D(envelope.Vertices())))
+	if k.LeadIn > 0 {
+		// countersink at the entry
+		c := k.LeadIn
+		cone := Cone3D(2.0*c, r, r+2.0*c, 0)
+		s = Union3D(s, Transform3D(cone, Translate3d(V3{0, 0, k.Length})))
+	}
+	return s
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,223 @@
+//-----------------------------------------------------------------------------
+/*
+
+3MF Save
+
+A 3MF file is a zip archive of XML parts. The model part (3D/3dmodel.model)
+has a mesh object for each body: a vertex list and a triangle list that
+indexes the vertices. The vertices of the triangles are welded (vertices with
+the same float32 coordinates are shared), units are millimeters.
+
+The metadata goes in the model part:
+
+	Application  - "sdfx" and the version
+	CreationDate - the generation time
+	Description  - the metadata summary line
+	sdfx:config  - the configuration (JSON)
+
+Bodies that are printed together (E.g. the colors of a two color print) can
+be saved as the objects of one file, so they keep their alignment.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+//-----------------------------------------------------------------------------
+
+const tmf_content_types = `<?xml version="1.0" encoding="UTF-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="model" ContentType="application/vnd.ms-package.3dmanufacturing-3dmodel+xml"/>
+</Types>
+`
+
+const tmf_rels = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Target="/3D/3dmodel.model" Id="rel0" Type="http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel"/>
+</Relationships>
+`
+
+const TMF_MODEL = "3D/3dmodel.model"
+
+// Object3MF is a named body of a 3MF file.
+type Object3MF struct {
+	Name string
+	Mesh []*Triangle3
+}
+
+//-----------------------------------------------------------------------------
+
+// Save3MF writes a triangle mesh to a 3MF file with the metadata in the model.
+func Save3MF(path string, mesh []*Triangle3, meta *Metadata) error {
+	return Save3MFObjects(path, []*Object3MF{{Mesh: mesh}}, meta)
+}
+
+// Save3MFObjects writes a set of bodies to a 3MF file.
+func Save3MFObjects(path string, objects []*Object3MF, meta *Metadata) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Encode3MF(f, objects, meta); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Encode3MF writes a set of bodies in 3MF format.
+func Encode3MF(w io.Writer, objects []*Object3MF, meta *Metadata) error {
+	z := zip.NewWriter(w)
+	parts := []struct {
+		name string
+		data string
+	}{
+		{"[Content_Types].xml", tmf_content_types},
+		{"_rels/.rels", tmf_rels},
+	}
+	for _, p := range parts {
+		f, err := z.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, p.data); err != nil {
+			return err
+		}
+	}
+	f, err := z.Create(TMF_MODEL)
+	if err != nil {
+		return err
+	}
+	buf := bufio.NewWriter(f)
+	if err := encode_3mf_model(buf, objects, meta); err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+// Write the model part of a 3MF file.
+func encode_3mf_model(w *bufio.Writer, objects []*Object3MF, meta *Metadata) error {
+	w.WriteString(xml.Header)
+	w.WriteString(`<model unit="millimeter" xml:lang="en-US" xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02" xmlns:sdfx="https://github.com/deadsy/sdfx">` + "\n")
+	if meta != nil {
+		for _, m := range [][2]string{
+			{"Application", "sdfx " + meta.Version},
+			{"CreationDate", meta.Time.Format(time.RFC3339)},
+			{"Description", meta.Summary()},
+			{"sdfx:config", meta.Config},
+		} {
+			fmt.Fprintf(w, "<metadata name=\"%s\">", m[0])
+			if err := xml.EscapeText(w, []byte(m[1])); err != nil {
+				return err
+			}
+			w.WriteString("</metadata>\n")
+		}
+	}
+	w.WriteString("<resources>\n")
+	for i, o := range objects {
+		fmt.Fprintf(w, "<object id=\"%d\" type=\"model\"", i+1)
+		if o.Name != "" {
+			w.WriteString(" name=\"")
+			if err := xml.EscapeText(w, []byte(o.Name)); err != nil {
+				return err
+			}
+			w.WriteString("\"")
+		}
+		w.WriteString(">\n<mesh>\n<vertices>\n")
+		// weld the vertices
+		index := make(map[[3]float32]int)
+		var triangles [][3]int
+		for _, t := range o.Mesh {
+			var idx [3]int
+			for j, v := range t.V {
+				k := [3]float32{float32(v.X), float32(v.Y), float32(v.Z)}
+				n, ok := index[k]
+				if !ok {
+					n = len(index)
+					index[k] = n
+					fmt.Fprintf(w, "<vertex x=\"%g\" y=\"%g\" z=\"%g\"/>\n", k[0], k[1], k[2])
+				}
+				idx[j] = n
+			}
+			if idx[0] == idx[1] || idx[1] == idx[2] || idx[2] == idx[0] {
+				// degenerate triangle
+				continue
+			}
+			triangles = append(triangles, idx)
+		}
+		w.WriteString("</vertices>\n<triangles>\n")
+		for _, t := range triangles {
+			fmt.Fprintf(w, "<triangle v1=\"%d\" v2=\"%d\" v3=\"%d\"/>\n", t[0], t[1], t[2])
+		}
+		w.WriteString("</triangles>\n</mesh>\n</object>\n")
+	}
+	w.WriteString("</resources>\n<build>\n")
+	for i := range objects {
+		fmt.Fprintf(w, "<item objectid=\"%d\"/>\n", i+1)
+	}
+	_, err := w.WriteString("</build>\n</model>\n")
+	return err
+}
+
+//-----------------------------------------------------------------------------
+
+// Read3MFMetadata returns the metadata of a 3MF file (name -> value).
+func Read3MFMetadata(path string) (map[string]string, error) {
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+	f, err := z.Open(TMF_MODEL)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	meta := make(map[string]string)
+	d := xml.NewDecoder(f)
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			return meta, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		e, ok := t.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if e.Name.Local == "resources" {
+			// the metadata comes before the resources
+			return meta, nil
+		}
+		if e.Name.Local != "metadata" {
+			continue
+		}
+		var v string
+		if err := d.DecodeElement(&v, &e); err != nil {
+			return nil, err
+		}
+		for _, a := range e.Attr {
+			if a.Name.Local == "name" {
+				meta[a.Value] = v
+			}
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,203 @@
+//-----------------------------------------------------------------------------
+/*
+
+Top Surface Analysis
+
+Find the near-horizontal top faces of a solid and return each face as a 2D
+region with its height. The regions can be extruded into modifier meshes for
+a slicer, E.g. to force ironing on a flat top or to restrict fuzzy skin.
+
+The SDF3 is sampled on a grid (as for the printability checks). Surface
+samples with a normal that is tilted less than the maximum angle from
+straight up are flagged and grouped into connected faces. Faces with an area below the
+minimum are dropped (E.g. the flat top of a small boss). A face includes the
+parts covered by overhangs above it, and steps smaller than the resolution
+don't separate faces.
+
+The region of a face is an SDF2 built from the grid cells of the face, it's
+accurate to about the resolution. Extrude it at the face height to make a
+modifier mesh:
+
+	for _, f := range TopSurfaces(s, &k) {
+		m := Extrude3D(f.Region, 1)
+		m = Transform3D(m, Translate3d(V3{0, 0, f.Z}))
+		...
+	}
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+
+// width of the distance band around a region (cells)
+const REGION_BAND = 3
+
+type TopSurfaceParms struct {
+	MaxAngle   float64 // maximum tilt of the face from horizontal (degrees)
+	MinArea    float64 // minimum face area (projected onto the xy plane)
+	Resolution float64 // grid sampling resolution
+}
+
+type TopSurface struct {
+	Region SDF2    // the face projected onto the xy plane
+	Z      float64 // mean height of the face
+	Area   float64 // projected area of the face
+	Angle  float64 // maximum tilt of the face from horizontal (degrees)
+}
+
+//-----------------------------------------------------------------------------
+
+// RegionSDF2 is a 2D region sampled on a grid of square cells.
+type RegionSDF2 struct {
+	base V2        // position of sample 0,0
+	res  float64   // resolution
+	n    V2i       // grid size
+	d    []float64 // distance at the cell centers
+	bb   Box2
+}
+
+// Return the region covered by a set of cells. The cell i,j is centered at
+// base + (i,j) * res.
+func new_region_sdf2(cells []V2i, base V2, res float64) *RegionSDF2 {
+	// the cell extents
+	x0, y0, x1, y1 := cells[0][0], cells[0][1], cells[0][0], cells[0][1]
+	for _, c := range cells {
+		if c[0] < x0 {
+			x0 = c[0]
+		}
+		if c[0] > x1 {
+			x1 = c[0]
+		}
+		if c[1] < y0 {
+			y0 = c[1]
+		}
+		if c[1] > y1 {
+			y1 = c[1]
+		}
+	}
+	h := V2{0.5 * res, 0.5 * res}
+	s := RegionSDF2{res: res}
+	s.bb = Box2{
+		base.Add(V2{float64(x0), float64(y0)}.MulScalar(res)).Sub(h),
+		base.Add(V2{float64(x1), float64(y1)}.MulScalar(res)).Add(h),
+	}
+	// add a band of empty cells
+	x0, y0 = x0-REGION_BAND, y0-REGION_BAND
+	s.n = V2i{x1 - x0 + 1 + REGION_BAND, y1 - y0 + 1 + REGION_BAND}
+	s.base = base.Add(V2{float64(x0), float64(y0)}.MulScalar(res))
+	filled := make([]bool, s.n[0]*s.n[1])
+	for _, c := range cells {
+		filled[c[0]-x0+s.n[0]*(c[1]-y0)] = true
+	}
+	// distance to the nearest cell of the other state (within the band)
+	s.d = make([]float64, len(filled))
+	for j := 0; j < s.n[1]; j++ {
+		for i := 0; i < s.n[0]; i++ {
+			k := i + s.n[0]*j
+			d := REGION_BAND * res
+			for y := j - REGION_BAND; y <= j+REGION_BAND; y++ {
+				for x := i - REGION_BAND; x <= i+REGION_BAND; x++ {
+					if x < 0 || y < 0 || x >= s.n[0] || y >= s.n[1] || filled[x+s.n[0]*y] == filled[k] {
+						continue
+					}
+					// distance to the cell square
+					p := V2{float64(x - i), float64(y - j)}.MulScalar(res).Abs().Sub(h)
+					d = Min(d, V2{Max(p.X, 0), Max(p.Y, 0)}.Length())
+				}
+			}
+			if filled[k] {
+				d = -d
+			}
+			s.d[k] = d
+		}
+	}
+	return &s
+}
+
+// Evaluate returns the minimum distance to the region.
+func (s *RegionSDF2) Evaluate(p V2) float64 {
+	// grid coordinates
+	u := p.Sub(s.base).DivScalar(s.res)
+	umax := s.n.ToV2().SubScalar(1)
+	if u.X < 0 || u.Y < 0 || u.X > umax.X || u.Y > umax.Y {
+		// outside the grid, the grid edge is outside the band
+		q := V2{u.X - Clamp(u.X, 0, umax.X), u.Y - Clamp(u.Y, 0, umax.Y)}
+		return q.MulScalar(s.res).Length() + REGION_BAND*s.res
+	}
+	i := int(Min(math.Floor(u.X), umax.X-1))
+	j := int(Min(math.Floor(u.Y), umax.Y-1))
+	fx, fy := u.X-float64(i), u.Y-float64(j)
+	k := i + s.n[0]*j
+	d0 := s.d[k]*(1-fx) + s.d[k+1]*fx
+	d1 := s.d[k+s.n[0]]*(1-fx) + s.d[k+s.n[0]+1]*fx
+	return d0*(1-fy) + d1*fy
+}
+
+// BoundingBox returns the bounding box of the region.
+func (s *RegionSDF2) BoundingBox() Box2 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+
+// TopSurfaces returns the near-horizontal top faces of an SDF3.
+func TopSurfaces(s SDF3, k *TopSurfaceParms) []*TopSurface {
+	if k.Resolution <= 0 || k.MaxAngle < 0 || k.MaxAngle >= 90 || k.MinArea < 0 {
+		panic("invalid top surface parameters")
+	}
+	g := new_print_grid(s, k.Resolution)
+	limit := math.Cos(DtoR(k.MaxAngle))
+	flag := make([]bool, len(g.d))
+	height := make(map[int]float64)
+	angle := make(map[int]float64)
+	for i, d := range g.d {
+		// samples near the surface
+		if Abs(d) > 0.5*g.res {
+			continue
+		}
+		p := g.position(i)
+		n := g.gradient(p)
+		if n.Length() < 0.5 {
+			// not a real surface
+			continue
+		}
+		n = n.Normalize()
+		if n.Z >= limit {
+			flag[i] = true
+			// the closest surface point
+			height[i] = p.Z - n.Z*d
+			angle[i] = RtoD(math.Acos(Clamp(n.Z, -1, 1)))
+		}
+	}
+	var faces []*TopSurface
+	for _, r := range g.regions(flag) {
+		// the xy cells of the face
+		columns := make(map[V2i]bool)
+		var cells []V2i
+		f := TopSurface{}
+		for _, i := range r {
+			c := g.coord(i)
+			xy := V2i{c[0], c[1]}
+			if !columns[xy] {
+				columns[xy] = true
+				cells = append(cells, xy)
+			}
+			f.Z += height[i]
+			f.Angle = Max(f.Angle, angle[i])
+		}
+		f.Z /= float64(len(r))
+		f.Area = float64(len(cells)) * g.res * g.res
+		if f.Area < k.MinArea {
+			continue
+		}
+		f.Region = new_region_sdf2(cells, V2{g.base.X, g.base.Y}, g.res)
+		faces = append(faces, &f)
+	}
+	return faces
+}
+
+//-----------------------------------------------------------------------------
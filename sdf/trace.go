@@ -0,0 +1,104 @@
+//-----------------------------------------------------------------------------
+/*
+
+Evaluation Tracing
+
+Wrap an SDF so a user supplied function is called on each evaluation.
+This is a diagnostic aid. It keeps debug output out of the evaluation
+hot path of the SDFs themselves.
+
+Note: The renderers evaluate in parallel, so a trace function may be
+called concurrently.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "fmt"
+
+//-----------------------------------------------------------------------------
+
+// TraceFunc2 is called with the point and distance of an SDF2 evaluation.
+type TraceFunc2 func(p V2, d float64)
+
+// TraceFunc3 is called with the point and distance of an SDF3 evaluation.
+type TraceFunc3 func(p V3, d float64)
+
+// PrintTrace2 returns a trace function that prints each SDF2 evaluation.
+func PrintTrace2(name string) TraceFunc2 {
+	return func(p V2, d float64) {
+		fmt.Printf("%s p %v d %f\n", name, p, d)
+	}
+}
+
+// PrintTrace3 returns a trace function that prints each SDF3 evaluation.
+func PrintTrace3(name string) TraceFunc3 {
+	return func(p V3, d float64) {
+		fmt.Printf("%s p %v d %f\n", name, p, d)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// TraceSDF2 calls a trace function for each evaluation of an SDF2.
+type TraceSDF2 struct {
+	sdf   SDF2
+	trace TraceFunc2
+}
+
+// Trace2D returns an SDF2 that calls trace for each evaluation of sdf.
+func Trace2D(sdf SDF2, trace TraceFunc2) SDF2 {
+	if sdf == nil || trace == nil {
+		return sdf
+	}
+	return &TraceSDF2{
+		sdf:   sdf,
+		trace: trace,
+	}
+}
+
+// Evaluate returns the minimum distance to the traced SDF2.
+func (s *TraceSDF2) Evaluate(p V2) float64 {
+	d := s.sdf.Evaluate(p)
+	s.trace(p, d)
+	return d
+}
+
+// BoundingBox returns the bounding box of the traced SDF2.
+func (s *TraceSDF2) BoundingBox() Box2 {
+	return s.sdf.BoundingBox()
+}
+
+//-----------------------------------------------------------------------------
+
+// TraceSDF3 calls a trace function for each evaluation of an SDF3.
+type TraceSDF3 struct {
+	sdf   SDF3
+	trace TraceFunc3
+}
+
+// Trace3D returns an SDF3 that calls trace for each evaluation of sdf.
+func Trace3D(sdf SDF3, trace TraceFunc3) SDF3 {
+	if sdf == nil || trace == nil {
+		return sdf
+	}
+	return &TraceSDF3{
+		sdf:   sdf,
+		trace: trace,
+	}
+}
+
+// Evaluate returns the minimum distance to the traced SDF3.
+func (s *TraceSDF3) Evaluate(p V3) float64 {
+	d := s.sdf.Evaluate(p)
+	s.trace(p, d)
+	return d
+}
+
+// BoundingBox returns the bounding box of the traced SDF3.
+func (s *TraceSDF3) BoundingBox() Box3 {
+	return s.sdf.BoundingBox()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,111 @@
+//-----------------------------------------------------------------------------
+/*
+
+Sorting Trays
+
+A tray with a grid of compartments for sorting small parts. The compartments
+are tapered so the parts slide down to one corner, each compartment funnels
+to the corner nearest the outside corner of the tray. The rims of the
+compartments are chamfered.
+
+Trays stack: there is a lip around the top of the tray and the bottom is
+stepped in to fit inside the lip of the tray below.
+
+The tray sits on the z = 0 plane.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+
+type TrayParms struct {
+	Size      V2      // outside size of the tray
+	Height    float64 // height of the tray (not including the stacking lip)
+	Grid      V2i     // number of compartments in x and y
+	Wall      float64 // wall thickness
+	Floor     float64 // floor thickness (at the bottom of the compartments)
+	Taper     float64 // compartment size at the floor as a fraction of the top size (0..1]
+	Chamfer   float64 // chamfer on the compartment rims
+	Stack     float64 // height of the stacking lip (0 for none)
+	Clearance float64 // clearance between stacked trays
+}
+
+// Return a compartment cutter that funnels to a corner.
+// size is the top size, the funnel corner (direction d) is at the origin.
+func tray_pocket(size V2, height, taper float64, d V2) SDF3 {
+	// the bottom profile has the funnel corner at the origin
+	b := size.MulScalar(taper)
+	profile := Transform2D(Box2D(b, 0), Translate2d(b.MulScalar(0.5).Mul(d.Neg())))
+	s := ScaleExtrude3D(profile, height, V2{1.0 / taper, 1.0 / taper})
+	s = Transform3D(s, Translate3d(V3{0, 0, 0.5 * height}))
+	// extend the top of the pocket
+	top := Extrude3D(Transform2D(Box2D(size, 0), Translate2d(size.MulScalar(0.5).Mul(d.Neg()))), 2.0*height)
+	top = Transform3D(top, Translate3d(V3{0, 0, 2.0 * height}))
+	return Union3D(s, top)
+}
+
+// SortingTray3D returns a stackable sorting tray with funnelled compartments.
+func SortingTray3D(k *TrayParms) SDF3 {
+	if k.Grid[0] < 1 || k.Grid[1] < 1 {
+		panic("invalid compartment grid")
+	}
+	if k.Height <= 0 || k.Wall <= 0 || k.Floor <= 0 || k.Floor >= k.Height {
+		panic("invalid tray parameters")
+	}
+	if k.Taper <= 0 || k.Taper > 1 || k.Chamfer < 0 || k.Chamfer >= 0.5*k.Wall {
+		panic("invalid compartment taper or chamfer")
+	}
+	if k.Stack < 0 || k.Stack >= k.Floor || k.Clearance < 0 || k.Clearance >= 0.5*k.Wall {
+		panic("invalid stacking lip")
+	}
+	n := V2{float64(k.Grid[0]), float64(k.Grid[1])}
+	// compartment size
+	c := k.Size.SubScalar(2.0 * k.Wall).Sub(n.SubScalar(1).MulScalar(k.Wall)).Div(n)
+	if c.X <= 0 || c.Y <= 0 {
+		panic("tray is too small for the compartments")
+	}
+
+	body := Box3D(V3{k.Size.X, k.Size.Y, k.Height}, 0)
+	body = Transform3D(body, Translate3d(V3{0, 0, 0.5 * k.Height}))
+
+	// compartments
+	h := k.Height - k.Floor
+	var pockets []SDF3
+	for j := 0; j < k.Grid[1]; j++ {
+		for i := 0; i < k.Grid[0]; i++ {
+			// compartment center
+			x := -0.5*k.Size.X + k.Wall + (float64(i)+0.5)*c.X + float64(i)*k.Wall
+			y := -0.5*k.Size.Y + k.Wall + (float64(j)+0.5)*c.Y + float64(j)*k.Wall
+			// funnel to the corner nearest the tray corner
+			d := V2{math.Copysign(1, x), math.Copysign(1, y)}
+			corner := V2{x, y}.Add(c.MulScalar(0.5).Mul(d))
+			pocket := tray_pocket(c, h, k.Taper, d)
+			pockets = append(pockets, Transform3D(pocket, Translate3d(V3{corner.X, corner.Y, k.Floor})))
+		}
+	}
+	var s SDF3
+	if k.Chamfer > 0 {
+		s = ChamferDifference3D(k.Chamfer, 0.25*PI, body, Union3D(pockets...))
+	} else {
+		s = Difference3D(body, Union3D(pockets...))
+	}
+
+	if k.Stack > 0 {
+		// lip around the top: half the outside wall
+		inner := k.Size.SubScalar(k.Wall)
+		lip := Difference3D(Box3D(V3{k.Size.X, k.Size.Y, k.Stack}, 0), Box3D(V3{inner.X, inner.Y, 2.0 * k.Stack}, 0))
+		lip = Transform3D(lip, Translate3d(V3{0, 0, k.Height + 0.5*k.Stack}))
+		s = Union3D(s, lip)
+		// step in the bottom to fit inside the lip
+		inset := inner.SubScalar(2.0 * k.Clearance)
+		step := Difference3D(Box3D(V3{2.0 * k.Size.X, 2.0 * k.Size.Y, 2.0 * k.Stack}, 0), Box3D(V3{inset.X, inset.Y, 4.0 * k.Stack}, 0))
+		s = Difference3D(s, step)
+	}
+	return s
+}
+
+//-----------------------------------------------------------------------------
@@ -38,3 +38,62 @@ func (t *Triangle3) Normal() V3 {
 }
 
 //-----------------------------------------------------------------------------
+
+// Return the closest point on the triangle to a point.
+// See: Ericson, Real-Time Collision Detection, 5.1.5
+func (t *Triangle3) closest(p V3) V3 {
+	a, b, c := t.V[0], t.V[1], t.V[2]
+	ab := b.Sub(a)
+	ac := c.Sub(a)
+	ap := p.Sub(a)
+	d1 := ab.Dot(ap)
+	d2 := ac.Dot(ap)
+	if d1 <= 0 && d2 <= 0 {
+		return a
+	}
+	bp := p.Sub(b)
+	d3 := ab.Dot(bp)
+	d4 := ac.Dot(bp)
+	if d3 >= 0 && d4 <= d3 {
+		return b
+	}
+	vc := d1*d4 - d3*d2
+	if vc <= 0 && d1 >= 0 && d3 <= 0 {
+		return a.Add(ab.MulScalar(d1 / (d1 - d3)))
+	}
+	cp := p.Sub(c)
+	d5 := ab.Dot(cp)
+	d6 := ac.Dot(cp)
+	if d6 >= 0 && d5 <= d6 {
+		return c
+	}
+	vb := d5*d2 - d1*d6
+	if vb <= 0 && d2 >= 0 && d6 <= 0 {
+		return a.Add(ac.MulScalar(d2 / (d2 - d6)))
+	}
+	va := d3*d6 - d5*d4
+	if va <= 0 && (d4-d3) >= 0 && (d5-d6) >= 0 {
+		return b.Add(c.Sub(b).MulScalar((d4 - d3) / ((d4 - d3) + (d5 - d6))))
+	}
+	denom := 1 / (va + vb + vc)
+	return a.Add(ab.MulScalar(vb * denom)).Add(ac.MulScalar(vc * denom))
+}
+
+// Return the z of the point on the triangle with the xy position q.
+// Return false if q is outside the xy projection of the triangle.
+func (t *Triangle3) z_crossing(q V2) (float64, bool) {
+	a, b, c := t.V[0], t.V[1], t.V[2]
+	det := (b.X-a.X)*(c.Y-a.Y) - (c.X-a.X)*(b.Y-a.Y)
+	if det == 0 {
+		// the triangle is parallel to the z axis
+		return 0, false
+	}
+	u := ((q.X-a.X)*(c.Y-a.Y) - (c.X-a.X)*(q.Y-a.Y)) / det
+	v := ((b.X-a.X)*(q.Y-a.Y) - (q.X-a.X)*(b.Y-a.Y)) / det
+	if u < 0 || v < 0 || u+v > 1 {
+		return 0, false
+	}
+	return a.Z + u*(b.Z-a.Z) + v*(c.Z-a.Z), true
+}
+
+//-----------------------------------------------------------------------------
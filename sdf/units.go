@@ -0,0 +1,123 @@
+//-----------------------------------------------------------------------------
+/*
+
+Angle and Length Units
+
+Typed angles and lengths so the units are written at the point of use:
+
+T(MM(6), Inch(0.25), 0).Rx(Deg(20))
+
+An Angle is stored in radians and a Length in millimeters, so a value can be
+converted to a float64 for the rest of the API with Radians() or MM().
+
+The transform builders (xform.go) take typed angles and lengths. Untyped
+constants are accepted as radians and millimeters, but a float64 variable
+needs an explicit conversion, so a value in the wrong units is less likely to
+slip through. The matrix constructors keep their float64 arguments, the typed
+versions are RotateAngle3d, RotateAngle2d, TranslateLength3d and
+TranslateLength2d.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "fmt"
+
+//-----------------------------------------------------------------------------
+// Angles
+
+type Angle float64 // radians
+
+// Deg returns an angle in degrees.
+func Deg(degrees float64) Angle {
+	return Angle(DtoR(degrees))
+}
+
+// Rad returns an angle in radians.
+func Rad(radians float64) Angle {
+	return Angle(radians)
+}
+
+// Radians returns the angle in radians.
+func (a Angle) Radians() float64 {
+	return float64(a)
+}
+
+// Degrees returns the angle in degrees.
+func (a Angle) Degrees() float64 {
+	return RtoD(float64(a))
+}
+
+func (a Angle) String() string {
+	return fmt.Sprintf("%g deg", a.Degrees())
+}
+
+//-----------------------------------------------------------------------------
+// Lengths
+
+type Length float64 // millimeters
+
+// MM returns a length in millimeters.
+func MM(mm float64) Length {
+	return Length(mm)
+}
+
+// CM returns a length in centimeters.
+func CM(cm float64) Length {
+	return Length(10 * cm)
+}
+
+// Inch returns a length in inches.
+func Inch(inches float64) Length {
+	return Length(MM_PER_INCH * inches)
+}
+
+// MM returns the length in millimeters.
+func (l Length) MM() float64 {
+	return float64(l)
+}
+
+// Inches returns the length in inches.
+func (l Length) Inches() float64 {
+	return float64(l) / MM_PER_INCH
+}
+
+func (l Length) String() string {
+	return fmt.Sprintf("%g mm", l.MM())
+}
+
+// V3L returns a 3d vector (millimeters) from lengths.
+func V3L(x, y, z Length) V3 {
+	return V3{x.MM(), y.MM(), z.MM()}
+}
+
+// V2L returns a 2d vector (millimeters) from lengths.
+func V2L(x, y Length) V2 {
+	return V2{x.MM(), y.MM()}
+}
+
+//-----------------------------------------------------------------------------
+// Typed Transforms
+
+// RotateAngle3d returns an orthographic 3d rotation matrix (right hand rule).
+func RotateAngle3d(v V3, a Angle) M44 {
+	return Rotate3d(v, a.Radians())
+}
+
+// RotateAngle2d returns an orthographic 2d rotation matrix (typed angle).
+func RotateAngle2d(a Angle) M33 {
+	return Rotate2d(a.Radians())
+}
+
+// TranslateLength3d returns a 3d translation matrix (typed lengths).
+func TranslateLength3d(x, y, z Length) M44 {
+	return Translate3d(V3L(x, y, z))
+}
+
+// TranslateLength2d returns a 2d translation matrix (typed lengths).
+func TranslateLength2d(x, y Length) M33 {
+	return Translate2d(V2L(x, y))
+}
+
+//-----------------------------------------------------------------------------
@@ -129,10 +129,22 @@ func ChamferMin(k float64) MinFunc {
 	}
 }
 
+// Angled Chamfer Minimum, makes a chamfered edge with the chamfer face at angle theta
+// to the surface of the first object. k is the width of the chamfer along that surface.
+func ChamferAngleMin(k, theta float64) MinFunc {
+	s := math.Sin(theta)
+	c := math.Cos(theta)
+	return func(a, b float64) float64 {
+		return Min(Min(a, b), a*c+b*s-k*s)
+	}
+}
+
 // Exponential Smooth Minimum (k = 32).
 func ExpMin(k float64) MinFunc {
 	return func(a, b float64) float64 {
-		return -math.Log(math.Exp(-k*a)+math.Exp(-k*b)) / k
+		// shift by the minimum to avoid overflow
+		m := Min(a, b)
+		return m - math.Log(math.Exp(-k*(a-m))+math.Exp(-k*(b-m)))/k
 	}
 }
 
@@ -170,6 +182,28 @@ func PolyMax(k float64) MaxFunc {
 	}
 }
 
+// Chamfer Maximum, makes a 45-degree chamfered edge.
+func ChamferMax(k float64) MaxFunc {
+	return ChamferAngleMax(k, PI/4)
+}
+
+// Angled Chamfer Maximum, see ChamferAngleMin.
+func ChamferAngleMax(k, theta float64) MaxFunc {
+	min := ChamferAngleMin(k, theta)
+	return func(a, b float64) float64 {
+		return -min(-a, -b)
+	}
+}
+
+// Exponential Smooth Maximum (k = 32).
+func ExpMax(k float64) MaxFunc {
+	return func(a, b float64) float64 {
+		// shift by the maximum to avoid overflow
+		m := Max(a, b)
+		return m + math.Log(math.Exp(k*(a-m))+math.Exp(k*(b-m)))/k
+	}
+}
+
 //-----------------------------------------------------------------------------
 // Extrude Functions: Map a V3 to V2 - the point used to evaluate the SDF2.
 
@@ -0,0 +1,227 @@
+//-----------------------------------------------------------------------------
+/*
+
+Distance Verification
+
+Cross check the distance returned by an SDF against a brute force distance.
+The boundary of the SDF is sampled densely: the SDF is evaluated on a grid
+and the zero crossing on each grid edge with a sign change is found by
+bisection. The brute force distance of a test point is the distance to the
+closest boundary sample, signed by the SDF.
+
+The brute force distance is only as good as the boundary sampling, errors
+smaller than the grid resolution don't mean much.
+
+For an exact SDF the maximum error should be around the resolution. A lot
+of operations (E.g. intersection, difference, warps, the inside of a union)
+return a bound on the distance. A bound should never be greater than the
+true distance, so the over estimate (SDF - true distance) is reported
+separately.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+//-----------------------------------------------------------------------------
+
+type DistanceCheck struct {
+	Name       string  // name of the SDF/operator
+	Resolution float64 // boundary sampling resolution
+	Boundary   int     // number of boundary samples
+	Samples    int     // number of test points
+	MaxError   float64 // maximum |SDF - true distance|
+	MaxOver    float64 // maximum SDF - true distance (> 0 is a bad bound)
+}
+
+func (c *DistanceCheck) String() string {
+	return fmt.Sprintf("%s: max error %.4f, max over %.4f (resolution %.4f, %d boundary, %d samples)",
+		c.Name, c.MaxError, c.MaxOver, c.Resolution, c.Boundary, c.Samples)
+}
+
+// Return the zero crossing on a segment by bisection (f(a) and f(b) have different signs).
+func bisect_zero(f func(t float64) float64) float64 {
+	t0, t1 := 0.0, 1.0
+	neg := f(t0) < 0
+	for i := 0; i < 24; i++ {
+		t := 0.5 * (t0 + t1)
+		if (f(t) < 0) == neg {
+			t0 = t
+		} else {
+			t1 = t
+		}
+	}
+	return 0.5 * (t0 + t1)
+}
+
+//-----------------------------------------------------------------------------
+
+// Return points on the boundary of an SDF3.
+func boundary3(s SDF3, bb Box3, step float64) []V3 {
+	size := bb.Size()
+	n := V3i{
+		int(math.Ceil(size.X/step)) + 1,
+		int(math.Ceil(size.Y/step)) + 1,
+		int(math.Ceil(size.Z/step)) + 1,
+	}
+	pos := func(i, j, k int) V3 {
+		return bb.Min.Add(V3{float64(i), float64(j), float64(k)}.MulScalar(step))
+	}
+	idx := func(i, j, k int) int {
+		return (k*n[1]+j)*n[0] + i
+	}
+	v := make([]float64, n[0]*n[1]*n[2])
+	for k := 0; k < n[2]; k++ {
+		for j := 0; j < n[1]; j++ {
+			for i := 0; i < n[0]; i++ {
+				v[idx(i, j, k)] = s.Evaluate(pos(i, j, k))
+			}
+		}
+	}
+	var points []V3
+	for k := 0; k < n[2]; k++ {
+		for j := 0; j < n[1]; j++ {
+			for i := 0; i < n[0]; i++ {
+				p0 := pos(i, j, k)
+				neg := v[idx(i, j, k)] < 0
+				for _, d := range []V3i{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}} {
+					i1, j1, k1 := i+d[0], j+d[1], k+d[2]
+					if i1 >= n[0] || j1 >= n[1] || k1 >= n[2] {
+						continue
+					}
+					if (v[idx(i1, j1, k1)] < 0) == neg {
+						continue
+					}
+					dp := pos(i1, j1, k1).Sub(p0)
+					t := bisect_zero(func(t float64) float64 {
+						return s.Evaluate(p0.Add(dp.MulScalar(t)))
+					})
+					points = append(points, p0.Add(dp.MulScalar(t)))
+				}
+			}
+		}
+	}
+	return points
+}
+
+// CheckDistance3D cross checks the distance of an SDF3 against a brute force distance.
+// cells = number of grid cells on the longest axis for the boundary sampling
+// samples = number of random test points
+func CheckDistance3D(name string, s SDF3, cells, samples int) *DistanceCheck {
+	if cells < 2 || samples < 1 {
+		panic("invalid cells/samples")
+	}
+	// enlarge the box so the whole boundary is sampled
+	bb := s.BoundingBox()
+	bb = bb.ScaleAboutCenter(1.2)
+	step := bb.Size().MaxComponent() / float64(cells)
+	boundary := boundary3(s, bb, step)
+	if len(boundary) == 0 {
+		panic("no boundary found")
+	}
+	c := DistanceCheck{Name: name, Resolution: step, Boundary: len(boundary), Samples: samples}
+	c.MaxOver = math.Inf(-1)
+	rnd := rand.New(rand.NewSource(1))
+	size := bb.Size()
+	for i := 0; i < samples; i++ {
+		p := bb.Min.Add(V3{rnd.Float64(), rnd.Float64(), rnd.Float64()}.Mul(size))
+		d2 := math.Inf(1)
+		for _, b := range boundary {
+			d2 = Min(d2, p.Sub(b).Length2())
+		}
+		d := s.Evaluate(p)
+		dt := math.Sqrt(d2)
+		if d < 0 {
+			dt = -dt
+		}
+		c.MaxError = Max(c.MaxError, Abs(d-dt))
+		// compare magnitudes so inside and outside are treated the same
+		c.MaxOver = Max(c.MaxOver, Abs(d)-Abs(dt))
+	}
+	return &c
+}
+
+//-----------------------------------------------------------------------------
+
+// Return points on the boundary of an SDF2.
+func boundary2(s SDF2, bb Box2, step float64) []V2 {
+	size := bb.Size()
+	n := V2i{
+		int(math.Ceil(size.X/step)) + 1,
+		int(math.Ceil(size.Y/step)) + 1,
+	}
+	pos := func(i, j int) V2 {
+		return bb.Min.Add(V2{float64(i), float64(j)}.MulScalar(step))
+	}
+	v := make([]float64, n[0]*n[1])
+	for j := 0; j < n[1]; j++ {
+		for i := 0; i < n[0]; i++ {
+			v[j*n[0]+i] = s.Evaluate(pos(i, j))
+		}
+	}
+	var points []V2
+	for j := 0; j < n[1]; j++ {
+		for i := 0; i < n[0]; i++ {
+			p0 := pos(i, j)
+			neg := v[j*n[0]+i] < 0
+			for _, d := range []V2i{{1, 0}, {0, 1}} {
+				i1, j1 := i+d[0], j+d[1]
+				if i1 >= n[0] || j1 >= n[1] {
+					continue
+				}
+				if (v[j1*n[0]+i1] < 0) == neg {
+					continue
+				}
+				dp := pos(i1, j1).Sub(p0)
+				t := bisect_zero(func(t float64) float64 {
+					return s.Evaluate(p0.Add(dp.MulScalar(t)))
+				})
+				points = append(points, p0.Add(dp.MulScalar(t)))
+			}
+		}
+	}
+	return points
+}
+
+// CheckDistance2D cross checks the distance of an SDF2 against a brute force distance.
+// cells = number of grid cells on the longest axis for the boundary sampling
+// samples = number of random test points
+func CheckDistance2D(name string, s SDF2, cells, samples int) *DistanceCheck {
+	if cells < 2 || samples < 1 {
+		panic("invalid cells/samples")
+	}
+	bb := s.BoundingBox()
+	bb = bb.ScaleAboutCenter(1.2)
+	step := bb.Size().MaxComponent() / float64(cells)
+	boundary := boundary2(s, bb, step)
+	if len(boundary) == 0 {
+		panic("no boundary found")
+	}
+	c := DistanceCheck{Name: name, Resolution: step, Boundary: len(boundary), Samples: samples}
+	c.MaxOver = math.Inf(-1)
+	rnd := rand.New(rand.NewSource(1))
+	size := bb.Size()
+	for i := 0; i < samples; i++ {
+		p := bb.Min.Add(V2{rnd.Float64(), rnd.Float64()}.Mul(size))
+		d2 := math.Inf(1)
+		for _, b := range boundary {
+			d2 = Min(d2, p.Sub(b).Length2())
+		}
+		d := s.Evaluate(p)
+		dt := math.Sqrt(d2)
+		if d < 0 {
+			dt = -dt
+		}
+		c.MaxError = Max(c.MaxError, Abs(d-dt))
+		c.MaxOver = Max(c.MaxOver, Abs(d)-Abs(dt))
+	}
+	return &c
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,286 @@
+//-----------------------------------------------------------------------------
+/*
+
+Narrow Band Voxels
+
+Sample an SDF3 on a regular lattice of voxels and keep the samples that are
+close to the surface. The result is an SDF3, so a voxelized solid can be used
+like any other SDF, and voxel grids can be combined with boolean operations
+that work on the samples directly. E.g. voxelize an expensive subtree once,
+or combine solids that only exist as samples.
+
+The lattice is global: voxel (i, j, k) is at (i, j, k) * cell, so grids with
+the same cell size line up and booleans are exact at the samples.
+
+The voxels are stored in blocks. A block that is entirely more than the band
+width from the surface isn't stored, it has a single distance (+/- band) that
+gives the inside/outside state. Within the band the distance is interpolated
+from the samples (exact at the samples). Outside the band the distance is
+clamped to the band, which is a lower bound.
+
+Booleans use min/max of the samples, so (like Union3D, etc.) the result is a
+bound on the distance near the intersection of the surfaces.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"runtime"
+	"sync"
+)
+
+//-----------------------------------------------------------------------------
+
+// number of voxels per block edge
+const VOXEL_BLOCK = 8
+
+// half width of the narrow band in voxels
+const VOXEL_BAND = 3
+
+type voxel_block [VOXEL_BLOCK * VOXEL_BLOCK * VOXEL_BLOCK]float64
+
+type VoxelSDF3 struct {
+	cell   float64        // voxel size
+	band   float64        // half width of the narrow band
+	lo     V3i            // first block
+	n      V3i            // number of blocks on each axis
+	blocks []*voxel_block // nil for blocks outside the band
+	fill   []float64      // distance for blocks outside the band
+	bb     Box3
+}
+
+// Voxelize3D returns a narrow band voxel grid for an SDF3.
+func Voxelize3D(s SDF3, cell float64) *VoxelSDF3 {
+	if cell <= 0 {
+		panic("invalid cell size")
+	}
+	v := new_voxels(cell, VOXEL_BAND*cell, s.BoundingBox())
+	// the distance from the center of a block to its furthest voxel
+	r := 0.5 * math.Sqrt(3) * float64(VOXEL_BLOCK-1) * cell
+	v.build(
+		func(b V3i) (float64, bool) {
+			d := s.Evaluate(v.block_center(b))
+			if Abs(d)-r > v.band {
+				return math.Copysign(v.band, d), true
+			}
+			return 0, false
+		},
+		func(i V3i) float64 {
+			return s.Evaluate(i.ToV3().MulScalar(cell))
+		},
+	)
+	return v
+}
+
+//-----------------------------------------------------------------------------
+
+// Return floor(a / b) for b > 0.
+func floor_div(a, b int) int {
+	q := a / b
+	if a%b < 0 {
+		q--
+	}
+	return q
+}
+
+// Return an empty voxel grid with blocks covering a bounding box.
+func new_voxels(cell, band float64, bb Box3) *VoxelSDF3 {
+	v := VoxelSDF3{cell: cell, band: band, bb: bb}
+	// pad the box so the edge blocks are outside the band
+	lo := bb.Min.SubScalar(band + cell).DivScalar(cell)
+	hi := bb.Max.AddScalar(band + cell).DivScalar(cell)
+	v.lo = V3i{
+		floor_div(int(math.Floor(lo.X)), VOXEL_BLOCK),
+		floor_div(int(math.Floor(lo.Y)), VOXEL_BLOCK),
+		floor_div(int(math.Floor(lo.Z)), VOXEL_BLOCK),
+	}
+	v.n = V3i{
+		floor_div(int(math.Ceil(hi.X)), VOXEL_BLOCK) - v.lo[0] + 1,
+		floor_div(int(math.Ceil(hi.Y)), VOXEL_BLOCK) - v.lo[1] + 1,
+		floor_div(int(math.Ceil(hi.Z)), VOXEL_BLOCK) - v.lo[2] + 1,
+	}
+	n := v.n[0] * v.n[1] * v.n[2]
+	v.blocks = make([]*voxel_block, n)
+	v.fill = make([]float64, n)
+	return &v
+}
+
+// Return the index of a block, or -1 if it's outside the grid.
+func (v *VoxelSDF3) block_index(b V3i) int {
+	j := V3i{b[0] - v.lo[0], b[1] - v.lo[1], b[2] - v.lo[2]}
+	for i := 0; i < 3; i++ {
+		if j[i] < 0 || j[i] >= v.n[i] {
+			return -1
+		}
+	}
+	return j[0] + v.n[0]*(j[1]+v.n[1]*j[2])
+}
+
+// Return the block for block coordinates, or the fill distance if it isn't stored.
+func (v *VoxelSDF3) block(b V3i) (*voxel_block, float64) {
+	k := v.block_index(b)
+	if k < 0 {
+		return nil, v.band
+	}
+	return v.blocks[k], v.fill[k]
+}
+
+// Return the center of a block.
+func (v *VoxelSDF3) block_center(b V3i) V3 {
+	return b.ToV3().MulScalar(VOXEL_BLOCK).AddScalar(0.5 * (VOXEL_BLOCK - 1)).MulScalar(v.cell)
+}
+
+// Return the distance at a voxel.
+func (v *VoxelSDF3) voxel(i V3i) float64 {
+	b := V3i{floor_div(i[0], VOXEL_BLOCK), floor_div(i[1], VOXEL_BLOCK), floor_div(i[2], VOXEL_BLOCK)}
+	blk, fill := v.block(b)
+	if blk == nil {
+		return fill
+	}
+	x := i[0] - b[0]*VOXEL_BLOCK
+	y := i[1] - b[1]*VOXEL_BLOCK
+	z := i[2] - b[2]*VOXEL_BLOCK
+	return blk[x+VOXEL_BLOCK*(y+VOXEL_BLOCK*z)]
+}
+
+// Build the voxel blocks. The fill function returns the distance for blocks
+// outside the band, or false if the block should be sampled with f.
+func (v *VoxelSDF3) build(fill func(b V3i) (float64, bool), f func(i V3i) float64) {
+	blocks := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range blocks {
+				b := V3i{k % v.n[0], (k / v.n[0]) % v.n[1], k / (v.n[0] * v.n[1])}
+				b = b.Add(v.lo)
+				if d, ok := fill(b); ok {
+					v.fill[k] = d
+					continue
+				}
+				v.blocks[k], v.fill[k] = v.sample_block(b, f)
+			}
+		}()
+	}
+	for k := range v.blocks {
+		blocks <- k
+	}
+	close(blocks)
+	wg.Wait()
+}
+
+// Sample a block. Return nil and the fill distance if all the samples are
+// outside the band on the same side of the surface.
+func (v *VoxelSDF3) sample_block(b V3i, f func(i V3i) float64) (*voxel_block, float64) {
+	var blk voxel_block
+	i0 := V3i{b[0] * VOXEL_BLOCK, b[1] * VOXEL_BLOCK, b[2] * VOXEL_BLOCK}
+	lo, hi := math.MaxFloat64, -math.MaxFloat64
+	for z := 0; z < VOXEL_BLOCK; z++ {
+		for y := 0; y < VOXEL_BLOCK; y++ {
+			for x := 0; x < VOXEL_BLOCK; x++ {
+				d := Clamp(f(i0.Add(V3i{x, y, z})), -v.band, v.band)
+				blk[x+VOXEL_BLOCK*(y+VOXEL_BLOCK*z)] = d
+				lo = Min(lo, d)
+				hi = Max(hi, d)
+			}
+		}
+	}
+	if lo == hi && Abs(lo) == v.band {
+		return nil, lo
+	}
+	return &blk, 0
+}
+
+//-----------------------------------------------------------------------------
+
+// Evaluate returns the interpolated distance to the voxelized surface.
+func (v *VoxelSDF3) Evaluate(p V3) float64 {
+	// the sample lattice covered by the grid
+	s := v.cell * VOXEL_BLOCK
+	lo := v.lo.ToV3().MulScalar(s)
+	hi := v.lo.Add(v.n).ToV3().MulScalar(s).SubScalar(v.cell)
+	q := p.Max(lo).Min(hi)
+	// lattice coordinates
+	g := q.DivScalar(v.cell)
+	i := V3i{int(math.Floor(g.X)), int(math.Floor(g.Y)), int(math.Floor(g.Z))}
+	u := g.Sub(i.ToV3())
+	lerp := func(a, b, t float64) float64 { return a + t*(b-a) }
+	x00 := lerp(v.voxel(i), v.voxel(i.Add(V3i{1, 0, 0})), u.X)
+	x10 := lerp(v.voxel(i.Add(V3i{0, 1, 0})), v.voxel(i.Add(V3i{1, 1, 0})), u.X)
+	x01 := lerp(v.voxel(i.Add(V3i{0, 0, 1})), v.voxel(i.Add(V3i{1, 0, 1})), u.X)
+	x11 := lerp(v.voxel(i.Add(V3i{0, 1, 1})), v.voxel(i.Add(V3i{1, 1, 1})), u.X)
+	d := lerp(lerp(x00, x10, u.Y), lerp(x01, x11, u.Y), u.Z)
+	if q == p {
+		return d
+	}
+	// outside the grid: the edge blocks are outside the band
+	x := p.Sub(q).Length()
+	return Max(x+v.band, d-x)
+}
+
+// BoundingBox returns the bounding box of the voxelized solid.
+func (v *VoxelSDF3) BoundingBox() Box3 {
+	return v.bb
+}
+
+// Cell returns the voxel size.
+func (v *VoxelSDF3) Cell() float64 {
+	return v.cell
+}
+
+// Blocks returns the number of stored (narrow band) blocks and the total number of blocks.
+func (v *VoxelSDF3) Blocks() (int, int) {
+	n := 0
+	for _, b := range v.blocks {
+		if b != nil {
+			n++
+		}
+	}
+	return n, len(v.blocks)
+}
+
+//-----------------------------------------------------------------------------
+// Voxel Booleans
+
+// Combine two voxel grids with an operation on the samples.
+func voxel_op(a, b *VoxelSDF3, bb Box3, op func(x, y float64) float64) *VoxelSDF3 {
+	if a.cell != b.cell {
+		panic("voxel grids have different cell sizes")
+	}
+	v := new_voxels(a.cell, Min(a.band, b.band), bb)
+	v.build(
+		func(i V3i) (float64, bool) {
+			ka, fa := a.block(i)
+			kb, fb := b.block(i)
+			if ka == nil && kb == nil {
+				return Clamp(op(fa, fb), -v.band, v.band), true
+			}
+			return 0, false
+		},
+		func(i V3i) float64 {
+			return op(a.voxel(i), b.voxel(i))
+		},
+	)
+	return v
+}
+
+// VoxelUnion3D returns the union of two voxel grids.
+func VoxelUnion3D(a, b *VoxelSDF3) *VoxelSDF3 {
+	return voxel_op(a, b, a.bb.Extend(b.bb), Min)
+}
+
+// VoxelDifference3D returns the difference of two voxel grids (a - b).
+func VoxelDifference3D(a, b *VoxelSDF3) *VoxelSDF3 {
+	return voxel_op(a, b, a.bb, func(x, y float64) float64 { return Max(x, -y) })
+}
+
+// VoxelIntersect3D returns the intersection of two voxel grids.
+func VoxelIntersect3D(a, b *VoxelSDF3) *VoxelSDF3 {
+	return voxel_op(a, b, a.bb, Max)
+}
+
+//-----------------------------------------------------------------------------
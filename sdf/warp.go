@@ -0,0 +1,241 @@
+//-----------------------------------------------------------------------------
+/*
+
+Space Warps
+
+Operators that deform space: the point is mapped back into the space of the
+original object before it is evaluated.
+
+Bend: The x-axis is wrapped around a circle in the XZ plane. The bend axis is
+parallel to the y-axis at z = radius. A +ve radius bends the ends of the
+object up, a -ve radius bends them down. Distances along the x-axis are kept
+at z = 0 (E.g. a flat plate keeps its length at the bottom face).
+
+Taper: The XY cross section is scaled linearly over the z extent of the
+object, 1 at the bottom and scale at the top.
+
+Shear: x (and y) are moved in proportion to z (2D: x in proportion to y).
+E.g. italic text, parallelogram gussets, dovetails. The distance is divided
+by the largest stretch of the shear so it is a bound.
+
+The warps don't preserve distance. The distance is scaled by the local
+stretch of the warp so it is a reasonable bound, but it is not exact.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "math"
+
+//-----------------------------------------------------------------------------
+// Bend
+
+type BendSDF3 struct {
+	sdf    SDF3
+	radius float64 // bend radius (+ve)
+	flip   bool    // bend down
+	bb     Box3
+}
+
+// Bend3D returns an SDF3 bent about an axis parallel to the y-axis.
+func Bend3D(sdf SDF3, radius float64) SDF3 {
+	if radius == 0 {
+		panic("invalid bend radius")
+	}
+	s := BendSDF3{}
+	s.sdf = sdf
+	s.radius = Abs(radius)
+	s.flip = radius < 0
+	bb := sdf.BoundingBox()
+	if s.flip {
+		bb = MirrorXY().MulBox(bb)
+	}
+	r := s.radius
+	if bb.Max.Z >= r {
+		panic("object is too thick for the bend radius")
+	}
+	if Max(-bb.Min.X, bb.Max.X) >= PI*r {
+		panic("object is too long for the bend radius")
+	}
+	// the extremes of the bent box are at the ends and the quadrant angles
+	a0 := bb.Min.X / r
+	a1 := bb.Max.X / r
+	angles := []float64{a0, a1}
+	for _, a := range []float64{-0.5 * PI, 0, 0.5 * PI} {
+		if a > a0 && a < a1 {
+			angles = append(angles, a)
+		}
+	}
+	var bb_min, bb_max V3
+	for i, a := range angles {
+		for j, z := range []float64{bb.Min.Z, bb.Max.Z} {
+			p := s.bend(V3{a * r, 0, z})
+			if i == 0 && j == 0 {
+				bb_min, bb_max = p, p
+			}
+			bb_min = bb_min.Min(p)
+			bb_max = bb_max.Max(p)
+		}
+	}
+	bb = Box3{V3{bb_min.X, bb.Min.Y, bb_min.Z}, V3{bb_max.X, bb.Max.Y, bb_max.Z}}
+	if s.flip {
+		bb = MirrorXY().MulBox(bb)
+	}
+	s.bb = bb
+	return &s
+}
+
+// Return the bent position of an unbent point (radius > 0).
+func (s *BendSDF3) bend(p V3) V3 {
+	a := p.X / s.radius
+	r := s.radius - p.Z
+	return V3{r * math.Sin(a), p.Y, s.radius - r*math.Cos(a)}
+}
+
+// Evaluate returns the minimum distance to a bent SDF3.
+func (s *BendSDF3) Evaluate(p V3) float64 {
+	if s.flip {
+		p.Z = -p.Z
+	}
+	// unbend the point
+	dz := s.radius - p.Z
+	r := math.Sqrt(p.X*p.X + dz*dz)
+	q := V3{s.radius * math.Atan2(p.X, dz), p.Y, s.radius - r}
+	if s.flip {
+		q.Z = -q.Z
+	}
+	// scale by the stretch along the bend
+	return s.sdf.Evaluate(q) * Min(1, r/s.radius)
+}
+
+// BoundingBox returns the bounding box of a bent SDF3.
+func (s *BendSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+// Taper
+
+type TaperSDF3 struct {
+	sdf    SDF3
+	z0     float64 // bottom of the taper
+	height float64 // height of the taper
+	scale  float64 // scale at the top
+	bb     Box3
+}
+
+// Taper3D returns an SDF3 with the XY cross section scaled linearly along z.
+// The scale is 1 at the bottom of the bounding box and scale at the top.
+func Taper3D(sdf SDF3, scale float64) SDF3 {
+	if scale <= 0 {
+		panic("invalid taper scale")
+	}
+	s := TaperSDF3{}
+	s.sdf = sdf
+	s.scale = scale
+	bb := sdf.BoundingBox()
+	s.z0 = bb.Min.Z
+	s.height = bb.Max.Z - bb.Min.Z
+	if s.height <= 0 {
+		panic("object has no height to taper")
+	}
+	k := Max(1, scale)
+	s.bb = Box3{V3{bb.Min.X * k, bb.Min.Y * k, bb.Min.Z}, V3{bb.Max.X * k, bb.Max.Y * k, bb.Max.Z}}
+	return &s
+}
+
+// Evaluate returns the minimum distance to a tapered SDF3.
+func (s *TaperSDF3) Evaluate(p V3) float64 {
+	t := Clamp((p.Z-s.z0)/s.height, 0, 1)
+	k := Mix(1, s.scale, t)
+	q := V3{p.X / k, p.Y / k, p.Z}
+	// the slope of the sides adds to the stretch
+	g := Abs(s.scale-1) / s.height * V2{q.X, q.Y}.Length()
+	return s.sdf.Evaluate(q) * Min(1, k) / math.Sqrt(1+g*g)
+}
+
+// BoundingBox returns the bounding box of a tapered SDF3.
+func (s *TaperSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+// Shear
+
+// Return the largest singular value of a shear with shear factor k.
+func shear_stretch(k float64) float64 {
+	return 0.5 * (k + math.Sqrt(k*k+4))
+}
+
+type ShearSDF3 struct {
+	sdf SDF3
+	k   V2      // shear factors
+	s   float64 // largest stretch
+	bb  Box3
+}
+
+// Shear3D returns a sheared SDF3, x += k.X * z and y += k.Y * z.
+func Shear3D(sdf SDF3, k V2) SDF3 {
+	s := ShearSDF3{}
+	s.sdf = sdf
+	s.k = k
+	s.s = shear_stretch(k.Length())
+	bb := sdf.BoundingBox()
+	v := bb.Vertices()
+	bb_min, bb_max := s.shear(v[0]), s.shear(v[0])
+	for _, p := range v {
+		bb_min = bb_min.Min(s.shear(p))
+		bb_max = bb_max.Max(s.shear(p))
+	}
+	s.bb = Box3{bb_min, bb_max}
+	return &s
+}
+
+// Return the sheared position of a point.
+func (s *ShearSDF3) shear(p V3) V3 {
+	return V3{p.X + s.k.X*p.Z, p.Y + s.k.Y*p.Z, p.Z}
+}
+
+// Evaluate returns the minimum distance to a sheared SDF3.
+func (s *ShearSDF3) Evaluate(p V3) float64 {
+	q := V3{p.X - s.k.X*p.Z, p.Y - s.k.Y*p.Z, p.Z}
+	return s.sdf.Evaluate(q) / s.s
+}
+
+// BoundingBox returns the bounding box of a sheared SDF3.
+func (s *ShearSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+type ShearSDF2 struct {
+	sdf SDF2
+	k   float64 // shear factor
+	s   float64 // largest stretch
+	bb  Box2
+}
+
+// Shear2D returns a sheared SDF2, x += k * y.
+func Shear2D(sdf SDF2, k float64) SDF2 {
+	s := ShearSDF2{}
+	s.sdf = sdf
+	s.k = k
+	s.s = shear_stretch(Abs(k))
+	bb := sdf.BoundingBox()
+	x0 := V2{bb.Min.X + k*bb.Min.Y, bb.Min.X + k*bb.Max.Y}
+	x1 := V2{bb.Max.X + k*bb.Min.Y, bb.Max.X + k*bb.Max.Y}
+	s.bb = Box2{V2{x0.MinComponent(), bb.Min.Y}, V2{x1.MaxComponent(), bb.Max.Y}}
+	return &s
+}
+
+// Evaluate returns the minimum distance to a sheared SDF2.
+func (s *ShearSDF2) Evaluate(p V2) float64 {
+	return s.sdf.Evaluate(V2{p.X - s.k*p.Y, p.Y}) / s.s
+}
+
+// BoundingBox returns the bounding box of a sheared SDF2.
+func (s *ShearSDF2) BoundingBox() Box2 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,199 @@
+//-----------------------------------------------------------------------------
+/*
+
+Transform Builders
+
+Build a transform with a chain of operations rather than nesting matrix
+products:
+
+T(0, 0, MM(10)).Rx(Deg(20)).Scale(2).Apply(s)
+
+is the same as
+
+Transform3D(ScaleUniform3D(s, 2), Translate3d(V3{0, 0, 10}).Mul(RotateX(DtoR(20))))
+
+The operations are matrix products in the order written, so like nested
+function calls they apply to the object from right to left: the object is
+scaled, then rotated, then translated.
+
+Scaling is uniform, so the transformed SDF is still a distance function.
+The builders are values, a partial chain can be reused.
+
+Translations and rotations take typed lengths and angles (units.go).
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+//-----------------------------------------------------------------------------
+// 3D
+
+type Xform3 struct {
+	m M44     // transform (including the scaling)
+	k float64 // uniform scale
+}
+
+// NewXform3 returns an identity 3d transform.
+func NewXform3() Xform3 {
+	return Xform3{Identity3d(), 1}
+}
+
+// T returns a 3d translation.
+func T(x, y, z Length) Xform3 {
+	return NewXform3().T(x, y, z)
+}
+
+// Return the transform followed by a matrix (applied to the object first).
+func (x Xform3) mul(m M44) Xform3 {
+	return Xform3{x.m.Mul(m), x.k}
+}
+
+// T translates by x, y, z.
+func (x Xform3) T(dx, dy, dz Length) Xform3 {
+	return x.mul(Translate3d(V3L(dx, dy, dz)))
+}
+
+// Translate translates by a vector.
+func (x Xform3) Translate(v V3) Xform3 {
+	return x.mul(Translate3d(v))
+}
+
+// Rx rotates about the x-axis.
+func (x Xform3) Rx(a Angle) Xform3 {
+	return x.mul(RotateX(a.Radians()))
+}
+
+// Ry rotates about the y-axis.
+func (x Xform3) Ry(a Angle) Xform3 {
+	return x.mul(RotateY(a.Radians()))
+}
+
+// Rz rotates about the z-axis.
+func (x Xform3) Rz(a Angle) Xform3 {
+	return x.mul(RotateZ(a.Radians()))
+}
+
+// RxDeg rotates about the x-axis (degrees).
+func (x Xform3) RxDeg(a float64) Xform3 {
+	return x.Rx(Deg(a))
+}
+
+// RyDeg rotates about the y-axis (degrees).
+func (x Xform3) RyDeg(a float64) Xform3 {
+	return x.Ry(Deg(a))
+}
+
+// RzDeg rotates about the z-axis (degrees).
+func (x Xform3) RzDeg(a float64) Xform3 {
+	return x.Rz(Deg(a))
+}
+
+// Rotate rotates about an axis.
+func (x Xform3) Rotate(axis V3, a Angle) Xform3 {
+	return x.mul(Rotate3d(axis, a.Radians()))
+}
+
+// Mirror mirrors across a plane (a point on the plane and the plane normal).
+func (x Xform3) Mirror(p, n V3) Xform3 {
+	return x.mul(MirrorPlane3d(p, n))
+}
+
+// Scale scales uniformly.
+func (x Xform3) Scale(k float64) Xform3 {
+	if k <= 0 {
+		panic("invalid scale")
+	}
+	y := x.mul(Scale3d(V3{k, k, k}))
+	y.k *= k
+	return y
+}
+
+// Matrix returns the transform matrix.
+func (x Xform3) Matrix() M44 {
+	return x.m
+}
+
+// Apply returns the transformed SDF3.
+func (x Xform3) Apply(s SDF3) SDF3 {
+	if x.k == 1 {
+		return Transform3D(s, x.m)
+	}
+	// A uniform scale commutes with the rotations, so scale first and the
+	// rest of the transform is distance preserving.
+	return Transform3D(ScaleUniform3D(s, x.k), x.m.Mul(Scale3d(V3{1 / x.k, 1 / x.k, 1 / x.k})))
+}
+
+//-----------------------------------------------------------------------------
+// 2D
+
+type Xform2 struct {
+	m M33     // transform (including the scaling)
+	k float64 // uniform scale
+}
+
+// NewXform2 returns an identity 2d transform.
+func NewXform2() Xform2 {
+	return Xform2{Identity2d(), 1}
+}
+
+// T2 returns a 2d translation.
+func T2(x, y Length) Xform2 {
+	return NewXform2().T(x, y)
+}
+
+// Return the transform followed by a matrix (applied to the object first).
+func (x Xform2) mul(m M33) Xform2 {
+	return Xform2{x.m.Mul(m), x.k}
+}
+
+// T translates by x, y.
+func (x Xform2) T(dx, dy Length) Xform2 {
+	return x.mul(Translate2d(V2L(dx, dy)))
+}
+
+// Translate translates by a vector.
+func (x Xform2) Translate(v V2) Xform2 {
+	return x.mul(Translate2d(v))
+}
+
+// R rotates about the origin.
+func (x Xform2) R(a Angle) Xform2 {
+	return x.mul(Rotate2d(a.Radians()))
+}
+
+// RDeg rotates about the origin (degrees).
+func (x Xform2) RDeg(a float64) Xform2 {
+	return x.R(Deg(a))
+}
+
+// Mirror mirrors across a line (a point on the line and the line normal).
+func (x Xform2) Mirror(p, n V2) Xform2 {
+	return x.mul(MirrorLine2d(p, n))
+}
+
+// Scale scales uniformly.
+func (x Xform2) Scale(k float64) Xform2 {
+	if k <= 0 {
+		panic("invalid scale")
+	}
+	y := x.mul(Scale2d(V2{k, k}))
+	y.k *= k
+	return y
+}
+
+// Matrix returns the transform matrix.
+func (x Xform2) Matrix() M33 {
+	return x.m
+}
+
+// Apply returns the transformed SDF2.
+func (x Xform2) Apply(s SDF2) SDF2 {
+	if x.k == 1 {
+		return Transform2D(s, x.m)
+	}
+	// see Xform3.Apply
+	return Transform2D(ScaleUniform2D(s, x.k), x.m.Mul(Scale2d(V2{1 / x.k, 1 / x.k})))
+}
+
+//-----------------------------------------------------------------------------